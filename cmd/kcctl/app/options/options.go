@@ -401,4 +401,6 @@ func AddFlagsToSSH(ssh *sshutils.SSH, flags *pflag.FlagSet) {
 	flags.StringVar(&ssh.Password, "passwd", ssh.Password, "Deploy ssh password")
 	flags.StringVar(&ssh.PkFile, "pk-file", ssh.PkFile, "ssh pk file which used to remote access other agent nodes")
 	flags.StringVar(&ssh.PkPassword, "pk-passwd", ssh.PkPassword, "the password of the ssh pk file which used to remote access other agent nodes")
+	flags.IntVar(&ssh.Port, "ssh-port", ssh.ConnectPort(), "ssh port of nodes, independent of the ports of services on them")
+	flags.StringVar(&ssh.SudoCmd, "sudo-cmd", ssh.SudoCommand(), "privilege escalation command of the non-root ssh user, e.g. a sudo at non-standard path, or doas which must permit the user with nopass since only sudo reads --passwd from stdin")
 }
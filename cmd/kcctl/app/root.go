@@ -30,6 +30,8 @@ import (
 
 	"github.com/kubeclipper/kubeclipper/pkg/cli/drain"
 
+	"github.com/kubeclipper/kubeclipper/pkg/cli/cordon"
+
 	"github.com/kubeclipper/kubeclipper/pkg/cli/join"
 
 	"github.com/kubeclipper/kubeclipper/pkg/cli/create"
@@ -86,6 +88,8 @@ func NewKubeClipperCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 	cmds.AddCommand(version.NewCmdVersion(ioStreams))
 	cmds.AddCommand(join.NewCmdJoin(ioStreams))
 	cmds.AddCommand(drain.NewCmdDrain(ioStreams))
+	cmds.AddCommand(cordon.NewCmdCordon(ioStreams))
+	cmds.AddCommand(cordon.NewCmdUncordon(ioStreams))
 	cmds.AddCommand(registry.NewCmdRegistry(ioStreams))
 	cmds.AddCommand(resource.NewCmdResource(ioStreams))
 	cmds.AddCommand(completion.NewCmdCompletion(ioStreams.Out))
@@ -19,6 +19,8 @@
 package options
 
 import (
+	"fmt"
+
 	cliflag "k8s.io/component-base/cli/flag"
 
 	"github.com/kubeclipper/kubeclipper/pkg/agent"
@@ -44,6 +46,13 @@ func (s *AgentOptions) Validate() []error {
 	errors = append(errors, s.LogOptions.Validate()...)
 	errors = append(errors, s.OpLogOptions.Validate()...)
 	errors = append(errors, s.ImageProxyOptions.Validate()...)
+	errors = append(errors, s.RegisterBackoff.Validate()...)
+	if s.MaxConcurrentOperations < 1 {
+		errors = append(errors, fmt.Errorf("max concurrent operations must be greater than or equal to 1"))
+	}
+	if s.NodeStatusUpdateJitter < 0 || s.NodeStatusUpdateJitter >= 1 {
+		errors = append(errors, fmt.Errorf("node status update jitter must be in [0, 1)"))
+	}
 	return errors
 }
 
@@ -19,6 +19,10 @@
 package agent
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/kubeclipper/kubeclipper/pkg/agent/config"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	"github.com/kubeclipper/kubeclipper/pkg/oplog"
@@ -32,16 +36,29 @@ type Server struct {
 }
 
 func (s *Server) PrepareRun(stopCh <-chan struct{}) error {
+	if err := Preflight(); err != nil {
+		if s.Config.StrictPreflight {
+			return err
+		}
+		logger.Warn(err.Error())
+	}
 	opLog, err := oplog.NewOperationLog(s.Config.OpLogOptions)
 	if err != nil {
 		return err
 	}
-	s.taskService = task.NewService(s.Config.AgentID, s.Config.MetaData.Region, s.Config.IPDetect, s.Config.RegisterNode, s.Config.MQOptions,
+	opts := []task.ServiceOption{
 		task.WithNodeStatusUpdateFrequency(s.Config.NodeStatusUpdateFrequency),
+		task.WithNodeStatusUpdateJitter(s.Config.NodeStatusUpdateJitter),
 		task.WithLeaseDurationSeconds(240),
 		task.WithOplog(opLog),
-		task.WithRepoMirror(s.Config.ImageProxyOptions.KcImageRepoMirror),
-	)
+		task.WithRepoMirrors(s.Config.ImageProxyOptions.Mirrors()),
+		task.WithRuntimeErrors(Preflight),
+		task.WithMaxConcurrentOperations(s.Config.MaxConcurrentOperations),
+	}
+	if b := s.Config.RegisterBackoff; b != nil {
+		opts = append(opts, task.WithRegisterBackoff(b.Duration, b.Cap, b.Factor, b.MaxRetries))
+	}
+	s.taskService = task.NewService(s.Config.AgentID, s.Config.MetaData.Region, s.Config.IPDetect, s.Config.RegisterNode, s.Config.MQOptions, opts...)
 	return s.taskService.PrepareRun(stopCh)
 }
 
@@ -49,8 +66,40 @@ func (s *Server) Run(stopCh <-chan struct{}) error {
 	if err := s.taskService.Run(stopCh); err != nil {
 		return err
 	}
+	s.handleDrainSignals(stopCh)
 	<-stopCh
 	logger.Debugf("get stopCh signal, exit...")
 	s.taskService.Close()
 	return nil
 }
+
+// drainer is implemented by services which can stop accepting new tasks before maintenance.
+type drainer interface {
+	Drain()
+	Uncordon()
+}
+
+// handleDrainSignals drains the agent on SIGUSR1 and uncordons it on SIGUSR2.
+func (s *Server) handleDrainSignals(stopCh <-chan struct{}) {
+	d, ok := s.taskService.(drainer)
+	if !ok {
+		return
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-stopCh:
+				return
+			case sig := <-sigCh:
+				if sig == syscall.SIGUSR1 {
+					d.Drain()
+				} else {
+					d.Uncordon()
+				}
+			}
+		}
+	}()
+}
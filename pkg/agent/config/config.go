@@ -19,6 +19,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"strings"
@@ -58,6 +59,55 @@ type Config struct {
 	MQOptions                 *natsio.NatsOptions `json:"mq,omitempty" yaml:"mq,omitempty"  mapstructure:"mq"`
 	OpLogOptions              *oplog.Options      `json:"oplog,omitempty" yaml:"oplog,omitempty" mapstructure:"oplog"`
 	ImageProxyOptions         *imageproxy.Options `json:"imageProxy,omitempty" yaml:"imageProxy,omitempty" mapstructure:"imageProxy"`
+	RegisterBackoff           *RegisterBackoff    `json:"registerBackoff,omitempty" yaml:"registerBackoff,omitempty" mapstructure:"registerBackoff"`
+	// NodeStatusUpdateJitter is the fraction of NodeStatusUpdateFrequency each node status update is shifted by at random,
+	// so that the heartbeats of many agents don't synchronize, 0 disables the jitter.
+	NodeStatusUpdateJitter float64 `json:"nodeStatusUpdateJitter,omitempty" yaml:"nodeStatusUpdateJitter,omitempty" mapstructure:"nodeStatusUpdateJitter"`
+	// MaxConcurrentOperations is the maximum number of operations run at once, the rest are queued.
+	MaxConcurrentOperations int `json:"maxConcurrentOperations,omitempty" yaml:"maxConcurrentOperations,omitempty" mapstructure:"maxConcurrentOperations"`
+	// StrictPreflight makes the agent fail to start when required binaries are missing, instead of only warning.
+	StrictPreflight bool `json:"strictPreflight,omitempty" yaml:"strictPreflight,omitempty" mapstructure:"strictPreflight"`
+}
+
+// RegisterBackoff defines how the agent retries registering itself at startup.
+type RegisterBackoff struct {
+	// Duration is the initial retry interval.
+	Duration time.Duration `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// Factor multiplies the interval after each attempt.
+	Factor float64 `json:"factor,omitempty" yaml:"factor,omitempty"`
+	// Cap is the maximum retry interval.
+	Cap time.Duration `json:"cap,omitempty" yaml:"cap,omitempty"`
+	// MaxRetries is the number of attempts before giving up.
+	MaxRetries int `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+}
+
+func NewRegisterBackoff() *RegisterBackoff {
+	return &RegisterBackoff{
+		Duration:   time.Second,
+		Factor:     2,
+		Cap:        30 * time.Second,
+		MaxRetries: 20,
+	}
+}
+
+func (b *RegisterBackoff) Validate() []error {
+	var errs []error
+	if b == nil {
+		return errs
+	}
+	if b.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("register backoff duration must be greater than 0"))
+	}
+	if b.Factor < 1 {
+		errs = append(errs, fmt.Errorf("register backoff factor must be greater than or equal to 1"))
+	}
+	if b.Cap < b.Duration {
+		errs = append(errs, fmt.Errorf("register backoff cap must be greater than or equal to duration"))
+	}
+	if b.MaxRetries <= 0 {
+		errs = append(errs, fmt.Errorf("register backoff max retries must be greater than 0"))
+	}
+	return errs
 }
 
 type MetaData struct {
@@ -75,11 +125,14 @@ func New() *Config {
 	return &Config{
 		RegisterNode:              true,
 		NodeStatusUpdateFrequency: 5 * time.Minute,
+		NodeStatusUpdateJitter:    0.1,
 		LogOptions:                logger.NewLogOptions(),
 		MQOptions:                 natsio.NewOptions(),
 		DownloaderOptions:         downloader.NewOptions(),
 		OpLogOptions:              oplog.NewOptions(),
 		ImageProxyOptions:         imageproxy.NewOptions(),
+		RegisterBackoff:           NewRegisterBackoff(),
+		MaxConcurrentOperations:   4,
 	}
 }
 
@@ -0,0 +1,83 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// preflight holds the checks Preflight runs; the probes are injectable for tests.
+type preflight struct {
+	// binaries must all be found in PATH.
+	binaries []string
+	// anyOf are groups of which at least one binary must be found in PATH.
+	anyOf [][]string
+	// paths must all exist.
+	paths    []string
+	lookPath func(file string) (string, error)
+	stat     func(name string) (os.FileInfo, error)
+}
+
+// defaultPreflight covers the tools and paths the agent uses during operations.
+// The container runtime is satisfied by any of docker, containerd or ctr.
+var defaultPreflight = preflight{
+	binaries: []string{"bash", "tar", "systemctl", "cp", "rm", "mkdir"},
+	anyOf:    [][]string{{"docker", "containerd", "ctr"}},
+	paths:    []string{"/etc/systemd/system"},
+	lookPath: exec.LookPath,
+	stat:     os.Stat,
+}
+
+// Preflight probes the required binaries and paths, and returns an error listing the missing ones.
+func Preflight() error {
+	return defaultPreflight.run()
+}
+
+func (p preflight) run() error {
+	var missing []string
+	for _, bin := range p.binaries {
+		if _, err := p.lookPath(bin); err != nil {
+			missing = append(missing, bin)
+		}
+	}
+	for _, group := range p.anyOf {
+		found := false
+		for _, bin := range group {
+			if _, err := p.lookPath(bin); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, strings.Join(group, "|"))
+		}
+	}
+	for _, path := range p.paths {
+		if _, err := p.stat(path); err != nil {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight check failed, missing required binaries or paths: %s", strings.Join(missing, ", "))
+}
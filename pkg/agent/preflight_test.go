@@ -0,0 +1,103 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package agent
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func fakePreflight(bins, paths []string) preflight {
+	p := defaultPreflight
+	p.lookPath = func(file string) (string, error) {
+		for _, b := range bins {
+			if b == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", os.ErrNotExist
+	}
+	p.stat = func(name string) (os.FileInfo, error) {
+		for _, s := range paths {
+			if s == name {
+				return nil, nil
+			}
+		}
+		return nil, os.ErrNotExist
+	}
+	return p
+}
+
+func TestPreflight(t *testing.T) {
+	base := []string{"bash", "tar", "systemctl", "cp", "rm", "mkdir"}
+	paths := []string{"/etc/systemd/system"}
+	tests := []struct {
+		name    string
+		bins    []string
+		paths   []string
+		missing []string
+	}{
+		{
+			name:  "docker runtime",
+			bins:  append(base, "docker"),
+			paths: paths,
+		},
+		{
+			name:  "containerd runtime",
+			bins:  append(base, "containerd"),
+			paths: paths,
+		},
+		{
+			name:  "ctr only",
+			bins:  append(base, "ctr"),
+			paths: paths,
+		},
+		{
+			name:    "no container runtime",
+			bins:    base,
+			paths:   paths,
+			missing: []string{"docker|containerd|ctr"},
+		},
+		{
+			name:    "missing binary and path",
+			bins:    []string{"bash", "tar", "systemctl", "cp", "rm", "docker"},
+			missing: []string{"mkdir", "/etc/systemd/system"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fakePreflight(tt.bins, tt.paths).run()
+			if len(tt.missing) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error listing %v", tt.missing)
+			}
+			for _, m := range tt.missing {
+				if !strings.Contains(err.Error(), m) {
+					t.Errorf("error %q does not list %q", err.Error(), m)
+				}
+			}
+		})
+	}
+}
@@ -664,6 +664,35 @@ func (h *handler) EnableNode(request *restful.Request, response *restful.Respons
 	_ = response.WriteHeaderAndEntity(http.StatusOK, updateNode)
 }
 
+func (h *handler) CordonNode(request *restful.Request, response *restful.Response) {
+	h.drainNode(request, response, true)
+}
+
+func (h *handler) UncordonNode(request *restful.Request, response *restful.Response) {
+	h.drainNode(request, response, false)
+}
+
+// drainNode drains or uncordons the agent of node by message queue, the node reports the
+// schedulable condition on its next heartbeat.
+func (h *handler) drainNode(request *restful.Request, response *restful.Response, drain bool) {
+	name := request.PathParameter(query.ParameterName)
+	ctx := request.Request.Context()
+	node, err := h.clusterOperator.GetNodeEx(ctx, name, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if err = h.delivery.DeliverNodeDrain(ctx, node.Name, drain); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, node)
+}
+
 func (h *handler) syncNodeDisable(node *v1.Node, reqDisable bool) error {
 	_, nodeDisable := node.Labels[common.LabelNodeDisable]
 	if reqDisable == nodeDisable {
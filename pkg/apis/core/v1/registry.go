@@ -330,6 +330,26 @@ func SetupWebService(h *handler) *restful.WebService {
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Node{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
+	webservice.Route(webservice.PATCH("/nodes/{name}/cordon").
+		To(h.CordonNode).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreNodeTag}).
+		Doc("Cordon node, its agent rejects new tasks while finishing in-flight ones.").
+		Param(webservice.PathParameter(query.ParameterName, "node name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Node{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
+	webservice.Route(webservice.PATCH("/nodes/{name}/uncordon").
+		To(h.UncordonNode).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreNodeTag}).
+		Doc("Uncordon node, its agent accepts new tasks again.").
+		Param(webservice.PathParameter(query.ParameterName, "node name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Node{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.DELETE("/nodes/{name}").
 		To(h.DeleteNode).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreNodeTag}).
@@ -295,8 +295,27 @@ imageProxy:
 
 const DockerDaemonTmpl = `
 {
-    "insecure-registries": ["{{.Node}}"],
+    "insecure-registries": [{{range $i, $r := .InsecureRegistries}}{{if $i}}, {{end}}"{{$r}}"{{end}}],
     "data-root": "{{.DataRoot}}",
     "exec-opts": ["native.cgroupdriver=systemd"]
 }
 `
+
+const DockerMirrorTmpl = `{
+    "registry-mirrors": ["{{.Scheme}}://{{.Node}}"]{{if not .TLS}},
+    "insecure-registries": ["{{.Node}}"]{{end}}
+}
+`
+
+const ContainerdMirrorTmpl = `[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["{{.Scheme}}://{{.Node}}"]
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."{{.Node}}"]
+  endpoint = ["{{.Scheme}}://{{.Node}}"]
+{{- if not .TLS}}
+[plugins."io.containerd.grpc.v1.cri".registry.configs."{{.Node}}".tls]
+  insecure_skip_verify = true
+{{- else if .CAFile}}
+[plugins."io.containerd.grpc.v1.cri".registry.configs."{{.Node}}".tls]
+  ca_file = "{{.CAFile}}"
+{{- end}}
+`
@@ -0,0 +1,184 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package cordon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/query"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
+)
+
+const (
+	cordonLongDescription = `
+  Cordon kc-agent nodes before maintenance.
+
+  The agent of a cordoned node rejects new tasks with a clear reason while finishing in-flight ones,
+  and reports the Schedulable condition false on its next heartbeat. Sending SIGUSR1 to kubeclipper-agent
+  on the node cordons it as well.`
+	cordonExample = `
+  # Cordon kc-agent nodes
+  kcctl cordon --agent 192.168.10.19,192.168.10.20
+
+  Please read 'kcctl cordon -h' get more cordon flags.`
+	uncordonLongDescription = `
+  Uncordon kc-agent nodes after maintenance.
+
+  The agent of an uncordoned node accepts new tasks again. Sending SIGUSR2 to kubeclipper-agent
+  on the node uncordons it as well.`
+	uncordonExample = `
+  # Uncordon kc-agent nodes
+  kcctl uncordon --agent 192.168.10.19,192.168.10.20
+
+  Please read 'kcctl uncordon -h' get more uncordon flags.`
+)
+
+type CordonOptions struct {
+	options.IOStreams
+	client  *kc.Client
+	cliOpts *options.CliOptions
+
+	agents []string
+	// cordon is false to uncordon the agents
+	cordon bool
+}
+
+func NewCordonOptions(streams options.IOStreams, cordon bool) *CordonOptions {
+	return &CordonOptions{
+		IOStreams: streams,
+		cliOpts:   options.NewCliOptions(),
+		cordon:    cordon,
+	}
+}
+
+func NewCmdCordon(streams options.IOStreams) *cobra.Command {
+	return newCmd(NewCordonOptions(streams, true), "cordon", "cordon kc-agent nodes, rejecting new tasks", cordonLongDescription, cordonExample)
+}
+
+func NewCmdUncordon(streams options.IOStreams) *cobra.Command {
+	return newCmd(NewCordonOptions(streams, false), "uncordon", "uncordon kc-agent nodes, accepting new tasks", uncordonLongDescription, uncordonExample)
+}
+
+func newCmd(o *CordonOptions, use, short, long, example string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   use + " (--agent <agentIps>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 short,
+		Long:                  long,
+		Example:               example,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			utils.CheckErr(o.ValidateArgs())
+			utils.CheckErr(o.RunCordon())
+		},
+	}
+
+	o.cliOpts.AddFlags(cmd.Flags())
+	cmd.Flags().StringSliceVar(&o.agents, "agent", o.agents, use+" agent node ip.")
+
+	utils.CheckErr(cmd.RegisterFlagCompletionFunc("agent", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return o.listNode(toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}))
+	utils.CheckErr(cmd.MarkFlagRequired("agent"))
+	return cmd
+}
+
+func (c *CordonOptions) listNode(toComplete string) []string {
+	utils.CheckErr(c.Complete())
+	nodes, err := c.client.ListNodes(context.TODO(), kc.Queries(*query.New()))
+	if err != nil {
+		return nil
+	}
+	set := sets.NewString()
+	for _, node := range nodes.Items {
+		if strings.HasPrefix(node.Status.Ipv4DefaultIP, toComplete) {
+			set.Insert(node.Status.Ipv4DefaultIP)
+		}
+	}
+	return set.List()
+}
+
+func (c *CordonOptions) Complete() error {
+	var err error
+	if err = c.cliOpts.Complete(); err != nil {
+		return err
+	}
+	c.client, err = c.cliOpts.ToRawConfig().ToKcClient()
+	return err
+}
+
+func (c *CordonOptions) ValidateArgs() error {
+	if c.cliOpts.Config == "" {
+		return errors.New("config path cannot be empty")
+	}
+	if len(c.agents) == 0 {
+		return errors.New("--agent is required")
+	}
+	return nil
+}
+
+func (c *CordonOptions) RunCordon() error {
+	nodes, err := c.client.ListNodes(context.TODO(), kc.Queries(*query.New()))
+	if err != nil {
+		return err
+	}
+	for _, ip := range sets.NewString(c.agents...).List() {
+		node, err := nodeByIP(nodes.Items, ip)
+		if err != nil {
+			return err
+		}
+		if c.cordon {
+			err = c.client.CordonNode(context.TODO(), node.Name)
+		} else {
+			err = c.client.UncordonNode(context.TODO(), node.Name)
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "%s node %s failed", c.action(), ip)
+		}
+		logger.Infof("node %s %sed", ip, c.action())
+	}
+	return nil
+}
+
+func (c *CordonOptions) action() string {
+	if c.cordon {
+		return "cordon"
+	}
+	return "uncordon"
+}
+
+// nodeByIP returns the node whose default ip is ip.
+func nodeByIP(nodes []v1.Node, ip string) (*v1.Node, error) {
+	for i := range nodes {
+		if nodes[i].Status.Ipv4DefaultIP == ip {
+			return &nodes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("node %s does not exist", ip)
+}
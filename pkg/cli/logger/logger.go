@@ -79,6 +79,17 @@ type loggingT struct {
 	verbosity Level // V logging level, the value of the -v flag/
 	Colorful  bool
 	Caller    bool
+	// quiet suppresses the logs below error, and prints errors to stderr
+	quiet bool
+	// prefixKey is the key whose value is printed as the line prefix, see SetPrefixKey
+	prefixKey string
+}
+
+// SetQuiet suppresses info and warning logs regardless of -v, errors are still printed to stderr.
+func SetQuiet(quiet bool) {
+	_logging.mu.Lock()
+	defer _logging.mu.Unlock()
+	_logging.quiet = quiet
 }
 
 func (l *loggingT) writeTS(buf *bytes.Buffer) {
@@ -139,7 +150,11 @@ func (l *loggingT) addHeader(buf *bytes.Buffer, s severity) {
 }
 
 func (l *loggingT) output(buf *bytes.Buffer, s severity) {
-	_, _ = color.Output.Write(buf.Bytes())
+	if l.quiet {
+		_, _ = color.Error.Write(buf.Bytes())
+	} else {
+		_, _ = color.Output.Write(buf.Bytes())
+	}
 	if s == fatalLog {
 		trace := stacks(false)
 		_, _ = color.Error.Write(trace)
@@ -147,20 +162,61 @@ func (l *loggingT) output(buf *bytes.Buffer, s severity) {
 	}
 }
 
-func (l *loggingT) printf(s severity, format string, args ...interface{}) {
+// writeValues appends the key/value pairs to the message as ' key=value',
+// a value containing spaces is quoted.
+func (l *loggingT) writeValues(buf *bytes.Buffer, values []interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	if buf.Bytes()[buf.Len()-1] == '\n' {
+		buf.Truncate(buf.Len() - 1)
+	}
+	for i := 0; i < len(values); i += 2 {
+		key := fmt.Sprint(values[i])
+		val := "(MISSING)"
+		if i+1 < len(values) {
+			val = fmt.Sprint(values[i+1])
+		}
+		if val == "" || strings.ContainsAny(val, " \t\n") {
+			val = strconv.Quote(val)
+		}
+		if l.Colorful {
+			key = color.GreenString(key)
+		}
+		buf.WriteString(" ")
+		buf.WriteString(key)
+		buf.WriteString("=")
+		buf.WriteString(val)
+	}
+}
+
+func (l *loggingT) printf(s severity, values []interface{}, format string, args ...interface{}) {
+	if l.quiet && s < errorLog {
+		return
+	}
 	buf := &bytes.Buffer{}
+	values = l.writePrefix(buf, values)
 	l.addHeader(buf, s)
 	_, _ = fmt.Fprintf(buf, format, args...)
+	l.writeValues(buf, values)
 	if buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
 	}
 	l.output(buf, s)
 }
 
-func (l *loggingT) println(s severity, args ...interface{}) {
+func (l *loggingT) println(s severity, values []interface{}, args ...interface{}) {
+	if l.quiet && s < errorLog {
+		return
+	}
 	buf := &bytes.Buffer{}
+	values = l.writePrefix(buf, values)
 	l.addHeader(buf, s)
 	_, _ = fmt.Fprintln(buf, args...)
+	if len(values) > 0 {
+		l.writeValues(buf, values)
+		buf.WriteByte('\n')
+	}
 	l.output(buf, s)
 }
 
@@ -227,6 +283,7 @@ func (l *Level) Set(value string) error {
 type Logger interface {
 	Enabled() bool
 	V(level Level) Logger
+	WithValues(keysAndValues ...interface{}) Logger
 	Info(args ...interface{})
 	Infof(format string, args ...interface{})
 	Warn(args ...interface{})
@@ -241,6 +298,7 @@ var _ Logger = verbose{}
 
 type verbose struct {
 	enabled bool
+	values  []interface{}
 }
 
 func newVerbose(b bool) verbose {
@@ -252,90 +310,100 @@ func (v verbose) Enabled() bool {
 }
 
 func (v verbose) V(level Level) Logger {
-	if _logging.verbosity.get() >= level {
-		return newVerbose(true)
-	}
-	return newVerbose(false)
+	return verbose{enabled: _logging.verbosity.get() >= level, values: v.values}
+}
+
+// WithValues returns a logger which appends the key/value pairs to every message.
+func (v verbose) WithValues(keysAndValues ...interface{}) Logger {
+	values := make([]interface{}, 0, len(v.values)+len(keysAndValues))
+	values = append(values, v.values...)
+	values = append(values, keysAndValues...)
+	return verbose{enabled: v.enabled, values: values}
 }
 
 func (v verbose) Info(args ...interface{}) {
 	if v.enabled {
-		_logging.println(infoLog, args...)
+		_logging.println(infoLog, v.values, args...)
 	}
 }
 
 func (v verbose) Infof(format string, args ...interface{}) {
 	if v.enabled {
-		_logging.printf(infoLog, format, args...)
+		_logging.printf(infoLog, v.values, format, args...)
 	}
 }
 
 func (v verbose) Warn(args ...interface{}) {
 	if v.enabled {
-		_logging.println(warningLog, args...)
+		_logging.println(warningLog, v.values, args...)
 	}
 }
 
 func (v verbose) Warnf(format string, args ...interface{}) {
 	if v.enabled {
-		_logging.printf(warningLog, format, args...)
+		_logging.printf(warningLog, v.values, format, args...)
 	}
 }
 
 func (v verbose) Error(args ...interface{}) {
 	if v.enabled {
-		_logging.println(errorLog, args...)
+		_logging.println(errorLog, v.values, args...)
 	}
 }
 
 func (v verbose) Errorf(format string, args ...interface{}) {
 	if v.enabled {
-		_logging.printf(errorLog, format, args...)
+		_logging.printf(errorLog, v.values, format, args...)
 	}
 }
 
 func (v verbose) Fatal(args ...interface{}) {
 	if v.enabled {
-		_logging.println(fatalLog, args...)
+		_logging.println(fatalLog, v.values, args...)
 	}
 }
 
 func (v verbose) Fatalf(format string, args ...interface{}) {
 	if v.enabled {
-		_logging.printf(fatalLog, format, args...)
+		_logging.printf(fatalLog, v.values, format, args...)
 	}
 }
 
 func Info(args ...interface{}) {
-	_logging.println(infoLog, args...)
+	_logging.println(infoLog, nil, args...)
 }
 
 func Infof(format string, args ...interface{}) {
-	_logging.printf(infoLog, format, args...)
+	_logging.printf(infoLog, nil, format, args...)
 }
 
 func Warn(args ...interface{}) {
-	_logging.println(warningLog, args...)
+	_logging.println(warningLog, nil, args...)
 }
 
 func Warnf(format string, args ...interface{}) {
-	_logging.printf(warningLog, format, args...)
+	_logging.printf(warningLog, nil, format, args...)
 }
 
 func Error(args ...interface{}) {
-	_logging.println(errorLog, args...)
+	_logging.println(errorLog, nil, args...)
 }
 
 func Errorf(format string, args ...interface{}) {
-	_logging.printf(errorLog, format, args...)
+	_logging.printf(errorLog, nil, format, args...)
 }
 
 func Fatal(args ...interface{}) {
-	_logging.println(fatalLog, args...)
+	_logging.println(fatalLog, nil, args...)
 }
 
 func Fatalf(format string, args ...interface{}) {
-	_logging.printf(fatalLog, format, args...)
+	_logging.printf(fatalLog, nil, format, args...)
+}
+
+// WithValues returns a logger which appends the key/value pairs to every message.
+func WithValues(keysAndValues ...interface{}) Logger {
+	return verbose{enabled: true, values: keysAndValues}
 }
 
 func V(level Level) Logger {
@@ -0,0 +1,104 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSetQuiet(t *testing.T) {
+	output, errOutput := color.Output, color.Error
+	defer func() {
+		color.Output, color.Error = output, errOutput
+		SetQuiet(false)
+	}()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	color.Output, color.Error = stdout, stderr
+
+	SetQuiet(true)
+	Infof("pushed %d images", 3)
+	Warn("docker may fail to push")
+	WithValues("node", "10.0.0.111").Info("registry installed")
+	Errorf("push %s failed", "pause:3.2")
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing printed to stdout, got %q", stdout.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stderr.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "push pause:3.2 failed") {
+		t.Errorf("expected only the error printed to stderr, got %q", stderr.String())
+	}
+
+	stderr.Reset()
+	SetQuiet(false)
+	Info("registry installed")
+	if !strings.Contains(stdout.String(), "registry installed") || stderr.Len() != 0 {
+		t.Errorf("expected info printed to stdout, got stdout %q and stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestSetPrefixKey(t *testing.T) {
+	output, colorful := color.Output, _logging.Colorful
+	defer func() {
+		color.Output, _logging.Colorful = output, colorful
+		SetPrefixKey("")
+	}()
+	stdout := &bytes.Buffer{}
+	color.Output, _logging.Colorful = stdout, false
+
+	SetPrefixKey("node")
+	WithValues("node", "10.0.0.111", "step", "push").Info("registry installed")
+	line := stdout.String()
+	if !strings.HasPrefix(line, "[10.0.0.111][") || strings.Contains(line, "node=") || !strings.HasSuffix(line, "registry installed step=push\n") {
+		t.Errorf("expected node as prefix, got %q", line)
+	}
+
+	stdout.Reset()
+	SetPrefixKey("")
+	WithValues("node", "10.0.0.111").Infof("registry installed")
+	if line = stdout.String(); strings.HasPrefix(line, "[10.0.0.111]") || !strings.HasSuffix(line, "registry installed node=10.0.0.111\n") {
+		t.Errorf("expected node as value without prefix key, got %q", line)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	colorful := _logging.Colorful
+	defer func() { _logging.Colorful = colorful }()
+	_logging.Colorful = false
+
+	out := &bytes.Buffer{}
+	w := NewPrefixWriter(out, "10.0.0.111")
+	for _, chunk := range []string{"registry listening", " on [::]:5000\nGET /v2/ 200\npartial"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "[10.0.0.111] registry listening on [::]:5000\n[10.0.0.111] GET /v2/ 200\n"
+	if out.String() != expected {
+		t.Errorf("expected complete lines prefixed %q, got %q", expected, out.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected += "[10.0.0.111] partial\n"; out.String() != expected {
+		t.Errorf("expected partial line flushed %q, got %q", expected, out.String())
+	}
+}
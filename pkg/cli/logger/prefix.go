@@ -0,0 +1,126 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// prefixColors are the colors a prefix is printed in, picked by the prefix so that each node keeps its color.
+var prefixColors = []func(format string, a ...interface{}) string{
+	color.CyanString,
+	color.GreenString,
+	color.YellowString,
+	color.BlueString,
+	color.MagentaString,
+	color.HiCyanString,
+	color.HiGreenString,
+	color.HiYellowString,
+	color.HiBlueString,
+	color.HiMagentaString,
+}
+
+// SetPrefixKey makes the value of key, e.g. the node, printed as a '[value]' prefix of the log line
+// instead of a key=value pair, so that the interleaved logs of concurrent nodes are told apart.
+// An empty key disables the prefix.
+func SetPrefixKey(key string) {
+	_logging.mu.Lock()
+	defer _logging.mu.Unlock()
+	_logging.prefixKey = key
+}
+
+// Prefix returns '[name]', colorized by name with --colorized on a terminal.
+func Prefix(name string) string {
+	if !_logging.Colorful {
+		return "[" + name + "]"
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return "[" + prefixColors[h.Sum32()%uint32(len(prefixColors))](name) + "]"
+}
+
+// writePrefix writes the value of the prefix key as the line prefix, the other values are returned.
+func (l *loggingT) writePrefix(buf *bytes.Buffer, values []interface{}) []interface{} {
+	if l.prefixKey == "" {
+		return values
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		if key, ok := values[i].(string); ok && key == l.prefixKey {
+			buf.WriteString(Prefix(fmt.Sprint(values[i+1])))
+			rest := make([]interface{}, 0, len(values)-2)
+			rest = append(rest, values[:i]...)
+			return append(rest, values[i+2:]...)
+		}
+	}
+	return values
+}
+
+// PrefixWriter prefixes every line written to it with '[name] ', e.g. the streamed output of a command on a node.
+// A partial line is held until its end is written, or until Flush.
+type PrefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func NewPrefixWriter(w io.Writer, name string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(Prefix(name) + " ")}
+}
+
+func (p *PrefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes the pending partial line, terminated by a newline.
+func (p *PrefixWriter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *PrefixWriter) writeLine(line []byte) error {
+	out := make([]byte, 0, len(p.prefix)+len(line))
+	out = append(out, p.prefix...)
+	_, err := p.w.Write(append(out, line...))
+	return err
+}
@@ -20,7 +20,9 @@ package printer
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"text/template"
 
 	"sigs.k8s.io/yaml"
 
@@ -30,13 +32,51 @@ import (
 
 type PrintFlags struct {
 	format string
+	// template is the golang text/template used when format is go-template
+	template string
 }
 
 func (p *PrintFlags) AllowedFormats() []string {
 	if p == nil {
 		return []string{}
 	}
-	return []string{"json", "yaml", "table"}
+	return []string{"json", "yaml", "table", "go-template", "jsonl"}
+}
+
+// Streaming reports whether the output format is jsonl, in which case the
+// caller may write every item by PrintLine as soon as it is available.
+func (p *PrintFlags) Streaming() bool {
+	return p != nil && p.format == "jsonl"
+}
+
+// PrintLine writes v as a single line of compact JSON.
+func PrintLine(v interface{}, w io.Writer) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Validate checks the template parses when format is go-template.
+func (p *PrintFlags) Validate() error {
+	if p == nil || p.format != "go-template" {
+		return nil
+	}
+	if p.template == "" {
+		return fmt.Errorf("--template must be specified when output format is go-template")
+	}
+	_, err := p.parseTemplate()
+	return err
+}
+
+func (p *PrintFlags) parseTemplate() (*template.Template, error) {
+	t, err := template.New("output").Option("missingkey=error").Parse(p.template)
+	if err != nil {
+		return nil, fmt.Errorf("parse template error: %s", err.Error())
+	}
+	return t, nil
 }
 
 // TODO
@@ -60,6 +100,17 @@ func (p *PrintFlags) Print(pr ResourcePrinter, w io.Writer) error {
 		}
 		_, err = w.Write(data)
 		return err
+	case "jsonl":
+		return PrintLine(pr, w)
+	case "go-template":
+		t, err := p.parseTemplate()
+		if err != nil {
+			return err
+		}
+		if err = t.Execute(w, pr); err != nil {
+			return fmt.Errorf("execute template error: %s", err.Error())
+		}
+		return nil
 	case "table":
 		fallthrough
 	default:
@@ -78,7 +129,8 @@ func (p *PrintFlags) AddFlags(c *cobra.Command) {
 	if p == nil {
 		return
 	}
-	c.Flags().StringVarP(&p.format, "output", "o", p.format, "Output format either: json,yaml,table")
+	c.Flags().StringVarP(&p.format, "output", "o", p.format, "Output format either: json,yaml,table,go-template,jsonl")
+	c.Flags().StringVar(&p.template, "template", p.template, "Template string to use when -o=go-template, the template format is golang text/template")
 }
 
 func NewPrintFlags() *PrintFlags {
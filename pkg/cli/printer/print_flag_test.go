@@ -0,0 +1,98 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type fakeResource struct {
+	Name string
+	Tags []string
+}
+
+func (f *fakeResource) JSONPrint() ([]byte, error) {
+	return JSONPrinter(f)
+}
+
+func (f *fakeResource) YAMLPrint() ([]byte, error) {
+	return YAMLPrinter(f)
+}
+
+func (f *fakeResource) TablePrint() ([]string, [][]string) {
+	return []string{"name"}, [][]string{{f.Name}}
+}
+
+func TestPrintFlags_GoTemplate(t *testing.T) {
+	tests := []struct {
+		template    string
+		expected    string
+		validateErr string
+		printErr    string
+	}{
+		{template: "{{.Name}}\t{{len .Tags}}", expected: "caas4/cephcsi\t2"},
+		{template: "", validateErr: "--template must be specified"},
+		{template: "{{.Name", validateErr: "parse template error"},
+		{template: "{{.Missing}}", printErr: "execute template error"},
+	}
+	for _, tt := range tests {
+		p := &PrintFlags{format: "go-template", template: tt.template}
+		err := p.Validate()
+		if tt.validateErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.validateErr) {
+				t.Errorf("template %q: expected validate error %q, got %v", tt.template, tt.validateErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("template %q: unexpected validate error: %v", tt.template, err)
+		}
+		out := &bytes.Buffer{}
+		err = p.Print(&fakeResource{Name: "caas4/cephcsi", Tags: []string{"v3.4.0", "v3.5.0"}}, out)
+		if tt.printErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.printErr) {
+				t.Errorf("template %q: expected print error %q, got %v", tt.template, tt.printErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("template %q: unexpected print error: %v", tt.template, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("template %q: expected %q, got %q", tt.template, tt.expected, out.String())
+		}
+	}
+}
+
+func TestPrintFlags_JSONLines(t *testing.T) {
+	p := &PrintFlags{format: "jsonl"}
+	if !p.Streaming() {
+		t.Fatal("expected jsonl output to be streaming")
+	}
+	out := &bytes.Buffer{}
+	if err := p.Print(&fakeResource{Name: "caas4/cephcsi", Tags: []string{"v3.4.0"}}, out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"Name":"caas4/cephcsi","Tags":["v3.4.0"]}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
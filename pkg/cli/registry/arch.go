@@ -0,0 +1,89 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+)
+
+// archDirs maps the supported --arch to its directory in package,
+// e.g. kc/registry/v2/<dir> and kc/resource/docker/<version>/<dir>.
+var archDirs = map[string]string{
+	"amd64":   "amd64",
+	"arm64":   "arm64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	// a variant can't be a single path element
+	"arm/v7": "armv7",
+}
+
+// archAliases are normalized to the supported arch by Complete, e.g. the output of 'uname -m'.
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm/v7",
+}
+
+func supportedArches() []string {
+	arches := make([]string, 0, len(archDirs))
+	for arch := range archDirs {
+		arches = append(arches, arch)
+	}
+	sort.Strings(arches)
+	return arches
+}
+
+func validateArch(arch string) error {
+	if _, ok := archDirs[arch]; !ok {
+		return fmt.Errorf("--arch %s is not supported, must be one of %s", arch, strings.Join(supportedArches(), ","))
+	}
+	return nil
+}
+
+// archDir returns the directory of o.Arch in package.
+func (o *RegistryOptions) archDir() string {
+	if dir, ok := archDirs[o.Arch]; ok {
+		return dir
+	}
+	return o.Arch
+}
+
+// checkPackageArch checks the package extracted on node contains the registry image of o.Arch,
+// so that a package built for another arch fails before docker is installed.
+func (o *RegistryOptions) checkPackageArch() error {
+	dir := fmt.Sprintf("%s/kc/registry/v2", config.DefaultPkgPath)
+	ret, err := o.runCmd("ls " + dir)
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("package %s has no registry images: %w", o.Pkg, err)
+	}
+	dirs := strings.Fields(ret.Stdout)
+	for _, v := range dirs {
+		if v == o.archDir() {
+			return nil
+		}
+	}
+	return fmt.Errorf("package %s contains no arch %s, it has %s in %s", o.Pkg, o.Arch, strings.Join(dirs, ","), dir)
+}
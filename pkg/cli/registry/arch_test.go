@@ -0,0 +1,72 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArch(t *testing.T) {
+	tests := []struct {
+		arch    string
+		dir     string
+		invalid bool
+	}{
+		{arch: "", dir: "amd64"},
+		{arch: "x86_64", dir: "amd64"},
+		{arch: "ppc64le", dir: "ppc64le"},
+		{arch: "arm/v7", dir: "armv7"},
+		{arch: "armv7l", dir: "armv7"},
+		{arch: "mips", invalid: true},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.Arch = tt.arch
+		if err := o.Complete(); err != nil {
+			t.Fatal(err)
+		}
+		err := validateArch(o.Arch)
+		if tt.invalid {
+			if err == nil || !strings.Contains(err.Error(), "amd64,arm/v7,arm64,ppc64le,s390x") {
+				t.Errorf("%s: expected error listing supported arches, got %v", tt.arch, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.arch, err)
+		}
+		if dir := o.archDir(); dir != tt.dir {
+			t.Errorf("%s: expected arch dir %s, got %s", tt.arch, tt.dir, dir)
+		}
+	}
+}
+
+func TestCheckPackageArch(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{"ls /tmp/kc/registry/v2": "amd64\narm64\n"}}
+	o := newFakeOptions(runner)
+	o.Arch = "arm64"
+	if err := o.checkPackageArch(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.Arch = "s390x"
+	if err := o.checkPackageArch(); err == nil || !strings.Contains(err.Error(), "it has amd64,arm64") {
+		t.Errorf("expected error listing the arches in package, got %v", err)
+	}
+}
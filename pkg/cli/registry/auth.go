@@ -0,0 +1,182 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerConfig is the part of docker config.json the registry credentials are read from.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// credentialHelper runs 'docker-credential-<helper> get' for server, and returns its username and secret.
+// It's a variable so that tests replace it.
+var credentialHelper = func(helper, server string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// helpers exit non-zero with this message if they have no credential of server
+		if strings.Contains(string(out), "credentials not found") {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("docker-credential-%s get %s error: %s %s", helper, server, err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	cred := struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}{}
+	if err = json.Unmarshal(out, &cred); err != nil {
+		return "", "", fmt.Errorf("invalid output of docker-credential-%s: %w", helper, err)
+	}
+	return cred.Username, cred.Secret, nil
+}
+
+// validateRegistryAuth validates --registry-auth is 'user:password'.
+func (o *RegistryOptions) validateRegistryAuth() error {
+	if o.RegistryAuth == "" {
+		return nil
+	}
+	if user, password, ok := strings.Cut(o.RegistryAuth, ":"); !ok || user == "" || password == "" {
+		return fmt.Errorf("--registry-auth must be user:password")
+	}
+	return nil
+}
+
+// dockerConfigPath returns --docker-config, or config.json of $DOCKER_CONFIG or ~/.docker as docker does.
+func (o *RegistryOptions) dockerConfigPath() string {
+	if o.DockerConfig != "" {
+		return o.DockerConfig
+	}
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// registryAuthorization returns the 'Authorization' header of the registry API requests, empty if there is
+// no credential. The credential of 'node:port' in docker config.json wins, --registry-auth is the fallback.
+// It's resolved once, a credential helper may prompt or be slow.
+func (o *RegistryOptions) registryAuthorization() string {
+	o.authOnce.Do(func() {
+		server := fmt.Sprintf("%s:%d", o.Node, o.RegistryPort)
+		user, password, err := o.dockerConfigAuth(server)
+		if err != nil {
+			o.log("docker-config").Warnf("read credential of %s from %s error: %s", server, o.dockerConfigPath(), err.Error())
+		}
+		if user == "" && o.RegistryAuth != "" {
+			user, password, _ = strings.Cut(o.RegistryAuth, ":")
+			o.log("docker-config").V(2).Infof("no credential of %s in %s, use --registry-auth", server, o.dockerConfigPath())
+		}
+		if user == "" {
+			return
+		}
+		o.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	})
+	return o.authorization
+}
+
+// dockerConfigAuth reads the credential of server from docker config.json, by the credential helper of server,
+// the credential store, or the 'auths' entry in this order as docker does. A missing file is no credential.
+func (o *RegistryOptions) dockerConfigAuth(server string) (string, string, error) {
+	p := o.dockerConfigPath()
+	if p == "" {
+		return "", "", nil
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) && o.DockerConfig == "" {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	config := new(dockerConfig)
+	if err = json.Unmarshal(data, config); err != nil {
+		return "", "", fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	helper := config.CredHelpers[server]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		user, secret, err := credentialHelper(helper, server)
+		if err != nil {
+			return "", "", err
+		}
+		// an identity token is exchanged by OAuth2, which the registry deployed by kcctl does not support
+		if user != "" && user != "<token>" {
+			o.log("docker-config").V(2).Infof("use credential of %s from docker-credential-%s", server, helper)
+			return user, secret, nil
+		}
+	}
+
+	for key, entry := range config.Auths {
+		if normalizeRegistryHost(key) != server {
+			continue
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid auth of %s: %w", key, err)
+			}
+			user, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return "", "", fmt.Errorf("invalid auth of %s, must be base64 of user:password", key)
+			}
+			o.log("docker-config").V(2).Infof("use credential of %s from %s", server, p)
+			return user, password, nil
+		}
+		if entry.Username != "" {
+			o.log("docker-config").V(2).Infof("use credential of %s from %s", server, p)
+			return entry.Username, entry.Password, nil
+		}
+	}
+	return "", "", nil
+}
+
+// normalizeRegistryHost returns the 'host:port' of an 'auths' key of docker config.json,
+// which may be an url such as 'https://10.0.0.111:5000/v2/'.
+func normalizeRegistryHost(key string) string {
+	if i := strings.Index(key, "://"); i >= 0 {
+		key = key[i+3:]
+	}
+	key, _, _ = strings.Cut(key, "/")
+	return key
+}
@@ -0,0 +1,102 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+)
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestRegistryAuthorization(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("admin:secret"))
+	tests := []struct {
+		name         string
+		config       string
+		registryAuth string
+		expected     string
+	}{
+		{name: "auths", config: `{"auths": {"10.0.0.111:5000": {"auth": "` + auth + `"}}}`, expected: basicAuth("admin", "secret")},
+		{name: "auths url", config: `{"auths": {"https://10.0.0.111:5000/v2/": {"auth": "` + auth + `"}}}`, expected: basicAuth("admin", "secret")},
+		{name: "config wins", config: `{"auths": {"10.0.0.111:5000": {"auth": "` + auth + `"}}}`, registryAuth: "user:password", expected: basicAuth("admin", "secret")},
+		{name: "other registry", config: `{"auths": {"10.0.0.112:5000": {"auth": "` + auth + `"}}}`, registryAuth: "user:password", expected: basicAuth("user", "password")},
+		{name: "cred helper", config: `{"credHelpers": {"10.0.0.111:5000": "pass"}}`, expected: basicAuth("helper", "token")},
+		{name: "identity token", config: `{"credsStore": "pass", "auths": {"10.0.0.111:5000": {"auth": "` + auth + `"}}}`, expected: basicAuth("admin", "secret")},
+		{name: "invalid config", config: `{`, registryAuth: "user:password", expected: basicAuth("user", "password")},
+		{name: "none", config: `{}`},
+	}
+	helper := credentialHelper
+	defer func() { credentialHelper = helper }()
+	for _, tt := range tests {
+		credentialHelper = func(helper, server string) (string, string, error) {
+			if tt.name == "identity token" {
+				return "<token>", "refresh", nil
+			}
+			return "helper", "token", nil
+		}
+		p := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(p, []byte(tt.config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		o := NewRegistryOptions(options.IOStreams{})
+		o.Node = "10.0.0.111"
+		o.RegistryPort = 5000
+		o.DockerConfig = p
+		o.RegistryAuth = tt.registryAuth
+		if got := o.requestHeader(nil)["Authorization"]; got != tt.expected {
+			t.Errorf("%s: expected authorization %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestDockerConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	o := NewRegistryOptions(options.IOStreams{})
+	if p := o.dockerConfigPath(); p != filepath.Join(dir, "config.json") {
+		t.Errorf("expected config.json in $DOCKER_CONFIG, got %s", p)
+	}
+	// a missing default config is no credential, not an error
+	if user, _, err := o.dockerConfigAuth("10.0.0.111:5000"); err != nil || user != "" {
+		t.Errorf("expected no credential without config.json, got %q, %v", user, err)
+	}
+	o.DockerConfig = "/custom/config.json"
+	if p := o.dockerConfigPath(); p != "/custom/config.json" {
+		t.Errorf("expected --docker-config, got %s", p)
+	}
+	if _, _, err := o.dockerConfigAuth("10.0.0.111:5000"); err == nil {
+		t.Error("expected error of a missing --docker-config")
+	}
+}
+
+func TestValidateRegistryAuth(t *testing.T) {
+	for auth, valid := range map[string]bool{"": true, "user:password": true, "user": false, ":password": false, "user:": false} {
+		o := NewRegistryOptions(options.IOStreams{})
+		o.RegistryAuth = auth
+		if err := o.validateRegistryAuth(); (err == nil) != valid {
+			t.Errorf("--registry-auth %q: expected valid %v, got %v", auth, valid, err)
+		}
+	}
+}
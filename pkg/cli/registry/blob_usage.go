@@ -0,0 +1,227 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// manifestGrepBatch is the maximum number of manifests read by one grep command on node.
+const manifestGrepBatch = 200
+
+// manifestDigestRegexp matches a digest referenced by a manifest, e.g. a layer, the config or a manifest of a list.
+var manifestDigestRegexp = regexp.MustCompile(`sha256:[0-9a-f]{64}`)
+
+// blobRefs is what the repositories of the registry volume reference.
+type blobRefs struct {
+	// layers maps a blob digest to the repositories linking it by _layers
+	layers map[string][]string
+	// revisions maps a manifest digest to the repositories linking it by _manifests/revisions
+	revisions map[string][]string
+	// tags maps 'repo:tag' to its manifest digest
+	tags map[string]string
+}
+
+// registryV2Dir is the root of the registry storage in the registry volume.
+func (o *RegistryOptions) registryV2Dir() string {
+	return fmt.Sprintf("%s/docker/registry/v2", o.RegistryVolume)
+}
+
+// blobPath returns the data file of digest relative to the blobs directory.
+func blobPath(digest string) string {
+	hex := strings.TrimPrefix(digest, "sha256:")
+	if len(hex) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("sha256/%s/%s/data", hex[:2], hex)
+}
+
+// listBlobUsage walks the blob store on node and prints the largest blobs with the repositories and
+// tags referencing them, a blob referenced by no repository is orphaned until garbage-collect is run.
+func (o *RegistryOptions) listBlobUsage() error {
+	sizes, err := o.blobSizes()
+	if err != nil {
+		return err
+	}
+	refs, err := o.blobRefs()
+	if err != nil {
+		return err
+	}
+	manifests, err := o.manifestReferences(refs.revisions)
+	if err != nil {
+		return err
+	}
+
+	tagsOf := make(map[string][]string)
+	for tag, manifest := range refs.tags {
+		for _, digest := range referencedDigests(manifest, manifests) {
+			tagsOf[digest] = append(tagsOf[digest], tag)
+		}
+	}
+	usage := &BlobUsage{Items: make([]BlobUsageItem, 0, len(sizes))}
+	for digest, size := range sizes {
+		repositories := append(append([]string{}, refs.layers[digest]...), refs.revisions[digest]...)
+		usage.Items = append(usage.Items, BlobUsageItem{
+			Digest:       digest,
+			Size:         size,
+			Repositories: sortedUnique(repositories),
+			Tags:         sortedUnique(tagsOf[digest]),
+			Orphaned:     len(repositories) == 0,
+		})
+	}
+	sort.SliceStable(usage.Items, func(i, j int) bool {
+		if usage.Items[i].Size != usage.Items[j].Size {
+			return usage.Items[i].Size > usage.Items[j].Size
+		}
+		return usage.Items[i].Digest < usage.Items[j].Digest
+	})
+	if o.Top > 0 && len(usage.Items) > o.Top {
+		usage.Items = usage.Items[:o.Top]
+	}
+	return o.PrintFlags.Print(usage, o.IOStreams.Out)
+}
+
+// blobSizes returns the size of every blob in the blob store on node.
+func (o *RegistryOptions) blobSizes() (map[string]int64, error) {
+	ret, err := o.runCmd(fmt.Sprintf("find %s/blobs -type f -name data -printf '%%s %%P\\n'", o.registryV2Dir()))
+	if err != nil {
+		return nil, err
+	}
+	if err = ret.Error(); err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	for _, line := range nonEmptyLines(ret.Stdout) {
+		// e.g. '2813316 sha256/8a/8a49...ce/data'
+		size, file, ok := strings.Cut(line, " ")
+		parts := strings.Split(file, "/")
+		if !ok || len(parts) != 4 {
+			continue
+		}
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[parts[0]+":"+parts[2]] = n
+	}
+	return sizes, nil
+}
+
+// blobRefs reads the layer, revision and tag links of every repository on node.
+func (o *RegistryOptions) blobRefs() (*blobRefs, error) {
+	repositories := o.registryV2Dir() + "/repositories/"
+	ret, err := o.runCmd(fmt.Sprintf(`find %s -type f -name link \( -path '*/_layers/*' -o -path '*/_manifests/revisions/*' -o -path '*/_manifests/tags/*/current/*' \) -exec grep -H '' {} +`, repositories))
+	if err != nil {
+		return nil, err
+	}
+	if err = ret.Error(); err != nil {
+		return nil, err
+	}
+	refs := &blobRefs{layers: map[string][]string{}, revisions: map[string][]string{}, tags: map[string]string{}}
+	for _, line := range nonEmptyLines(ret.Stdout) {
+		// e.g. '/opt/registry/docker/registry/v2/repositories/caas4/etcd/_layers/sha256/8a49...ce/link:sha256:8a49...ce'
+		file, digest, ok := strings.Cut(strings.TrimPrefix(line, repositories), ":")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(file, "/_layers/"); i > 0 {
+			refs.layers[digest] = append(refs.layers[digest], file[:i])
+		} else if i = strings.Index(file, "/_manifests/revisions/"); i > 0 {
+			refs.revisions[digest] = append(refs.revisions[digest], file[:i])
+		} else if i = strings.Index(file, "/_manifests/tags/"); i > 0 {
+			tag := strings.TrimSuffix(file[i+len("/_manifests/tags/"):], "/current/link")
+			refs.tags[file[:i]+":"+tag] = digest
+		}
+	}
+	return refs, nil
+}
+
+// manifestReferences reads the manifests on node, and returns the digests each of them references.
+func (o *RegistryOptions) manifestReferences(revisions map[string][]string) (map[string][]string, error) {
+	blobs := o.registryV2Dir() + "/blobs/"
+	var files []string
+	for digest := range revisions {
+		if file := blobPath(digest); file != "" {
+			files = append(files, blobs+file)
+		}
+	}
+	sort.Strings(files)
+	references := make(map[string][]string)
+	for start := 0; start < len(files); start += manifestGrepBatch {
+		end := start + manifestGrepBatch
+		if end > len(files) {
+			end = len(files)
+		}
+		// -s: a manifest removed meanwhile is not an error, -H: the file is printed with a single manifest as well
+		ret, err := o.runCmd(fmt.Sprintf("grep -sHo 'sha256:[0-9a-f]\\{64\\}' %s", strings.Join(files[start:end], " ")))
+		if err != nil {
+			return nil, err
+		}
+		// grep exits 1 if nothing matched, e.g. a manifest without layers
+		if ret.ExitCode > 1 {
+			return nil, ret.Error()
+		}
+		for _, line := range nonEmptyLines(ret.Stdout) {
+			file, digest, ok := strings.Cut(strings.TrimPrefix(line, blobs), ":")
+			parts := strings.Split(file, "/")
+			if !ok || len(parts) != 4 || !manifestDigestRegexp.MatchString(digest) {
+				continue
+			}
+			manifest := parts[0] + ":" + parts[2]
+			references[manifest] = append(references[manifest], digest)
+		}
+	}
+	return references, nil
+}
+
+// referencedDigests returns manifest and the digests it references, through the manifests of a manifest list.
+func referencedDigests(manifest string, manifests map[string][]string) []string {
+	digests := []string{manifest}
+	seen := map[string]bool{manifest: true}
+	for i := 0; i < len(digests); i++ {
+		for _, digest := range manifests[digests[i]] {
+			if !seen[digest] {
+				seen[digest] = true
+				digests = append(digests, digest)
+			}
+		}
+	}
+	return digests
+}
+
+// sortedUnique returns the sorted distinct values of s.
+func sortedUnique(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(s))
+	var values []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
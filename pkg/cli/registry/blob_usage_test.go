@@ -0,0 +1,93 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestListBlobUsage(t *testing.T) {
+	hex := func(c string) string { return strings.Repeat(c, 64) }
+	var (
+		layer    = "sha256:" + hex("a")
+		shared   = "sha256:" + hex("b")
+		config   = "sha256:" + hex("c")
+		manifest = "sha256:" + hex("d")
+		list     = "sha256:" + hex("e")
+		orphan   = "sha256:" + hex("f")
+	)
+	v2 := "/opt/registry/docker/registry/v2"
+	blobFile := func(digest string) string { return v2 + "/blobs/" + blobPath(digest) }
+	repos := v2 + "/repositories/"
+	runner := &fakeRunner{outputs: map[string]string{
+		`find /opt/registry/docker/registry/v2/blobs -type f -name data -printf '%s %P\n'`: strings.Join([]string{
+			"300 sha256/aa/" + hex("a") + "/data",
+			"200 sha256/bb/" + hex("b") + "/data",
+			"10 sha256/cc/" + hex("c") + "/data",
+			"5 sha256/dd/" + hex("d") + "/data",
+			"4 sha256/ee/" + hex("e") + "/data",
+			"500 sha256/ff/" + hex("f") + "/data",
+		}, "\n"),
+		`find /opt/registry/docker/registry/v2/repositories/ -type f -name link \( -path '*/_layers/*' -o -path '*/_manifests/revisions/*' -o -path '*/_manifests/tags/*/current/*' \) -exec grep -H '' {} +`: strings.Join([]string{
+			repos + "caas4/etcd/_layers/sha256/" + hex("a") + "/link:" + layer,
+			repos + "caas4/etcd/_layers/sha256/" + hex("b") + "/link:" + shared,
+			repos + "caas4/etcd/_layers/sha256/" + hex("c") + "/link:" + config,
+			repos + "caas4/etcd/_manifests/revisions/sha256/" + hex("d") + "/link:" + manifest,
+			repos + "caas4/etcd/_manifests/revisions/sha256/" + hex("e") + "/link:" + list,
+			repos + "caas4/etcd/_manifests/tags/v3.5/current/link:" + list,
+			repos + "caas4/pause/_layers/sha256/" + hex("b") + "/link:" + shared,
+		}, "\n"),
+		"grep -sHo 'sha256:[0-9a-f]\\{64\\}' " + blobFile(manifest) + " " + blobFile(list): strings.Join([]string{
+			blobFile(manifest) + ":" + config,
+			blobFile(manifest) + ":" + layer,
+			blobFile(manifest) + ":" + shared,
+			blobFile(list) + ":" + manifest,
+		}, "\n"),
+	}}
+	out := &bytes.Buffer{}
+	o := newFakeOptions(runner)
+	o.IOStreams.Out = out
+	o.Top = 3
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err := cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.listBlobUsage(); err != nil {
+		t.Fatal(err)
+	}
+	var usage BlobUsage
+	if err := json.Unmarshal(out.Bytes(), &usage); err != nil {
+		t.Fatalf("invalid json output %q: %v", out.String(), err)
+	}
+	expected := []BlobUsageItem{
+		{Digest: orphan, Size: 500, Orphaned: true},
+		{Digest: layer, Size: 300, Repositories: []string{"caas4/etcd"}, Tags: []string{"caas4/etcd:v3.5"}},
+		{Digest: shared, Size: 200, Repositories: []string{"caas4/etcd", "caas4/pause"}, Tags: []string{"caas4/etcd:v3.5"}},
+	}
+	if !reflect.DeepEqual(usage.Items, expected) {
+		t.Errorf("expected blobs %+v, got %+v", expected, usage.Items)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/logger"
+)
+
+const (
+	cacheKeyCatalog   = "catalog"
+	cacheKeyTagPrefix = "tags/"
+)
+
+// completionCache caches the catalog and tags of a registry for shell completion.
+type completionCache struct {
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Values    []string  `json:"values"`
+}
+
+// cacheFile returns the cache file of the registry, keyed by node and port.
+func (o *RegistryOptions) cacheFile() string {
+	return filepath.Join(o.cacheDir, fmt.Sprintf("%s_%d.json", o.Node, o.RegistryPort))
+}
+
+func defaultCacheDir() string {
+	return filepath.Join(homedir.HomeDir(), config.DefaultConfigPath, "registry-cache")
+}
+
+// cachedValues returns the cached values of key if not expired, otherwise fetches and caches them.
+// The cache is locked while fetching, so that an invalidation by delete in another kcctl
+// can't be overwritten by values fetched before the delete.
+func (o *RegistryOptions) cachedValues(key string, fetch func() ([]string, error)) ([]string, error) {
+	if o.NoCache || o.CacheTTL <= 0 {
+		return fetch()
+	}
+	unlock := o.lockCache()
+	defer unlock()
+	cache := o.loadCache()
+	if entry, ok := cache.Entries[key]; ok && time.Since(entry.UpdatedAt) < o.CacheTTL {
+		return entry.Values, nil
+	}
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	cache.Entries[key] = cacheEntry{UpdatedAt: time.Now(), Values: values}
+	if err = o.saveCache(cache); err != nil {
+		logger.V(2).Warnf("save registry cache error: %s", err.Error())
+	}
+	return values, nil
+}
+
+func (o *RegistryOptions) loadCache() *completionCache {
+	cache := &completionCache{Entries: make(map[string]cacheEntry)}
+	data, err := os.ReadFile(o.cacheFile())
+	if err != nil {
+		return cache
+	}
+	if err = json.Unmarshal(data, cache); err != nil || cache.Entries == nil {
+		// corrupted cache is dropped
+		return &completionCache{Entries: make(map[string]cacheEntry)}
+	}
+	return cache
+}
+
+// saveCache replaces the cache file by renaming a fully written temporary file,
+// so that a reader never sees a half-written cache.
+func (o *RegistryOptions) saveCache(cache *completionCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(o.cacheDir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(o.cacheDir, filepath.Base(o.cacheFile())+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), o.cacheFile())
+}
+
+// lockCache locks the cache file of the registry against other kcctl processes, the returned func unlocks it.
+// The cache is best effort, it is used without lock if locking failed.
+func (o *RegistryOptions) lockCache() func() {
+	if err := os.MkdirAll(o.cacheDir, 0700); err != nil {
+		logger.V(2).Warnf("create registry cache dir error: %s", err.Error())
+		return func() {}
+	}
+	unlock, err := lockFile(o.cacheFile() + ".lock")
+	if err != nil {
+		logger.V(2).Warnf("lock registry cache error: %s", err.Error())
+		return func() {}
+	}
+	return unlock
+}
+
+// invalidateCache drops the cache of the registry, called after its content changed.
+func (o *RegistryOptions) invalidateCache() {
+	unlock := o.lockCache()
+	defer unlock()
+	if err := os.Remove(o.cacheFile()); err != nil && !os.IsNotExist(err) {
+		logger.V(2).Warnf("remove registry cache error: %s", err.Error())
+	}
+}
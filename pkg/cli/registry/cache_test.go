@@ -0,0 +1,143 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedValues(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.cacheDir = t.TempDir()
+	var calls int
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"v3.4.0", "v3.5.0"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		values, err := o.cachedValues("tags/caas4/cephcsi", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, []string{"v3.4.0", "v3.5.0"}) {
+			t.Errorf("unexpected values %v", values)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected values fetched once, got %d", calls)
+	}
+
+	o.NoCache = true
+	if _, err := o.cachedValues("tags/caas4/cephcsi", fetch); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected --no-cache to bypass cache, got %d fetches", calls)
+	}
+
+	o.NoCache = false
+	o.invalidateCache()
+	if _, err := o.cachedValues("tags/caas4/cephcsi", fetch); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected invalidated cache to be fetched again, got %d fetches", calls)
+	}
+
+	o.CacheTTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := o.cachedValues("tags/caas4/cephcsi", fetch); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("expected expired cache to be fetched again, got %d fetches", calls)
+	}
+
+	if _, err := o.cachedValues("catalog", func() ([]string, error) {
+		return nil, errors.New("connection refused")
+	}); err == nil {
+		t.Error("expected fetch error to be returned")
+	}
+}
+
+func TestCachedValues_ConcurrentInvalidate(t *testing.T) {
+	dir := t.TempDir()
+	const key = "tags/caas4/cephcsi"
+	var (
+		mu   sync.Mutex
+		tags = []string{"v3.4.0", "v3.5.0"}
+	)
+	// fetch reads the registry slowly, so that delete may happen in between
+	fetch := func() ([]string, error) {
+		mu.Lock()
+		values := append([]string(nil), tags...)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		return values, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each completion runs as a separate kcctl invocation
+			o := newFakeOptions(&fakeRunner{})
+			o.cacheDir = dir
+			values, err := o.cachedValues(key, fetch)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(values) == 0 || values[0] != "v3.4.0" {
+				t.Errorf("unexpected values %v", values)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		o := newFakeOptions(&fakeRunner{})
+		o.cacheDir = dir
+		mu.Lock()
+		tags = []string{"v3.4.0"}
+		mu.Unlock()
+		o.invalidateCache()
+	}()
+	wg.Wait()
+
+	o := newFakeOptions(&fakeRunner{})
+	o.cacheDir = dir
+	values, err := o.cachedValues(key, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []string{"v3.4.0"}) {
+		t.Errorf("expected no stale values after delete, got %v", values)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(matches) != 0 {
+		t.Errorf("unexpected temporary cache files %v", matches)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+// CleanDryRun prints what clean would stop and remove on node, from the state gathered by read-only commands.
+// Neither the lock file is created nor anything is stopped or removed.
+func (o *RegistryOptions) CleanDryRun() error {
+	plan, err := o.cleanPlan()
+	if err != nil {
+		return err
+	}
+	for _, line := range plan {
+		_, _ = fmt.Fprintln(o.IOStreams.Out, line)
+	}
+	return nil
+}
+
+// cleanPlan returns the actions of Uninstall in its order, each with the current state it applies to.
+func (o *RegistryOptions) cleanPlan() ([]string, error) {
+	var plan []string
+	if o.Force {
+		ret, err := o.runCmd("pgrep -f /usr/bin/docker")
+		if err != nil {
+			return nil, err
+		}
+		if pids := nonEmptyLines(ret.Stdout); len(pids) > 0 {
+			plan = append(plan, fmt.Sprintf("docker processes %s would be killed", strings.Join(pids, ",")))
+		}
+	}
+
+	unitExists := false
+	if !o.Rootless {
+		exists, err := o.registryUnitExists()
+		if err != nil {
+			return nil, err
+		}
+		unitExists = exists
+	}
+	if unitExists {
+		plan = append(plan, fmt.Sprintf("systemd service %s would be disabled, %s and %s would be removed", registryUnit, registryUnitPath, registryEnvPath))
+	} else {
+		ret, err := o.runDockerCmd(fmt.Sprintf("docker inspect -f '{{.State.Status}}' %s", o.RegistryName))
+		if err != nil {
+			return nil, err
+		}
+		switch state := strings.TrimSpace(ret.Stdout); {
+		case ret.ExitCode != 0 || state == "":
+			plan = append(plan, fmt.Sprintf("registry container %s does not exist", o.RegistryName))
+		case state == "running":
+			plan = append(plan, fmt.Sprintf("registry container %s is running, would be stopped and removed", o.RegistryName))
+		default:
+			plan = append(plan, fmt.Sprintf("registry container %s is %s, would be removed", o.RegistryName, state))
+		}
+	}
+
+	if o.RemoveDocker {
+		binaries, err := o.existingPaths("/usr/bin/docker* /usr/bin/containerd* /usr/bin/ctr /usr/bin/runc")
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, "docker service would be disabled")
+		for _, v := range binaries {
+			plan = append(plan, fmt.Sprintf("%s would be removed", v))
+		}
+		ret, err := o.runCmd("mount | grep /run/docker/netns/default | wc -l")
+		if err != nil {
+			return nil, err
+		}
+		if ret.StdoutToString("") == "1" {
+			plan = append(plan, "/var/run/docker/netns/default is mounted, would be unmounted")
+		}
+	}
+
+	patterns := []string{fmt.Sprintf("%s/kc*", config.DefaultPkgPath), "/var/run/docker*", fmt.Sprintf("%s/kc", o.DataRoot)}
+	if o.RemoveVolume {
+		patterns = append(patterns, o.RegistryVolume)
+	}
+	for _, pattern := range patterns {
+		sizes, err := o.pathSizes(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range sizes {
+			plan = append(plan, fmt.Sprintf("%s (%s) would be removed", v[1], v[0]))
+		}
+	}
+	if !o.RemoveVolume {
+		sizes, err := o.pathSizes(o.RegistryVolume)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range sizes {
+			plan = append(plan, fmt.Sprintf("registry volume %s (%s) is kept", v[1], v[0]))
+		}
+	}
+	if len(plan) == 0 {
+		plan = append(plan, fmt.Sprintf("nothing to clean on %s", o.Node))
+	}
+	return plan, nil
+}
+
+// existingPaths returns the paths matching the shell patterns which exist on node.
+func (o *RegistryOptions) existingPaths(patterns string) ([]string, error) {
+	ret, err := o.runCmd(sshutils.WrapSh(fmt.Sprintf("ls -d %s 2>/dev/null; true", patterns)))
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(ret.Stdout), nil
+}
+
+// pathSizes returns the '[size, path]' pairs of the paths matching the shell pattern on node, by 'du -sh'.
+// A pattern matching nothing yields no pairs.
+func (o *RegistryOptions) pathSizes(pattern string) ([][2]string, error) {
+	ret, err := o.runCmd(sshutils.WrapSh(fmt.Sprintf("du -sh %s 2>/dev/null; true", pattern)))
+	if err != nil {
+		return nil, err
+	}
+	var sizes [][2]string
+	for _, line := range nonEmptyLines(ret.Stdout) {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		sizes = append(sizes, [2]string{fields[0], fields[1]})
+	}
+	return sizes, nil
+}
@@ -0,0 +1,113 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+func TestCleanDryRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		removeVolume bool
+		removeDocker bool
+		outputs      map[string]string
+		failures     map[string]string
+		want         []string
+		notWant      []string
+	}{
+		{
+			name:         "running container, volume removed",
+			removeVolume: true,
+			outputs: map[string]string{
+				"docker inspect -f '{{.State.Status}}' registry":             "running\n",
+				sshutils.WrapSh("du -sh /tmp/kc* 2>/dev/null; true"):         "1.2G\t/tmp/kc\n3.4G\t/tmp/kc.tar.gz\n",
+				sshutils.WrapSh("du -sh /var/run/docker* 2>/dev/null; true"): "0\t/var/run/docker.sock\n",
+				sshutils.WrapSh("du -sh /opt/registry 2>/dev/null; true"):    "12G\t/opt/registry\n",
+			},
+			failures: map[string]string{"test -f " + registryUnitPath: ""},
+			want: []string{
+				"registry container registry is running, would be stopped and removed",
+				"/tmp/kc (1.2G) would be removed",
+				"/tmp/kc.tar.gz (3.4G) would be removed",
+				"/var/run/docker.sock (0) would be removed",
+				"/opt/registry (12G) would be removed",
+			},
+			notWant: []string{"is kept", "docker service", "/var/lib/docker/kc"},
+		},
+		{
+			name:         "missing container, docker removed, volume kept",
+			removeDocker: true,
+			outputs: map[string]string{
+				sshutils.WrapSh("ls -d /usr/bin/docker* /usr/bin/containerd* /usr/bin/ctr /usr/bin/runc 2>/dev/null; true"): "/usr/bin/docker\n/usr/bin/runc\n",
+				"mount | grep /run/docker/netns/default | wc -l":                                                            "1",
+				sshutils.WrapSh("du -sh /opt/registry 2>/dev/null; true"):                                                   "12G\t/opt/registry\n",
+			},
+			failures: map[string]string{
+				"test -f " + registryUnitPath:                    "",
+				"docker inspect -f '{{.State.Status}}' registry": "Error: No such object: registry",
+			},
+			want: []string{
+				"registry container registry does not exist",
+				"docker service would be disabled",
+				"/usr/bin/docker would be removed",
+				"/usr/bin/runc would be removed",
+				"/var/run/docker/netns/default is mounted, would be unmounted",
+				"registry volume /opt/registry (12G) is kept",
+			},
+			notWant: []string{"/opt/registry (12G) would be removed"},
+		},
+		{
+			name:    "systemd service",
+			want:    []string{"systemd service " + registryUnit + " would be disabled"},
+			notWant: []string{"registry container"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{outputs: tt.outputs, failures: tt.failures}
+			out := &bytes.Buffer{}
+			o := newFakeOptions(runner)
+			o.IOStreams.Out = out
+			o.RemoveVolume, o.RemoveDocker = tt.removeVolume, tt.removeDocker
+			if err := o.CleanDryRun(); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in plan, got:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("unexpected %q in plan, got:\n%s", notWant, out.String())
+				}
+			}
+			for _, cmd := range runner.cmds {
+				if strings.HasPrefix(cmd, "rm ") || strings.Contains(cmd, "docker stop") || strings.Contains(cmd, "systemctl disable") ||
+					strings.Contains(cmd, nodeLockPath) {
+					t.Errorf("unexpected command changing node in dry run: %s", cmd)
+				}
+			}
+		})
+	}
+}
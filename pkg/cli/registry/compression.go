@@ -0,0 +1,126 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	compressionAuto = "auto"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+	compressionXz   = "xz"
+	compressionNone = "none"
+)
+
+var (
+	allowCompression = sets.NewString(compressionAuto, compressionGzip, compressionZstd, compressionXz, compressionNone)
+
+	compressionSuffixes = map[string][]string{
+		compressionGzip: {".tar.gz", ".tgz", ".gz"},
+		compressionZstd: {".tar.zst", ".tar.zstd", ".zst", ".zstd"},
+		compressionXz:   {".tar.xz", ".txz", ".xz"},
+	}
+	compressionMagics = map[string][]byte{
+		compressionGzip: {0x1f, 0x8b},
+		compressionZstd: {0x28, 0xb5, 0x2f, 0xfd},
+		compressionXz:   {0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00},
+	}
+)
+
+// detectCompression returns the compression of the local package file,
+// the --compression flag takes precedence, then the file extension, and finally the magic bytes.
+func (o *RegistryOptions) detectCompression(pkg string) (string, error) {
+	if o.Compression != "" && o.Compression != compressionAuto {
+		return o.Compression, nil
+	}
+	for compression, suffixes := range compressionSuffixes {
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(pkg, suffix) {
+				return compression, nil
+			}
+		}
+	}
+	f, err := os.Open(pkg)
+	if os.IsNotExist(err) {
+		// remote package, keep the gzip assumption
+		return compressionGzip, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	header := make([]byte, 6)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	for compression, magic := range compressionMagics {
+		if bytes.HasPrefix(header[:n], magic) {
+			return compression, nil
+		}
+	}
+	return compressionNone, nil
+}
+
+// decompressedName returns the file name without compression extension.
+func decompressedName(pkg, compression string) string {
+	for _, suffix := range compressionSuffixes[compression] {
+		if strings.HasSuffix(pkg, suffix) {
+			name := strings.TrimSuffix(pkg, suffix)
+			if strings.HasPrefix(suffix, ".tar") || suffix == ".tgz" || suffix == ".txz" {
+				name += ".tar"
+			}
+			return name
+		}
+	}
+	return pkg + ".tar"
+}
+
+// decompressCmd returns the shell command writing the decompressed src to stdout.
+func decompressCmd(compression, src string) string {
+	switch compression {
+	case compressionNone:
+		return fmt.Sprintf("cat %s", src)
+	default:
+		return fmt.Sprintf("%s -dc %s", compression, src)
+	}
+}
+
+// checkDecompressor makes sure the decompressor exists on node.
+func (o *RegistryOptions) checkDecompressor(compression string) error {
+	if compression == compressionNone {
+		return nil
+	}
+	ret, err := o.runCmd(fmt.Sprintf("command -v %s", compression))
+	if err != nil {
+		return err
+	}
+	if ret.ExitCode != 0 {
+		return fmt.Errorf("%s is required to decompress the package, but not found on node %s", compression, o.Node)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectCompression(t *testing.T) {
+	dir := t.TempDir()
+	zstdPkg := filepath.Join(dir, "images")
+	if err := os.WriteFile(zstdPkg, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		override    string
+		pkg         string
+		compression string
+	}{
+		{name: "gzip by extension", override: compressionAuto, pkg: "images.tar.gz", compression: compressionGzip},
+		{name: "xz by extension", override: compressionAuto, pkg: "images.tar.xz", compression: compressionXz},
+		{name: "zstd by magic", override: compressionAuto, pkg: zstdPkg, compression: compressionZstd},
+		{name: "override", override: compressionNone, pkg: "images.tar.gz", compression: compressionNone},
+	}
+	for _, tt := range tests {
+		o := &RegistryOptions{Compression: tt.override}
+		compression, err := o.detectCompression(tt.pkg)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if compression != tt.compression {
+			t.Errorf("%s: got %s, want %s", tt.name, compression, tt.compression)
+		}
+	}
+}
+
+func TestDecompressedName(t *testing.T) {
+	tests := []struct {
+		pkg         string
+		compression string
+		name        string
+	}{
+		{pkg: "/root/images.tar.gz", compression: compressionGzip, name: "/root/images.tar"},
+		{pkg: "/root/images.tar.zst", compression: compressionZstd, name: "/root/images.tar"},
+		{pkg: "/root/images.tgz", compression: compressionGzip, name: "/root/images.tar"},
+		{pkg: "/root/images", compression: compressionZstd, name: "/root/images.tar"},
+	}
+	for _, tt := range tests {
+		if name := decompressedName(tt.pkg, tt.compression); name != tt.name {
+			t.Errorf("decompressedName(%q) = %q, want %q", tt.pkg, name, tt.name)
+		}
+	}
+}
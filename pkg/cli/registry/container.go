@@ -0,0 +1,115 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// registryNameRegexp is the container name docker accepts.
+var registryNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+func validateRegistryName(name string) error {
+	if !registryNameRegexp.MatchString(name) {
+		return fmt.Errorf("--registry-name %s is invalid, must match %s", name, registryNameRegexp.String())
+	}
+	return nil
+}
+
+// validateRegistryImage checks image is a reference docker run accepts, e.g. registry:2.8.3.
+func validateRegistryImage(image string) error {
+	if image == "" {
+		return fmt.Errorf("--registry-image must not be empty")
+	}
+	ref, _, _ := strings.Cut(image, "@")
+	repository, _ := splitImageRef(ref)
+	if repository == "" || strings.ContainsAny(image, " \t\n'\"") {
+		return fmt.Errorf("--registry-image %s is not a valid image reference", image)
+	}
+	return nil
+}
+
+// isRegistryImage reports whether image is the docker registry image, e.g. registry:2 or a mirrored one.
+func isRegistryImage(image string) bool {
+	image, _, _ = strings.Cut(image, "@")
+	repository, _ := splitImageRef(image)
+	return repository == "registry" || strings.HasSuffix(repository, "/registry")
+}
+
+// inspectRegistryContainer reports whether the container of --registry-name exists on node and is running.
+// A container of that name running another image than registry is an error, deploy would fail or replace it.
+func (o *RegistryOptions) inspectRegistryContainer() (bool, bool, error) {
+	ret, err := o.runDockerCmd(fmt.Sprintf("docker inspect -f '{{.Config.Image}} {{.State.Running}}' %s", o.RegistryName))
+	if err != nil {
+		return false, false, err
+	}
+	if ret.ExitCode != 0 {
+		if strings.Contains(ret.Stderr, "No such object") || strings.Contains(ret.Stderr, "No such container") {
+			return false, false, nil
+		}
+		return false, false, ret.Error()
+	}
+	fields := strings.Fields(ret.Stdout)
+	if len(fields) != 2 {
+		return false, false, fmt.Errorf("invalid output of 'docker inspect %s': %s", o.RegistryName, strings.TrimSpace(ret.Stdout))
+	}
+	if !isRegistryImage(fields[0]) && fields[0] != o.RegistryImage {
+		return false, false, fmt.Errorf("container %s on %s runs image %s, not a docker registry, "+
+			"please rename or remove it, or deploy the registry as another container by --registry-name", o.RegistryName, o.Node, fields[0])
+	}
+	return true, fields[1] == "true", nil
+}
+
+// reuseRegistryContainer starts the existing registry container if it is stopped, so that deploy is idempotent.
+// The settings of the existing container are kept.
+func (o *RegistryOptions) reuseRegistryContainer(running bool) error {
+	o.registryReused = true
+	o.log("install-registry").Warnf("registry container %s exists, reuse it with its settings", o.RegistryName)
+	if running {
+		return nil
+	}
+	ret, err := o.runDockerCmd("docker start " + o.RegistryName)
+	if err != nil {
+		return err
+	}
+	return ret.Error()
+}
+
+// ensureRegistryImage checks the registry image is loaded from the package, or pulls it on node,
+// so that a missing image fails before the registry container is run.
+func (o *RegistryOptions) ensureRegistryImage() error {
+	ret, err := o.runDockerCmd("docker image inspect -f '{{.Id}}' " + o.RegistryImage)
+	if err != nil {
+		return err
+	}
+	if ret.ExitCode == 0 {
+		return nil
+	}
+	o.log("install-registry").Infof("registry image %s is not in the package, pull it", o.RegistryImage)
+	ret, err = o.runDockerCmd("docker pull " + o.RegistryImage)
+	if err != nil {
+		return err
+	}
+	if ret.ExitCode != 0 {
+		return fmt.Errorf("registry image %s is neither in the package nor pullable: %s", o.RegistryImage, strings.TrimSpace(ret.Stderr))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+const inspectRegistryCmd = "docker inspect -f '{{.Config.Image}} {{.State.Running}}' registry"
+
+func TestValidateRegistryName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"registry":    true,
+		"kc-registry": true,
+		"registry_2":  true,
+		"-registry":   false,
+		"reg/istry":   false,
+		"r":           false,
+	} {
+		if err := validateRegistryName(name); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", name, valid, err)
+		}
+	}
+}
+
+func TestIsRegistryImage(t *testing.T) {
+	for image, expected := range map[string]bool{
+		"registry:2":                       true,
+		"mirror.example.com/registry:2.8":  true,
+		"registry@sha256:0123456789abcdef": true,
+		"nginx:latest":                     false,
+		"caas4/registry-ui:v1":             false,
+	} {
+		if got := isRegistryImage(image); got != expected {
+			t.Errorf("%s: expected %v, got %v", image, expected, got)
+		}
+	}
+}
+
+func TestInspectRegistryContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		failure string
+		exists  bool
+		running bool
+		message string
+	}{
+		{name: "not found", failure: "Error: No such object: registry"},
+		{name: "running", output: "registry:2 true\n", exists: true, running: true},
+		{name: "stopped", output: "mirror.example.com/registry:2 false\n", exists: true},
+		{name: "taken", output: "nginx:latest true\n", message: "--registry-name"},
+		{name: "docker down", failure: "Cannot connect to the Docker daemon", message: "Cannot connect"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{outputs: map[string]string{inspectRegistryCmd: tt.output}}
+		if tt.failure != "" {
+			runner.failures = map[string]string{inspectRegistryCmd: tt.failure}
+		}
+		exists, running, err := newFakeOptions(runner).inspectRegistryContainer()
+		if tt.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if exists != tt.exists || running != tt.running {
+			t.Errorf("%s: expected exists %v running %v, got %v %v", tt.name, tt.exists, tt.running, exists, running)
+		}
+	}
+}
+
+func TestInstallRegistry_ReuseStopped(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{inspectRegistryCmd: "registry:2 false\n"}}
+	o := newFakeOptions(runner)
+	if err := o.installRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range runner.cmds {
+		if strings.HasPrefix(cmd, "docker run") {
+			t.Errorf("expected the existing registry container reused, got %q", cmd)
+		}
+	}
+	if last := runner.cmds[len(runner.cmds)-1]; last != "docker start registry" {
+		t.Errorf("expected the stopped registry container started, got %v", runner.cmds)
+	}
+	if err := o.removeRegistryContainer(); err != nil {
+		t.Fatal(err)
+	}
+	if last := runner.cmds[len(runner.cmds)-1]; last != "docker start registry" {
+		t.Errorf("expected rollback to keep the reused registry container, got %v", runner.cmds)
+	}
+}
+
+func TestValidateRegistryImage(t *testing.T) {
+	for image, valid := range map[string]bool{
+		"registry:2.8.3":                   true,
+		"mirror.example.com/registry:2":    true,
+		"registry@sha256:0123456789abcdef": true,
+		"":                                 false,
+		":2":                               false,
+		"registry:2 --privileged":          false,
+	} {
+		if err := validateRegistryImage(image); (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got %v", image, valid, err)
+		}
+	}
+}
+
+func TestInstallRegistry_RegistryImage(t *testing.T) {
+	inspect := "docker image inspect -f '{{.Id}}' registry:2.8.3"
+	tests := []struct {
+		name    string
+		missing bool
+		pull    string
+		message string
+	}{
+		{name: "loaded"},
+		{name: "pulled", missing: true},
+		{name: "not pullable", missing: true, pull: "manifest unknown", message: "neither in the package nor pullable"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{failures: map[string]string{inspectRegistryCmd: "Error: No such object: registry"}}
+		if tt.missing {
+			runner.failures[inspect] = "Error: No such image: registry:2.8.3"
+		}
+		if tt.pull != "" {
+			runner.failures["docker pull registry:2.8.3"] = tt.pull
+		}
+		o := newFakeOptions(runner)
+		o.RegistryImage = "registry:2.8.3"
+		err := o.installRegistry()
+		if tt.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		pulled := strings.Contains(strings.Join(runner.cmds, "\n"), "docker pull registry:2.8.3")
+		if pulled != tt.missing {
+			t.Errorf("%s: expected pulled %v, got commands %v", tt.name, tt.missing, runner.cmds)
+		}
+		if last := runner.cmds[len(runner.cmds)-1]; !strings.HasSuffix(last, "--name registry registry:2.8.3") {
+			t.Errorf("%s: expected the registry container run with registry:2.8.3, got %q", tt.name, last)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+const daemonConfigPath = "/etc/docker/daemon.json"
+
+// mergeDaemonConfig merges the registry required settings into the existing daemon.json,
+// the settings already configured by user are kept.
+func (o *RegistryOptions) mergeDaemonConfig(existing []byte) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	if len(strings.TrimSpace(string(existing))) > 0 {
+		if err := json.Unmarshal(existing, &merged); err != nil {
+			return nil, fmt.Errorf("parse %s error: %w", o.daemonConfigFile(), err)
+		}
+	}
+	var insecure []interface{}
+	if v, ok := merged["insecure-registries"].([]interface{}); ok {
+		insecure = v
+	}
+	for _, registry := range o.insecureRegistries() {
+		found := false
+		for _, v := range insecure {
+			if v == registry {
+				found = true
+				break
+			}
+		}
+		if !found {
+			insecure = append(insecure, registry)
+			merged["insecure-registries"] = insecure
+		}
+	}
+	if o.Rootless {
+		// rootless docker keeps its data in the home of ssh user, and its cgroup driver depends on the setup
+		return merged, nil
+	}
+	if _, ok := merged["data-root"]; !ok {
+		merged["data-root"] = o.DataRoot
+	}
+	if _, ok := merged["exec-opts"]; !ok {
+		merged["exec-opts"] = []interface{}{"native.cgroupdriver=systemd"}
+	}
+	return merged, nil
+}
+
+// insecureRegistries returns the registries docker on node must trust without TLS, the deployed registry
+// and the upstream images are pulled from with --pull-images-from.
+func (o *RegistryOptions) insecureRegistries() []string {
+	registries := []string{o.publishedRegistry()}
+	if o.PullImagesFrom != "" {
+		registries = append(registries, o.PullImagesFrom)
+	}
+	return registries
+}
+
+// validateDaemonRegistry checks the daemon.json on node trusts the address the registry port is published on,
+// otherwise docker trusts a port nothing listens on and fails to push images to registry.
+// docker trusts a TLS registry by the CA of --push-ca-file instead.
+func (o *RegistryOptions) validateDaemonRegistry() error {
+	if o.PushCAFile != "" {
+		return nil
+	}
+	file := o.daemonConfigFile()
+	ret, err := o.runDockerCmd(fmt.Sprintf("cat %s", file))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("read %s error: %w", file, err)
+	}
+	var daemon struct {
+		InsecureRegistries []string `json:"insecure-registries"`
+	}
+	if strings.TrimSpace(ret.Stdout) != "" {
+		if err = json.Unmarshal([]byte(ret.Stdout), &daemon); err != nil {
+			return fmt.Errorf("parse %s error: %w", file, err)
+		}
+	}
+	published := o.publishedRegistry()
+	for _, v := range daemon.InsecureRegistries {
+		if v == published {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s on %s trusts registries [%s], but the registry container is published by '%s' at %s, please check --node, --registry-port and --bind-address, or the insecure-registries of %s",
+		file, o.Node, strings.Join(daemon.InsecureRegistries, ","), o.publishArg(), published, file)
+}
+
+// daemonConfigDiff returns the unified diff between the existing and the merged daemon.json,
+// empty string means no change is required.
+func (o *RegistryOptions) daemonConfigDiff(existing []byte) (string, []byte, error) {
+	merged, err := o.mergeDaemonConfig(existing)
+	if err != nil {
+		return "", nil, err
+	}
+	current := make(map[string]interface{})
+	if len(strings.TrimSpace(string(existing))) > 0 {
+		_ = json.Unmarshal(existing, &current)
+	}
+	proposed, err := json.MarshalIndent(merged, "", "    ")
+	if err != nil {
+		return "", nil, err
+	}
+	if reflect.DeepEqual(current, merged) {
+		return "", proposed, nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(string(proposed) + "\n"),
+		FromFile: o.daemonConfigFile(),
+		ToFile:   o.daemonConfigFile() + " (proposed)",
+		Context:  3,
+	})
+	return diff, proposed, err
+}
+
+// syncDaemonConfig shows the daemon.json changes required by registry on a node with docker running,
+// and applies them after confirmation.
+func (o *RegistryOptions) syncDaemonConfig() error {
+	file := o.daemonConfigFile()
+	ret, err := o.runDockerCmd(fmt.Sprintf("cat %s", file))
+	if err != nil {
+		return err
+	}
+	// daemon.json may not exist
+	existing := ""
+	if ret.ExitCode == 0 {
+		existing = ret.Stdout
+	}
+	diff, proposed, err := o.daemonConfigDiff([]byte(existing))
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		logger.V(2).Infof("%s is up to date", file)
+		return nil
+	}
+	_, _ = o.IOStreams.Out.Write([]byte(diff))
+	if !options.AssumeYes {
+		_, _ = o.IOStreams.Out.Write([]byte(fmt.Sprintf("Apply the changes above to %s and restart docker? Please input (yes/no)", file)))
+		if !utils.AskForConfirmation() {
+			logger.Warnf("%s is not changed, docker may fail to push images to registry", file)
+			return nil
+		}
+	}
+	cmdList := []string{
+		fmt.Sprintf("mkdir -p %s", path.Dir(file)),
+		sshutils.WrapEcho(string(proposed), file),
+		o.restartDockerCmd(),
+	}
+	for _, cmd := range cmdList {
+		ret, err = o.runDockerCmd(cmd)
+		if err != nil {
+			return err
+		}
+		if err = ret.Error(); err != nil {
+			return err
+		}
+	}
+	logger.Infof("update %s successfully", file)
+	return nil
+}
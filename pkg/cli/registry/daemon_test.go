@@ -0,0 +1,158 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDaemonConfigDiff(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	tests := []struct {
+		name     string
+		existing string
+		changed  bool
+		insecure []interface{}
+		dataRoot string
+	}{
+		{
+			name:     "missing daemon.json",
+			existing: "",
+			changed:  true,
+			insecure: []interface{}{"10.0.0.111:5000"},
+			dataRoot: "/var/lib/docker",
+		},
+		{
+			name:     "keep user settings",
+			existing: `{"data-root": "/data/docker", "insecure-registries": ["mirror:5000"]}`,
+			changed:  true,
+			insecure: []interface{}{"mirror:5000", "10.0.0.111:5000"},
+			dataRoot: "/data/docker",
+		},
+		{
+			name: "up to date",
+			existing: `{"data-root": "/data/docker", "exec-opts": ["native.cgroupdriver=cgroupfs"],
+"insecure-registries": ["10.0.0.111:5000"]}`,
+			changed:  false,
+			insecure: []interface{}{"10.0.0.111:5000"},
+			dataRoot: "/data/docker",
+		},
+	}
+	for _, tt := range tests {
+		diff, _, err := o.daemonConfigDiff([]byte(tt.existing))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if (diff != "") != tt.changed {
+			t.Errorf("%s: expected changed=%v, got diff %q", tt.name, tt.changed, diff)
+		}
+		merged, err := o.mergeDaemonConfig([]byte(tt.existing))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(merged["insecure-registries"], tt.insecure) {
+			t.Errorf("%s: expected insecure-registries %v, got %v", tt.name, tt.insecure, merged["insecure-registries"])
+		}
+		if merged["data-root"] != tt.dataRoot {
+			t.Errorf("%s: expected data-root %s, got %v", tt.name, tt.dataRoot, merged["data-root"])
+		}
+	}
+
+	if _, err := o.mergeDaemonConfig([]byte("{invalid")); err == nil {
+		t.Error("expected error for invalid daemon.json")
+	}
+}
+
+func TestValidateDaemonRegistry(t *testing.T) {
+	tests := []struct {
+		name        string
+		port        int
+		bindAddress string
+		registry    string
+	}{
+		{name: "defaults", port: 5000, bindAddress: "0.0.0.0", registry: "10.0.0.111:5000"},
+		{name: "custom port", port: 5001, bindAddress: "0.0.0.0", registry: "10.0.0.111:5001"},
+		{name: "loopback", port: 5001, bindAddress: "127.0.0.1", registry: "127.0.0.1:5001"},
+		{name: "ipv6", port: 5000, bindAddress: "fd00::1", registry: "[fd00::1]:5000"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{outputs: map[string]string{}}
+		o := newFakeOptions(runner)
+		o.RegistryPort = tt.port
+		o.BindAddress = tt.bindAddress
+		content, err := o.getDaemonTemplateContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var daemon map[string]interface{}
+		if err = json.Unmarshal([]byte(content), &daemon); err != nil {
+			t.Fatalf("%s: invalid daemon.json %q: %v", tt.name, content, err)
+		}
+		if insecure := []interface{}{tt.registry}; !reflect.DeepEqual(daemon["insecure-registries"], insecure) {
+			t.Errorf("%s: expected insecure-registries %v, got %v", tt.name, insecure, daemon["insecure-registries"])
+		}
+		if !strings.Contains(o.publishArg(), strconv.Itoa(tt.port)+":5000") {
+			t.Errorf("%s: expected port %d published, got %s", tt.name, tt.port, o.publishArg())
+		}
+		runner.outputs["cat /etc/docker/daemon.json"] = content
+		if err = o.validateDaemonRegistry(); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateDaemonRegistry_Node(t *testing.T) {
+	tests := []struct {
+		name     string
+		daemon   string
+		pushCA   string
+		failures map[string]string
+		wantErr  string
+	}{
+		{name: "trusted", daemon: `{"insecure-registries": ["mirror:5000", "10.0.0.111:5001"]}`},
+		{name: "trusts another port", daemon: `{"insecure-registries": ["10.0.0.111:5000"]}`,
+			wantErr: "trusts registries [10.0.0.111:5000], but the registry container is published by '-p 5001:5000' at 10.0.0.111:5001"},
+		{name: "no insecure registries", daemon: `{"data-root": "/var/lib/docker"}`, wantErr: "trusts registries []"},
+		{name: "empty", daemon: "", wantErr: "trusts registries []"},
+		{name: "invalid", daemon: "{invalid", wantErr: "parse /etc/docker/daemon.json error"},
+		{name: "missing", failures: map[string]string{"cat /etc/docker/daemon.json": "No such file or directory"},
+			wantErr: "read /etc/docker/daemon.json error"},
+		{name: "TLS registry", daemon: `{"insecure-registries": ["10.0.0.111:5000"]}`, pushCA: "/tmp/ca.crt"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{outputs: map[string]string{"cat /etc/docker/daemon.json": tt.daemon}, failures: tt.failures}
+		o := newFakeOptions(runner)
+		o.RegistryPort = 5001
+		o.PushCAFile = tt.pushCA
+		err := o.validateDaemonRegistry()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
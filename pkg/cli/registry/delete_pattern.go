@@ -0,0 +1,180 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+)
+
+// patternMatches holds the tags of a repository matching --tag-pattern.
+type patternMatches struct {
+	repository string
+	tags       []string
+}
+
+// deleteByPattern deletes the tags matching --tag-pattern of --name, or of every repository with --all-repos.
+// The repositories are handled concurrently, the tags of a repository one by one.
+func (o *RegistryOptions) deleteByPattern() error {
+	pattern, err := regexp.Compile(o.TagPattern)
+	if err != nil {
+		return fmt.Errorf("invalid --tag-pattern %q: %w", o.TagPattern, err)
+	}
+	names := []string{o.Name}
+	if o.AllRepos {
+		if names, err = o.catalog(); err != nil {
+			return err
+		}
+		sort.Strings(names)
+	}
+	matches, err := o.matchTags(names, pattern)
+	if err != nil {
+		return err
+	}
+	var total int
+	for _, m := range matches {
+		total += len(m.tags)
+	}
+	if total == 0 {
+		o.log("delete-tag-pattern").Infof("no tags match %q", o.TagPattern)
+		return nil
+	}
+	if o.DryRun {
+		for _, m := range matches {
+			for _, tag := range m.tags {
+				_, _ = fmt.Fprintf(o.IOStreams.Out, "%s:%s would be deleted\n", m.repository, tag)
+			}
+		}
+		return nil
+	}
+	if !options.AssumeYes {
+		_, _ = fmt.Fprintf(o.IOStreams.Out, "%d tags of %d repositories will be deleted, continue? Please input (yes/no)", total, len(matches))
+		if !utils.AskForConfirmation() {
+			return nil
+		}
+	}
+
+	report := &TagDeletionReport{Items: make([]TagDeletion, len(matches))}
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, tagCountConcurrency)
+	for i, m := range matches {
+		wg.Add(1)
+		go func(i int, m patternMatches) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			report.Items[i] = o.deleteMatchedTags(m)
+		}(i, m)
+	}
+	wg.Wait()
+	if err = o.PrintFlags.Print(report, o.IOStreams.Out); err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("failed to delete tags of %d of %d repositories", failed, len(report.Items))
+	}
+	o.log("delete-tag-pattern").Infof("deleted %d tags of %d repositories, run '%s' on %s to reclaim disk space",
+		total, len(matches), garbageCollectCmd, o.Node)
+	return nil
+}
+
+// matchTags lists the tags of names concurrently, and returns the repositories with tags matching pattern.
+func (o *RegistryOptions) matchTags(names []string, pattern *regexp.Regexp) ([]patternMatches, error) {
+	all := make([]patternMatches, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, tagCountConcurrency)
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			tags, err := o.tagsOf(name)
+			if err != nil {
+				errs[i] = fmt.Errorf("list tags of %s error: %w", name, err)
+				return
+			}
+			all[i].repository = name
+			for _, tag := range tags {
+				if pattern.MatchString(tag) {
+					all[i].tags = append(all[i].tags, tag)
+				}
+			}
+		}(i, name)
+	}
+	wg.Wait()
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	var matches []patternMatches
+	for _, m := range all {
+		if len(m.tags) > 0 {
+			sort.Strings(m.tags)
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
+
+// deleteMatchedTags deletes the tags of a repository like deleteTag, and stops at the first error.
+func (o *RegistryOptions) deleteMatchedTags(m patternMatches) TagDeletion {
+	result := TagDeletion{Repository: m.repository, Matched: len(m.tags)}
+	for _, tag := range m.tags {
+		if o.interrupted() {
+			result.Error = "interrupted"
+			return result
+		}
+		if err := o.deleteTagOf("delete-tag-pattern", m.repository, tag); err != nil {
+			result.Error = fmt.Sprintf("delete tag %s error: %s", tag, err.Error())
+			return result
+		}
+		result.Deleted++
+	}
+	return result
+}
+
+// deleteTagOf deletes tag of repository by registry API, or from registry volume if delete is disabled,
+// and logs the result under step. A tag sharing the manifest of a tag deleted before is gone already, which is not an error.
+func (o *RegistryOptions) deleteTagOf(step, repository, tag string) error {
+	deleted, err := o.deleteManifest(repository, tag)
+	if err != nil {
+		if exists, existsErr := o.manifestExists(repository, tag); existsErr == nil && !exists {
+			return nil
+		}
+		return err
+	}
+	if deleted {
+		o.log(step).V(2).Infof("deleted %s:%s by registry API", repository, tag)
+		return nil
+	}
+	imagePath := fmt.Sprintf("%s/docker/registry/v2/repositories/%s/_manifests/tags/%s", o.RegistryVolume, repository, tag)
+	if err = o.removePath(imagePath); err != nil {
+		return err
+	}
+	o.log(step).V(2).Infof("removed %s:%s from registry volume", repository, tag)
+	return nil
+}
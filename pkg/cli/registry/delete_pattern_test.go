@@ -0,0 +1,142 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+)
+
+func TestDeleteByPattern(t *testing.T) {
+	tags := map[string][]string{
+		"caas4/cephcsi": {"v1", "v1-snapshot", "v2-snapshot"},
+		"caas4/etcd":    {"3.5.0"},
+		"csi/provision": {"x-snapshot"},
+	}
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v2/_catalog":
+			_, _ = w.Write([]byte(`{"repositories":["caas4/cephcsi","caas4/etcd","csi/provision"]}`))
+		case strings.HasSuffix(r.URL.Path, "/tags/list"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+			data, _ := json.Marshal(Image{Name: name, Tags: tags[name]})
+			_, _ = w.Write(data)
+		case r.Method == http.MethodHead && strings.Contains(r.URL.Path, "/manifests/"):
+			w.Header().Set("Docker-Content-Digest", "sha256:"+strings.ReplaceAll(r.URL.Path, "/", "_"))
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/manifests/"):
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assumeYes := options.AssumeYes
+	options.AssumeYes = true
+	defer func() { options.AssumeYes = assumeYes }()
+	out := &bytes.Buffer{}
+	o := newFakeOptions(&fakeRunner{})
+	o.IOStreams.Out = out
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.AllRepos = true
+	o.TagPattern = "-snapshot$"
+	o.cacheDir = t.TempDir()
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err = cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 3 {
+		t.Errorf("expected 3 manifests deleted, got %v", deleted)
+	}
+	report := &TagDeletionReport{}
+	if err = json.Unmarshal(out.Bytes(), report); err != nil {
+		t.Fatalf("invalid report %q: %v", out.String(), err)
+	}
+	expected := []TagDeletion{
+		{Repository: "caas4/cephcsi", Matched: 2, Deleted: 2},
+		{Repository: "csi/provision", Matched: 1, Deleted: 1},
+	}
+	if len(report.Items) != len(expected) || report.Items[0] != expected[0] || report.Items[1] != expected[1] {
+		t.Errorf("expected report %v, got %v", expected, report.Items)
+	}
+}
+
+func TestValidateArgsDeleteTagPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(o *RegistryOptions)
+		message string
+	}{
+		{name: "name", setup: func(o *RegistryOptions) { o.Name = "caas4/cephcsi" }},
+		{name: "all repos", setup: func(o *RegistryOptions) { o.AllRepos = true }},
+		{name: "no repository", setup: func(o *RegistryOptions) {}, message: "one of --name or --all-repos"},
+		{name: "name and all repos", setup: func(o *RegistryOptions) { o.Name, o.AllRepos = "caas4/cephcsi", true }, message: "mutually exclusive"},
+		{name: "with tag", setup: func(o *RegistryOptions) { o.AllRepos, o.Tag = true, "v1" }, message: "mutually exclusive with --tag"},
+		{name: "invalid", setup: func(o *RegistryOptions) { o.AllRepos, o.TagPattern = true, "(" }, message: "invalid --tag-pattern"},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.SSHConfig.Password = "password"
+		o.TagPattern = "-snapshot$"
+		tt.setup(o)
+		err := o.ValidateArgsDelete(&cobra.Command{})
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+	o := newFakeOptions(&fakeRunner{})
+	o.SSHConfig.Password = "password"
+	o.AllRepos = true
+	if err := o.ValidateArgsDelete(&cobra.Command{}); err == nil || !strings.Contains(err.Error(), "requires --tag-pattern") {
+		t.Errorf("expected --all-repos without --tag-pattern rejected, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// imageConfig holds the platform fields and the creation time of an image config blob.
+type imageConfig struct {
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Variant      string    `json:"variant,omitempty"`
+	Created      time.Time `json:"created"`
+}
+
+// listImageDetails prints the platforms of every tag of o.Name, the tags are inspected concurrently.
+// With --since or --before, only the tags whose image was created within the window are printed.
+func (o *RegistryOptions) listImageDetails() error {
+	since, before, err := o.timeWindow(time.Now())
+	if err != nil {
+		return err
+	}
+	tags, err := o.tags()
+	if err != nil {
+		return err
+	}
+	sort.Strings(tags)
+	details := &ImageDetails{Name: o.Name, Items: make([]ImageDetail, len(tags))}
+	errs := make([]error, len(tags))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, tagCountConcurrency)
+	for i, tag := range tags {
+		wg.Add(1)
+		go func(i int, tag string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			detail, err := o.inspectTag(o.Name, tag)
+			if err != nil {
+				errs[i] = fmt.Errorf("inspect %s:%s error: %w", o.Name, tag, err)
+				return
+			}
+			details.Items[i] = detail
+		}(i, tag)
+	}
+	wg.Wait()
+	if err = utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
+	if o.timeFiltered() {
+		details.Items = filterCreated(details.Items, since, before)
+	}
+	return o.PrintFlags.Print(details, o.IOStreams.Out)
+}
+
+// filterCreated returns the items created within the window of since and before.
+func filterCreated(items []ImageDetail, since, before time.Time) []ImageDetail {
+	filtered := make([]ImageDetail, 0, len(items))
+	for _, v := range items {
+		if inTimeWindow(v.Created, since, before) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// inspectTag reads the manifest of tag. The platforms of a manifest list or an OCI index are listed from its entries,
+// the platform and the creation time of a single image manifest are read from its config blob. The creation time of
+// a manifest list, the latest of its platform images, is only read with --since or --before, as it costs two
+// requests per platform.
+func (o *RegistryOptions) inspectTag(name, tag string) (ImageDetail, error) {
+	detail := ImageDetail{Tag: tag}
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, tag))
+	header := map[string]string{"Accept": manifestMediaTypes}
+	resp, code, respHeader, respErr := o.request(url, "GET", o.requestHeader(header), nil)
+	if respErr != nil {
+		return detail, respErr
+	}
+	body, err := repositoryResponse(name, resp, code)
+	if err != nil {
+		return detail, err
+	}
+	detail.Digest, detail.MediaType = respHeader.Get("Docker-Content-Digest"), respHeader.Get("Content-Type")
+	m := new(manifest)
+	if err = json.Unmarshal(body, m); err != nil {
+		return detail, fmt.Errorf("invalid manifest: %w", err)
+	}
+	for _, v := range m.Manifests {
+		entry := PlatformManifest{Digest: v.Digest, MediaType: v.MediaType}
+		if v.Platform != nil {
+			entry.Architecture, entry.OS, entry.Variant = v.Platform.Architecture, v.Platform.OS, v.Platform.Variant
+		}
+		detail.Manifests = append(detail.Manifests, entry)
+	}
+	if m.Config == nil {
+		if o.timeFiltered() {
+			detail.Created, err = o.latestCreated(name, m.Manifests)
+		}
+		return detail, err
+	}
+	config, err := o.imageConfig(name, m.Config.Digest)
+	if err != nil {
+		return detail, err
+	}
+	detail.Created = createdTime(config)
+	detail.Manifests = []PlatformManifest{{
+		Digest:       detail.Digest,
+		MediaType:    detail.MediaType,
+		Architecture: config.Architecture,
+		OS:           config.OS,
+		Variant:      config.Variant,
+	}}
+	return detail, nil
+}
+
+// imageConfig reads the config blob of an image manifest by 'GET /v2/<name>/blobs/<digest>'.
+func (o *RegistryOptions) imageConfig(name, digest string) (*imageConfig, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/blobs/%s", name, digest))
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(nil), nil)
+	if respErr != nil {
+		return nil, respErr
+	}
+	body, err := repositoryResponse(name, resp, code)
+	if err != nil {
+		return nil, err
+	}
+	config := new(imageConfig)
+	if err = json.Unmarshal(body, config); err != nil {
+		return nil, fmt.Errorf("invalid config blob %s: %w", digest, err)
+	}
+	return config, nil
+}
+
+// latestCreated returns the latest creation time of the platform images of a manifest list, nil if none has one.
+func (o *RegistryOptions) latestCreated(name string, platforms []descriptor) (*time.Time, error) {
+	var latest *time.Time
+	for _, platform := range platforms {
+		m, err := o.manifestOf(name, platform.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if m.Config == nil {
+			continue
+		}
+		config, err := o.imageConfig(name, m.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if created := createdTime(config); created != nil && (latest == nil || created.After(*latest)) {
+			latest = created
+		}
+	}
+	return latest, nil
+}
+
+// manifestOf reads the manifest or manifest list of digest in repository name.
+func (o *RegistryOptions) manifestOf(name, digest string) (*manifest, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, digest))
+	header := map[string]string{"Accept": manifestMediaTypes}
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(header), nil)
+	if respErr != nil {
+		return nil, respErr
+	}
+	body, err := repositoryResponse(name, resp, code)
+	if err != nil {
+		return nil, err
+	}
+	m := new(manifest)
+	if err = json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s of %s: %w", digest, name, err)
+	}
+	return m, nil
+}
+
+// createdTime returns the creation time of config, nil if it has none.
+func createdTime(config *imageConfig) *time.Time {
+	if config.Created.IsZero() {
+		return nil
+	}
+	created := config.Created
+	return &created
+}
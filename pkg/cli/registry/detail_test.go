@@ -0,0 +1,96 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestListImageDetails(t *testing.T) {
+	const (
+		listType     = "application/vnd.docker.distribution.manifest.list.v2+json"
+		manifestType = "application/vnd.docker.distribution.manifest.v2+json"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/caas4/cephcsi/tags/list":
+			_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["v2","v1"]}`))
+		case "/v2/caas4/cephcsi/manifests/v1":
+			w.Header().Set("Content-Type", listType)
+			w.Header().Set("Docker-Content-Digest", "sha256:list")
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"manifests":[` +
+				`{"mediaType":"` + manifestType + `","digest":"sha256:amd64","platform":{"architecture":"amd64","os":"linux"}},` +
+				`{"mediaType":"` + manifestType + `","digest":"sha256:arm64","platform":{"architecture":"arm64","os":"linux","variant":"v8"}}]}`))
+		case "/v2/caas4/cephcsi/manifests/v2":
+			w.Header().Set("Content-Type", manifestType)
+			w.Header().Set("Docker-Content-Digest", "sha256:single")
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:config"},"layers":[{"digest":"sha256:l1"}]}`))
+		case "/v2/caas4/cephcsi/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"architecture":"arm64","os":"linux","rootfs":{"type":"layers"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	o := newFakeOptions(&fakeRunner{})
+	o.IOStreams.Out = out
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.Name = "caas4/cephcsi"
+	o.cacheDir = t.TempDir()
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err = cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.listImageDetails(); err != nil {
+		t.Fatal(err)
+	}
+	details := &ImageDetails{}
+	if err = json.Unmarshal(out.Bytes(), details); err != nil {
+		t.Fatalf("invalid output %q: %v", out.String(), err)
+	}
+	if len(details.Items) != 2 || details.Items[0].Tag != "v1" || details.Items[1].Tag != "v2" {
+		t.Fatalf("expected details of v1 and v2, got %+v", details.Items)
+	}
+	list := details.Items[0]
+	if list.Digest != "sha256:list" || list.MediaType != listType || len(list.Manifests) != 2 ||
+		list.Manifests[0].Platform() != "linux/amd64" || list.Manifests[1].Platform() != "linux/arm64/v8" ||
+		list.Manifests[1].Digest != "sha256:arm64" {
+		t.Errorf("unexpected detail of manifest list: %+v", list)
+	}
+	single := details.Items[1]
+	if len(single.Manifests) != 1 || single.Manifests[0].Platform() != "linux/arm64" || single.Manifests[0].Digest != "sha256:single" {
+		t.Errorf("unexpected detail of single manifest: %+v", single)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// dockerInvocationRegexp matches the docker CLI invoked by a command, at its start or after a shell separator.
+var dockerInvocationRegexp = regexp.MustCompile(`(^|[|;&(]\s*)docker(\s)`)
+
+// validateDockerHost validates --docker-host is a tcp, unix or ssh address of a docker daemon,
+// which replaces the local docker and so can not be used with the flags managing it.
+func (o *RegistryOptions) validateDockerHost() error {
+	if o.DockerHost == "" {
+		return nil
+	}
+	u, err := url.Parse(o.DockerHost)
+	if err != nil || (u.Scheme != "tcp" && u.Scheme != "unix" && u.Scheme != "ssh") || (u.Host == "" && u.Path == "") {
+		return fmt.Errorf("--docker-host %s is invalid, must be like tcp://10.0.0.5:2375, unix:///var/run/docker.sock or ssh://user@host", o.DockerHost)
+	}
+	// the address is put into the commands run on node
+	if strings.ContainsAny(o.DockerHost, " '\"$`;&|") {
+		return fmt.Errorf("--docker-host %s is invalid, must not contain spaces, quotes or shell characters", o.DockerHost)
+	}
+	switch {
+	case o.Rootless:
+		return fmt.Errorf("--docker-host can not be used with --rootless")
+	case o.Systemd:
+		return fmt.Errorf("--docker-host can not be used with --systemd, systemd service %s runs the registry by the local docker", registryUnit)
+	case o.RemoveDocker:
+		return fmt.Errorf("--remove-docker can not be used with --docker-host, the docker daemon is not managed by kcctl")
+	}
+	return nil
+}
+
+// withDockerHost makes every docker CLI invoked by cmd target --docker-host by '-H', as DOCKER_HOST would.
+// DOCKER_HOST itself is not exported, since the command is split on '&&' to run each part by sudo.
+func (o *RegistryOptions) withDockerHost(cmd string) string {
+	if o.DockerHost == "" {
+		return cmd
+	}
+	return dockerInvocationRegexp.ReplaceAllString(cmd, "${1}docker -H "+shellQuote(o.DockerHost)+"${2}")
+}
+
+// checkDockerHost checks the docker CLI on node reaches the daemon of --docker-host.
+func (o *RegistryOptions) checkDockerHost() error {
+	ret, err := o.runDockerCmd("docker info -f '{{.ServerVersion}}'")
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return unreachableError(fmt.Errorf("docker daemon %s is not reachable from %s: %s", o.DockerHost, o.Node, strings.TrimSpace(ret.Stderr)))
+	}
+	o.log("docker-host").V(2).Infof("docker daemon %s of version %s is reachable", o.DockerHost, strings.TrimSpace(ret.Stdout))
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithDockerHost(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.DockerHost = "tcp://10.0.0.5:2375"
+	tests := map[string]string{
+		"docker stop registry && docker rm registry":      "docker -H 'tcp://10.0.0.5:2375' stop registry && docker -H 'tcp://10.0.0.5:2375' rm registry",
+		"echo 'p' | docker login 10.0.0.6:5000":           "echo 'p' | docker -H 'tcp://10.0.0.5:2375' login 10.0.0.6:5000",
+		"docker images | grep -v REPOSITORY | wc -l":      "docker -H 'tcp://10.0.0.5:2375' images | grep -v REPOSITORY | wc -l",
+		"cat /etc/docker/daemon.json":                     "cat /etc/docker/daemon.json",
+		"systemctl restart docker && rm -rf /data/docker": "systemctl restart docker && rm -rf /data/docker",
+	}
+	for cmd, expected := range tests {
+		if got := o.withDockerHost(cmd); got != expected {
+			t.Errorf("%q: expected %q, got %q", cmd, expected, got)
+		}
+	}
+	o.DockerHost = ""
+	if got := o.withDockerHost("docker ps"); got != "docker ps" {
+		t.Errorf("expected the command unchanged without --docker-host, got %q", got)
+	}
+}
+
+func TestValidateDockerHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		rootless bool
+		systemd  bool
+		message  string
+	}{
+		{host: "tcp://10.0.0.5:2375"},
+		{host: "unix:///var/run/docker.sock"},
+		{host: "ssh://kc@10.0.0.5"},
+		{host: "10.0.0.5:2375", message: "is invalid"},
+		{host: "http://10.0.0.5:2375", message: "is invalid"},
+		{host: "unix:///var/run/docker.sock;id", message: "shell characters"},
+		{host: "tcp://10.0.0.5:2375", rootless: true, message: "--rootless"},
+		{host: "tcp://10.0.0.5:2375", systemd: true, message: "--systemd"},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.DockerHost, o.Rootless, o.Systemd = tt.host, tt.rootless, tt.systemd
+		err := o.validateDockerHost()
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.host, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.host, tt.message, err)
+		}
+	}
+}
+
+func TestCheckDockerHost(t *testing.T) {
+	cmd := "docker -H 'tcp://10.0.0.5:2375' info -f '{{.ServerVersion}}'"
+	runner := &fakeRunner{outputs: map[string]string{cmd: "24.0.7\n"}}
+	o := newFakeOptions(runner)
+	o.DockerHost = "tcp://10.0.0.5:2375"
+	if err := o.checkDockerHost(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runner.failures = map[string]string{cmd: "Cannot connect to the Docker daemon at tcp://10.0.0.5:2375"}
+	if err := o.checkDockerHost(); err == nil || !strings.Contains(err.Error(), "not reachable") {
+		t.Errorf("expected unreachable daemon error, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// envKeyRegexp matches an environment variable name.
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// managedEnvKeys are the registry settings deploy manages, with the flag which manages each of them.
+var managedEnvKeys = map[string]string{
+	"REGISTRY_HTTP_ADDR":                        "--registry-port",
+	"REGISTRY_STORAGE":                          "--registry-volume",
+	"REGISTRY_STORAGE_FILESYSTEM_ROOTDIRECTORY": "--registry-volume",
+}
+
+// parseEnv splits a --env 'KEY=VALUE' entry, the value may be empty.
+func parseEnv(entry string) (string, string, error) {
+	key, value, ok := strings.Cut(entry, "=")
+	if !ok {
+		return "", "", fmt.Errorf("--env %s is invalid, must be KEY=VALUE", entry)
+	}
+	if !envKeyRegexp.MatchString(key) {
+		return "", "", fmt.Errorf("--env %s has invalid key %s, must match %s", entry, key, envKeyRegexp.String())
+	}
+	// commands run by sudo are split on '&&'
+	if strings.Contains(value, "&&") {
+		return "", "", fmt.Errorf("--env %s is invalid, the value can not contain '&&'", entry)
+	}
+	return key, value, nil
+}
+
+// envArgs returns the '-e' arguments of the registry container, the cache settings come first,
+// so that an --env entry of the same key overrides them.
+func (o *RegistryOptions) envArgs() []string {
+	env := o.registryEnv()
+	if o.Systemd {
+		// the unit passes no value through its ExecStart, see registryEnvPath
+		if len(env) == 0 {
+			return nil
+		}
+		return []string{"--env-file " + registryEnvPath}
+	}
+	args := make([]string, 0, len(env))
+	for _, entry := range env {
+		args = append(args, "-e "+shellQuote(entry))
+	}
+	return args
+}
+
+// registryEnv returns the environment of the registry container, the cache settings followed by --env.
+func (o *RegistryOptions) registryEnv() []string {
+	return append(o.cacheEnv(), o.Env...)
+}
+
+// warnManagedEnv warns about the --env entries overriding a setting deploy manages,
+// e.g. the registry may listen on another port than the published one.
+func (o *RegistryOptions) warnManagedEnv() {
+	for _, entry := range o.Env {
+		key, _, _ := strings.Cut(entry, "=")
+		if flag, ok := managedEnvKeys[key]; ok {
+			o.log("install-registry").Warnf("--env %s overrides the setting deploy manages by %s, the registry may be unreachable or lose its data", key, flag)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		entry   string
+		key     string
+		value   string
+		message string
+	}{
+		{entry: "REGISTRY_LOG_LEVEL=debug", key: "REGISTRY_LOG_LEVEL", value: "debug"},
+		{entry: `REGISTRY_STORAGE_MAINTENANCE_READONLY={"enabled":true}`, key: "REGISTRY_STORAGE_MAINTENANCE_READONLY", value: `{"enabled":true}`},
+		{entry: "EMPTY=", key: "EMPTY"},
+		{entry: "REGISTRY_LOG_LEVEL", message: "must be KEY=VALUE"},
+		{entry: "1KEY=v", message: "invalid key"},
+		{entry: "KEY=a && b", message: "'&&'"},
+	}
+	for _, tt := range tests {
+		key, value, err := parseEnv(tt.entry)
+		if tt.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.entry, tt.message, err)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("%s: expected %s=%s, got %s=%s, %v", tt.entry, tt.key, tt.value, key, value, err)
+		}
+	}
+}
+
+func TestRunRegistryCmdEnv(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.Env = []string{"REGISTRY_LOG_LEVEL=debug", "REGISTRY_HTTP_SECRET=it's secret"}
+	expected := "docker run -d -v /opt/registry:/var/lib/registry -p 5000:5000 --restart=always " +
+		`-e 'REGISTRY_LOG_LEVEL=debug' -e 'REGISTRY_HTTP_SECRET=it'\''s secret' --name registry registry:2`
+	if cmd := o.runRegistryCmd(); cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
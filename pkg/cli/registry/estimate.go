@@ -0,0 +1,203 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// localLayer is a layer of a local image.
+type localLayer struct {
+	DiffID string
+	// uncompressed size of the layer
+	Size int64
+	// digests of the compressed blob, known only if docker pushed or pulled the layer before
+	Digests []string
+}
+
+// v2Metadata is an entry of docker 'distribution/v2metadata-by-diffid'.
+type v2Metadata struct {
+	Digest           string `json:"Digest"`
+	SourceRepository string `json:"SourceRepository"`
+}
+
+// estimatePush reports how many layer bytes pushing the loaded images would transfer,
+// nothing is pushed. A layer is new unless registry already holds one of its blobs
+// or an earlier image in the report transfers it.
+func (o *RegistryOptions) estimatePush() error {
+	images, err := o.pushableImages()
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		o.log("estimate").Info("no images to push")
+		return nil
+	}
+	imageRoot, err := o.dockerImageRoot()
+	if err != nil {
+		return err
+	}
+
+	estimate := &PushEstimate{}
+	layers := make(map[string]localLayer)
+	counted := make(map[string]bool)
+	transferred := make(map[string]bool)
+	for _, image := range images {
+		diffIDs, err := o.imageDiffIDs(image)
+		if err != nil {
+			return err
+		}
+		for i, chainID := range chainIDs(diffIDs) {
+			if _, ok := layers[diffIDs[i]]; ok {
+				continue
+			}
+			layer, err := o.localLayer(imageRoot, diffIDs[i], chainID)
+			if err != nil {
+				return err
+			}
+			layers[diffIDs[i]] = layer
+		}
+		for _, target := range o.retagTargets(image) {
+			item := PushEstimateItem{Image: image.Ref(), Target: target, Layers: len(diffIDs)}
+			repository := targetRepository(target)
+			for _, diffID := range diffIDs {
+				layer := layers[diffID]
+				item.TotalBytes += layer.Size
+				if !counted[diffID] {
+					counted[diffID] = true
+					estimate.TotalBytes += layer.Size
+				}
+				if transferred[diffID] || o.blobExists(repository, layer.Digests) {
+					continue
+				}
+				transferred[diffID] = true
+				item.NewLayers++
+				item.NewBytes += layer.Size
+				estimate.NewBytes += layer.Size
+			}
+			estimate.Items = append(estimate.Items, item)
+		}
+	}
+
+	if o.KeepLocalImages {
+		o.keepImages()
+	} else if err = o.removeImages(); err != nil {
+		return err
+	}
+	return o.PrintFlags.Print(estimate, o.IOStreams.Out)
+}
+
+// dockerImageRoot returns the image metadata directory of docker, e.g. /var/lib/docker/image/overlay2.
+func (o *RegistryOptions) dockerImageRoot() (string, error) {
+	ret, err := o.runDockerCmd(`docker info -f '{{.DockerRootDir}}/image/{{.Driver}}'`)
+	if err != nil {
+		return "", err
+	}
+	if err = ret.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(ret.Stdout), nil
+}
+
+// imageDiffIDs returns the uncompressed layer digests of image, from the base layer up.
+func (o *RegistryOptions) imageDiffIDs(image localImage) ([]string, error) {
+	ret, err := o.runDockerCmd(fmt.Sprintf(`docker image inspect -f '{{range .RootFS.Layers}}{{println .}}{{end}}' %s`, image.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err = ret.Error(); err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(ret.Stdout), nil
+}
+
+// localLayer reads the layer size from docker layerdb and the blob digests from distribution metadata.
+func (o *RegistryOptions) localLayer(imageRoot, diffID, chainID string) (localLayer, error) {
+	layer := localLayer{DiffID: diffID}
+	cmd := fmt.Sprintf("cat %s/layerdb/sha256/%s/size && echo && (cat %s/distribution/v2metadata-by-diffid/sha256/%s 2>/dev/null || true)",
+		imageRoot, strings.TrimPrefix(chainID, "sha256:"), imageRoot, strings.TrimPrefix(diffID, "sha256:"))
+	ret, err := o.runCmd(cmd)
+	if err != nil {
+		return layer, err
+	}
+	if err = ret.Error(); err != nil {
+		return layer, fmt.Errorf("read layer %s error: %w", diffID, err)
+	}
+	size, metadata, _ := strings.Cut(ret.Stdout, "\n")
+	if layer.Size, err = strconv.ParseInt(strings.TrimSpace(size), 10, 64); err != nil {
+		return layer, fmt.Errorf("invalid size of layer %s: %s", diffID, size)
+	}
+	if metadata = strings.TrimSpace(metadata); metadata == "" {
+		return layer, nil
+	}
+	var entries []v2Metadata
+	if err = json.Unmarshal([]byte(metadata), &entries); err != nil {
+		return layer, fmt.Errorf("invalid distribution metadata of layer %s: %w", diffID, err)
+	}
+	for _, v := range entries {
+		layer.Digests = append(layer.Digests, v.Digest)
+	}
+	return layer, nil
+}
+
+// blobExists checks by 'HEAD /v2/<name>/blobs/<digest>' whether registry holds any of digests in repository.
+func (o *RegistryOptions) blobExists(repository string, digests []string) bool {
+	for _, digest := range digests {
+		url := o.apiURL(fmt.Sprintf("/v2/%s/blobs/%s", repository, digest))
+		_, code, _, err := o.request(url, "HEAD", o.requestHeader(nil), nil)
+		if err != nil {
+			o.log("estimate").V(2).Warnf("check blob %s of %s error: %s", digest, repository, err.Error())
+			continue
+		}
+		if code == http.StatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+// chainIDs returns the chain ID of every layer, which names the layer in docker layerdb:
+// the first chain ID is the diff ID, the next is sha256 of '<parent chain ID> <diff ID>'.
+func chainIDs(diffIDs []string) []string {
+	ids := make([]string, 0, len(diffIDs))
+	for i, diffID := range diffIDs {
+		if i == 0 {
+			ids = append(ids, diffID)
+			continue
+		}
+		sum := sha256.Sum256([]byte(ids[i-1] + " " + diffID))
+		ids = append(ids, "sha256:"+hex.EncodeToString(sum[:]))
+	}
+	return ids
+}
+
+// targetRepository returns the repository of a re-tag target 'ip:port/<repo>:<tag>'.
+func targetRepository(target string) string {
+	_, ref, _ := strings.Cut(target, "/")
+	if i := strings.LastIndex(ref, ":"); i > 0 {
+		ref = ref[:i]
+	}
+	return ref
+}
@@ -0,0 +1,120 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestEstimatePush(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && strings.HasSuffix(r.URL.Path, "/blobs/sha256:b1") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageRoot := "/var/lib/docker/image/overlay2"
+	outputs := map[string]string{
+		`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`:                  "caas4/a v1 id1\ncaas4/b v1 id2\n",
+		`docker info -f '{{.DockerRootDir}}/image/{{.Driver}}'`:                      imageRoot + "\n",
+		`docker image inspect -f '{{range .RootFS.Layers}}{{println .}}{{end}}' id1`: "sha256:l1\nsha256:l2\n",
+		`docker image inspect -f '{{range .RootFS.Layers}}{{println .}}{{end}}' id2`: "sha256:l1\nsha256:l3\n",
+	}
+	layers := map[string]string{
+		"sha256:l1": "100\n" + `[{"Digest":"sha256:b1","SourceRepository":"docker.io/caas4/a"}]`,
+		"sha256:l2": "200\n",
+		"sha256:l3": "300\n" + `[{"Digest":"sha256:b3","SourceRepository":"docker.io/caas4/b"}]`,
+	}
+	for _, diffIDs := range [][]string{{"sha256:l1", "sha256:l2"}, {"sha256:l1", "sha256:l3"}} {
+		for i, chainID := range chainIDs(diffIDs) {
+			cmd := fmt.Sprintf("cat %s/layerdb/sha256/%s/size && echo && (cat %s/distribution/v2metadata-by-diffid/sha256/%s 2>/dev/null || true)",
+				imageRoot, strings.TrimPrefix(chainID, "sha256:"), imageRoot, strings.TrimPrefix(diffIDs[i], "sha256:"))
+			outputs[cmd] = layers[diffIDs[i]]
+		}
+	}
+
+	runner := &fakeRunner{outputs: outputs}
+	o := newFakeOptions(runner)
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	o.KeepLocalImages = true
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err = cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.estimatePush(); err != nil {
+		t.Fatal(err)
+	}
+	estimate := &PushEstimate{}
+	if err = json.Unmarshal(out.Bytes(), estimate); err != nil {
+		t.Fatalf("unmarshal push estimate %q: %v", out.String(), err)
+	}
+	if estimate.TotalBytes != 600 || estimate.NewBytes != 500 {
+		t.Errorf("expected 600 total and 500 new bytes, got %d and %d", estimate.TotalBytes, estimate.NewBytes)
+	}
+	newBytes := []int64{200, 0, 300, 0}
+	if len(estimate.Items) != len(newBytes) {
+		t.Fatalf("expected %d estimate items, got %v", len(newBytes), estimate.Items)
+	}
+	for i, item := range estimate.Items {
+		if item.NewBytes != newBytes[i] {
+			t.Errorf("%s: expected %d new bytes, got %d", item.Target, newBytes[i], item.NewBytes)
+		}
+	}
+	for _, c := range runner.cmds {
+		if strings.HasPrefix(c, "docker push") {
+			t.Errorf("unexpected push command %s", c)
+		}
+	}
+}
+
+func TestChainIDs(t *testing.T) {
+	ids := chainIDs([]string{"sha256:a", "sha256:b"})
+	// sha256 of 'sha256:a sha256:b'
+	expected := []string{"sha256:a", "sha256:970a948bffa8de94d6e22d747ba8c95030e6e546909f98f54e99a13005e173a8"}
+	if len(ids) != 2 || ids[0] != expected[0] || ids[1] != expected[1] {
+		t.Errorf("expected chain ids %v, got %v", expected, ids)
+	}
+}
+
+func TestTargetRepository(t *testing.T) {
+	if repo := targetRepository("10.0.0.111:5000/library/caas4/a:v1"); repo != "library/caas4/a" {
+		t.Errorf("expected library/caas4/a, got %s", repo)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+// The exit codes of registry subcommands, scripts may rely on them.
+const (
+	// exitUsage is the exit code of invalid flags and arguments, and of errors without a category.
+	exitUsage = 1
+	// exitUnreachable is the exit code of a node or registry which can not be reached.
+	exitUnreachable = 2
+	// exitRemote is the exit code of a command failed on node.
+	exitRemote = 3
+)
+
+// exitError is an error of a category, which exits the subcommand with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks err as an error of flags and arguments, nil stays nil.
+func usageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitUsage, err: err}
+}
+
+// unreachableError marks err as an error of reaching node or registry, nil stays nil.
+func unreachableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitUnreachable, err: err}
+}
+
+// exitCode returns the exit code of err by its category, a command exited non-zero on node is exitRemote,
+// and a network error is exitUnreachable.
+func exitCode(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	var ce *sshutils.CmdError
+	if errors.As(err, &ce) {
+		return exitRemote
+	}
+	// the connection errors of registry API requests
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return exitUnreachable
+	}
+	return exitUsage
+}
+
+// sshError marks the error of running a command on node over ssh as unreachable,
+// the errors of canceled or timed out commands are kept.
+func sshError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return unreachableError(err)
+}
@@ -0,0 +1,137 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+func TestExitCode(t *testing.T) {
+	cmdErr := sshutils.Result{Host: "10.0.0.111", Cmd: "docker ps", ExitCode: 1, Stderr: "permission denied"}.Error()
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "uncategorized", err: errors.New("unknown"), want: exitUsage},
+		{name: "usage", err: usageError(errors.New("--node must be specified")), want: exitUsage},
+		{name: "unreachable", err: unreachableError(errors.New("registry is not reachable")), want: exitUnreachable},
+		{name: "network", err: fmt.Errorf("request failed: %w", dialErr), want: exitUnreachable},
+		{name: "ssh", err: sshError(errors.New("ssh: handshake failed")), want: exitUnreachable},
+		{name: "remote command", err: cmdErr, want: exitRemote},
+		{name: "wrapped remote command", err: fmt.Errorf("install docker: %w", cmdErr), want: exitRemote},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+	if usageError(nil) != nil || unreachableError(nil) != nil || sshError(nil) != nil {
+		t.Error("expected nil error kept nil")
+	}
+}
+
+func TestExitCode_Operations(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	_, err := o.runWith(func(sshConfig *sshutils.SSH, host, cmd string) (sshutils.Result, error) {
+		return sshutils.Result{Host: host, Cmd: cmd}, errors.New("ssh: handshake failed")
+	}, o.Node, "docker ps")
+	if exitCode(err) != exitUnreachable {
+		t.Errorf("expected ssh error exits with %d, got %d: %v", exitUnreachable, exitCode(err), err)
+	}
+	if err = o.ValidateArgsDeploy(); exitCode(usageError(err)) != exitUsage {
+		t.Errorf("expected validation error exits with %d, got %v", exitUsage, err)
+	}
+	runner := &fakeRunner{failures: map[string]string{"docker ps": "Cannot connect to the Docker daemon"}}
+	o = newFakeOptions(runner)
+	ret, err := o.runCmd("docker ps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode(ret.Error()) != exitRemote {
+		t.Errorf("expected failed command exits with %d, got %d", exitRemote, exitCode(ret.Error()))
+	}
+}
+
+func TestExitCode_InstallSteps(t *testing.T) {
+	loadCmd := "docker load -i images.tar"
+	tests := []struct {
+		name     string
+		rollback bool
+		logsTail int
+		run      func(o *RegistryOptions) error
+		want     int
+	}{
+		{name: "remote command", want: exitRemote},
+		{name: "remote command with registry logs", logsTail: 10, want: exitRemote},
+		{name: "remote command rolled back", rollback: true, want: exitRemote},
+		{name: "remote command with registry logs rolled back", rollback: true, logsTail: 10, want: exitRemote},
+		{name: "unreachable node rolled back", rollback: true, want: exitUnreachable, run: func(o *RegistryOptions) error {
+			_, err := o.runWith(func(sshConfig *sshutils.SSH, host, cmd string) (sshutils.Result, error) {
+				return sshutils.Result{Host: host, Cmd: cmd}, errors.New("ssh: handshake failed")
+			}, o.Node, loadCmd)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{
+			outputs:  map[string]string{"docker logs --tail 10 registry": "level=fatal msg=\"configuration error\""},
+			failures: map[string]string{loadCmd: "no space left on device", "rm -rf /tmp/images.tar": "busy"},
+		}
+		o := newFakeOptions(runner)
+		o.IOStreams.Out = &bytes.Buffer{}
+		o.RollbackOnFailure = tt.rollback
+		o.LogsTail = tt.logsTail
+		run := tt.run
+		if run == nil {
+			run = func(o *RegistryOptions) error {
+				ret, err := o.runCmd(loadCmd)
+				if err != nil {
+					return err
+				}
+				return ret.Error()
+			}
+		}
+		err := o.runInstallSteps([]installStep{{
+			name:             "load-images",
+			run:              func() error { return run(o) },
+			withRegistryLogs: true,
+			rollback: func() error {
+				ret, err := o.runCmd("rm -rf /tmp/images.tar")
+				if err != nil {
+					return err
+				}
+				return ret.Error()
+			},
+		}})
+		if err == nil {
+			t.Fatalf("%s: expected the step to fail", tt.name)
+		}
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d: %v", tt.name, tt.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// healthPollInterval is the interval of polling the registry API until it's ready.
+const healthPollInterval = 2 * time.Second
+
+// waitRegistryReady polls 'GET /v2/' of the deployed registry from node until it answers, or --health-timeout expires.
+// The registry container may be running long before it serves, e.g. on a slow registry volume.
+func (o *RegistryOptions) waitRegistryReady() error {
+	if o.HealthTimeout <= 0 {
+		return nil
+	}
+	hook := fmt.Sprintf("curl -s -o /dev/null -w '%%{http_code}' --connect-timeout 5 http://%s/v2/", o.publishedRegistry())
+	deadline := time.Now().Add(o.HealthTimeout)
+	for {
+		// curl exits non-zero if the connection failed, the http code is checked only
+		ret, err := o.runCmd(hook)
+		if err != nil {
+			return err
+		}
+		// 401 means the registry requires authentication, it is serving still
+		code := strings.TrimSpace(ret.Stdout)
+		if code == "200" || code == "401" {
+			o.log("wait-ready").V(2).Infof("registry %s answers %s", o.publishedRegistry(), code)
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if code == "" || code == "000" {
+				return unreachableError(fmt.Errorf("registry %s is not reachable within --health-timeout %s", o.publishedRegistry(), o.HealthTimeout))
+			}
+			return fmt.Errorf("registry %s is not ready within --health-timeout %s, GET /v2/ returns %s", o.publishedRegistry(), o.HealthTimeout, code)
+		}
+		if remaining > healthPollInterval {
+			remaining = healthPollInterval
+		}
+		if err = o.sleep(remaining); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits d, and returns early once o.ctx is done.
+func (o *RegistryOptions) sleep(d time.Duration) error {
+	if o.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-o.ctx.Done():
+		return o.ctx.Err()
+	}
+}
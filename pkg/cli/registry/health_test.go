@@ -0,0 +1,58 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitRegistryReady(t *testing.T) {
+	const curl = "curl -s -o /dev/null -w '%{http_code}' --connect-timeout 5 http://10.0.0.111:5000/v2/"
+	tests := []struct {
+		name     string
+		outputs  map[string]string
+		failures map[string]string
+		timeout  time.Duration
+		message  string
+	}{
+		{name: "ready", outputs: map[string]string{curl: "200"}, timeout: time.Minute},
+		{name: "auth required", outputs: map[string]string{curl: "401"}, timeout: time.Minute},
+		{name: "skipped", failures: map[string]string{curl: ""}},
+		{name: "unreachable", outputs: map[string]string{curl: "000"}, failures: map[string]string{curl: ""},
+			timeout: 10 * time.Millisecond, message: "is not reachable within --health-timeout"},
+		{name: "not ready", outputs: map[string]string{curl: "503"}, timeout: 10 * time.Millisecond, message: "GET /v2/ returns 503"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{outputs: tt.outputs, failures: tt.failures}
+		o := newFakeOptions(runner)
+		o.HealthTimeout = tt.timeout
+		err := o.waitRegistryReady()
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// etcHostsMarker ends the lines of /etc/hosts written by --update-hosts, so that they are replaced on redeploy.
+const etcHostsMarker = "# kcctl registry"
+
+// parseExtraHost splits a --extra-host 'host:ip' entry, the ip may be IPv6 as docker '--add-host' accepts.
+func parseExtraHost(entry string) (string, string, error) {
+	host, ip, ok := strings.Cut(entry, ":")
+	if !ok {
+		return "", "", fmt.Errorf("--extra-host %s is invalid, must be host:ip", entry)
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(host)); len(errs) > 0 {
+		return "", "", fmt.Errorf("--extra-host %s has invalid host: %s", entry, strings.Join(errs, "; "))
+	}
+	if net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("--extra-host %s has invalid ip %s", entry, ip)
+	}
+	return host, ip, nil
+}
+
+// addHostArgs returns the '--add-host' arguments of the registry container.
+func (o *RegistryOptions) addHostArgs() []string {
+	args := make([]string, 0, len(o.ExtraHosts))
+	for _, entry := range o.ExtraHosts {
+		args = append(args, "--add-host "+entry)
+	}
+	return args
+}
+
+// updateEtcHosts writes --extra-host into /etc/hosts of node, replacing the entries of an earlier deploy.
+func (o *RegistryOptions) updateEtcHosts() error {
+	cmds := []string{removeEtcHostsCmd()}
+	for _, entry := range o.ExtraHosts {
+		host, ip, err := parseExtraHost(entry)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, fmt.Sprintf("sed -i '$a %s %s %s' /etc/hosts", ip, host, etcHostsMarker))
+	}
+	ret, err := o.runCmd(strings.Join(cmds, " && "))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return err
+	}
+	o.log("update-hosts").Infof("added %d entries to /etc/hosts of %s", len(o.ExtraHosts), o.Node)
+	return nil
+}
+
+// removeEtcHosts removes the entries written by updateEtcHosts from /etc/hosts of node.
+func (o *RegistryOptions) removeEtcHosts() error {
+	ret, err := o.runCmd(removeEtcHostsCmd())
+	if err != nil {
+		return err
+	}
+	return ret.Error()
+}
+
+func removeEtcHostsCmd() string {
+	return fmt.Sprintf("sed -i '/ %s$/d' /etc/hosts", etcHostsMarker)
+}
@@ -0,0 +1,64 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"testing"
+)
+
+func TestParseExtraHost(t *testing.T) {
+	tests := []struct {
+		entry   string
+		host    string
+		ip      string
+		wantErr bool
+	}{
+		{entry: "registry.example.com:10.0.0.5", host: "registry.example.com", ip: "10.0.0.5"},
+		{entry: "Mirror:fd00::5", host: "Mirror", ip: "fd00::5"},
+		{entry: "registry.example.com", wantErr: true},
+		{entry: ":10.0.0.5", wantErr: true},
+		{entry: "bad_host:10.0.0.5", wantErr: true},
+		{entry: "registry.example.com:10.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		host, ip, err := parseExtraHost(tt.entry)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.entry, tt.wantErr, err)
+			continue
+		}
+		if host != tt.host || ip != tt.ip {
+			t.Errorf("%s: expected %s and %s, got %s and %s", tt.entry, tt.host, tt.ip, host, ip)
+		}
+	}
+}
+
+func TestUpdateEtcHosts(t *testing.T) {
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	o.ExtraHosts = []string{"registry.example.com:10.0.0.5", "mirror:fd00::5"}
+	if err := o.updateEtcHosts(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "sed -i '/ # kcctl registry$/d' /etc/hosts && " +
+		"sed -i '$a 10.0.0.5 registry.example.com # kcctl registry' /etc/hosts && " +
+		"sed -i '$a fd00::5 mirror # kcctl registry' /etc/hosts"
+	if len(runner.cmds) != 1 || runner.cmds[0] != expected {
+		t.Errorf("expected %q, got %v", expected, runner.cmds)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+)
+
+const (
+	imagesDiffLongDescription = `
+  Compare the images of a local images package with docker registry.
+
+  The image references are read from the manifest of the package locally without loading it,
+  and each of them is checked by 'HEAD /v2/<name>/manifests/<tag>' against registry.
+  Missing images are the ones a push would upload, the command does not fail because of them.`
+	imagesDiffExample = `
+  # Show which images of the package are already present in registry
+  kcctl registry images-diff --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz
+  # Print the diff as json
+  kcctl registry images-diff --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz -o json
+
+  Please read 'kcctl registry images-diff -h' get more registry images-diff flags.`
+)
+
+func NewCmdRegistryImagesDiff(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "images-diff (--node <node>) (--registry-port <registry-port>) (--images-pkg <images-pkg>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry diff images package",
+		Long:                  imagesDiffLongDescription,
+		Example:               imagesDiffExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsImagesDiff()))
+			o.checkErr(o.ImagesDiff())
+		},
+	}
+	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().StringVar(&o.Pkg, "images-pkg", o.Pkg, "local docker images pkg to compare.")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	utils.CheckErr(cmd.MarkFlagRequired("images-pkg"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsImagesDiff() error {
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if o.Pkg == "" {
+		return fmt.Errorf("--images-pkg must be specified")
+	}
+	return nil
+}
+
+// ImagesDiff prints which images of the package are present in registry and which are missing.
+func (o *RegistryOptions) ImagesDiff() error {
+	images, err := o.readImagesPkg(o.Pkg)
+	if err != nil {
+		return err
+	}
+	exists := make([]bool, len(images))
+	errs := make([]error, len(images))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, tagCountConcurrency)
+	for i, ref := range images {
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			name, tag := splitImageRef(ref)
+			if exists[i], errs[i] = o.manifestExists(name, tag); errs[i] != nil {
+				errs[i] = fmt.Errorf("check image %s error: %w", ref, errs[i])
+			}
+		}(i, ref)
+	}
+	wg.Wait()
+	if err = utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
+	result := &VerifyResult{}
+	for i, ref := range images {
+		if exists[i] {
+			result.Present = append(result.Present, ref)
+		} else {
+			result.Missing = append(result.Missing, ref)
+		}
+	}
+	if err = o.PrintFlags.Print(result, o.IOStreams.Out); err != nil {
+		return err
+	}
+	o.log("images-diff").Infof("%d of %d images are missing in registry", len(result.Missing), len(images))
+	return nil
+}
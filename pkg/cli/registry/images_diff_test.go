@@ -0,0 +1,129 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func writeImagesPkg(t *testing.T, path string, compressed bool) {
+	manifest := []byte(`[{"RepoTags":["caas4/etcd:3.5.0","caas4/pause:3.2"]},{"RepoTags":["k8s.gcr.io/coredns:1.8.0","busybox:latest"]}]`)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if compressed {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	if err = tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tw.Write(manifest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImagesDiff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/caas4/etcd/manifests/3.5.0", "/v2/coredns/manifests/1.8.0":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, pkg := range []struct {
+		name       string
+		compressed bool
+	}{
+		{name: "images.tar.gz", compressed: true},
+		{name: "images.tar", compressed: false},
+	} {
+		t.Run(pkg.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := newFakeOptions(&fakeRunner{})
+			o.IOStreams.Out = out
+			o.Node = host
+			o.RegistryPort, _ = strconv.Atoi(port)
+			o.Pkg = filepath.Join(dir, pkg.name)
+			writeImagesPkg(t, o.Pkg, pkg.compressed)
+			cmd := &cobra.Command{}
+			o.PrintFlags.AddFlags(cmd)
+			if err = cmd.Flags().Set("output", "json"); err != nil {
+				t.Fatal(err)
+			}
+			if err = o.ImagesDiff(); err != nil {
+				t.Fatal(err)
+			}
+			result := &VerifyResult{}
+			if err = json.Unmarshal(out.Bytes(), result); err != nil {
+				t.Fatal(err)
+			}
+			want := &VerifyResult{
+				Present: []string{"caas4/etcd:3.5.0", "coredns:1.8.0"},
+				Missing: []string{"caas4/pause:3.2"},
+			}
+			if !reflect.DeepEqual(result, want) {
+				t.Errorf("ImagesDiff() = %+v, want %+v", result, want)
+			}
+		})
+	}
+}
+
+func TestReadImagesPkgUnsupportedCompression(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	pkg := filepath.Join(t.TempDir(), "images.tar.zst")
+	if err := os.WriteFile(pkg, []byte{0x28, 0xb5, 0x2f, 0xfd}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.readImagesPkg(pkg); err == nil {
+		t.Error("readImagesPkg() expected error for zstd pkg")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitInterrupted is the exit code of a subcommand stopped by Ctrl-C, i.e. 128 + SIGINT.
+const exitInterrupted = 130
+
+// watchInterrupt cancels o.ctx on Ctrl-C or SIGTERM, so that no more commands are run on nodes.
+// The signals are handled once only, a second Ctrl-C during cleanup exits immediately.
+func (o *RegistryOptions) watchInterrupt() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	o.interrupt, o.ctx = ctx, ctx
+	go func() {
+		<-ctx.Done()
+		stop()
+		o.log("interrupt").Warn("interrupted, stopping, press Ctrl-C again to exit immediately")
+	}()
+}
+
+// interrupted reports whether the subcommand is interrupted by Ctrl-C.
+func (o *RegistryOptions) interrupted() bool {
+	return o.interrupt != nil && o.interrupt.Err() != nil
+}
+
+// cleanupInterrupted runs cleanup of step after interrupted, the commands are not canceled any more.
+func (o *RegistryOptions) cleanupInterrupted(step string, cleanup func() error) {
+	o.ctx = nil
+	o.log("interrupt").Infof("cleanup step '%s'", step)
+	if err := cleanup(); err != nil {
+		o.log("interrupt").Warnf("cleanup step '%s' error: %s", step, err.Error())
+	}
+}
+
+// checkErr is utils.CheckErr, but exits with the code of the error category, see exitCode.
+// An error after interrupted exits with exitInterrupted instead.
+func (o *RegistryOptions) checkErr(err error) {
+	if err == nil {
+		return
+	}
+	if o.interrupted() {
+		_, _ = fmt.Fprintf(os.Stderr, "registry %s interrupted: %s\n", o.subcommand, err.Error())
+		os.Exit(exitInterrupted)
+	}
+	_, _ = fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,66 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunInstallStepsInterrupted(t *testing.T) {
+	for _, rollbackOnFailure := range []bool{false, true} {
+		runner := &fakeRunner{}
+		o := newFakeOptions(runner)
+		o.IOStreams.Out = &bytes.Buffer{}
+		o.RollbackOnFailure = rollbackOnFailure
+		ctx, cancel := context.WithCancel(context.Background())
+		o.interrupt, o.ctx = ctx, ctx
+		steps := []installStep{
+			{
+				name:     "first",
+				run:      func() error { _, err := o.runCmd("run first"); return err },
+				rollback: func() error { _, err := o.runCmd("rollback first"); return err },
+			},
+			{
+				name: "second",
+				run: func() error {
+					cancel()
+					_, err := o.runCmd("run second")
+					return err
+				},
+				rollback: func() error { _, err := o.runCmd("rollback second"); return err },
+			},
+			{name: "third", run: func() error { _, err := o.runCmd("run third"); return err }},
+		}
+		err := o.runInstallSteps(steps)
+		if err == nil || !strings.Contains(err.Error(), "deploy interrupted while running step 'second'") {
+			t.Errorf("rollback %v: expected interrupted error, got %v", rollbackOnFailure, err)
+		}
+		// the second step never runs its command, only the cleanup commands run after interrupted
+		expected := []string{"run first", "rollback second"}
+		if rollbackOnFailure {
+			expected = append(expected, "rollback first")
+		}
+		if strings.Join(runner.cmds, ",") != strings.Join(expected, ",") {
+			t.Errorf("rollback %v: expected commands %v, got %v", rollbackOnFailure, expected, runner.cmds)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+const (
+	// nodeLockPath is the advisory lock file of deploy and clean on node.
+	nodeLockPath = "/var/run/kc-registry.lock"
+	// staleLockAge is the age a lock is considered left behind by a killed kcctl, far beyond the deploy timeout.
+	staleLockAge = 2 * time.Hour
+)
+
+// nodeLock is the owner recorded in the lock file, as '<host> <pid> <subcommand> <unix time>'.
+type nodeLock struct {
+	host       string
+	pid        int
+	subcommand string
+	since      time.Time
+}
+
+func (l nodeLock) String() string {
+	return fmt.Sprintf("%s %d %s %d", l.host, l.pid, l.subcommand, l.since.Unix())
+}
+
+// parseNodeLock parses the lock file content, ok is false if it is not written by lockNode.
+func parseNodeLock(content string) (nodeLock, bool) {
+	fields := strings.Fields(content)
+	if len(fields) != 4 {
+		return nodeLock{}, false
+	}
+	pid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nodeLock{}, false
+	}
+	since, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nodeLock{}, false
+	}
+	return nodeLock{host: fields[0], pid: pid, subcommand: fields[2], since: time.Unix(since, 0)}, true
+}
+
+// lockNode creates the lock file on node, so that a concurrent deploy or clean of the node aborts
+// instead of colliding. A stale lock, or any lock with --force-lock, is taken over.
+func (o *RegistryOptions) lockNode() error {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	owner := nodeLock{host: host, pid: os.Getpid(), subcommand: o.subcommand, since: time.Now()}
+	if owner.subcommand == "" {
+		owner.subcommand = "registry"
+	}
+	// noclobber makes the creation fail if the lock file exists
+	ret, err := o.runCmd(sshutils.WrapSh(fmt.Sprintf("set -C; echo '%s' > %s", owner, nodeLockPath)))
+	if err != nil {
+		return err
+	}
+	if ret.ExitCode == 0 {
+		o.nodeLocked = true
+		return nil
+	}
+	ret, err = o.runCmd("cat " + nodeLockPath)
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("lock %s on %s error: %w", nodeLockPath, o.Node, err)
+	}
+	held, ok := parseNodeLock(ret.Stdout)
+	switch {
+	case o.ForceLock:
+		o.log("lock").Warnf("--force-lock is set, take over lock %s on %s", nodeLockPath, o.Node)
+	case !ok:
+		o.log("lock").Warnf("invalid lock %s on %s, take it over", nodeLockPath, o.Node)
+	case time.Since(held.since) > staleLockAge:
+		o.log("lock").Warnf("lock %s on %s held by %s of %s pid %d since %s is stale, take it over",
+			nodeLockPath, o.Node, held.subcommand, held.host, held.pid, held.since.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("operation in progress on %s: %s by %s pid %d since %s, retry after it finished, "+
+			"or remove %s by --force-lock if it was killed", o.Node, held.subcommand, held.host, held.pid,
+			held.since.Format(time.RFC3339), nodeLockPath)
+	}
+	ret, err = o.runCmd(sshutils.WrapEcho(owner.String(), nodeLockPath))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return err
+	}
+	o.nodeLocked = true
+	return nil
+}
+
+// unlockNode removes the lock file created by lockNode, even after the deploy deadline passed.
+func (o *RegistryOptions) unlockNode() {
+	if !o.nodeLocked {
+		return
+	}
+	o.ctx = nil
+	ret, err := o.runCmd("rm -f " + nodeLockPath)
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil {
+		o.log("lock").Warnf("remove lock %s on %s error: %s, remove it by hand", nodeLockPath, o.Node, err.Error())
+		return
+	}
+	o.nodeLocked = false
+}
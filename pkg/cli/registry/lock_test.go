@@ -0,0 +1,87 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+func TestParseNodeLock(t *testing.T) {
+	l, ok := parseNodeLock("ops-host 4242 deploy 1700000000\n")
+	if !ok || l.host != "ops-host" || l.pid != 4242 || l.subcommand != "deploy" || l.since.Unix() != 1700000000 {
+		t.Errorf("unexpected lock %+v, %v", l, ok)
+	}
+	for _, content := range []string{"", "ops-host deploy 1700000000", "ops-host pid deploy 1700000000"} {
+		if _, ok = parseNodeLock(content); ok {
+			t.Errorf("%q: expected invalid lock", content)
+		}
+	}
+}
+
+func TestLockNode(t *testing.T) {
+	fresh := fmt.Sprintf("ops-host 4242 deploy %d\n", time.Now().Add(-time.Minute).Unix())
+	stale := fmt.Sprintf("ops-host 4242 deploy %d\n", time.Now().Add(-3*time.Hour).Unix())
+	tests := []struct {
+		name    string
+		held    string
+		force   bool
+		message string
+	}{
+		{name: "free"},
+		{name: "held", held: fresh, message: "operation in progress on 10.0.0.111: deploy by ops-host pid 4242"},
+		{name: "stale", held: stale},
+		{name: "invalid", held: "garbage\n"},
+		{name: "force", held: fresh, force: true},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{outputs: map[string]string{"cat " + nodeLockPath: tt.held}}
+		o := newFakeOptions(runner)
+		o.ForceLock = tt.force
+		o.cmdRunner = func(sshConfig *sshutils.SSH, host, cmd string) (sshutils.Result, error) {
+			if tt.held != "" && strings.Contains(cmd, "set -C;") {
+				runner.cmds = append(runner.cmds, cmd)
+				return sshutils.Result{Host: host, Cmd: cmd, Stderr: "cannot overwrite existing file", ExitCode: 1}, nil
+			}
+			return runner.run(sshConfig, host, cmd)
+		}
+		err := o.lockNode()
+		if tt.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+			}
+			if o.nodeLocked {
+				t.Errorf("%s: expected the lock not held", tt.name)
+			}
+			continue
+		}
+		if err != nil || !o.nodeLocked {
+			t.Errorf("%s: expected the lock held, got %v", tt.name, err)
+			continue
+		}
+		o.unlockNode()
+		if last := runner.cmds[len(runner.cmds)-1]; last != "rm -f "+nodeLockPath || o.nodeLocked {
+			t.Errorf("%s: expected the lock removed, got commands %v", tt.name, runner.cmds)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+const (
+	logsLongDescription = `
+  Print the logs of docker registry container.
+
+  The logs are streamed from the registry node over ssh, with --follow they are printed
+  as the registry writes them until Ctrl-C. Each line is prefixed with '[node]' unless --no-prefix is set.`
+	logsExample = `
+  # Print the last 100 lines of registry logs
+  kcctl registry logs --pk-file key --node 10.0.0.111 --tail 100
+  # Follow the registry logs
+  kcctl registry logs --pk-file key --node 10.0.0.111 --follow
+  # Follow the logs of a registry container with custom name
+  kcctl registry logs --pk-file key --node 10.0.0.111 --follow --registry-name harbor-registry
+
+  Please read 'kcctl registry logs -h' get more registry logs flags.`
+)
+
+func NewCmdRegistryLogs(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "logs (--pk-file <file path>) (--node <node>) [--tail <lines>] [--follow] [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "print registry container logs",
+		Long:                  logsLongDescription,
+		Example:               logsExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.checkErr(usageError(o.ValidateArgsLogs()))
+			if !o.preCheck() {
+				return
+			}
+			o.checkErr(o.Logs())
+		},
+	}
+
+	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.LogsTail, "tail", o.LogsTail, "number of lines to show from the end of the logs, negative shows all lines")
+	cmd.Flags().BoolVar(&o.Follow, "follow", o.Follow, "follow the logs until Ctrl-C")
+	cmd.Flags().StringVar(&o.RegistryName, "registry-name", o.RegistryName, "name of the registry container")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsLogs() error {
+	if err := o.validateSSH(); err != nil {
+		return err
+	}
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if o.RegistryName == "" {
+		return fmt.Errorf("--registry-name must be specified")
+	}
+	return nil
+}
+
+// Logs streams the registry container logs to o.IOStreams, Ctrl-C stops following them.
+func (o *RegistryOptions) Logs() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	run := o.streamRunner
+	if run == nil {
+		run = sshutils.SSHStreamCmdWithSudo
+	}
+	stdout, stderr := o.IOStreams.Out, o.IOStreams.ErrOut
+	if !o.NoPrefix {
+		prefixOut, prefixErr := logger.NewPrefixWriter(stdout, o.Node), logger.NewPrefixWriter(stderr, o.Node)
+		defer func() {
+			_ = prefixOut.Flush()
+			_ = prefixErr.Flush()
+		}()
+		stdout, stderr = prefixOut, prefixErr
+	}
+	code, err := run(ctx, o.SSHConfig, o.Node, o.logsCmd(), stdout, stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("get logs of registry container %s on %s failed with exit code %d", o.RegistryName, o.Node, code)
+	}
+	return nil
+}
+
+// logsCmd returns the 'docker logs' command of the registry container.
+func (o *RegistryOptions) logsCmd() string {
+	tail := "all"
+	if o.LogsTail >= 0 {
+		tail = fmt.Sprintf("%d", o.LogsTail)
+	}
+	cmd := fmt.Sprintf("docker logs --tail %s", tail)
+	if o.Follow {
+		cmd += " -f"
+	}
+	return fmt.Sprintf("%s %s", cmd, o.RegistryName)
+}
@@ -0,0 +1,70 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+func TestLogs(t *testing.T) {
+	var cmds []string
+	o := newFakeOptions(&fakeRunner{})
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	o.IOStreams.Out, o.IOStreams.ErrOut = out, errOut
+	o.streamRunner = func(ctx context.Context, sshConfig *sshutils.SSH, host, cmd string, stdout, stderr io.Writer) (int, error) {
+		cmds = append(cmds, cmd)
+		if strings.HasSuffix(cmd, " missing") {
+			_, _ = io.WriteString(stderr, "Error: No such container: missing\n")
+			return 1, nil
+		}
+		_, _ = io.WriteString(stdout, "registry listening on [::]:5000\n")
+		return 0, nil
+	}
+
+	o.LogsTail = 100
+	o.Follow = true
+	o.RegistryName = "harbor-registry"
+	if err := o.Logs(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[10.0.0.111] registry listening on [::]:5000\n" {
+		t.Errorf("expected logs streamed to out with node prefix, got %q", out.String())
+	}
+
+	o.LogsTail = -1
+	o.Follow = false
+	o.RegistryName = "missing"
+	o.NoPrefix = true
+	if err := o.Logs(); err == nil || !strings.Contains(err.Error(), "exit code 1") {
+		t.Errorf("expected exit code error of missing container, got %v", err)
+	}
+	expected := []string{"docker logs --tail 100 -f harbor-registry", "docker logs --tail all missing"}
+	if strings.Join(cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected commands %v, got %v", expected, cmds)
+	}
+	if !strings.HasPrefix(errOut.String(), "Error: No such container") {
+		t.Errorf("expected docker error streamed to err out, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// isManifestList reports whether mediaType is of a docker manifest list or an OCI index.
+func isManifestList(mediaType string) bool {
+	return strings.Contains(mediaType, "manifest.list") || strings.Contains(mediaType, "image.index")
+}
+
+// platformManifests returns the platform manifests referenced by the manifest list of digest in repository name.
+func (o *RegistryOptions) platformManifests(name, digest string) ([]descriptor, error) {
+	m, err := o.manifestOf(name, digest)
+	if err != nil {
+		return nil, err
+	}
+	return m.Manifests, nil
+}
+
+// deletePlatformManifests deletes the platform manifests of the deleted manifest list of tag with --recursive,
+// otherwise warns they remain. A platform manifest which failed to be deleted is warned, the tag is gone already.
+func (o *RegistryOptions) deletePlatformManifests(name, tag string, platforms []descriptor) {
+	if len(platforms) == 0 {
+		return
+	}
+	if !o.Recursive {
+		o.log("delete-manifest").Warnf("%s:%s is a manifest list, its %d platform manifests remain in %s, delete them along with it by --recursive",
+			name, tag, len(platforms), name)
+		return
+	}
+	var deleted int
+	for _, platform := range platforms {
+		url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, platform.Digest))
+		resp, code, _, respErr := o.request(url, "DELETE", o.requestHeader(nil), nil)
+		if respErr != nil {
+			o.log("delete-manifest").Warnf("delete platform manifest %s of %s:%s error: %s", platform.Digest, name, tag, respErr.Error())
+			continue
+		}
+		switch code {
+		case http.StatusAccepted, http.StatusOK:
+			deleted++
+		case http.StatusNotFound:
+			// shared with a manifest list deleted before
+			o.log("delete-manifest").V(2).Infof("platform manifest %s of %s:%s is gone already", platform.Digest, name, tag)
+		default:
+			_, err := repositoryResponse(name, resp, code)
+			if err == nil {
+				err = fmt.Errorf("unexpected status code %d", code)
+			}
+			o.log("delete-manifest").Warnf("delete platform manifest %s of %s:%s error: %s", platform.Digest, name, tag, err.Error())
+		}
+	}
+	o.log("delete-manifest").Infof("deleted %d of %d platform manifests of %s:%s", deleted, len(platforms), name, tag)
+}
@@ -0,0 +1,85 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDeleteTag_ManifestList(t *testing.T) {
+	const list = "sha256:list"
+	for _, recursive := range []bool{false, true} {
+		var (
+			mu      sync.Mutex
+			deleted []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			reference := strings.TrimPrefix(r.URL.Path, "/v2/caas4/etcd/manifests/")
+			switch {
+			case r.Method == http.MethodHead && reference == "v3.5":
+				if !strings.Contains(r.Header.Get("Accept"), "manifest.list") {
+					// registry picks the amd64 manifest for a client not accepting manifest lists
+					w.Header().Set("Docker-Content-Digest", "sha256:amd64")
+					return
+				}
+				w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+				w.Header().Set("Docker-Content-Digest", list)
+			case r.Method == http.MethodGet && reference == list:
+				_, _ = w.Write([]byte(`{"schemaVersion":2,"manifests":[` +
+					`{"digest":"sha256:amd64","platform":{"architecture":"amd64","os":"linux"}},` +
+					`{"digest":"sha256:arm64","platform":{"architecture":"arm64","os":"linux"}}]}`))
+			case r.Method == http.MethodDelete:
+				deleted = append(deleted, reference)
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := newFakeOptions(&fakeRunner{})
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.Name = "caas4/etcd"
+		o.Tag = "v3.5"
+		o.Recursive = recursive
+		err = o.deleteTag()
+		server.Close()
+		if err != nil {
+			t.Fatalf("recursive %v: unexpected error: %v", recursive, err)
+		}
+		expected := []string{list}
+		if recursive {
+			expected = append(expected, "sha256:amd64", "sha256:arm64")
+		}
+		if !reflect.DeepEqual(deleted, expected) {
+			t.Errorf("recursive %v: expected %v deleted, got %v", recursive, expected, deleted)
+		}
+	}
+}
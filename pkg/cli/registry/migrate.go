@@ -0,0 +1,306 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+const (
+	migrateVolumeLongDescription = `
+  Move the volume of docker registry to another path on node.
+
+  The registry container is stopped, the registry volume is copied to --new-volume by rsync, or by 'cp -a'
+  if rsync is not installed, and the container is recreated with the same settings mounting --new-volume.
+  The old volume is removed after the registry answers 'GET /v2/' within --health-timeout, unless --keep-old.
+  If the registry fails to start from --new-volume, it is recreated from the old volume.
+
+  --new-volume must be empty, and its filesystem must have free space for the whole registry volume.
+  The settings of a registry run by the systemd unit kc-registry.service are kept as well.
+  The environment variables of the container are carried over if they start with REGISTRY_.`
+	migrateVolumeExample = `
+  # Move the registry volume to /data/registry, the old volume is removed
+  kcctl registry migrate-volume --pk-file key --node 10.0.0.111 --new-volume /data/registry
+  # Move the registry volume to /data/registry and keep the old volume
+  kcctl registry migrate-volume --pk-file key --node 10.0.0.111 --new-volume /data/registry --keep-old
+
+  Please read 'kcctl registry migrate-volume -h' get more registry migrate-volume flags.`
+)
+
+func NewCmdRegistryMigrateVolume(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "migrate-volume (--pk-file <file path>) (--node <node>) (--new-volume <path>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry migrate volume to a new path",
+		Long:                  migrateVolumeLongDescription,
+		Example:               migrateVolumeExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsMigrateVolume()))
+			if !o.preCheck() {
+				return
+			}
+			o.checkErr(o.MigrateVolume())
+		},
+	}
+
+	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().StringVar(&o.NewVolume, "new-volume", o.NewVolume, "absolute path on node the registry volume is moved to")
+	cmd.Flags().BoolVar(&o.KeepOld, "keep-old", o.KeepOld, "keep the old registry volume after the registry started from --new-volume")
+	cmd.Flags().StringVar(&o.RegistryName, "registry-name", o.RegistryName, "name of the registry container")
+	cmd.Flags().BoolVar(&o.Rootless, "rootless", o.Rootless, "docker on node runs rootless as the ssh user")
+	cmd.Flags().DurationVar(&o.HealthTimeout, "health-timeout", o.HealthTimeout, "timeout of waiting the registry API to answer after the container restarted, 0 skips the wait")
+	cmd.Flags().BoolVar(&o.ForceLock, "force-lock", o.ForceLock, "take over the lock of another deploy or clean running on node, e.g. one which was killed")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	utils.CheckErr(cmd.MarkFlagRequired("new-volume"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsMigrateVolume() error {
+	if err := o.validateSSH(); err != nil {
+		return err
+	}
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if err := validateRegistryName(o.RegistryName); err != nil {
+		return err
+	}
+	if !path.IsAbs(o.NewVolume) || path.Clean(o.NewVolume) == "/" {
+		return fmt.Errorf("--new-volume %s is invalid, must be an absolute path other than /", o.NewVolume)
+	}
+	// the path is put unquoted into the commands run on node
+	if strings.ContainsAny(o.NewVolume, " '\"&;|$`") {
+		return fmt.Errorf("--new-volume %s is invalid, must not contain spaces, quotes or shell characters", o.NewVolume)
+	}
+	if o.HealthTimeout < 0 {
+		return fmt.Errorf("--health-timeout must not be negative")
+	}
+	return nil
+}
+
+// MigrateVolume moves the registry volume to --new-volume and recreates the registry container mounting it.
+// The registry is recreated from the old volume if it fails to start from the new one.
+func (o *RegistryOptions) MigrateVolume() error {
+	if err := o.lockNode(); err != nil {
+		return err
+	}
+	defer o.unlockNode()
+
+	c, err := o.inspectContainer("node", o.Node, o.RegistryName)
+	if err != nil {
+		return err
+	}
+	oldVolume := c.volume()
+	if oldVolume == "" {
+		return fmt.Errorf("container %s on %s mounts no volume at /var/lib/registry, nothing to migrate", o.RegistryName, o.Node)
+	}
+	oldVolume = path.Clean(oldVolume)
+	newVolume := path.Clean(o.NewVolume)
+	switch {
+	case newVolume == oldVolume:
+		return fmt.Errorf("--new-volume %s is the registry volume already", newVolume)
+	case strings.HasPrefix(newVolume, oldVolume+"/"), strings.HasPrefix(oldVolume, newVolume+"/"):
+		return fmt.Errorf("--new-volume %s and the registry volume %s must not be nested", newVolume, oldVolume)
+	}
+	if err = o.prepareNewVolume(oldVolume, newVolume); err != nil {
+		return err
+	}
+	systemd, err := o.registryUnitExists()
+	if err != nil {
+		return err
+	}
+	o.applyContainer(c, systemd)
+
+	if err = o.stopRegistryContainer(); err != nil {
+		return err
+	}
+	o.log("migrate-volume").Infof("registry container %s is stopped, copy %s to %s", o.RegistryName, oldVolume, newVolume)
+	if err = o.copyVolume(oldVolume, newVolume); err != nil {
+		return o.restoreVolume(oldVolume, err)
+	}
+	o.RegistryVolume = newVolume
+	if err = o.recreateRegistryContainer(); err == nil {
+		err = o.waitRegistryReady()
+	}
+	if err != nil {
+		return o.restoreVolume(oldVolume, o.withRegistryLogs(err))
+	}
+
+	if o.KeepOld {
+		o.log("migrate-volume").Infof("registry runs from %s, the old volume %s is kept", newVolume, oldVolume)
+		return nil
+	}
+	if err = o.removePath(oldVolume); err != nil {
+		o.log("migrate-volume").Warnf("remove old volume %s error: %s, remove it by hand", oldVolume, err.Error())
+		return nil
+	}
+	o.log("migrate-volume").Infof("registry runs from %s, the old volume %s is removed", newVolume, oldVolume)
+	return nil
+}
+
+// prepareNewVolume creates newVolume, which must be empty, and checks its filesystem has free space for oldVolume.
+func (o *RegistryOptions) prepareNewVolume(oldVolume, newVolume string) error {
+	cmd := fmt.Sprintf("mkdir -p %s && ls -A %s", newVolume, newVolume)
+	if o.Rootless {
+		// rootless docker can not mount the volume out of the home of ssh user unless it owns it
+		cmd = fmt.Sprintf("mkdir -p %s && chown %s %s && ls -A %s", newVolume, o.SSHConfig.User, newVolume, newVolume)
+	}
+	ret, err := o.runCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("create new volume %s on %s error: %w", newVolume, o.Node, err)
+	}
+	if strings.TrimSpace(ret.Stdout) != "" {
+		return fmt.Errorf("new volume %s on %s is not empty, please choose an empty or missing path", newVolume, o.Node)
+	}
+
+	usedKB, err := o.diskKB(fmt.Sprintf("du -sk %s | awk '{print $1}'", oldVolume), "size of registry volume "+oldVolume)
+	if err != nil {
+		return err
+	}
+	freeKB, err := o.diskKB(fmt.Sprintf("df -P -k %s | awk 'NR==2{print $4}'", newVolume), "free space of new volume "+newVolume)
+	if err != nil {
+		return err
+	}
+	if usedKB >= freeKB {
+		return fmt.Errorf("new volume %s has %s free on %s, less than the registry volume %s of %s",
+			newVolume, humanBytes(freeKB*1024), o.Node, oldVolume, humanBytes(usedKB*1024))
+	}
+	o.log("migrate-volume").V(2).Infof("registry volume %s uses %s, new volume %s has %s free",
+		oldVolume, humanBytes(usedKB*1024), newVolume, humanBytes(freeKB*1024))
+	return nil
+}
+
+// diskKB runs cmd printing a number of KB on node, what names the number in errors.
+func (o *RegistryOptions) diskKB(cmd, what string) (int64, error) {
+	ret, err := o.runCmd(cmd)
+	if err != nil {
+		return 0, err
+	}
+	if err = ret.Error(); err != nil {
+		return 0, fmt.Errorf("get %s error: %w", what, err)
+	}
+	kb, err := strconv.ParseInt(strings.TrimSpace(ret.Stdout), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", what, ret.Stdout)
+	}
+	return kb, nil
+}
+
+// applyContainer sets the deploy options from the inspected registry container, so that
+// runRegistryCmd recreates it with the same settings. Only the REGISTRY_ variables are carried over,
+// the others are set by the registry image.
+func (o *RegistryOptions) applyContainer(c *registryContainer, systemd bool) {
+	o.Systemd = systemd
+	o.RegistryImage = c.Config.Image
+	if bindings := c.HostConfig.PortBindings["5000/tcp"]; len(bindings) > 0 {
+		if port, err := strconv.Atoi(bindings[0].HostPort); err == nil {
+			o.RegistryPort = port
+		}
+		o.BindAddress = bindings[0].HostIP
+	}
+	o.RestartPolicy = c.restartPolicy()
+	o.MemoryLimit = ""
+	if c.HostConfig.Memory > 0 {
+		o.MemoryLimit = strconv.FormatInt(c.HostConfig.Memory, 10)
+	}
+	o.CPULimit = float64(c.HostConfig.NanoCpus) / 1e9
+	// 'docker inspect' prints the '--add-host' entries as 'host:ip', the form of --extra-host
+	o.ExtraHosts = append([]string(nil), c.HostConfig.ExtraHosts...)
+	o.Env = nil
+	for _, entry := range c.Config.Env {
+		if strings.HasPrefix(entry, "REGISTRY_") {
+			o.Env = append(o.Env, entry)
+		}
+	}
+}
+
+// stopRegistryContainer stops the registry, by kc-registry.service if it runs the registry.
+func (o *RegistryOptions) stopRegistryContainer() error {
+	var ret sshutils.Result
+	var err error
+	if o.Systemd {
+		ret, err = o.runCmd("systemctl stop " + registryUnit)
+	} else {
+		ret, err = o.runDockerCmd("docker stop " + o.RegistryName)
+	}
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("stop registry container %s on %s error: %w", o.RegistryName, o.Node, err)
+	}
+	return nil
+}
+
+// copyVolume copies oldVolume into newVolume keeping the owners and modes.
+func (o *RegistryOptions) copyVolume(oldVolume, newVolume string) error {
+	cmd := fmt.Sprintf("if command -v rsync >/dev/null; then rsync -a %s/ %s/; else cp -a %s/. %s/; fi",
+		oldVolume, newVolume, oldVolume, newVolume)
+	ret, err := o.runCmd(sshutils.WrapSh(cmd))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("copy registry volume %s to %s on %s error: %w", oldVolume, newVolume, o.Node, err)
+	}
+	return nil
+}
+
+// recreateRegistryContainer replaces the registry container by one mounting o.RegistryVolume.
+func (o *RegistryOptions) recreateRegistryContainer() error {
+	if o.Systemd {
+		return o.installRegistryUnit()
+	}
+	for _, cmd := range []string{"docker rm -f " + o.RegistryName, o.runRegistryCmd()} {
+		ret, err := o.runDockerCmd(cmd)
+		if err != nil {
+			return err
+		}
+		if err = ret.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreVolume recreates the registry container from oldVolume after the migration failed by cause.
+// The copy in --new-volume is left for diagnosis.
+func (o *RegistryOptions) restoreVolume(oldVolume string, cause error) error {
+	o.log("migrate-volume").Warnf("migrate registry volume error: %s, restart registry from %s", cause.Error(), oldVolume)
+	o.RegistryVolume = oldVolume
+	if err := o.recreateRegistryContainer(); err != nil {
+		return fmt.Errorf("%s, and restart registry from %s error: %w", cause.Error(), oldVolume, err)
+	}
+	return cause
+}
@@ -0,0 +1,134 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+const migrateInspect = `[{
+  "Config": {"Image": "registry:2", "Env": ["PATH=/usr/local/sbin:/usr/local/bin", "REGISTRY_STORAGE_DELETE_ENABLED=true"]},
+  "HostConfig": {"PortBindings": {"5000/tcp": [{"HostIp": "", "HostPort": "5000"}]}, "RestartPolicy": {"Name": "always"}},
+  "Mounts": [{"Source": "/opt/registry", "Destination": "/var/lib/registry"}]
+}]`
+
+func newMigrateRunner() *fakeRunner {
+	return &fakeRunner{
+		outputs: map[string]string{
+			"docker inspect registry":                         migrateInspect,
+			"du -sk /opt/registry | awk '{print $1}'":         "1048576",
+			"df -P -k /data/registry | awk 'NR==2{print $4}'": "4194304",
+		},
+		failures: map[string]string{"test -f " + registryUnitPath: ""},
+	}
+}
+
+func hasCmd(cmds []string, substr string) bool {
+	for _, cmd := range cmds {
+		if strings.Contains(cmd, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMigrateVolume(t *testing.T) {
+	for _, keepOld := range []bool{false, true} {
+		runner := newMigrateRunner()
+		o := newFakeOptions(runner)
+		o.NewVolume = "/data/registry"
+		o.KeepOld = keepOld
+		o.HealthTimeout = 0
+		if err := o.MigrateVolume(); err != nil {
+			t.Fatalf("keep old %v: unexpected error: %v", keepOld, err)
+		}
+		if !hasCmd(runner.cmds, "docker stop registry") || !hasCmd(runner.cmds, "rsync -a /opt/registry/ /data/registry/") {
+			t.Errorf("keep old %v: expected registry stopped and volume copied, got %v", keepOld, runner.cmds)
+		}
+		if !hasCmd(runner.cmds, "docker run -d -v /data/registry:/var/lib/registry -p 5000:5000 --restart=always -e 'REGISTRY_STORAGE_DELETE_ENABLED=true' --name registry registry:2") {
+			t.Errorf("keep old %v: expected registry recreated on the new volume with its settings, got %v", keepOld, runner.cmds)
+		}
+		if hasCmd(runner.cmds, "-e 'PATH=") {
+			t.Errorf("keep old %v: expected the image environment not carried over, got %v", keepOld, runner.cmds)
+		}
+		if removed := hasCmd(runner.cmds, "rm -rf /opt/registry"); removed == keepOld {
+			t.Errorf("keep old %v: unexpected removal of old volume %v, got %v", keepOld, removed, runner.cmds)
+		}
+	}
+}
+
+func TestMigrateVolume_Precheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		newVolume string
+		outputs   map[string]string
+		message   string
+	}{
+		{name: "same", newVolume: "/opt/registry/", message: "is the registry volume already"},
+		{name: "nested", newVolume: "/opt/registry/new", message: "must not be nested"},
+		{name: "not empty", newVolume: "/data/registry", outputs: map[string]string{"mkdir -p /data/registry && ls -A /data/registry": "docker\n"}, message: "is not empty"},
+		{name: "no space", newVolume: "/data/registry", outputs: map[string]string{"df -P -k /data/registry | awk 'NR==2{print $4}'": "1024"}, message: "less than the registry volume"},
+	}
+	for _, tt := range tests {
+		runner := newMigrateRunner()
+		for cmd, out := range tt.outputs {
+			runner.outputs[cmd] = out
+		}
+		o := newFakeOptions(runner)
+		o.NewVolume = tt.newVolume
+		err := o.MigrateVolume()
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+		if hasCmd(runner.cmds, "docker stop") {
+			t.Errorf("%s: expected registry not stopped, got %v", tt.name, runner.cmds)
+		}
+	}
+}
+
+func TestMigrateVolume_Restore(t *testing.T) {
+	runner := newMigrateRunner()
+	o := newFakeOptions(runner)
+	o.NewVolume = "/data/registry"
+	o.HealthTimeout = 0
+	o.LogsTail = 0
+	newRun := "docker run -d -v /data/registry:/var/lib/registry -p 5000:5000 --restart=always -e 'REGISTRY_STORAGE_DELETE_ENABLED=true' --name registry registry:2"
+	runner.failures[newRun] = "port is already allocated"
+	err := o.MigrateVolume()
+	if err == nil {
+		t.Fatal("expected error of the failed registry restart")
+	}
+	if !hasCmd(runner.cmds, "docker run -d -v /opt/registry:/var/lib/registry") {
+		t.Errorf("expected registry restarted from the old volume, got %v", runner.cmds)
+	}
+	if hasCmd(runner.cmds, "rm -rf /opt/registry") {
+		t.Errorf("expected old volume kept, got %v", runner.cmds)
+	}
+}
+
+func TestValidateArgsMigrateVolume(t *testing.T) {
+	for _, volume := range []string{"data/registry", "/", "/data/my registry", "/data/$(id)"} {
+		o := newFakeOptions(&fakeRunner{})
+		o.SSHConfig.PkFile = "key"
+		o.NewVolume = volume
+		if err := o.ValidateArgsMigrateVolume(); err == nil {
+			t.Errorf("expected --new-volume %q invalid", volume)
+		}
+	}
+}
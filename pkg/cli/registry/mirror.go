@@ -0,0 +1,125 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+)
+
+const (
+	engineDocker     = "docker"
+	engineContainerd = "containerd"
+)
+
+var allowClientEngine = sets.NewString(engineDocker, engineContainerd)
+
+const (
+	mirrorConfigLongDescription = `
+  Print the mirror config snippet for clients of docker registry.
+
+  For docker the snippet is merged into /etc/docker/daemon.json,
+  for containerd it is merged into /etc/containerd/config.toml.
+  Nothing is changed on the registry node or the client, the snippet is only printed to stdout.`
+	mirrorConfigExample = `
+  # Print docker daemon.json mirror config of an insecure registry
+  kcctl registry mirror-config --node 10.0.0.111 --registry-port 5000
+  # Print containerd config.toml mirror config of an insecure registry
+  kcctl registry mirror-config --node 10.0.0.111 --registry-port 5000 --client-engine containerd
+  # Print containerd config.toml mirror config of a TLS registry
+  kcctl registry mirror-config --node 10.0.0.111 --registry-port 5000 --client-engine containerd --tls --ca-file /etc/containerd/certs.d/10.0.0.111:5000/ca.crt
+
+  Please read 'kcctl registry mirror-config -h' get more registry mirror-config flags.`
+)
+
+func NewCmdRegistryMirrorConfig(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "mirror-config (--node <node>) (--registry-port <registry-port>) [--client-engine <docker|containerd>] [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "print client mirror config of registry",
+		Long:                  mirrorConfigLongDescription,
+		Example:               mirrorConfigExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.checkErr(usageError(o.ValidateArgsMirrorConfig()))
+			o.checkErr(o.MirrorConfig())
+		},
+	}
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "registry port")
+	cmd.Flags().StringVar(&o.ClientEngine, "client-engine", o.ClientEngine, fmt.Sprintf("container engine of the client, support %v", allowClientEngine.List()))
+	cmd.Flags().BoolVar(&o.TLS, "tls", o.TLS, "registry is served with TLS, the insecure config is printed if not set")
+	cmd.Flags().StringVar(&o.ClientCAFile, "ca-file", o.ClientCAFile, "path of the registry CA file on the client, only used by containerd with --tls")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsMirrorConfig() error {
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if o.RegistryPort <= 0 || o.RegistryPort > 65535 {
+		return fmt.Errorf("--registry-port %d is invalid", o.RegistryPort)
+	}
+	if !allowClientEngine.Has(o.ClientEngine) {
+		return fmt.Errorf("--client-engine only support %v", allowClientEngine.List())
+	}
+	if o.ClientCAFile != "" && !o.TLS {
+		return fmt.Errorf("--ca-file must be used with --tls")
+	}
+	return nil
+}
+
+func (o *RegistryOptions) MirrorConfig() error {
+	content, err := o.getMirrorTemplateContent()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(o.IOStreams.Out, content)
+	return err
+}
+
+// getMirrorTemplateContent renders the mirror config of registry for the client engine.
+func (o *RegistryOptions) getMirrorTemplateContent() (string, error) {
+	var tmpl string
+	switch o.ClientEngine {
+	case engineDocker:
+		tmpl = config.DockerMirrorTmpl
+	case engineContainerd:
+		tmpl = config.ContainerdMirrorTmpl
+	default:
+		return "", fmt.Errorf("unsupported client engine %s", o.ClientEngine)
+	}
+	scheme := "http"
+	if o.TLS {
+		scheme = "https"
+	}
+	var data = make(map[string]interface{})
+	data["Node"] = fmt.Sprintf(`%s:%d`, o.Node, o.RegistryPort)
+	data["Scheme"] = scheme
+	data["TLS"] = o.TLS
+	data["CAFile"] = o.ClientCAFile
+	return renderTemplate(tmpl, data)
+}
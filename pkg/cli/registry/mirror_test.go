@@ -0,0 +1,94 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMirrorConfigDocker(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.RegistryPort = 5000
+	o.ClientEngine = engineDocker
+	for _, tls := range []bool{false, true} {
+		o.TLS = tls
+		content, err := o.getMirrorTemplateContent()
+		if err != nil {
+			t.Fatalf("tls=%v: unexpected error: %v", tls, err)
+		}
+		var daemon map[string][]string
+		if err := json.Unmarshal([]byte(content), &daemon); err != nil {
+			t.Fatalf("tls=%v: invalid daemon.json %q: %v", tls, content, err)
+		}
+		want := "http://10.0.0.111:5000"
+		if tls {
+			want = "https://10.0.0.111:5000"
+		}
+		if len(daemon["registry-mirrors"]) != 1 || daemon["registry-mirrors"][0] != want {
+			t.Errorf("tls=%v: expected registry-mirrors [%s], got %v", tls, want, daemon["registry-mirrors"])
+		}
+		if _, ok := daemon["insecure-registries"]; ok == tls {
+			t.Errorf("tls=%v: unexpected insecure-registries %v", tls, daemon["insecure-registries"])
+		}
+	}
+}
+
+func TestMirrorConfigContainerd(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.RegistryPort = 5000
+	o.ClientEngine = engineContainerd
+
+	content, err := o.getMirrorTemplateContent()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(content, `endpoint = ["http://10.0.0.111:5000"]`) || !strings.Contains(content, "insecure_skip_verify = true") {
+		t.Errorf("unexpected insecure containerd config:\n%s", content)
+	}
+
+	o.TLS = true
+	o.ClientCAFile = "/etc/containerd/certs.d/10.0.0.111:5000/ca.crt"
+	content, err = o.getMirrorTemplateContent()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(content, "insecure_skip_verify") || !strings.Contains(content, `ca_file = "/etc/containerd/certs.d/10.0.0.111:5000/ca.crt"`) {
+		t.Errorf("unexpected TLS containerd config:\n%s", content)
+	}
+}
+
+func TestValidateArgsMirrorConfig(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.RegistryPort = 5000
+	o.ClientEngine = "cri-o"
+	if err := o.ValidateArgsMirrorConfig(); err == nil {
+		t.Error("expected error for unsupported client engine")
+	}
+	o.ClientEngine = engineContainerd
+	o.ClientCAFile = "ca.crt"
+	if err := o.ValidateArgsMirrorConfig(); err == nil {
+		t.Error("expected error for --ca-file without --tls")
+	}
+	o.TLS = true
+	if err := o.ValidateArgsMirrorConfig(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	mediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// manifestList is the docker manifest list of the images pushed for every arch with --all-arch.
+type manifestList struct {
+	SchemaVersion int                 `json:"schemaVersion"`
+	MediaType     string              `json:"mediaType"`
+	Manifests     []manifestListEntry `json:"manifests"`
+}
+
+type manifestListEntry struct {
+	MediaType string           `json:"mediaType"`
+	Digest    string           `json:"digest"`
+	Size      int64            `json:"size"`
+	Platform  manifestPlatform `json:"platform"`
+}
+
+type manifestPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (o *RegistryOptions) validateAllArch() error {
+	if !o.AllArch {
+		return nil
+	}
+	if !strings.Contains(o.ImageGlob, "{arch}") {
+		return fmt.Errorf("--all-arch requires '{arch}' in --image-glob %s, the arch of an image archive is told by its path", o.ImageGlob)
+	}
+	switch {
+	case o.NoPush:
+		return fmt.Errorf("--all-arch can not be used with --no-push")
+	case o.PullImagesFrom != "":
+		return fmt.Errorf("--all-arch can not be used with --pull-images-from, images are pulled for the arch of node only")
+	case o.KeepLocalImages:
+		return fmt.Errorf("--all-arch can not be used with --keep-local-images, the images of an arch are removed before loading the next one")
+	}
+	return nil
+}
+
+// packageArches returns the arches, sorted, having any image archive under --resource-dir on node matching
+// --image-glob with '{arch}' replaced by their directory.
+func (o *RegistryOptions) packageArches() ([]string, error) {
+	dir, files, err := o.resourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	var arches []string
+	for _, arch := range supportedArches() {
+		glob := o.archImageGlob(archDirs[arch])
+		for _, file := range files {
+			if rel := strings.TrimPrefix(file, dir+"/"); rel != file && matchGlob(glob, rel) {
+				arches = append(arches, arch)
+				break
+			}
+		}
+	}
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("no image archives of any arch match --image-glob %s under --resource-dir %s on %s", o.ImageGlob, dir, o.Node)
+	}
+	return arches, nil
+}
+
+// loadArchImages loads the image archives of arch into docker on node.
+func (o *RegistryOptions) loadArchImages(arch string) error {
+	archives, err := o.archImageArchives(archDirs[arch])
+	if err != nil {
+		return err
+	}
+	for _, archive := range archives {
+		o.log("load-images").V(2).Infof("load image archive %s of arch %s", archive, arch)
+		ret, err := o.runDockerCmd("docker load -i " + shellQuote(archive))
+		if err != nil {
+			return err
+		}
+		if err = ret.Error(); err != nil {
+			return err
+		}
+	}
+	o.log("load-images").Infof("load %d image archives of arch %s successfully", len(archives), arch)
+	return nil
+}
+
+// pushAllArches pushes the images of every arch in the package with --all-arch. The images of an arch are loaded,
+// pushed as '<target>-<arch dir>' and removed before the next arch, since the images of different arches share
+// their tags locally. Then every target is pushed as a manifest list of its arches.
+func (o *RegistryOptions) pushAllArches() error {
+	arches, err := o.packageArches()
+	if err != nil {
+		return err
+	}
+	o.log("push").Infof("package contains images of arches %s", strings.Join(arches, ","))
+	report := &PushReport{Arches: arches}
+	for _, arch := range arches {
+		if err = o.loadArchImages(arch); err != nil {
+			return err
+		}
+		images, err := o.pushableImages()
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			for _, target := range o.retagTargets(image) {
+				result := o.pushImage(image, target+"-"+archDirs[arch])
+				result.Arch = arch
+				report.Items = append(report.Items, result)
+			}
+		}
+		if err = o.removeImages(); err != nil {
+			return err
+		}
+	}
+	if o.ScanCmd != "" {
+		if err = o.scanImages(report); err != nil {
+			return err
+		}
+	}
+	report.Items = append(report.Items, o.pushManifestLists(report.Items)...)
+	o.log("push").Infof("images of arches %s are processed", strings.Join(arches, ","))
+	return o.reportPush(report)
+}
+
+// pushPackageAllArches pushes the images of every arch in --images-pkg with push --all-arch, the package is
+// extracted on node as the one of deploy, and removed after push.
+func (o *RegistryOptions) pushPackageAllArches(compression string) error {
+	if err := o.sendPackage(compression); err != nil {
+		return err
+	}
+	defer func() {
+		if err := o.removePkg(); err != nil {
+			o.log("push").Warnf("remove pkg error: %s", err.Error())
+		}
+	}()
+	return o.pushAllArches()
+}
+
+// pushManifestLists pushes a manifest list for every target of the images pushed for each arch, so that the target
+// resolves to the image of the arch pulling it. An image whose digest was not reported by docker push can't be
+// referenced, it is left out of the list.
+func (o *RegistryOptions) pushManifestLists(items []PushResult) []PushResult {
+	platforms := map[string][]PushResult{}
+	for _, v := range items {
+		if v.Arch == "" || v.Status != PushStatusPushed {
+			continue
+		}
+		target := strings.TrimSuffix(v.Target, "-"+archDirs[v.Arch])
+		if v.Digest == "" {
+			o.log("push").Warnf("digest of %s is unknown, arch %s is left out of the manifest list of %s", v.Target, v.Arch, target)
+			continue
+		}
+		platforms[target] = append(platforms[target], v)
+	}
+	targets := make([]string, 0, len(platforms))
+	for target := range platforms {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	results := make([]PushResult, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, o.pushManifestList(target, platforms[target]))
+	}
+	return results
+}
+
+// pushManifestList puts the manifest list of the arch images of target into registry by curl on node.
+func (o *RegistryOptions) pushManifestList(target string, images []PushResult) PushResult {
+	list := manifestList{SchemaVersion: 2, MediaType: mediaTypeManifestList}
+	arches := make([]string, 0, len(images))
+	for _, image := range images {
+		list.Manifests = append(list.Manifests, manifestListEntry{
+			MediaType: mediaTypeManifest,
+			Digest:    image.Digest,
+			Size:      image.Size,
+			Platform:  archPlatform(image.Arch),
+		})
+		arches = append(arches, image.Arch)
+	}
+	result := PushResult{Image: images[0].Image, Target: target, Arch: strings.Join(arches, ",")}
+	body, err := json.Marshal(list)
+	if err != nil {
+		result.Status, result.Error = PushStatusFailed, err.Error()
+		return result
+	}
+
+	// the manifest reference is the tag of target, whose repository is after the registry
+	ref := strings.TrimPrefix(target, o.pushRegistry()+"/")
+	i := strings.LastIndex(ref, ":")
+	hook := fmt.Sprintf("curl -s -o /dev/null -w '%%{http_code}' --connect-timeout 5 -X PUT -H 'Content-Type: %s' --data-binary %s http://%s/v2/%s/manifests/%s",
+		mediaTypeManifestList, shellQuote(string(body)), o.pushRegistry(), ref[:i], ref[i+1:])
+	ret, err := o.runCmd(hook)
+	if err == nil {
+		// curl exits non-zero if the connection failed, the http code is checked only
+		if code := strings.TrimSpace(ret.Stdout); code != "201" {
+			err = fmt.Errorf("put manifest list of %s returns %q", target, code)
+		}
+	}
+	if err != nil {
+		o.log("push-image").V(2).Infof("push manifest list %s failed: %s", target, err.Error())
+		result.Status, result.Error = PushStatusFailed, err.Error()
+		return result
+	}
+	result.Status = PushStatusPushed
+	result.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	result.Size = int64(len(body))
+	return result
+}
+
+// archPlatform returns the platform of a supported arch, e.g. 'arm/v7' is arch arm of variant v7.
+func archPlatform(arch string) manifestPlatform {
+	architecture, variant, _ := strings.Cut(arch, "/")
+	return manifestPlatform{Architecture: architecture, OS: "linux", Variant: variant}
+}
@@ -0,0 +1,169 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const resourceFind = "find '/tmp/kc/resource' -type f"
+
+func TestPackageArches(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{resourceFind: strings.Join([]string{
+		"/tmp/kc/resource/k8s/v1.23.6/amd64/images.tar.gz",
+		"/tmp/kc/resource/k8s/v1.23.6/arm64/images.tar.gz",
+		"/tmp/kc/resource/calico/v3.21.2/armv7/images.tar.gz",
+		"/tmp/kc/resource/docker/19.03.12/s390x/configs.tar.gz",
+	}, "\n")}}
+	o := newFakeOptions(runner)
+	arches, err := o.packageArches()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"amd64", "arm/v7", "arm64"}; !reflect.DeepEqual(arches, expected) {
+		t.Errorf("expected arches %v, got %v", expected, arches)
+	}
+
+	o.ImageGlob = "**/{arch}/bundle.tar"
+	if _, err = o.packageArches(); err == nil || !strings.Contains(err.Error(), "no image archives of any arch") {
+		t.Errorf("expected no arches error, got %v", err)
+	}
+}
+
+func TestValidateAllArch(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *RegistryOptions)
+		wantErr string
+	}{
+		{name: "default glob", modify: func(o *RegistryOptions) {}},
+		{name: "glob without arch", modify: func(o *RegistryOptions) { o.ImageGlob = "images/*.tar" }, wantErr: "requires '{arch}'"},
+		{name: "no push", modify: func(o *RegistryOptions) { o.NoPush = true }, wantErr: "--no-push"},
+		{name: "pull images", modify: func(o *RegistryOptions) { o.PullImagesFrom = "registry.example.com" }, wantErr: "--pull-images-from"},
+		{name: "keep local images", modify: func(o *RegistryOptions) { o.KeepLocalImages = true }, wantErr: "--keep-local-images"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newFakeOptions(&fakeRunner{})
+			o.AllArch = true
+			tt.modify(o)
+			err := o.validateAllArch()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateArgsPush_AllArch(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.SSHConfig.PkFile = "key"
+	o.Pkg = "kc.tar.gz"
+	cmd := NewCmdRegistryPush(o)
+	for flag, value := range map[string]string{"all-arch": "true", "keep-local-images": "true"} {
+		if err := cmd.Flags().Set(flag, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.ValidateArgsPush(); err == nil || !strings.Contains(err.Error(), "--keep-local-images") {
+		t.Errorf("expected --all-arch conflicts with --keep-local-images, got %v", err)
+	}
+}
+
+func TestPushAllArches(t *testing.T) {
+	amd64Digest := "sha256:" + strings.Repeat("a", 64)
+	arm64Digest := "sha256:" + strings.Repeat("b", 64)
+	list := `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.list.v2+json","manifests":[` +
+		`{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + amd64Digest + `","size":527,"platform":{"architecture":"amd64","os":"linux"}},` +
+		`{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + arm64Digest + `","size":529,"platform":{"architecture":"arm64","os":"linux"}}]}`
+	putList := "curl -s -o /dev/null -w '%{http_code}' --connect-timeout 5 -X PUT -H 'Content-Type: application/vnd.docker.distribution.manifest.list.v2+json' --data-binary '" +
+		list + "' http://10.0.0.111:5000/v2/pause/manifests/3.2"
+	runner := &fakeRunner{outputs: map[string]string{
+		resourceFind: "/tmp/kc/resource/k8s/v1.23.6/amd64/images.tar.gz\n/tmp/kc/resource/k8s/v1.23.6/arm64/images.tar.gz\n",
+		`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n",
+		"docker push 10.0.0.111:5000/pause:3.2-amd64":               "3.2-amd64: digest: " + amd64Digest + " size: 527\n",
+		"docker push 10.0.0.111:5000/pause:3.2-arm64":               "3.2-arm64: digest: " + arm64Digest + " size: 529\n",
+		putList: "201",
+	}}
+	o := newFakeOptions(runner)
+	o.AllArch = true
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err := cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.pushAllArches(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"docker load -i '/tmp/kc/resource/k8s/v1.23.6/amd64/images.tar.gz'",
+		"docker tag 80d28bedfe5d 10.0.0.111:5000/pause:3.2-amd64",
+		"docker load -i '/tmp/kc/resource/k8s/v1.23.6/arm64/images.tar.gz'",
+		"docker tag 80d28bedfe5d 10.0.0.111:5000/pause:3.2-arm64",
+		putList,
+	} {
+		if !hasCmd(runner.cmds, expected) {
+			t.Errorf("expected command %s, got %v", expected, runner.cmds)
+		}
+	}
+
+	report := &PushReport{}
+	if err := json.Unmarshal(out.Bytes(), report); err != nil {
+		t.Fatalf("unmarshal push report %q: %v", out.String(), err)
+	}
+	if expected := []string{"amd64", "arm64"}; !reflect.DeepEqual(report.Arches, expected) {
+		t.Errorf("expected processed arches %v, got %v", expected, report.Arches)
+	}
+	if len(report.Items) != 3 {
+		t.Fatalf("expected 2 arch images and a manifest list, got %+v", report.Items)
+	}
+	item := report.Items[2]
+	if item.Target != "10.0.0.111:5000/pause:3.2" || item.Arch != "amd64,arm64" || item.Status != PushStatusPushed || item.Size != int64(len(list)) {
+		t.Errorf("unexpected manifest list result %+v", item)
+	}
+}
+
+func TestPushManifestList_Failed(t *testing.T) {
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	result := o.pushManifestList("10.0.0.111:5000/library/etcd:3.4.13-0", []PushResult{
+		{Image: "k8s.gcr.io/etcd:3.4.13-0", Arch: "arm/v7", Digest: "sha256:" + strings.Repeat("c", 64), Size: 527},
+	})
+	if result.Status != PushStatusFailed || !strings.Contains(result.Error, "returns \"\"") {
+		t.Errorf("expected manifest list failed, got %+v", result)
+	}
+	if !hasCmd(runner.cmds, `"platform":{"architecture":"arm","os":"linux","variant":"v7"}`) ||
+		!hasCmd(runner.cmds, "http://10.0.0.111:5000/v2/library/etcd/manifests/3.4.13-0") {
+		t.Errorf("unexpected manifest list put %v", runner.cmds)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// dockerVersion is the docker version bundled in the deploy package.
+const dockerVersion = "19.03.12"
+
+// validatePackageFile checks the local package of flag is a readable non-empty regular file, so that a mistyped
+// path fails before connecting to node. The error names the absolute path the package was resolved to.
+func validatePackageFile(flag, pkg string) error {
+	abs, err := filepath.Abs(pkg)
+	if err != nil {
+		abs = pkg
+	}
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s %s does not exist", flag, abs)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s is not accessible: %w", flag, abs, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s %s is not a regular file", flag, abs)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("%s %s is empty", flag, abs)
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return fmt.Errorf("%s %s is not readable: %w", flag, abs, err)
+	}
+	return f.Close()
+}
+
+// verifyPackage opens the local deploy package and checks it contains the
+// registry image, docker configs and resource images of o.Arch, or of any arch
+// with --all-arch, so that a mis-built package fails before the slow upload.
+// Resource images are not required with --no-push.
+func (o *RegistryOptions) verifyPackage(compression string) error {
+	f, err := os.Open(o.Pkg)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch compression {
+	case compressionGzip:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("read package %s error: %w", o.Pkg, err)
+		}
+		defer gr.Close()
+		r = gr
+	case compressionNone:
+	default:
+		return fmt.Errorf("offline verify supports gzip or uncompressed package only, got %s", compression)
+	}
+
+	registryImages := fmt.Sprintf("kc/registry/v2/%s/images.tar.gz", o.archDir())
+	dockerConfigs := fmt.Sprintf("kc/resource/docker/%s/%s/configs.tar.gz", dockerVersion, o.archDir())
+	found := map[string]bool{}
+	var resourceImages bool
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read package %s error: %w", o.Pkg, err)
+		}
+		name := path.Clean(hdr.Name)
+		found[name] = true
+		if rel := strings.TrimPrefix(name, path.Clean(o.ResourceDir)+"/"); rel != name && o.matchImageGlob(rel) {
+			resourceImages = true
+		}
+	}
+
+	for _, p := range []string{registryImages, dockerConfigs} {
+		if !found[p] {
+			return fmt.Errorf("missing %s for arch %s in package %s", p, o.Arch, o.Pkg)
+		}
+	}
+	// an absolute --resource-dir is out of the package
+	if !resourceImages && !o.NoPush && !path.IsAbs(o.ResourceDir) {
+		if o.AllArch {
+			return fmt.Errorf("missing %s/%s for any arch in package %s", path.Clean(o.ResourceDir), o.ImageGlob, o.Pkg)
+		}
+		return fmt.Errorf("missing %s/%s for arch %s in package %s", path.Clean(o.ResourceDir), o.imageGlob(), o.Arch, o.Pkg)
+	}
+	return nil
+}
+
+// matchImageGlob reports whether rel under --resource-dir matches --image-glob of --arch, or of any arch with --all-arch.
+func (o *RegistryOptions) matchImageGlob(rel string) bool {
+	if !o.AllArch {
+		return matchGlob(o.imageGlob(), rel)
+	}
+	for _, dir := range archDirs {
+		if matchGlob(o.archImageGlob(dir), rel) {
+			return true
+		}
+	}
+	return false
+}
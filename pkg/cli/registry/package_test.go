@@ -0,0 +1,129 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePackage(t *testing.T, files []string) string {
+	pkg := filepath.Join(t.TempDir(), "kc.tar.gz")
+	f, err := os.Create(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, name := range files {
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestVerifyPackage(t *testing.T) {
+	complete := []string{
+		"./kc/registry/v2/amd64/images.tar.gz",
+		"./kc/resource/docker/19.03.12/amd64/configs.tar.gz",
+		"./kc/resource/k8s/v1.23.6/amd64/images.tar.gz",
+	}
+	tests := []struct {
+		name    string
+		files   []string
+		arch    string
+		noPush  bool
+		message string
+	}{
+		{name: "complete", files: complete, arch: "amd64"},
+		{name: "other arch", files: complete, arch: "arm64", message: "missing kc/registry/v2/arm64/images.tar.gz for arch arm64"},
+		{name: "no docker configs", files: []string{complete[0], complete[2]}, arch: "amd64", message: "missing kc/resource/docker/19.03.12/amd64/configs.tar.gz for arch amd64"},
+		{name: "no resource images", files: complete[:2], arch: "amd64", message: "missing kc/resource/**/amd64/images.tar.gz"},
+		{name: "no resource images without push", files: complete[:2], arch: "amd64", noPush: true},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.Pkg = writePackage(t, tt.files)
+		o.Arch = tt.arch
+		o.NoPush = tt.noPush
+		err := o.verifyPackage(compressionGzip)
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
+
+func TestValidatePackageFile(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.tar.gz")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		pkg     string
+		wantErr string
+	}{
+		{name: "valid", pkg: writePackage(t, nil)},
+		{name: "missing", pkg: filepath.Join(dir, "kc.tar.gz"), wantErr: filepath.Join(dir, "kc.tar.gz") + " does not exist"},
+		{name: "directory", pkg: dir, wantErr: dir + " is not a regular file"},
+		{name: "empty", pkg: empty, wantErr: empty + " is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePackageFile("--pkg", tt.pkg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected valid package, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "--pkg "+tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(wd, "missing-kc.tar.gz")
+	if err = validatePackageFile("--pkg", "missing-kc.tar.gz"); err == nil || !strings.Contains(err.Error(), abs) {
+		t.Errorf("expected error with absolute path %s, got %v", abs, err)
+	}
+}
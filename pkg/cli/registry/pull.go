@@ -0,0 +1,116 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// validatePullImagesFrom validates --pull-images-from is 'host[:port]' of a registry and --images-list lists images.
+func (o *RegistryOptions) validatePullImagesFrom() error {
+	if o.PullImagesFrom == "" {
+		if o.ImagesFile != "" {
+			return fmt.Errorf("--images-list requires --pull-images-from")
+		}
+		return nil
+	}
+	if o.NoPush {
+		return fmt.Errorf("--pull-images-from can not be used with --no-push, the pulled images are pushed into registry")
+	}
+	host := o.PullImagesFrom
+	if h, port, err := net.SplitHostPort(o.PullImagesFrom); err == nil && port != "" {
+		host = h
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") || (strings.Contains(host, ":") && net.ParseIP(host) == nil) {
+		return fmt.Errorf("--pull-images-from %s is invalid, must be host[:port] of a registry", o.PullImagesFrom)
+	}
+	if o.ImagesFile == "" {
+		return fmt.Errorf("--pull-images-from requires --images-list")
+	}
+	images, err := readImagesFile(o.ImagesFile)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no images in --images-list %s", o.ImagesFile)
+	}
+	return nil
+}
+
+// checkUpstreamRegistry checks the upstream of --pull-images-from answers the registry API from node.
+func (o *RegistryOptions) checkUpstreamRegistry() error {
+	ok, err := o.registryReachable(o.PullImagesFrom)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return unreachableError(fmt.Errorf("upstream registry %s is not reachable from %s, please check the address and the network of node", o.PullImagesFrom, o.Node))
+	}
+	return nil
+}
+
+// pullImages pulls every image of --images-list from the upstream on node, and tags it as the listed reference,
+// so that push re-tags and pushes it like a bundled image.
+func (o *RegistryOptions) pullImages() error {
+	images, err := readImagesFile(o.ImagesFile)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, image := range images {
+		if !strings.Contains(image, "/") {
+			o.log("pull-images").Warnf("image %s has no namespace, it is pulled but not pushed", image)
+		}
+		if err = o.pullImage(image); err != nil {
+			if o.interrupted() {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d images failed to pull from %s: %w", len(errs), len(images), o.PullImagesFrom, utilerrors.NewAggregate(errs))
+	}
+	o.log("pull-images").Infof("%d images pulled from %s", len(images), o.PullImagesFrom)
+	return nil
+}
+
+// pullImage pulls image from the upstream, the upstream reference is untagged after tagged as image.
+func (o *RegistryOptions) pullImage(image string) error {
+	upstream := fmt.Sprintf("%s/%s", o.PullImagesFrom, image)
+	for _, cmd := range []string{
+		"docker pull " + upstream,
+		fmt.Sprintf("docker tag %s %s", upstream, image),
+		"docker rmi " + upstream,
+	} {
+		ret, err := o.runDockerCmd(cmd)
+		if err == nil {
+			err = ret.Error()
+		}
+		if err != nil {
+			o.log("pull-images").V(2).Infof("pull image %s failed: %s", upstream, err.Error())
+			return fmt.Errorf("pull image %s error: %w", upstream, err)
+		}
+	}
+	return nil
+}
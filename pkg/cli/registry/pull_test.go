@@ -0,0 +1,129 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidatePullImagesFrom(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "images.txt")
+	if err := os.WriteFile(list, []byte("# images\ncaas4/etcd:3.5.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		upstream string
+		list     string
+		noPush   bool
+		wantErr  string
+	}{
+		{name: "not set"},
+		{name: "host", upstream: "registry.example.com", list: list},
+		{name: "host and port", upstream: "registry.example.com:5000", list: list},
+		{name: "ipv6", upstream: "[fd00::1]:5000", list: list},
+		{name: "list without upstream", list: list, wantErr: "requires --pull-images-from"},
+		{name: "upstream without list", upstream: "registry.example.com", wantErr: "requires --images-list"},
+		{name: "scheme", upstream: "https://registry.example.com", list: list, wantErr: "is invalid"},
+		{name: "empty port", upstream: "registry.example.com:", list: list, wantErr: "is invalid"},
+		{name: "no push", upstream: "registry.example.com", list: list, noPush: true, wantErr: "--no-push"},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.PullImagesFrom, o.ImagesFile, o.NoPush = tt.upstream, tt.list, tt.noPush
+		err := o.validatePullImagesFrom()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestInstallSteps_PullImagesFrom(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.PullImagesFrom = "registry.example.com"
+	var names []string
+	for _, step := range o.installSteps() {
+		names = append(names, step.name)
+	}
+	want := []string{"check registry volume", "check upstream", "process package", "install docker",
+		"install registry", "wait registry ready", "remove pkg", "pull images", "push images"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected steps %v, got %v", want, names)
+	}
+}
+
+func TestPullImages(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "images.txt")
+	if err := os.WriteFile(list, []byte("caas4/etcd:3.5.0\ncaas4/pause:3.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{
+		failures: map[string]string{"docker pull registry.example.com/caas4/pause:3.2": "manifest unknown"},
+	}
+	o := newFakeOptions(runner)
+	o.PullImagesFrom = "registry.example.com"
+	o.ImagesFile = list
+	err := o.pullImages()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 images failed") || !strings.Contains(err.Error(), "caas4/pause:3.2") {
+		t.Errorf("expected pull failure of caas4/pause:3.2, got %v", err)
+	}
+	want := []string{
+		"docker pull registry.example.com/caas4/etcd:3.5.0",
+		"docker tag registry.example.com/caas4/etcd:3.5.0 caas4/etcd:3.5.0",
+		"docker rmi registry.example.com/caas4/etcd:3.5.0",
+		"docker pull registry.example.com/caas4/pause:3.2",
+	}
+	if !reflect.DeepEqual(runner.cmds, want) {
+		t.Errorf("expected commands %v, got %v", want, runner.cmds)
+	}
+}
+
+func TestDaemonConfig_PullImagesFrom(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.PullImagesFrom = "registry.example.com:5000"
+	content, err := o.getDaemonTemplateContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var daemon struct {
+		InsecureRegistries []string `json:"insecure-registries"`
+	}
+	if err = json.Unmarshal([]byte(content), &daemon); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.111:5000", "registry.example.com:5000"}
+	if !reflect.DeepEqual(daemon.InsecureRegistries, want) {
+		t.Errorf("expected insecure registries %v, got %v", want, daemon.InsecureRegistries)
+	}
+	merged, err := o.mergeDaemonConfig([]byte(`{"insecure-registries": ["registry.example.com:5000"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := merged["insecure-registries"]; !reflect.DeepEqual(got, []interface{}{"registry.example.com:5000", "10.0.0.111:5000"}) {
+		t.Errorf("expected the upstream kept once and registry added, got %v", got)
+	}
+}
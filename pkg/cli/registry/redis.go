@@ -0,0 +1,114 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// redisCheckTimeout is the seconds the redis check on node waits for the answer of redis.
+const redisCheckTimeout = 5
+
+var allowCache = sets.NewString("inmemory", "redis")
+
+func (o *RegistryOptions) validateCache() error {
+	if o.Cache == "" {
+		if o.RedisAddr != "" || o.RedisPassword != "" {
+			return fmt.Errorf("--redis-addr and --redis-password require --cache redis")
+		}
+		return nil
+	}
+	if !allowCache.Has(o.Cache) {
+		return fmt.Errorf("--cache must be one of %s", strings.Join(allowCache.List(), ","))
+	}
+	if o.Cache != "redis" {
+		if o.RedisAddr != "" || o.RedisPassword != "" {
+			return fmt.Errorf("--redis-addr and --redis-password require --cache redis")
+		}
+		return nil
+	}
+	if o.RedisAddr == "" {
+		return fmt.Errorf("--cache redis requires --redis-addr")
+	}
+	host, port, err := net.SplitHostPort(o.RedisAddr)
+	if err != nil || host == "" || port == "" || strings.ContainsAny(host, "/ ") {
+		return fmt.Errorf("--redis-addr %s is invalid, must be host:port", o.RedisAddr)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return fmt.Errorf("--redis-addr %s is the loopback of the registry container, use an address of redis reachable from the container", o.RedisAddr)
+	}
+	// the check on node sends the password inline, and commands run by sudo are split on '&&'
+	if strings.ContainsAny(o.RedisPassword, " \t\r\n") || strings.Contains(o.RedisPassword, "&&") {
+		return fmt.Errorf("--redis-password can not contain whitespaces or '&&'")
+	}
+	return nil
+}
+
+// cacheEnv returns the registry settings of the blob descriptor cache, the --env entries follow and override them.
+func (o *RegistryOptions) cacheEnv() []string {
+	if o.Cache == "" {
+		return nil
+	}
+	env := []string{"REGISTRY_STORAGE_CACHE_BLOBDESCRIPTOR=" + o.Cache}
+	if o.Cache != "redis" {
+		return env
+	}
+	env = append(env, "REGISTRY_REDIS_ADDR="+o.RedisAddr)
+	if o.RedisPassword != "" {
+		env = append(env, "REGISTRY_REDIS_PASSWORD="+o.RedisPassword)
+	}
+	return env
+}
+
+// redisCheckCmd returns the command which pings redis from node over bash /dev/tcp, authenticating first
+// with --redis-password, and prints the replies.
+func (o *RegistryOptions) redisCheckCmd() string {
+	host, port, _ := net.SplitHostPort(o.RedisAddr)
+	commands := "PING"
+	lines := 1
+	if o.RedisPassword != "" {
+		commands = shellQuote("AUTH "+o.RedisPassword) + " PING"
+		lines = 2
+	}
+	script := fmt.Sprintf(`exec 3<>/dev/tcp/%s/%s; printf '%%s\r\n' %s >&3; head -n %d <&3`, host, port, commands, lines)
+	return fmt.Sprintf("timeout %d bash -c %s", redisCheckTimeout, shellQuote(script))
+}
+
+// checkRedis checks redis of --redis-addr answers PING from node, with --redis-password if set.
+// The registry container reaches redis over the network of node.
+func (o *RegistryOptions) checkRedis() error {
+	ret, err := o.runCmd(o.redisCheckCmd())
+	if err != nil {
+		return err
+	}
+	replies := nonEmptyLines(ret.Stdout)
+	for _, reply := range replies {
+		if strings.HasPrefix(reply, "-") {
+			return fmt.Errorf("redis %s refused the registry from %s: %s, please check --redis-password", o.RedisAddr, o.Node, strings.TrimPrefix(reply, "-"))
+		}
+		if reply == "+PONG" {
+			o.log("check-redis").Infof("redis %s is reachable from %s", o.RedisAddr, o.Node)
+			return nil
+		}
+	}
+	return unreachableError(fmt.Errorf("redis %s is not reachable from %s, please check the address and the network of node", o.RedisAddr, o.Node))
+}
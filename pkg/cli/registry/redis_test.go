@@ -0,0 +1,120 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		cache    string
+		addr     string
+		password string
+		wantErr  string
+	}{
+		{name: "no cache"},
+		{name: "inmemory", cache: "inmemory"},
+		{name: "redis", cache: "redis", addr: "10.0.0.5:6379", password: "secret"},
+		{name: "redis hostname", cache: "redis", addr: "redis.example.com:6379"},
+		{name: "unknown cache", cache: "memcached", wantErr: "--cache must be one of"},
+		{name: "addr without cache", addr: "10.0.0.5:6379", wantErr: "require --cache redis"},
+		{name: "password with inmemory", cache: "inmemory", password: "secret", wantErr: "require --cache redis"},
+		{name: "redis without addr", cache: "redis", wantErr: "requires --redis-addr"},
+		{name: "addr without port", cache: "redis", addr: "10.0.0.5", wantErr: "must be host:port"},
+		{name: "loopback addr", cache: "redis", addr: "127.0.0.1:6379", wantErr: "loopback"},
+		{name: "localhost addr", cache: "redis", addr: "localhost:6379", wantErr: "loopback"},
+		{name: "password with space", cache: "redis", addr: "10.0.0.5:6379", password: "a b", wantErr: "whitespaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newFakeOptions(&fakeRunner{})
+			o.Cache, o.RedisAddr, o.RedisPassword = tt.cache, tt.addr, tt.password
+			err := o.validateCache()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunRegistryCmd_Cache(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.Cache, o.RedisAddr, o.RedisPassword = "redis", "10.0.0.5:6379", "secret"
+	o.Env = []string{"REGISTRY_REDIS_DB=1"}
+	cmd := o.runRegistryCmd()
+	want := "-e 'REGISTRY_STORAGE_CACHE_BLOBDESCRIPTOR=redis' -e 'REGISTRY_REDIS_ADDR=10.0.0.5:6379' -e 'REGISTRY_REDIS_PASSWORD=secret' -e 'REGISTRY_REDIS_DB=1'"
+	if !strings.Contains(cmd, want) {
+		t.Errorf("expected %q in %q", want, cmd)
+	}
+	o.Cache, o.RedisAddr, o.RedisPassword = "", "", ""
+	if cmd = o.runRegistryCmd(); strings.Contains(cmd, "CACHE") {
+		t.Errorf("unexpected cache settings without --cache: %s", cmd)
+	}
+}
+
+func TestCheckRedis(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		stdout      string
+		fail        bool
+		wantErr     string
+		unreachable bool
+	}{
+		{name: "pong", stdout: "+PONG\r\n"},
+		{name: "auth and pong", password: "secret", stdout: "+OK\r\n+PONG\r\n"},
+		{name: "auth required", stdout: "-NOAUTH Authentication required.\r\n", wantErr: "NOAUTH Authentication required"},
+		{name: "wrong password", password: "wrong", stdout: "-WRONGPASS invalid username-password pair\r\n-NOAUTH Authentication required.\r\n", wantErr: "WRONGPASS"},
+		{name: "unreachable", fail: true, wantErr: "is not reachable", unreachable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{outputs: map[string]string{}, failures: map[string]string{}}
+			o := newFakeOptions(runner)
+			o.Cache, o.RedisAddr, o.RedisPassword = "redis", "10.0.0.5:6379", tt.password
+			cmd := o.redisCheckCmd()
+			runner.outputs[cmd] = tt.stdout
+			if tt.fail {
+				runner.failures[cmd] = "bash: connect: Connection refused"
+			}
+			err := o.checkRedis()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.unreachable && exitCode(err) != exitUnreachable {
+				t.Errorf("expected unreachable error, got %v", err)
+			}
+			if !strings.Contains(cmd, "/dev/tcp/10.0.0.5/6379") || (tt.password != "" && !strings.Contains(cmd, "AUTH "+tt.password)) {
+				t.Errorf("unexpected redis check command %s", cmd)
+			}
+		})
+	}
+}
@@ -20,21 +20,32 @@ package registry
 
 import (
 	"bytes"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	pkgerr "github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubeclipper/kubeclipper/pkg/cli/printer"
 
 	"github.com/spf13/cobra"
+	"k8s.io/component-base/version"
 
 	"github.com/kubeclipper/kubeclipper/pkg/cli/sudo"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/httputil"
@@ -68,6 +79,7 @@ Examples:
   kcctl registry clean --pk-file key --node 10.0.0.111 --force true
   kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --data-root /var/lib/docker
   kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --data-root /var/lib/docker --force true
+  kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --remove-volume --dry-run
 
   kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz
 
@@ -75,9 +87,30 @@ Examples:
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type repository --number 6
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi --number 6
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type tag-count
 
   kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --tag v3.4.0
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --all-tags --dry-run
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --all-tags --remove-repository --registry-volume /opt/registry
+  # Print the snapshot tags of every repository which would be deleted
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --all-repos --tag-pattern '-snapshot$' --dry-run
+  # Delete the snapshot tags of every repository
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --all-repos --tag-pattern '-snapshot$'
 
+  kcctl registry verify --node 10.0.0.111 --registry-port 5000 --file images.txt
+  kcctl registry verify --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz
+
+  kcctl registry warmup --pk-file key --node 10.0.0.111 --registry-port 5000 --images-file images.txt
+
+  kcctl registry status --pk-file key --nodes 10.0.0.111,10.0.0.112
+
+  kcctl registry logs --pk-file key --node 10.0.0.111 --follow
+
+  kcctl registry rename-repo --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --new-name csi/cephcsi
+
+  kcctl registry mirror-config --node 10.0.0.111 --registry-port 5000 --client-engine containerd
+
+  kcctl registry migrate-volume --pk-file key --node 10.0.0.111 --new-volume /data/registry
 
 Flags:
   -h, --help                   help for registry
@@ -87,8 +120,15 @@ const (
 	longDescription = `
   Docker registry operation.
 
-  Currently, you can deploy, clean, push, list and delete docker registry.
-  Use docker engine API V2, visit the website(https://docs.docker.com/registry/spec/api/) for more information.`
+  Currently, you can deploy, clean, push, list, delete, rename repository, verify, diff images package, warm up, check status, print logs, print client mirror config and migrate volume of docker registry.
+  Use docker engine API V2, visit the website(https://docs.docker.com/registry/spec/api/) for more information.
+
+  Exit codes:
+    0    success
+    1    invalid flags or arguments, and other errors
+    2    node or registry is not reachable
+    3    command failed on node
+    130  interrupted by Ctrl-C`
 	registryExample = `
   # Deploy docker registry
   kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz
@@ -100,19 +140,101 @@ const (
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type repository
   # Delete docker registry
   kcctl registry delete --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi
+  # Rename a repository of docker registry
+  kcctl registry rename-repo --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --new-name csi/cephcsi
+  # Verify docker registry images
+  kcctl registry verify --node 10.0.0.111 --registry-port 5000 --file images.txt
+  # Pre-pull images through docker registry
+  kcctl registry warmup --pk-file key --node 10.0.0.111 --registry-port 5000 --images-file images.txt
+  # Show status of docker registry nodes
+  kcctl registry status --pk-file key --nodes 10.0.0.111,10.0.0.112
+  # Follow the logs of docker registry container
+  kcctl registry logs --pk-file key --node 10.0.0.111 --follow
+  # Print client mirror config of docker registry
+  kcctl registry mirror-config --node 10.0.0.111 --registry-port 5000 --client-engine containerd
+  # Move the volume of docker registry to another path
+  kcctl registry migrate-volume --pk-file key --node 10.0.0.111 --new-volume /data/registry
+
+  # Push docker images in a script, only errors are printed
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz -o json --quiet
 
   Please read 'kcctl registry -h' get more registry flags.`
 	deployLongDescription = `
-  Deploy docker registry by flags.`
+  Deploy docker registry by flags.
+
+  After the registry container started, deploy waits up to --health-timeout for 'GET /v2/' to answer,
+  the whole deploy is bounded by --deploy-timeout.
+  The duration of each deploy step is printed at the end, with -o json the push report and the step durations
+  are printed as JSON.
+  Deploy and clean hold the lock file /var/run/kc-registry.lock on node, a concurrent deploy or clean of the node
+  aborts, a lock older than 2 hours is taken over, and so is any lock with --force-lock.
+  With --docker-host, the docker CLI on node targets an external docker daemon, which must trust the registry,
+  docker is neither installed nor configured on node, and the daemon is checked by 'docker info' at precheck.
+  With --skip-docker-install, docker preinstalled on node, e.g. by a golden image, is used as is, neither installed
+  nor its daemon.json changed, and it is checked by 'docker info' at precheck. It must trust the registry already.
+  Every pull and push asks the registry for the descriptors of the blobs, which are read from the storage unless
+  cached. With --cache redis, they are cached in the redis of --redis-addr, shared by the registry replicas and kept
+  across restarts, which relieves the storage of read-heavy registries, e.g. one serving many nodes. Redis is checked
+  by PING from node at precheck.
+  With --scan-cmd, the pushed images are scanned as by 'kcctl registry push', and deploy fails if any image failed
+  the scan, unless --scan-report-only is set.
+  With --all-arch, the arches of the package are found by the '{arch}' directory of --image-glob, and the images of
+  each arch are loaded and pushed as '<tag>-<arch>', e.g. '3.6-arm64' or '3.6-armv7'. Every tag is then pushed as a
+  manifest list of the images of its arches, which docker resolves to the arch of the pulling node. The processed
+  arches are printed, and reported in the push report with -o json. --arch still selects the registry image.`
 	deployExample = `
   # Deploy docker registry
   kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz
   # Deploy docker registry by options
   kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --registry-volume /opt/registry --data-root /var/lib/docker
+  # Deploy docker registry resolving the upstream by its internal address on node and in the container
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --extra-host registry.example.com:10.0.0.5 --update-hosts
+  # Deploy docker registry in read-only mode with debug logs
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --env REGISTRY_STORAGE_MAINTENANCE_READONLY='{"enabled":true}' --env REGISTRY_LOG_LEVEL=debug
+  # Deploy docker registry loading the image archives of a bundle laid out as images/<name>.tar
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --resource-dir kc/bundle --image-glob 'images/*.tar'
+  # Deploy docker registry pushing the images of every arch in the package as manifest lists
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --all-arch
+  # Deploy docker registry on a node with docker preinstalled and configured
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --skip-docker-install
+  # Deploy docker registry caching blob descriptors in redis
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --cache redis --redis-addr 10.0.0.5:6379 --redis-password secret
+  # Deploy docker registry run by systemd service kc-registry after docker.service
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --systemd
+  # Deploy docker registry with limited resources
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --restart-policy unless-stopped --memory-limit 2g --cpu-limit 1.5
+  # Deploy docker registry after verifying the local package
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --arch amd64 --offline-verify
+  # Deploy an empty docker registry, images are pushed separately
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --no-push
+  # Deploy docker registry with the listed images pulled from an upstream registry instead of the bundled images
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --pull-images-from registry.example.com:5000 --images-list images.txt
+  # Deploy docker registry on a NFS mounted volume with at least 100GB free
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --registry-volume /mnt/nfs/registry --expect-mount --min-free-gb 100
+  # Deploy docker registry which is only reachable on the node itself
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --bind-address 127.0.0.1
+  # Deploy docker registry and undo the completed steps on failure, so that deploy can be retried
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --rollback-on-failure
+  # Deploy docker registry running a pinned registry image, pulled on node if the package doesn't contain it
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --registry-image registry:2.8.3
+  # Deploy a docker registry mirror with the settings of the registry running on 10.0.0.110
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --template-from 10.0.0.110
+  # Deploy docker registry over a shared link, the pkg is uploaded at most 10MiB/s
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --upload-rate-limit 10485760
+  # Deploy docker registry on a node running rootless docker as user kc
+  kcctl registry deploy --user kc --pk-file key --node 10.0.0.111 --pkg kc.tar.gz --rootless
+  # Deploy docker registry on a node whose sshd listens on port 2222
+  kcctl registry deploy --pk-file key --node 10.0.0.111 --ssh-port 2222 --pkg kc.tar.gz --registry-port 5000
 
   Please read 'kcctl registry deploy -h' get more registry deploy flags.`
 	cleanLongDescription = `
-  Clean docker registry by flags.`
+  Clean docker registry by flags.
+
+  The registry volume is kept unless --remove-volume is specified, so that image data survives a re-deploy.
+  A registry deployed with --systemd is stopped by disabling systemd service kc-registry, which is removed then.
+
+  With --dry-run, the current state of node is read and what would be stopped or removed is printed with the size
+  of each path, nothing is changed on node.`
 	cleanExample = `
   # Clean docker registry
   kcctl registry clean --pk-file key --node 10.0.0.111
@@ -120,29 +242,108 @@ const (
   kcctl registry clean --pk-file key --node 10.0.0.111 --remove-docker true
   # Forced to clean docker registry
   kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --data-root /var/lib/docker --force true
+  # Clean docker registry and delete its image data
+  kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --remove-volume
+  # Preview what clean would stop and remove
+  kcctl registry clean --pk-file key --node 10.0.0.111 --registry-volume /opt/registry --remove-volume --dry-run
 
   Please read 'kcctl registry clean -h' get more registry clean flags.`
 	pushLongDescription = `
-  Push docker image by flags.`
+  Push docker image by flags.
+
+  With --skip-existing, a tag already in registry is not pushed again if its manifest refers to the same image,
+  i.e. the config digest equals the ID of the loaded image. The report tells pushed and skipped images apart.
+
+  With --scan-cmd, the command is run on node for every pushed image, e.g. a vulnerability scanner. It is a template
+  rendered with {{.Image}}, the pushed reference, and {{.Digest}}, its manifest digest. An image for which it exits
+  non-zero failed the scan, push fails if any image did, unless --scan-report-only is set. The outcomes are in the report.
+
+  With --all-arch, --images-pkg is a package laid out as the one of 'kcctl registry deploy', e.g. kc.tar.gz. It is
+  extracted on node, and the images of every arch in it are pushed as manifest lists as by deploy --all-arch.`
 	pushExample = `
   # Push a Docker image
   kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz
+  # Push Docker images except the ones matched
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --exclude 'library/centos*'
+  # Push Docker images to an external registry which requires login
+  kcctl registry push --pk-file key --node 10.0.0.111 --images-pkg images.tar.gz --target-registry harbor.example.com:443 --target-auth admin:Harbor12345
+  # Push Docker images and write the digests of pushed images to a local file
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --manifest-out delivered.json
+  # Push a Docker image and keep the loaded images on node
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --keep-local-images
+  # Push only the images of an incremental bundle which are not in registry yet
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --skip-existing
+  # Push Docker images and print the push report as json
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz -o json
+  # Push Docker images and fail if trivy finds a critical vulnerability in any of them
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --scan-cmd 'trivy image --insecure --exit-code 1 --severity CRITICAL {{.Image}}'
+  # Estimate the layer bytes to transfer without pushing
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz --estimate
+  # Push the images of every arch in a deploy package as manifest lists
+  kcctl registry push --pk-file key --node 10.0.0.111 --registry-port 5000 --images-pkg kc.tar.gz --all-arch
 
   Please read 'kcctl registry push -h' get more registry push flags.`
 	listLongDescription = `
-  Lists docker repositories by flags.`
+  Lists docker repositories by flags.
+
+  With --type blob-usage, the blob store in --registry-volume is walked over ssh, and the largest blobs are listed
+  with the repositories linking them and the tags whose image contains them. A blob linked by no repository is
+  orphaned, registry garbage-collect reclaims it.
+
+  With --detail, --since and --before list only the tags whose image was created within the window, by the 'created'
+  time of its config blob. The window is bounded by a duration before now, or a date. The creation time of a manifest
+  list is the latest of its platform images, which are read only when the window is set.
+
+  The registry API is requested with the credential of node:registry-port in docker config.json, by its credential
+  helper or store if configured, or with --registry-auth if docker config.json has none.`
 	listExample = `
   # Lists docker repositories
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type repository
   # Lists docker images and specifies the number of returns
   kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --number 6
+  # Lists tag count of each repository
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type tag-count
+  # Lists docker repositories of a registry requiring authentication, with the credential of 'docker login'
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type repository --docker-config ~/.docker/config.json
+  # Lists the 20 largest blobs in the registry volume with the repositories and tags referencing them
+  kcctl registry list --pk-file key --node 10.0.0.111 --type blob-usage --top 20
+  # Lists the platforms each tag of an image supports
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi --detail
+  # Lists the tags of an image not pushed in the last 90 days
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi --detail --before 2160h
+  # Lists the tags of an image created in 2024
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi --detail --since 2024-01-01 --before 2025-01-01
+  # Lists docker images by custom template
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type image --name caas4/cephcsi -o go-template --template '{{.Name}}{{"\t"}}{{len .Tags}}'
+  # Stream all docker repositories one per line, 500 entries are fetched in each page
+  kcctl registry list --node 10.0.0.111 --registry-port 5000 --type repository --number 500 -o jsonl
+  # Lists docker repositories of a registry bound to 127.0.0.1 through a ssh tunnel
+  kcctl registry list --pk-file key --node 10.0.0.111 --registry-port 5000 --type repository --tunnel
 
   Please read 'kcctl registry list -h' get more registry list flags.`
 	deleteLongDescription = `
-  Delete the docker registry by flags.`
+  Delete the docker registry by flags.
+
+  The image is deleted by registry API first. If registry deletion is disabled (405),
+  the tag is removed from registry volume instead, and registry garbage-collect must be run to reclaim disk space.
+
+  With --tag-pattern, every tag of --name matching the regular expression is deleted the same way,
+  or of every repository with --all-repos. A summary of each repository is printed at the end.
+
+  A multi-arch tag is deleted with its manifest list, the platform manifests it references are deleted as well
+  with --recursive, otherwise they remain in the repository, untagged.
+
+  The registry API is requested with the credential of node:registry-port in docker config.json, by its credential
+  helper or store if configured, or with --registry-auth if docker config.json has none.`
 	deleteExample = `
   # Delete docker registry
   kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --tag v3.4.0
+  # Delete a multi-arch tag along with the platform manifests of its manifest list
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/etcd --tag v3.5 --recursive
+  # Print the tags which would be deleted of a repository
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --all-tags --dry-run
+  # Delete all tags of a repository and remove the repository
+  kcctl registry delete --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --all-tags --remove-repository --registry-volume /opt/registry
 
   Please read 'kcctl registry delete -h' get more registry delete flags.`
 )
@@ -164,18 +365,197 @@ type RegistryOptions struct {
 
 	// no install/uninstall docker
 	RemoveDocker bool
+	// delete the registry volume on clean, image data is kept by default
+	RemoveVolume bool
 	Force        bool
+	// keep loaded images on node after push
+	KeepLocalImages bool
+	// deploy docker and registry only, the bundled images are not loaded or pushed
+	NoPush bool
+	// require the registry volume or its parent to be a mountpoint
+	ExpectMount bool
+	// minimum free space in GB of the registry volume filesystem, 0 skips the check
+	MinFreeGB int
+	// report the layer bytes push would transfer instead of pushing
+	Estimate bool
+	// skip pushing the tags already in registry with the same image
+	SkipExisting bool
+	// file the delivery manifest of push is written to
+	ManifestOut string
+	// command run on node for every pushed image, rendered with its reference as {{.Image}}, a non-zero exit code fails it
+	ScanCmd string
+	// report the failed scans instead of failing push
+	ScanReportOnly bool
+	// 'host:port' of an external registry images are pushed to instead of the registry on node
+	TargetRegistry string
+	// 'user:password' to login the target registry
+	TargetAuth string
+	// number of registry container log lines printed on deploy failure
+	LogsTail int
+	// undo the completed deploy steps when deploy failed
+	RollbackOnFailure bool
+	// follow the registry container logs
+	Follow bool
+	// name of the registry container, for logs
+	RegistryName string
+	// image the registry container runs, loaded from the package or pulled
+	RegistryImage string
+	// take over the lock of a concurrent deploy or clean on node
+	ForceLock bool
 
 	Type   string
 	Name   string
 	Tag    string
 	Number int
+	// inspect the platforms of every tag for list --type image
+	Detail bool
+	// list the tags whose image was created since, or before, a duration ago or a date, with Detail
+	Since  string
+	Before string
+	// number of the largest blobs listed by list --type blob-usage, 0 means all
+	Top int
+	// delete all tags of the repository instead of a single tag
+	AllTags bool
+	// remove the repository directory after all tags are deleted
+	RemoveRepository bool
+	// delete the tags matching the regular expression, of --name or of every repository with AllRepos
+	TagPattern string
+	AllRepos   bool
+	// only print what would be deleted
+	DryRun bool
+	// delete the platform manifests of a deleted manifest list as well
+	Recursive bool
+	// target repository of rename-repo
+	NewName string
+	// path of docker config.json the credential of the registry is read from, ~/.docker/config.json by default
+	DockerConfig string
+	// 'user:password' of the registry on node, used if docker config.json has no credential of it
+	RegistryAuth string
+	// path the registry volume is moved to by migrate-volume
+	NewVolume string
+	// keep the old registry volume after migrate-volume
+	KeepOld bool
+	// file of images for verify, warmup and deploy with PullImagesFrom
+	ImagesFile string
+	// compression of package, auto detected by default
+	Compression string
+	// glob patterns of images excluded from push
+	Exclude []string
+	// deadline of the whole deploy flow
+	DeployTimeout time.Duration
+	// deadline of waiting the deployed registry to serve its API
+	HealthTimeout time.Duration
+	// CA bundle trusted by docker to push to a TLS registry
+	PushCAFile string
+	// extra nodes which trust the CA bundle besides the registry node
+	CANodes []string
+	// registry nodes checked by status
+	Nodes []string
+	// verify the local package contents before sending
+	OfflineVerify bool
+	// container engine of the client configured by mirror-config
+	ClientEngine string
+	// whether the registry serves TLS, for mirror-config
+	TLS bool
+	// path of the registry CA on client, for mirror-config
+	ClientCAFile string
+	// bypass the completion cache of catalog and tags
+	NoCache bool
+	// user-agent of the requests to registry API
+	UserAgent string
+	// number of retries of a registry API request failed transiently
+	HTTPRetries int
+	// address on node the registry port is published on
+	BindAddress string
+	// run the registry container by the systemd unit kc-registry.service instead of docker restart policy
+	Systemd bool
+	// 'host:ip' entries resolved by the registry container, and by node with UpdateHosts
+	ExtraHosts  []string
+	UpdateHosts bool
+	// 'KEY=VALUE' environment variables of the registry container
+	Env []string
+	// blob descriptor cache of the registry, inmemory or redis, empty keeps the default of the registry image
+	Cache string
+	// 'host:port' and password of redis with Cache redis
+	RedisAddr     string
+	RedisPassword string
+	// directory of the image archives on node, relative to the package unless absolute
+	ResourceDir string
+	// glob of the image archives under ResourceDir, '{arch}' is the arch directory
+	ImageGlob string
+	// upstream registry deploy pulls the images of ImagesFile from, instead of loading the bundled images
+	PullImagesFrom string
+	// reach registry API through a ssh tunnel to the loopback of node
+	Tunnel bool
+	// time to live of the completion cache
+	CacheTTL time.Duration
+	// docker on node runs rootless as the ssh user, docker commands run without sudo
+	Rootless bool
+	// address of the docker daemon the docker CLI on node targets, instead of the local one
+	DockerHost string
+	// docker on node is preinstalled, deploy neither installs nor configures it
+	SkipDockerInstall bool
+	// push the images of every arch in the package as manifest lists, instead of those of --arch
+	AllArch bool
+	// node running a registry whose container settings deploy replicates
+	TemplateFrom string
+	// registry container on --template-from, --registry-name if empty
+	TemplateName string
+	// suppress the logs except errors
+	Quiet bool
+	// print the node as a field of the log line instead of its '[node]' prefix
+	NoPrefix bool
+	// restart policy of the registry container
+	RestartPolicy string
+	// memory limit of the registry container, e.g. 512m, empty means unlimited
+	MemoryLimit string
+	// number of CPUs the registry container can use, 0 means unlimited
+	CPULimit float64
 
 	SSHConfig *sshutils.SSH
+	// cmdRunner runs command on node, defaults to sshutils.SSHCmdWithSudo
+	cmdRunner sshutils.SSHRunCmd
+	// userCmdRunner runs command on node as the ssh user, defaults to sshutils.SSHCmd
+	userCmdRunner sshutils.SSHRunCmd
+	// streamRunner runs command on node with its output streamed, defaults to sshutils.SSHStreamCmdWithSudo
+	streamRunner sshutils.SSHStreamCmd
+	// ctx cancels the running command, e.g. when deploy timed out
+	ctx context.Context
+	// interrupt is done once the subcommand is interrupted by Ctrl-C
+	interrupt context.Context
+	// cacheDir is where the completion cache is stored
+	cacheDir string
+	// subcommand is the name of the running registry subcommand, used as log field
+	subcommand string
+	// outputSet is whether -o is set on command line, the table of deploy and push is skipped with -q otherwise
+	outputSet bool
+	// apiBase overrides the base url of registry API while a tunnel is open
+	apiBase string
+	// dockerInstalled is set once deploy installs docker, which is then removed by rollback
+	dockerInstalled bool
+	// retryBackoff is the wait before the first retry of a registry API request, defaults to defaultRetryBackoff
+	retryBackoff time.Duration
+	// registryReused is set once deploy reuses an existing registry container, which is then kept by rollback
+	registryReused bool
+	// nodeLocked is set while deploy or clean holds the lock file on node
+	nodeLocked bool
+	// authOnce resolves authorization, the 'Authorization' header of the registry API requests, once
+	authOnce      sync.Once
+	authorization string
 }
 
+const (
+	// tagCountConcurrency is the maximum number of concurrent tag list requests.
+	tagCountConcurrency = 5
+	// defaultRegistryImage is the registry image bundled in the package.
+	defaultRegistryImage = "registry:2"
+)
+
 var (
-	allowType = sets.NewString("image", "repository")
+	allowType          = sets.NewString("image", "repository", "tag-count", "blob-usage")
+	allowRestartPolicy = sets.NewString("no", "always", "unless-stopped", "on-failure")
+	// memoryLimitRegexp matches docker memory limit, a positive number with an optional unit b, k, m or g.
+	memoryLimitRegexp = regexp.MustCompile(`^[1-9][0-9]*[bkmgBKMG]?$`)
 )
 
 func NewRegistryOptions(streams options.IOStreams) *RegistryOptions {
@@ -183,7 +563,9 @@ func NewRegistryOptions(streams options.IOStreams) *RegistryOptions {
 		IOStreams:  streams,
 		PrintFlags: printer.NewPrintFlags(),
 		SSHConfig: &sshutils.SSH{
-			User: "root",
+			User:              "root",
+			Port:              sshutils.DefaultPort,
+			KeepAliveInterval: 30 * time.Second,
 		},
 		DataRoot:       "/var/lib/docker",
 		RegistryVolume: "/opt/registry",
@@ -191,6 +573,22 @@ func NewRegistryOptions(streams options.IOStreams) *RegistryOptions {
 		Arch:           "amd64",
 		Tag:            "",
 		Number:         0,
+		Top:            10,
+		LogsTail:       20,
+		Compression:    compressionAuto,
+		DeployTimeout:  30 * time.Minute,
+		HealthTimeout:  time.Minute,
+		RestartPolicy:  "always",
+		ClientEngine:   engineDocker,
+		CacheTTL:       5 * time.Minute,
+		UserAgent:      "kcctl/" + version.Get().GitVersion,
+		HTTPRetries:    3,
+		BindAddress:    "0.0.0.0",
+		RegistryName:   "registry",
+		RegistryImage:  defaultRegistryImage,
+		ResourceDir:    defaultResourceDir,
+		ImageGlob:      defaultImageGlob,
+		cacheDir:       defaultCacheDir(),
 	}
 }
 
@@ -203,13 +601,37 @@ func NewCmdRegistry(streams options.IOStreams) *cobra.Command {
 		Long:                  longDescription,
 		Example:               registryExample,
 		Args:                  cobra.NoArgs,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			o.subcommand = cmd.Name()
+			o.outputSet = cmd.Flags().Changed("output")
+			logger.SetQuiet(o.Quiet)
+			if !o.NoPrefix {
+				logger.SetPrefixKey("node")
+			}
+			if o.subcommand != "logs" {
+				// logs stops following on Ctrl-C by itself
+				o.watchInterrupt()
+			}
+		},
 	}
+	cmd.PersistentFlags().StringVar(&o.UserAgent, "user-agent", o.UserAgent, "user-agent of the requests to registry API")
+	cmd.PersistentFlags().IntVar(&o.HTTPRetries, "http-retries", o.HTTPRetries, "number of retries of a registry API request on 429, 502, 503, 504 or a connection reset, with backoff honoring Retry-After, 0 disables retry")
+	cmd.PersistentFlags().BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "print errors only, to stderr, the output of deploy and push is printed only if -o is set")
+	cmd.PersistentFlags().BoolVar(&o.NoPrefix, "no-prefix", o.NoPrefix, "do not prefix the log lines and the streamed command output with '[node]'")
 
 	cmd.AddCommand(NewCmdRegistryDeploy(o))
 	cmd.AddCommand(NewCmdRegistryClean(o))
 	cmd.AddCommand(NewCmdRegistryPush(o))
 	cmd.AddCommand(NewCmdRegistryList(o))
 	cmd.AddCommand(NewCmdRegistryDelete(o))
+	cmd.AddCommand(NewCmdRegistryRenameRepo(o))
+	cmd.AddCommand(NewCmdRegistryVerify(o))
+	cmd.AddCommand(NewCmdRegistryImagesDiff(o))
+	cmd.AddCommand(NewCmdRegistryWarmup(o))
+	cmd.AddCommand(NewCmdRegistryStatus(o))
+	cmd.AddCommand(NewCmdRegistryLogs(o))
+	cmd.AddCommand(NewCmdRegistryMirrorConfig(o))
+	cmd.AddCommand(NewCmdRegistryMigrateVolume(o))
 
 	return cmd
 }
@@ -224,21 +646,65 @@ func NewCmdRegistryDeploy(o *RegistryOptions) *cobra.Command {
 		Args:                  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			utils.CheckErr(o.Complete())
-			utils.CheckErr(o.ValidateArgsDeploy())
+			o.checkErr(usageError(o.ValidateArgsDeploy()))
 			if !o.preCheck() {
 				return
 			}
-			utils.CheckErr(o.Install())
+			if o.TemplateFrom != "" {
+				o.checkErr(o.applyTemplate(cmd.Flags()))
+			}
+			o.checkErr(o.Install())
 		},
 	}
 
 	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
-	cmd.Flags().StringVar(&o.Arch, "arch", o.Arch, "registry arch.")
+	cmd.Flags().StringVar(&o.Arch, "arch", o.Arch, fmt.Sprintf("registry arch, one of %s", strings.Join(supportedArches(), ",")))
 	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
 	cmd.Flags().StringVar(&o.Pkg, "pkg", o.Pkg, "docker service and images pkg.")
 	cmd.Flags().StringVar(&o.DataRoot, "data-root", o.DataRoot, "set docker data-root value.")
 	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "set registry volume path")
 	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().BoolVar(&o.KeepLocalImages, "keep-local-images", o.KeepLocalImages, "keep loaded images on node after push")
+	cmd.Flags().StringVar(&o.ScanCmd, "scan-cmd", o.ScanCmd, "command run on node for every pushed image after push, a template rendered with {{.Image}} the pushed reference and {{.Digest}} its digest, a non-zero exit code fails the scan of the image")
+	cmd.Flags().BoolVar(&o.ScanReportOnly, "scan-report-only", o.ScanReportOnly, "only report the images failed the scan of --scan-cmd instead of failing")
+	cmd.Flags().BoolVar(&o.NoPush, "no-push", o.NoPush, "deploy docker and an empty registry, skip loading and pushing the bundled images")
+	cmd.Flags().StringVar(&o.ResourceDir, "resource-dir", o.ResourceDir, "directory on node the image archives are loaded from, relative to the package unless absolute")
+	cmd.Flags().StringVar(&o.ImageGlob, "image-glob", o.ImageGlob, "glob of the image archives under --resource-dir, '**' matches any directories and '{arch}' the directory of --arch")
+	cmd.Flags().BoolVar(&o.AllArch, "all-arch", o.AllArch, "load and push the images of every arch found in the package by '{arch}' of --image-glob, each tag is pushed as a manifest list of its arches")
+	cmd.Flags().StringVar(&o.PullImagesFrom, "pull-images-from", o.PullImagesFrom, "upstream registry 'host[:port]' the images of --images-list are pulled from on node and pushed into registry, instead of the bundled images")
+	cmd.Flags().StringVar(&o.ImagesFile, "images-list", o.ImagesFile, "file of images pulled from --pull-images-from, one 'repo:tag' per line.")
+	cmd.Flags().BoolVar(&o.ExpectMount, "expect-mount", o.ExpectMount, "fail if the registry volume or its parent is not a mountpoint, e.g. an NFS mount")
+	cmd.Flags().IntVar(&o.MinFreeGB, "min-free-gb", o.MinFreeGB, "minimum free space in GB of the registry volume filesystem, 0 skips the check")
+	cmd.Flags().IntVar(&o.LogsTail, "logs-tail", o.LogsTail, "number of registry container log lines to show when deploy failed")
+	cmd.Flags().StringVar(&o.Compression, "compression", o.Compression, fmt.Sprintf("compression of pkg, one of %s", strings.Join(allowCompression.List(), ",")))
+	cmd.Flags().StringArrayVar(&o.Exclude, "exclude", o.Exclude, "glob pattern of image reference or repository excluded from push, can be repeated.")
+	cmd.Flags().DurationVar(&o.DeployTimeout, "deploy-timeout", o.DeployTimeout, "timeout of the whole deploy, 0 means no timeout")
+	cmd.Flags().DurationVar(&o.HealthTimeout, "health-timeout", o.HealthTimeout, "timeout of waiting the registry API to answer after the container started, 0 skips the wait")
+	cmd.Flags().BoolVar(&o.RollbackOnFailure, "rollback-on-failure", o.RollbackOnFailure, "undo the completed steps in reverse when deploy failed, docker is removed only if deploy installed it, otherwise its daemon.json and registry CA changed by deploy are kept, the registry volume is kept")
+	cmd.Flags().StringVar(&o.PushCAFile, "push-ca-file", o.PushCAFile, "PEM encoded CA bundle of the TLS registry, installed into docker certs.d on node")
+	cmd.Flags().StringSliceVar(&o.CANodes, "ca-nodes", o.CANodes, "other nodes which the CA bundle is installed into besides the registry node")
+	o.addTransferFlags(cmd)
+	cmd.Flags().BoolVar(&o.OfflineVerify, "offline-verify", o.OfflineVerify, "verify the local pkg contains the files of arch before sending it to node")
+	cmd.Flags().StringVar(&o.RestartPolicy, "restart-policy", o.RestartPolicy, fmt.Sprintf("restart policy of registry container, one of %s, on-failure accepts max retries like on-failure:3", strings.Join(allowRestartPolicy.List(), ",")))
+	cmd.Flags().StringVar(&o.MemoryLimit, "memory-limit", o.MemoryLimit, "memory limit of registry container, e.g. 512m or 1g, unlimited if not set")
+	cmd.Flags().Float64Var(&o.CPULimit, "cpu-limit", o.CPULimit, "number of CPUs registry container can use, e.g. 1.5, 0 means unlimited")
+	cmd.Flags().StringVar(&o.BindAddress, "bind-address", o.BindAddress, "address on node the registry port is published on, e.g. 127.0.0.1 to keep registry localhost-only")
+	cmd.Flags().StringVar(&o.RegistryName, "registry-name", o.RegistryName, "name of the registry container, an existing container of this name running the registry image is reused")
+	cmd.Flags().BoolVar(&o.ForceLock, "force-lock", o.ForceLock, "take over the lock of another deploy or clean running on node, e.g. one which was killed")
+	cmd.Flags().StringVar(&o.RegistryImage, "registry-image", o.RegistryImage, "image of the registry container, e.g. registry:2.8.3, pulled on node if the package doesn't contain it")
+	cmd.Flags().BoolVar(&o.Systemd, "systemd", o.Systemd, "run the registry container by systemd service kc-registry, which starts after docker.service, instead of --restart-policy")
+	cmd.Flags().StringArrayVar(&o.ExtraHosts, "extra-host", o.ExtraHosts, "host:ip entry added to the registry container by --add-host, can be repeated")
+	cmd.Flags().StringArrayVar(&o.Env, "env", o.Env, "KEY=VALUE environment variable of the registry container, e.g. REGISTRY_LOG_LEVEL=debug, can be repeated")
+	cmd.Flags().StringVar(&o.Cache, "cache", o.Cache, "blob descriptor cache of the registry, inmemory or redis, the default of the registry image if not set")
+	cmd.Flags().StringVar(&o.RedisAddr, "redis-addr", o.RedisAddr, "'host:port' of redis the registry caches blob descriptors in with --cache redis, must be reachable from the registry container")
+	cmd.Flags().StringVar(&o.RedisPassword, "redis-password", o.RedisPassword, "password of redis with --cache redis")
+	cmd.Flags().BoolVar(&o.UpdateHosts, "update-hosts", o.UpdateHosts, "also write the --extra-host entries into /etc/hosts of node, replacing those of an earlier deploy")
+	cmd.Flags().BoolVar(&o.Rootless, "rootless", o.Rootless, "docker on node runs rootless as the ssh user, docker commands run without sudo and ~/.config/docker/daemon.json is configured, docker must be set up before deploy")
+	cmd.Flags().StringVar(&o.DockerHost, "docker-host", o.DockerHost, "address of an external docker daemon the docker CLI on node targets like DOCKER_HOST, e.g. tcp://10.0.0.5:2375, docker is then neither installed nor configured on node")
+	cmd.Flags().BoolVar(&o.SkipDockerInstall, "skip-docker-install", o.SkipDockerInstall, "docker on node is preinstalled and configured, deploy neither installs docker nor changes its daemon.json, only checks docker works")
+	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&o.TemplateFrom, "template-from", o.TemplateFrom, "node running a registry whose port, bind address, volume, restart policy and resource limits are replicated, flags set explicitly win")
+	cmd.Flags().StringVar(&o.TemplateName, "template-name", o.TemplateName, "name of the registry container on --template-from, defaults to --registry-name")
 
 	utils.CheckErr(cmd.MarkFlagRequired("node"))
 	utils.CheckErr(cmd.MarkFlagRequired("pkg"))
@@ -255,20 +721,33 @@ func NewCmdRegistryClean(o *RegistryOptions) *cobra.Command {
 		Args:                  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			utils.CheckErr(o.Complete())
-			utils.CheckErr(o.ValidateArgs())
+			o.checkErr(usageError(o.ValidateArgs()))
 			if !o.preCheck() {
 				return
 			}
-			utils.CheckErr(o.Uninstall())
+			if o.DryRun {
+				o.checkErr(o.CleanDryRun())
+				return
+			}
+			if !o.confirmClean() {
+				return
+			}
+			o.checkErr(o.Uninstall())
 		},
 	}
 
 	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
 	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
 	cmd.Flags().StringVar(&o.DataRoot, "data-root", o.DataRoot, "clean docker data-root value.")
+	cmd.Flags().StringVar(&o.RegistryName, "registry-name", o.RegistryName, "name of the registry container")
 	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "clean registry volume path")
+	cmd.Flags().BoolVar(&o.ForceLock, "force-lock", o.ForceLock, "take over the lock of another deploy or clean running on node, e.g. one which was killed")
 	cmd.Flags().BoolVar(&o.RemoveDocker, "remove-docker", o.RemoveDocker, "no uninstall docker")
 	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "force uninstall")
+	cmd.Flags().BoolVar(&o.RemoveVolume, "remove-volume", o.RemoveVolume, "delete the registry volume, image data is kept if not set")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "only print the containers, services and paths which would be stopped or removed, with their current state and size")
+	cmd.Flags().BoolVar(&o.Rootless, "rootless", o.Rootless, "docker on node runs rootless as the ssh user, docker commands run without sudo")
+	cmd.Flags().StringVar(&o.DockerHost, "docker-host", o.DockerHost, "address of the external docker daemon the registry was deployed to by --docker-host")
 
 	utils.CheckErr(cmd.MarkFlagRequired("node"))
 	return cmd
@@ -284,11 +763,11 @@ func NewCmdRegistryPush(o *RegistryOptions) *cobra.Command {
 		Args:                  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			utils.CheckErr(o.Complete())
-			utils.CheckErr(o.ValidateArgsPush())
+			o.checkErr(usageError(o.ValidateArgsPush()))
 			if !o.preCheck() {
 				return
 			}
-			utils.CheckErr(o.Push())
+			o.checkErr(o.Push())
 		},
 	}
 
@@ -296,6 +775,21 @@ func NewCmdRegistryPush(o *RegistryOptions) *cobra.Command {
 	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
 	cmd.Flags().StringVar(&o.Pkg, "images-pkg", o.Pkg, "docker images pkg.")
 	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().BoolVar(&o.KeepLocalImages, "keep-local-images", o.KeepLocalImages, "keep loaded images on node after push")
+	cmd.Flags().StringVar(&o.Compression, "compression", o.Compression, fmt.Sprintf("compression of images pkg, one of %s", strings.Join(allowCompression.List(), ",")))
+	cmd.Flags().StringArrayVar(&o.Exclude, "exclude", o.Exclude, "glob pattern of image reference or repository excluded from push, can be repeated.")
+	o.addTransferFlags(cmd)
+	cmd.Flags().StringVar(&o.BindAddress, "bind-address", o.BindAddress, "address on node the registry port is published on, images are pushed to it unless it is 0.0.0.0")
+	cmd.Flags().BoolVar(&o.Estimate, "estimate", o.Estimate, "report the total and new layer bytes of images without pushing, layers already in registry are not counted as new")
+	cmd.Flags().BoolVar(&o.SkipExisting, "skip-existing", o.SkipExisting, "skip the tags already in registry with the same image instead of pushing them again")
+	cmd.Flags().StringVar(&o.TargetRegistry, "target-registry", o.TargetRegistry, "host:port of an external registry, e.g. Harbor, images are loaded on node and pushed to it instead of the registry on node")
+	cmd.Flags().StringVar(&o.TargetAuth, "target-auth", o.TargetAuth, "user:password to login the target registry")
+	cmd.Flags().StringVar(&o.ManifestOut, "manifest-out", o.ManifestOut, "local file to write the JSON manifest of pushed images with their digests and sizes after push")
+	cmd.Flags().StringVar(&o.ScanCmd, "scan-cmd", o.ScanCmd, "command run on node for every pushed image after push, a template rendered with {{.Image}} the pushed reference and {{.Digest}} its digest, a non-zero exit code fails the scan of the image")
+	cmd.Flags().BoolVar(&o.ScanReportOnly, "scan-report-only", o.ScanReportOnly, "only report the images failed the scan of --scan-cmd instead of failing")
+	cmd.Flags().BoolVar(&o.Rootless, "rootless", o.Rootless, "docker on node runs rootless as the ssh user, docker commands run without sudo")
+	cmd.Flags().BoolVar(&o.AllArch, "all-arch", o.AllArch, "treat --images-pkg as a deploy package, and push the images of every arch in it, each tag is pushed as a manifest list of its arches")
+	o.PrintFlags.AddFlags(cmd)
 
 	utils.CheckErr(cmd.MarkFlagRequired("node"))
 	utils.CheckErr(cmd.MarkFlagRequired("images-pkg"))
@@ -312,17 +806,30 @@ func NewCmdRegistryList(o *RegistryOptions) *cobra.Command {
 		Args:                  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			utils.CheckErr(o.Complete())
-			utils.CheckErr(o.ValidateArgsList())
-			utils.CheckErr(o.List())
+			o.checkErr(usageError(o.ValidateArgsList()))
+			if o.Type == "blob-usage" && !o.preCheck() {
+				return
+			}
+			o.checkErr(o.List())
 		},
 	}
 	o.PrintFlags.AddFlags(cmd)
 	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
 	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node")
 	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
-	cmd.Flags().StringVar(&o.Type, "type", o.Type, "image or repository")
+	cmd.Flags().StringVar(&o.Type, "type", o.Type, "image, repository, tag-count or blob-usage")
 	cmd.Flags().StringVar(&o.Name, "name", o.Name, "image name")
 	cmd.Flags().IntVar(&o.Number, "number", o.Number, "number of entries in each response. It not present, all entries will be returned.")
+	cmd.Flags().BoolVar(&o.Detail, "detail", o.Detail, "list the digest and the platforms of each tag from its manifest or manifest list, requires --type image")
+	cmd.Flags().StringVar(&o.Since, "since", o.Since, "list the tags whose image was created since a duration ago like 720h, a RFC3339 time or a date like 2006-01-02, requires --detail")
+	cmd.Flags().StringVar(&o.Before, "before", o.Before, "list the tags whose image was created before a duration ago like 720h, a RFC3339 time or a date like 2006-01-02, requires --detail")
+	cmd.Flags().IntVar(&o.Top, "top", o.Top, "number of the largest blobs listed by --type blob-usage, 0 means all")
+	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "registry volume path on node, the blob store of --type blob-usage is read from it")
+	cmd.Flags().BoolVar(&o.Tunnel, "tunnel", o.Tunnel, "reach registry API through a ssh tunnel to 127.0.0.1 of node, for a registry deployed with --bind-address 127.0.0.1")
+	cmd.Flags().StringVar(&o.DockerConfig, "docker-config", o.DockerConfig, "path of docker config.json the credential of the registry is read from, $DOCKER_CONFIG/config.json or ~/.docker/config.json by default")
+	cmd.Flags().StringVar(&o.RegistryAuth, "registry-auth", o.RegistryAuth, "'user:password' of the registry, used if docker config.json has no credential of node:registry-port")
+	cmd.Flags().BoolVar(&o.NoCache, "no-cache", o.NoCache, "bypass the local cache used by completion")
+	cmd.Flags().DurationVar(&o.CacheTTL, "cache-ttl", o.CacheTTL, "time to live of the local cache used by completion")
 
 	utils.CheckErr(cmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return allowType.List(), cobra.ShellCompDirectiveNoFileComp
@@ -338,26 +845,39 @@ func NewCmdRegistryList(o *RegistryOptions) *cobra.Command {
 
 func NewCmdRegistryDelete(o *RegistryOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:                   "delete (--pk-file <file path>) (--node <node>) (--name <name>) (--registry-port <registry-port>) (--tag <tag>) [flags]delete (--pk-file <file path>) (--node <node>) (--name <name>) (--registry-port <registry-port>) (--tag <tag>) [flags]",
+		Use:                   "delete (--pk-file <file path>) (--node <node>) (--name <name> | --all-repos) (--registry-port <registry-port>) (--tag <tag> | --all-tags | --tag-pattern <regex>) [flags]",
 		DisableFlagsInUseLine: true,
 		Short:                 "registry delete image",
 		Long:                  deleteLongDescription,
 		Example:               deleteExample,
 		Run: func(cmd *cobra.Command, args []string) {
 			utils.CheckErr(o.Complete())
-			utils.CheckErr(o.ValidateArgsDelete(cmd))
+			o.checkErr(usageError(o.ValidateArgsDelete(cmd)))
 			if !o.preCheck() {
 				return
 			}
-			utils.CheckErr(o.Delete())
+			o.checkErr(o.Delete())
 		},
 	}
 
 	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	o.PrintFlags.AddFlags(cmd)
 	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
 	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
 	cmd.Flags().StringVar(&o.Name, "name", o.Name, "image name")
 	cmd.Flags().StringVar(&o.Tag, "tag", o.Tag, "image tag")
+	cmd.Flags().BoolVar(&o.AllTags, "all-tags", o.AllTags, "delete all tags of the image, mutually exclusive with --tag")
+	cmd.Flags().BoolVar(&o.RemoveRepository, "remove-repository", o.RemoveRepository, "remove the repository after all tags deleted, requires --all-tags")
+	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "registry volume path on node, tags and repositories not deletable by API are removed from it")
+	cmd.Flags().StringVar(&o.TagPattern, "tag-pattern", o.TagPattern, "delete the tags matching the regular expression, mutually exclusive with --tag and --all-tags")
+	cmd.Flags().BoolVar(&o.AllRepos, "all-repos", o.AllRepos, "delete the tags matching --tag-pattern of every repository, mutually exclusive with --name")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "only print the tags which would be deleted")
+	cmd.Flags().BoolVar(&o.Recursive, "recursive", o.Recursive, "also delete the platform manifests referenced by a deleted manifest list, they must not be shared with other tags")
+	cmd.Flags().BoolVar(&o.Tunnel, "tunnel", o.Tunnel, "reach registry API through a ssh tunnel to 127.0.0.1 of node, for a registry deployed with --bind-address 127.0.0.1")
+	cmd.Flags().StringVar(&o.DockerConfig, "docker-config", o.DockerConfig, "path of docker config.json the credential of the registry is read from, $DOCKER_CONFIG/config.json or ~/.docker/config.json by default")
+	cmd.Flags().StringVar(&o.RegistryAuth, "registry-auth", o.RegistryAuth, "'user:password' of the registry, used if docker config.json has no credential of node:registry-port")
+	cmd.Flags().BoolVar(&o.NoCache, "no-cache", o.NoCache, "bypass the local cache used by completion")
+	cmd.Flags().DurationVar(&o.CacheTTL, "cache-ttl", o.CacheTTL, "time to live of the local cache used by completion")
 
 	utils.CheckErr(cmd.RegisterFlagCompletionFunc("name", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return o.listRepos(toComplete), cobra.ShellCompDirectiveNoFileComp
@@ -367,35 +887,154 @@ func NewCmdRegistryDelete(o *RegistryOptions) *cobra.Command {
 	}))
 
 	utils.CheckErr(cmd.MarkFlagRequired("node"))
-	utils.CheckErr(cmd.MarkFlagRequired("name"))
-	utils.CheckErr(cmd.MarkFlagRequired("tag"))
 	return cmd
 }
 
+// log returns the logger with node, subcommand and step of the registry operation as fields.
+func (o *RegistryOptions) log(step string) logger.Logger {
+	return o.logOn(o.Node, step)
+}
+
+// logOn is like log, but for the operation on node.
+func (o *RegistryOptions) logOn(node, step string) logger.Logger {
+	return logger.WithValues("node", node, "cmd", o.subcommand, "step", step)
+}
+
+// runCmd runs cmd on the registry node with sudo, and returns early once o.ctx is done.
+func (o *RegistryOptions) runCmd(cmd string) (sshutils.Result, error) {
+	return o.runCmdOn(o.Node, cmd)
+}
+
+// runCmdOn runs cmd on host with sudo, and returns early once o.ctx is done.
+func (o *RegistryOptions) runCmdOn(host, cmd string) (sshutils.Result, error) {
+	run := o.cmdRunner
+	if run == nil {
+		run = sshutils.SSHCmdWithSudo
+	}
+	return o.runWith(run, host, cmd)
+}
+
+// runWith runs cmd on host by run, and returns early once o.ctx is done.
+func (o *RegistryOptions) runWith(run sshutils.SSHRunCmd, host, cmd string) (sshutils.Result, error) {
+	if o.ctx == nil {
+		ret, err := run(o.SSHConfig, host, cmd)
+		return ret, sshError(err)
+	}
+	if err := o.ctx.Err(); err != nil {
+		return sshutils.Result{Host: host, Cmd: cmd}, err
+	}
+	type result struct {
+		ret sshutils.Result
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		ret, err := run(o.SSHConfig, host, cmd)
+		ch <- result{ret: ret, err: err}
+	}()
+	select {
+	case r := <-ch:
+		return r.ret, sshError(r.err)
+	case <-o.ctx.Done():
+		return sshutils.Result{Host: host, Cmd: cmd}, o.ctx.Err()
+	}
+}
+
+// requestHeader returns header of the registry API request with the user-agent set,
+// and the authorization if there is a credential of the registry.
+func (o *RegistryOptions) requestHeader(header map[string]string) map[string]string {
+	h := make(map[string]string, len(header)+2)
+	for k, v := range header {
+		h[k] = v
+	}
+	h["User-Agent"] = o.UserAgent
+	if auth := o.registryAuthorization(); auth != "" {
+		h["Authorization"] = auth
+	}
+	return h
+}
+
 func (o *RegistryOptions) preCheck() bool {
-	return sudo.PreCheck("sudo", o.SSHConfig, o.IOStreams, []string{o.Node})
+	nodes := []string{o.Node}
+	if o.TemplateFrom != "" {
+		nodes = append(nodes, o.TemplateFrom)
+	}
+	if !sudo.PreCheck("sudo", o.SSHConfig, o.IOStreams, nodes) {
+		return false
+	}
+	if o.Rootless {
+		o.checkErr(o.checkRootless())
+	}
+	if o.DockerHost != "" {
+		o.checkErr(o.checkDockerHost())
+	}
+	if o.SkipDockerInstall {
+		o.checkErr(o.checkPreinstalledDocker())
+	}
+	return true
+}
+
+// validateSSH validates the ssh flags of the subcommands running on node.
+func (o *RegistryOptions) validateSSH() error {
+	if o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
+		return fmt.Errorf("one of --pk-file or --passwd must be specified")
+	}
+	return o.validateSSHPort()
+}
+
+func (o *RegistryOptions) validateSSHPort() error {
+	if o.SSHConfig.Port <= 0 || o.SSHConfig.Port > 65535 {
+		return fmt.Errorf("--ssh-port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// addTransferFlags registers the ssh flags of the subcommands sending pkg to node.
+func (o *RegistryOptions) addTransferFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&o.SSHConfig.KeepAliveInterval, "ssh-keepalive", o.SSHConfig.KeepAliveInterval, "interval of ssh keepalive requests during long-running commands, 0 disables keepalive")
+	cmd.Flags().Int64Var(&o.SSHConfig.UploadRateLimit, "upload-rate-limit", o.SSHConfig.UploadRateLimit, "maximum bytes per second of uploading pkg to node, e.g. 10485760 for 10MiB/s, 0 means unlimited")
+}
+
+// validateTransfer validates the flags registered by addTransferFlags.
+func (o *RegistryOptions) validateTransfer() error {
+	if o.SSHConfig.KeepAliveInterval < 0 {
+		return fmt.Errorf("--ssh-keepalive must not be negative")
+	}
+	if o.SSHConfig.UploadRateLimit < 0 {
+		return fmt.Errorf("--upload-rate-limit must not be negative")
+	}
+	return nil
 }
 
 func (o *RegistryOptions) Complete() error {
 	if o.Arch == "" {
 		o.Arch = "amd64"
 	}
+	if arch, ok := archAliases[o.Arch]; ok {
+		o.Arch = arch
+	}
 	return nil
 }
 
 func (o *RegistryOptions) ValidateArgs() error {
-	if o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
-		return fmt.Errorf("one of --pk-file or --passwd must be specified")
+	if err := o.validateSSH(); err != nil {
+		return err
 	}
 	if o.Node == "" {
 		return fmt.Errorf("--node must be specified")
 	}
-	return nil
+	if o.Rootless && o.RemoveDocker {
+		return fmt.Errorf("--remove-docker can not be used with --rootless, remove rootless docker by 'dockerd-rootless-setuptool.sh uninstall' instead")
+	}
+	if err := o.validateDockerHost(); err != nil {
+		return err
+	}
+	return validateRegistryName(o.RegistryName)
 }
 
 func (o *RegistryOptions) ValidateArgsPush() error {
-	if o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
-		return fmt.Errorf("one of --pk-file or --passwd must be specified")
+	if err := o.validateSSH(); err != nil {
+		return err
 	}
 	if o.Node == "" {
 		return fmt.Errorf("--node must be specified")
@@ -403,12 +1042,36 @@ func (o *RegistryOptions) ValidateArgsPush() error {
 	if o.Pkg == "" {
 		return fmt.Errorf("--image-pkg must be specified")
 	}
-	return nil
+	if !allowCompression.Has(o.Compression) {
+		return fmt.Errorf("--compression must be one of %s", strings.Join(allowCompression.List(), ","))
+	}
+	if err := o.validateTransfer(); err != nil {
+		return err
+	}
+	if o.Estimate && o.ManifestOut != "" {
+		return fmt.Errorf("--manifest-out can not be used with --estimate, nothing is pushed")
+	}
+	if o.Estimate && o.SkipExisting {
+		return fmt.Errorf("--skip-existing can not be used with --estimate, nothing is pushed")
+	}
+	if o.Estimate && o.AllArch {
+		return fmt.Errorf("--all-arch can not be used with --estimate")
+	}
+	if err := o.validateAllArch(); err != nil {
+		return err
+	}
+	if err := o.validateScan(); err != nil {
+		return err
+	}
+	if err := o.validateTargetRegistry(); err != nil {
+		return err
+	}
+	return validatePackageFile("--images-pkg", o.Pkg)
 }
 
 func (o *RegistryOptions) ValidateArgsDeploy() error {
-	if o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
-		return fmt.Errorf("one of --pk-file or --passwd must be specified")
+	if err := o.validateSSH(); err != nil {
+		return err
 	}
 	if o.Pkg == "" {
 		return fmt.Errorf("--pkg must be specified")
@@ -416,6 +1079,134 @@ func (o *RegistryOptions) ValidateArgsDeploy() error {
 	if o.Node == "" {
 		return fmt.Errorf("--node must be specified")
 	}
+	if err := validateArch(o.Arch); err != nil {
+		return err
+	}
+	if o.TemplateFrom == o.Node {
+		return fmt.Errorf("--template-from must be another node than --node")
+	}
+	if err := validateRegistryName(o.RegistryName); err != nil {
+		return err
+	}
+	if err := validateRegistryImage(o.RegistryImage); err != nil {
+		return err
+	}
+	if err := o.validateDockerHost(); err != nil {
+		return err
+	}
+	if err := o.validateSkipDockerInstall(); err != nil {
+		return err
+	}
+	if err := o.validateAllArch(); err != nil {
+		return err
+	}
+	if o.Systemd {
+		if o.Rootless {
+			return fmt.Errorf("--systemd can not be used with --rootless")
+		}
+		if o.RestartPolicy != "always" {
+			return fmt.Errorf("--restart-policy can not be used with --systemd, systemd service %s restarts the registry always", registryUnit)
+		}
+	}
+	if !allowCompression.Has(o.Compression) {
+		return fmt.Errorf("--compression must be one of %s", strings.Join(allowCompression.List(), ","))
+	}
+	if o.PushCAFile != "" {
+		if o.Rootless {
+			return fmt.Errorf("--push-ca-file can not be used with --rootless, rootless docker reads the CA from the home of ssh user")
+		}
+		if _, err := o.readPushCA(); err != nil {
+			return err
+		}
+	} else if len(o.CANodes) > 0 {
+		return fmt.Errorf("--ca-nodes requires --push-ca-file")
+	}
+	if err := o.validateTransfer(); err != nil {
+		return err
+	}
+	if o.HealthTimeout < 0 {
+		return fmt.Errorf("--health-timeout must not be negative")
+	}
+	if err := validateRestartPolicy(o.RestartPolicy); err != nil {
+		return err
+	}
+	if o.MemoryLimit != "" && !memoryLimitRegexp.MatchString(o.MemoryLimit) {
+		return fmt.Errorf("--memory-limit %s is invalid, must be a positive number with an optional unit b, k, m or g", o.MemoryLimit)
+	}
+	if o.CPULimit < 0 {
+		return fmt.Errorf("--cpu-limit must not be negative")
+	}
+	if o.MinFreeGB < 0 {
+		return fmt.Errorf("--min-free-gb must not be negative")
+	}
+	if err := validateBindAddress(o.BindAddress); err != nil {
+		return err
+	}
+	for _, entry := range o.ExtraHosts {
+		if _, _, err := parseExtraHost(entry); err != nil {
+			return err
+		}
+	}
+	if o.UpdateHosts && len(o.ExtraHosts) == 0 {
+		return fmt.Errorf("--update-hosts requires --extra-host")
+	}
+	for _, entry := range o.Env {
+		if _, _, err := parseEnv(entry); err != nil {
+			return err
+		}
+	}
+	if err := o.validateCache(); err != nil {
+		return err
+	}
+	if err := o.validateScan(); err != nil {
+		return err
+	}
+	if o.RegistryPort <= 0 || o.RegistryPort > 65535 {
+		return fmt.Errorf("--registry-port %d is invalid", o.RegistryPort)
+	}
+	if err := o.validatePullImagesFrom(); err != nil {
+		return err
+	}
+	if o.ResourceDir == "" {
+		return fmt.Errorf("--resource-dir must be specified")
+	}
+	if err := validateImageGlob(o.ImageGlob); err != nil {
+		return err
+	}
+	if o.NoPush {
+		if o.KeepLocalImages {
+			return fmt.Errorf("--keep-local-images can not be used with --no-push, no images are loaded")
+		}
+		if len(o.Exclude) > 0 {
+			return fmt.Errorf("--exclude can not be used with --no-push, no images are pushed")
+		}
+	}
+	return validatePackageFile("--pkg", o.Pkg)
+}
+
+// validateBindAddress validates the address of 'docker run -p <address>:<port>:5000' is an IPv4 or IPv6 address.
+func validateBindAddress(address string) error {
+	if net.ParseIP(address) == nil {
+		return fmt.Errorf("--bind-address %s is invalid, must be an IPv4 or IPv6 address", address)
+	}
+	return nil
+}
+
+// validateRestartPolicy validates docker restart policy, 'on-failure' may be followed by ':<max-retries>'.
+func validateRestartPolicy(policy string) error {
+	name, retries, hasRetries := strings.Cut(policy, ":")
+	if !allowRestartPolicy.Has(name) {
+		return fmt.Errorf("--restart-policy must be one of %s", strings.Join(allowRestartPolicy.List(), ","))
+	}
+	if !hasRetries {
+		return nil
+	}
+	if name != "on-failure" {
+		return fmt.Errorf("--restart-policy %s does not accept max retries", name)
+	}
+	if n, err := strconv.Atoi(retries); err != nil || n <= 0 {
+		return fmt.Errorf("--restart-policy max retries must be a positive integer")
+	}
 	return nil
 }
 
@@ -423,63 +1214,234 @@ func (o *RegistryOptions) ValidateArgsList() error {
 	if o.Node == "" {
 		return fmt.Errorf("--node must be specified")
 	}
-	if o.Type != "image" && o.Type != "repository" {
-		return fmt.Errorf("--type must be one of image,repository")
+	if !allowType.Has(o.Type) {
+		return fmt.Errorf("--type must be one of %s", strings.Join(allowType.List(), ","))
 	}
 	if o.Type == "image" && o.Name == "" {
 		return fmt.Errorf("when type=image,--name is required")
 	}
-	return nil
+	if o.Detail && o.Type != "image" {
+		return fmt.Errorf("--detail requires --type image")
+	}
+	if err := o.validateTimeFilter(); err != nil {
+		return err
+	}
+	if o.Type == "blob-usage" {
+		if err := o.validateSSH(); err != nil {
+			return err
+		}
+		if o.Top < 0 {
+			return fmt.Errorf("--top must not be negative")
+		}
+		if o.PrintFlags.Streaming() {
+			return fmt.Errorf("--type blob-usage can not be streamed")
+		}
+	}
+	if o.Tunnel && o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
+		return fmt.Errorf("--tunnel requires one of --pk-file or --passwd")
+	}
+	if o.Tunnel {
+		if err := o.validateSSHPort(); err != nil {
+			return err
+		}
+	}
+	if err := o.validateRegistryAuth(); err != nil {
+		return err
+	}
+	return o.PrintFlags.Validate()
 }
 
 func (o *RegistryOptions) ValidateArgsDelete(cmd *cobra.Command) error {
-	if o.SSHConfig.PkFile == "" && o.SSHConfig.Password == "" {
-		return fmt.Errorf("one of --pk-file or --passwd must be specified")
+	if err := o.validateSSH(); err != nil {
+		return err
 	}
 	if o.Node == "" {
 		return fmt.Errorf("--node must be specified")
 	}
+	if err := o.validateRegistryAuth(); err != nil {
+		return err
+	}
+	if o.AllRepos && o.TagPattern == "" {
+		return utils.UsageErrorf(cmd, "--all-repos requires --tag-pattern")
+	}
+	if o.TagPattern != "" {
+		return o.validateTagPattern(cmd)
+	}
 	if o.Name == "" {
 		return utils.UsageErrorf(cmd, "image name must be specified")
 	}
-	if o.Tag == "" {
+	if o.AllTags && o.Tag != "" {
+		return utils.UsageErrorf(cmd, "--tag and --all-tags are mutually exclusive")
+	}
+	if !o.AllTags && o.Tag == "" {
 		return utils.UsageErrorf(cmd, "image tag must be specified")
 	}
+	if o.RemoveRepository && !o.AllTags {
+		return utils.UsageErrorf(cmd, "--remove-repository requires --all-tags")
+	}
 	return nil
 }
 
-func (o *RegistryOptions) Install() error {
-	if err := o.processPackage(); err != nil {
-		return fmt.Errorf("process package error: %s", err.Error())
+func (o *RegistryOptions) validateTagPattern(cmd *cobra.Command) error {
+	if o.Name == "" && !o.AllRepos {
+		return utils.UsageErrorf(cmd, "one of --name or --all-repos must be specified with --tag-pattern")
 	}
-
-	if err := o.installDocker(); err != nil {
-		return fmt.Errorf("install docker error: %s", err.Error())
+	if o.Name != "" && o.AllRepos {
+		return utils.UsageErrorf(cmd, "--name and --all-repos are mutually exclusive")
 	}
-
-	if err := o.installRegistry(); err != nil {
-		return fmt.Errorf("install registry error: %s", err.Error())
+	if o.Tag != "" || o.AllTags {
+		return utils.UsageErrorf(cmd, "--tag-pattern is mutually exclusive with --tag and --all-tags")
+	}
+	if o.RemoveRepository {
+		return utils.UsageErrorf(cmd, "--remove-repository requires --all-tags")
 	}
+	if _, err := regexp.Compile(o.TagPattern); err != nil {
+		return utils.UsageErrorf(cmd, "invalid --tag-pattern %q: %s", o.TagPattern, err.Error())
+	}
+	return o.PrintFlags.Validate()
+}
 
-	// load images
-	if err := o.loadImages(); err != nil {
-		return fmt.Errorf("load images error: %s", err.Error())
+type installStep struct {
+	name string
+	run  func() error
+	// rollback undoes the step with --rollback-on-failure, nil if there is nothing to undo
+	rollback func() error
+	// the registry container may be running, so its logs help diagnose
+	withRegistryLogs bool
+}
+
+func (o *RegistryOptions) Install() error {
+	if err := o.lockNode(); err != nil {
+		return err
+	}
+	defer o.unlockNode()
+	if o.DeployTimeout > 0 {
+		parent := o.ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, o.DeployTimeout)
+		defer cancel()
+		o.ctx = ctx
+	}
+	if err := o.runInstallSteps(o.installSteps()); err != nil {
+		return err
 	}
+	if o.NoPush {
+		o.log("install").Info("registry install successfully, bundled images are not pushed")
+		return nil
+	}
+	o.log("install").Info("registry and images install successfully")
+	return nil
+}
 
-	// remove pkg
-	if err := o.removePkg(); err != nil {
-		return fmt.Errorf("remove pkg error: %s", err.Error())
+// runInstallSteps runs steps in order and prints their durations. On interrupt the current step is cleaned up,
+// or every step is rolled back with --rollback-on-failure.
+func (o *RegistryOptions) runInstallSteps(steps []installStep) error {
+	timing := &DeployTiming{}
+	for i, step := range steps {
+		started := time.Now()
+		err := step.run()
+		if err == nil && o.ctx != nil {
+			// some steps are not cancelable, check the deadline after them
+			err = o.ctx.Err()
+		}
+		timing.Add(step.name, time.Since(started))
+		if err == nil {
+			continue
+		}
+		if o.interrupted() {
+			err = fmt.Errorf("deploy interrupted while running step '%s'", step.name)
+			if !o.RollbackOnFailure && step.rollback != nil {
+				o.cleanupInterrupted(step.name, step.rollback)
+			}
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("deploy timed out after %s while running step '%s'", o.DeployTimeout, step.name)
+		} else {
+			err = fmt.Errorf("%s error: %w", step.name, err)
+			if step.withRegistryLogs {
+				err = o.withRegistryLogs(err)
+			}
+		}
+		if o.RollbackOnFailure {
+			// the failed step is rolled back as well, it may be done partially
+			err = o.rollback(steps[:i+1], err)
+		}
+		o.printTiming(timing)
+		return err
 	}
+	o.printTiming(timing)
+	return nil
+}
 
-	if err := o.push(); err != nil {
-		return fmt.Errorf("push images error: %s", err.Error())
+// printTiming prints the duration of each deploy step, so that the slowest step is easy to tell.
+func (o *RegistryOptions) printTiming(timing *DeployTiming) {
+	if o.quietOutput() {
+		return
 	}
+	if err := o.PrintFlags.Print(timing, o.IOStreams.Out); err != nil {
+		o.log("timing").Warnf("print deploy timing error: %s", err.Error())
+	}
+}
 
-	logger.Info("registry and images install successfully")
-	return nil
+// quietOutput reports whether the report of deploy and push is skipped, that is with -q unless -o is set explicitly.
+func (o *RegistryOptions) quietOutput() bool {
+	return o.Quiet && !o.outputSet
+}
+
+// installSteps returns the deploy steps, loading and pushing images are skipped with --no-push,
+// and images are pulled from the upstream instead of loaded with --pull-images-from.
+// /etc/hosts is updated only with --update-hosts.
+func (o *RegistryOptions) installSteps() []installStep {
+	steps := []installStep{{name: "check registry volume", run: o.checkRegistryVolume}}
+	if o.PullImagesFrom != "" {
+		steps = append(steps, installStep{name: "check upstream", run: o.checkUpstreamRegistry})
+	}
+	if o.Cache == "redis" {
+		steps = append(steps, installStep{name: "check redis", run: o.checkRedis})
+	}
+	steps = append(steps, installStep{name: "process package", run: o.processPackage, rollback: o.cleanPackage})
+	switch {
+	case !o.SkipDockerInstall:
+		steps = append(steps, installStep{name: "install docker", run: o.installDocker, rollback: o.rollbackDocker})
+	case o.PushCAFile != "":
+		steps = append(steps, installStep{name: "install push CA", run: o.installPushCA})
+	}
+	if o.UpdateHosts {
+		steps = append(steps, installStep{name: "update hosts", run: o.updateEtcHosts, rollback: o.removeEtcHosts})
+	}
+	steps = append(steps,
+		installStep{name: "install registry", run: o.installRegistry, rollback: o.removeRegistryContainer, withRegistryLogs: true},
+		installStep{name: "wait registry ready", run: o.waitRegistryReady, withRegistryLogs: true},
+	)
+	if o.NoPush {
+		return append(steps, installStep{name: "remove pkg", run: o.removePkg, withRegistryLogs: true})
+	}
+	if o.PullImagesFrom != "" {
+		return append(steps,
+			installStep{name: "remove pkg", run: o.removePkg, withRegistryLogs: true},
+			installStep{name: "pull images", run: o.pullImages, rollback: o.removeImages, withRegistryLogs: true},
+			installStep{name: "push images", run: o.push, withRegistryLogs: true},
+		)
+	}
+	if o.AllArch {
+		return append(steps,
+			installStep{name: "push all arches", run: o.pushAllArches, rollback: o.removeImages, withRegistryLogs: true},
+			installStep{name: "remove pkg", run: o.removePkg, withRegistryLogs: true},
+		)
+	}
+	return append(steps,
+		installStep{name: "load images", run: o.loadImages, rollback: o.removeImages, withRegistryLogs: true},
+		installStep{name: "remove pkg", run: o.removePkg, withRegistryLogs: true},
+		installStep{name: "push images", run: o.push, withRegistryLogs: true},
+	)
 }
 
 func (o *RegistryOptions) Uninstall() error {
+	if err := o.lockNode(); err != nil {
+		return err
+	}
+	defer o.unlockNode()
 	// dockerd or docker sometimes gets stuck
 	if o.Force {
 		err := o.killDocker()
@@ -488,10 +1450,19 @@ func (o *RegistryOptions) Uninstall() error {
 		}
 	}
 
-	// clean registry container
-	err := o.stopRegistry()
-	if err != nil {
-		return err
+	// clean registry container, which is removed along with its systemd service if deployed with --systemd
+	unitRemoved := false
+	if !o.Rootless {
+		removed, err := o.removeRegistryUnit()
+		if err != nil {
+			return err
+		}
+		unitRemoved = removed
+	}
+	if !unitRemoved {
+		if err := o.stopRegistry(); err != nil {
+			return err
+		}
 	}
 
 	// remove docker if you want
@@ -502,12 +1473,15 @@ func (o *RegistryOptions) Uninstall() error {
 		}
 	}
 
-	// clean registry volume and kc package
-	err = o.cleanRegistry()
+	// clean kc package, and registry volume if you want
+	err := o.cleanRegistry()
 	if err != nil {
 		return err
 	}
-	logger.Info("registry uninstall successfully")
+	if !o.RemoveVolume {
+		o.log("uninstall").Infof("registry volume %s is kept", o.RegistryVolume)
+	}
+	o.log("uninstall").Info("registry uninstall successfully")
 	return nil
 }
 
@@ -519,7 +1493,7 @@ func (o *RegistryOptions) cleanDocker() error {
 		"systemctl reset-failed docker || true",
 	}
 	for _, cmd := range cmdList {
-		ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		ret, err := o.runCmd(cmd)
 		if err != nil {
 			return err
 		}
@@ -530,7 +1504,7 @@ func (o *RegistryOptions) cleanDocker() error {
 
 	// remove docker data-root
 	hook := "mount | grep /run/docker/netns/default | wc -l"
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	ret, err := o.runCmd(hook)
 	if err != nil {
 		return err
 	}
@@ -541,7 +1515,7 @@ func (o *RegistryOptions) cleanDocker() error {
 	if ret.StdoutToString("") == "1" {
 		// umount if mounted
 		hook = "umount /var/run/docker/netns/default"
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+		ret, err = o.runCmd(hook)
 		if err != nil {
 			return err
 		}
@@ -550,21 +1524,39 @@ func (o *RegistryOptions) cleanDocker() error {
 		}
 	}
 	hook = fmt.Sprintf(`rm -rf /var/run/docker* %s/kc`, o.DataRoot)
-	ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	ret, err = o.runCmd(hook)
 	if err != nil {
 		return err
 	}
 	return ret.Error()
 }
 
+// confirmClean asks for confirmation before clean, calling out whether image data will be destroyed.
+func (o *RegistryOptions) confirmClean() bool {
+	if options.AssumeYes {
+		return true
+	}
+	if o.RemoveVolume {
+		_, _ = fmt.Fprintf(o.IOStreams.Out, "registry on %s will be removed and its volume %s will be DELETED, all image data will be lost. Continue? Please input (yes/no)",
+			o.Node, o.RegistryVolume)
+	} else {
+		_, _ = fmt.Fprintf(o.IOStreams.Out, "registry on %s will be removed, its volume %s is kept. Continue? Please input (yes/no)",
+			o.Node, o.RegistryVolume)
+	}
+	return utils.AskForConfirmation()
+}
+
 func (o *RegistryOptions) cleanRegistry() error {
-	// clean registry volume and kc package
+	// clean kc package, and registry volume if required
 	cmdList := []string{
-		fmt.Sprintf(`rm -rf %s %s/kc*`, o.RegistryVolume, config.DefaultPkgPath), //  clean registry volume
-		fmt.Sprintf(`rm -rf /var/run/docker* %s/kc`, o.DataRoot),                 // clean kc package
+		fmt.Sprintf(`rm -rf %s/kc*`, config.DefaultPkgPath),      // clean kc package
+		fmt.Sprintf(`rm -rf /var/run/docker* %s/kc`, o.DataRoot), // clean kc package
+	}
+	if o.RemoveVolume {
+		cmdList = append(cmdList, fmt.Sprintf(`rm -rf %s`, o.RegistryVolume)) // clean registry volume
 	}
 	for _, cmd := range cmdList {
-		ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		ret, err := o.runCmd(cmd)
 		if err != nil {
 			return err
 		}
@@ -576,8 +1568,8 @@ func (o *RegistryOptions) cleanRegistry() error {
 }
 
 func (o *RegistryOptions) stopRegistry() error {
-	hook := `docker stop registry && docker rm registry`
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	hook := fmt.Sprintf("docker stop %s && docker rm %s", o.RegistryName, o.RegistryName)
+	ret, err := o.runDockerCmd(hook)
 	if err != nil {
 		return err
 	}
@@ -586,22 +1578,22 @@ func (o *RegistryOptions) stopRegistry() error {
 
 func (o *RegistryOptions) killDocker() error {
 	hook := `ps -ef | grep /usr/bin/docker | grep -v color=auto | awk '{print  "kill -9 " $2}'`
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	ret, err := o.runCmd(hook)
 	if err != nil {
-		logger.Warnf("clean registry container error: %s", err.Error())
+		o.log("kill-docker").Warnf("clean registry container error: %s", err.Error())
 	}
 	if err = ret.Error(); err != nil {
-		logger.Warnf("clean registry container error: %s", err.Error())
+		o.log("kill-docker").Warnf("clean registry container error: %s", err.Error())
 	}
-	logger.V(4).Info("kill docker out:", ret.Stdout)
+	o.log("kill-docker").V(4).Info("kill docker out:", ret.Stdout)
 	split := strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("kill docker cmd count:", len(split))
-	logger.V(4).Info("kill docker cmd list:", split)
+	o.log("kill-docker").V(4).Info("kill docker cmd count:", len(split))
+	o.log("kill-docker").V(4).Info("kill docker cmd list:", split)
 	for _, cmd := range split {
 		if cmd == "" {
 			continue
 		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		ret, err = o.runCmd(cmd)
 		if err != nil {
 			return err
 		}
@@ -614,57 +1606,267 @@ func (o *RegistryOptions) killDocker() error {
 }
 
 func (o *RegistryOptions) Push() error {
+	compression, err := o.detectCompression(o.Pkg)
+	if err != nil {
+		return err
+	}
+	if o.TargetRegistry != "" {
+		if err = o.checkTargetRegistry(); err != nil {
+			return err
+		}
+		if o.TargetAuth != "" {
+			logout, err := o.loginTarget()
+			if err != nil {
+				return err
+			}
+			defer logout()
+		}
+	}
+	if err = o.checkDecompressor(compression); err != nil {
+		return err
+	}
+	if o.AllArch {
+		return o.pushPackageAllArches(compression)
+	}
 	// send image pkg
 	imagesPkg := filepath.Join(config.DefaultPkgPath, filepath.Base(o.Pkg))
-	hook := fmt.Sprintf("gzip -df %s", imagesPkg)
-	err := utils.SendPackageV2(o.SSHConfig, o.Pkg, []string{o.Node}, config.DefaultPkgPath, nil, &hook)
+	pkg := decompressedName(imagesPkg, compression)
+	var after *string
+	if compression == compressionNone {
+		pkg = imagesPkg
+	} else {
+		hook := fmt.Sprintf("%s > %s && rm -f %s", decompressCmd(compression, imagesPkg), pkg, imagesPkg)
+		after = &hook
+	}
+	err = utils.SendPackageV2(o.SSHConfig, o.Pkg, []string{o.Node}, config.DefaultPkgPath, nil, after)
 	if err != nil {
 		return err
 	}
-	index := strings.LastIndex(imagesPkg, ".gz")
-	pkg := imagesPkg[0:index]
-	hook = fmt.Sprintf("docker load -i %s && rm -rf %s", pkg, pkg)
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	defer func() {
+		if o.interrupted() {
+			o.cleanupInterrupted("push", func() error {
+				return o.removePath(fmt.Sprintf("%s %s", imagesPkg, pkg))
+			})
+		}
+	}()
+	ret, err := o.runDockerCmd(fmt.Sprintf("docker load -i %s", pkg))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return err
+	}
+	ret, err = o.runCmd(fmt.Sprintf("rm -rf %s", pkg))
 	if err != nil {
 		return err
 	}
 	if err = ret.Error(); err != nil {
 		return err
 	}
+	if o.Estimate {
+		return o.estimatePush()
+	}
 	return o.push()
 }
 
 func (o *RegistryOptions) List() error {
+	if o.Tunnel {
+		closeTunnel, err := o.openTunnel()
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+	}
 	var err error
 	switch o.Type {
 	case "image":
+		if o.Detail {
+			return o.listImageDetails()
+		}
+		if o.PrintFlags.Streaming() {
+			return o.streamImages()
+		}
 		err = o.listImages()
 	case "repository":
+		if o.PrintFlags.Streaming() {
+			return o.streamRepositories()
+		}
 		err = o.listRepositories()
+	case "tag-count":
+		err = o.listTagCounts()
+	case "blob-usage":
+		err = o.listBlobUsage()
 	}
 	return err
 }
 
 func (o *RegistryOptions) Delete() error {
+	if o.Tunnel {
+		closeTunnel, err := o.openTunnel()
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+	}
+	// some tags may be deleted before an error, so the cache is dropped anyway
+	defer o.invalidateCache()
+	if o.TagPattern != "" {
+		return o.deleteByPattern()
+	}
+	if o.AllTags {
+		return o.deleteAllTags()
+	}
+	return o.deleteTag()
+}
+
+// deleteTag deletes the tag with two strategies in order:
+//  1. delete the manifest by registry API, which is rejected with 405 unless registry runs with delete enabled;
+//  2. on 405, remove the tag directory from the registry volume, blobs stay on disk until GC is run.
+func (o *RegistryOptions) deleteTag() error {
 	if o.Tag == "" {
 		return errors.New("missing required arguments: 'tag'")
 	}
+	deleted, err := o.deleteManifest(o.Name, o.Tag)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		o.log("delete-tag").Infof("deleted %s:%s by registry API", o.Name, o.Tag)
+		return nil
+	}
+	o.log("delete-tag").Infof("registry delete API is disabled (405), fall back to removing %s:%s from registry volume. "+
+		"Redeploy the registry with REGISTRY_STORAGE_DELETE_ENABLED=true to delete by API", o.Name, o.Tag)
 	imagePath := fmt.Sprintf("%s/docker/registry/v2/repositories/%s/_manifests/tags/%s", o.RegistryVolume, o.Name, o.Tag)
-	if ok, _ := o.SSHConfig.IsFileExistV2(o.Node, imagePath); !ok {
-		return errors.New("there is an error in the image name or tag, please check the input")
+	if err = o.removePath(imagePath); err != nil {
+		return err
 	}
-	hook := fmt.Sprintf("rm -rf %s", imagePath)
-	_, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
-	return err
+	o.log("delete-tag").Infof("removed %s:%s from registry volume, run '%s' on %s to reclaim disk space", o.Name, o.Tag, garbageCollectCmd, o.Node)
+	return nil
+}
+
+// garbageCollectCmd removes the blobs which are no longer referenced by any manifest.
+const garbageCollectCmd = "docker exec registry bin/registry garbage-collect /etc/docker/registry/config.yml"
+
+// deleteManifest deletes the manifest of tag by 'DELETE /v2/<name>/manifests/<digest>',
+// the digest is resolved from the 'Docker-Content-Digest' header of 'HEAD /v2/<name>/manifests/<tag>'.
+// It returns false without error if registry answers 405, i.e. delete is disabled.
+// Note that every tag referencing the same manifest is deleted together.
+// The manifest list of a multi-arch tag is deleted itself, instead of the manifest registry picks for amd64,
+// and its platform manifests are deleted along with it by --recursive.
+func (o *RegistryOptions) deleteManifest(name, tag string) (bool, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, tag))
+	header := map[string]string{
+		"Accept": manifestMediaTypes,
+	}
+	_, code, respHeader, respErr := o.request(url, "HEAD", o.requestHeader(header), nil)
+	if respErr != nil {
+		return false, respErr
+	}
+	switch code {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, fmt.Errorf("image %s:%s not found, please check the image name or tag", name, tag)
+	default:
+		if _, err := repositoryResponse(name, nil, code); err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("unexpected status code %d", code)
+	}
+	digest := respHeader.Get("Docker-Content-Digest")
+	if digest == "" {
+		return false, fmt.Errorf("registry returns no digest of %s:%s", name, tag)
+	}
+	var platforms []descriptor
+	if isManifestList(respHeader.Get("Content-Type")) {
+		var err error
+		if platforms, err = o.platformManifests(name, digest); err != nil {
+			return false, err
+		}
+	}
+	url = o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, digest))
+	resp, code, _, respErr := o.request(url, "DELETE", o.requestHeader(nil), nil)
+	if respErr != nil {
+		return false, respErr
+	}
+	switch code {
+	case http.StatusAccepted, http.StatusOK:
+		o.deletePlatformManifests(name, tag, platforms)
+		return true, nil
+	case http.StatusMethodNotAllowed:
+		return false, nil
+	}
+	if _, err := repositoryResponse(name, resp, code); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("unexpected status code %d", code)
+}
+
+// removePath removes the path of registry volume on registry node,
+// a permission failure is reported with the exact path and the privileges required.
+func (o *RegistryOptions) removePath(p string) error {
+	ret, err := o.runCmd(fmt.Sprintf("rm -rf %s", p))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		if strings.Contains(ret.Stderr, "Permission denied") || strings.Contains(ret.Stderr, "Operation not permitted") {
+			return fmt.Errorf("permission denied to remove %s on %s, user %s requires root or passwordless sudo with write permission on %s",
+				p, o.Node, o.SSHConfig.User, o.RegistryVolume)
+		}
+		return fmt.Errorf("remove %s on %s error: %w", p, o.Node, err)
+	}
+	return nil
+}
+
+// deleteAllTags deletes every tag of the repository like deleteTag, and the repository itself if required.
+func (o *RegistryOptions) deleteAllTags() error {
+	tags, err := o.tags()
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		o.log("delete-all-tags").Infof("repository %s has no tags", o.Name)
+		return nil
+	}
+	repoPath := fmt.Sprintf("%s/docker/registry/v2/repositories/%s", o.RegistryVolume, o.Name)
+	if o.DryRun {
+		for _, tag := range tags {
+			_, _ = fmt.Fprintf(o.IOStreams.Out, "%s:%s would be deleted\n", o.Name, tag)
+		}
+		if o.RemoveRepository {
+			_, _ = fmt.Fprintf(o.IOStreams.Out, "repository %s would be removed\n", o.Name)
+		}
+		return nil
+	}
+	if !options.AssumeYes {
+		_, _ = fmt.Fprintf(o.IOStreams.Out, "%d tags of %s will be deleted, continue? Please input (yes/no)", len(tags), o.Name)
+		if !utils.AskForConfirmation() {
+			return nil
+		}
+	}
+	var deleted int
+	for _, tag := range tags {
+		if err := o.deleteTagOf("delete-all-tags", o.Name, tag); err != nil {
+			return fmt.Errorf("deleted %d of %d tags, delete tag %s error: %w", deleted, len(tags), tag, err)
+		}
+		deleted++
+	}
+	if o.RemoveRepository {
+		if err := o.removePath(repoPath); err != nil {
+			return fmt.Errorf("deleted %d tags, remove repository %s error: %w", deleted, o.Name, err)
+		}
+	}
+	o.log("delete-all-tags").Infof("deleted %d tags of repository %s, run '%s' on %s to reclaim disk space", deleted, o.Name, garbageCollectCmd, o.Node)
+	return nil
 }
 
 func (o *RegistryOptions) listRepositories() error {
-	url := fmt.Sprintf("http://%s:%d/v2/_catalog", o.Node, o.RegistryPort)
+	url := o.apiURL("/v2/_catalog")
 	params := make(map[string]string)
 	if o.Number != 0 {
 		params["n"] = strconv.Itoa(o.Number)
 	}
-	resp, code, respErr := httputil.CommonRequest(url, "GET", nil, params, nil)
+	resp, code, header, respErr := o.request(url, "GET", o.requestHeader(nil), params)
 	if respErr != nil {
 		return respErr
 	}
@@ -677,16 +1879,19 @@ func (o *RegistryOptions) listRepositories() error {
 	if err != nil {
 		return err
 	}
+	repository.Total = len(repository.Repositories)
+	// registry returns a 'Link' header pointing to the next page when the result is capped by 'n'
+	repository.Truncated = header.Get("Link") != ""
 	return o.PrintFlags.Print(repository, o.IOStreams.Out)
 }
 
 func (o *RegistryOptions) listImages() error {
-	url := fmt.Sprintf("http://%s:%d/v2/%s/tags/list", o.Node, o.RegistryPort, o.Name)
-	resp, code, respErr := httputil.CommonRequest(url, "GET", nil, nil, nil)
+	url := o.apiURL(fmt.Sprintf("/v2/%s/tags/list", o.Name))
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(nil), nil)
 	if respErr != nil {
 		return respErr
 	}
-	body, codeErr := httputil.CodeDispose(resp, code)
+	body, codeErr := repositoryResponse(o.Name, resp, code)
 	if codeErr != nil {
 		return codeErr
 	}
@@ -698,38 +1903,111 @@ func (o *RegistryOptions) listImages() error {
 	return o.PrintFlags.Print(image, o.IOStreams.Out)
 }
 
-func (o *RegistryOptions) getDaemonTemplateContent() (string, error) {
-	tmpl, err := template.New("text").Parse(config.DockerDaemonTmpl)
+// listTagCounts prints the tag count of each repository, sorted by count descending.
+func (o *RegistryOptions) listTagCounts() error {
+	repositories, err := o.repos()
 	if err != nil {
-		return "", fmt.Errorf("template parse failed: %s", err.Error())
+		return err
 	}
+	names := repositories["repositories"]
+	counts := make([]TagCount, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, tagCountConcurrency)
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			tags, err := o.tagsOf(name)
+			if err != nil {
+				errs[i] = fmt.Errorf("list tags of %s error: %w", name, err)
+				return
+			}
+			counts[i] = TagCount{Repository: name, Count: len(tags)}
+		}(i, name)
+	}
+	wg.Wait()
+	if err = utilerrors.NewAggregate(errs); err != nil {
+		return err
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Repository < counts[j].Repository
+	})
+	return o.PrintFlags.Print(&TagCounts{Items: counts}, o.IOStreams.Out)
+}
 
+func (o *RegistryOptions) getDaemonTemplateContent() (string, error) {
 	var data = make(map[string]interface{})
-	data["Node"] = fmt.Sprintf(`%s:%d`, o.Node, o.RegistryPort)
+	data["InsecureRegistries"] = o.insecureRegistries()
 	data["DataRoot"] = o.DataRoot
+	return renderTemplate(config.DockerDaemonTmpl, data)
+}
+
+// renderTemplate renders the text template with data.
+func renderTemplate(text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("text").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template parse failed: %w", err)
+	}
 	var buffer bytes.Buffer
 	if err := tmpl.Execute(&buffer, data); err != nil {
-		return "", fmt.Errorf("template execute failed: %s", err.Error())
+		return "", fmt.Errorf("template execute failed: %w", err)
 	}
 	return buffer.String(), nil
 }
 
 func (o *RegistryOptions) processPackage() error {
-	// send pkg
-	hook := fmt.Sprintf("rm -rf %s/kc && tar -xvf %s -C %s", config.DefaultPkgPath,
-		filepath.Join(config.DefaultPkgPath, path.Base(o.Pkg)), config.DefaultPkgPath)
-	logger.V(3).Info("processPackage hook:", hook)
-	err := utils.SendPackageV2(o.SSHConfig, o.Pkg, []string{o.Node}, config.DefaultPkgPath, nil, &hook)
+	compression, err := o.detectCompression(o.Pkg)
 	if err != nil {
 		return err
 	}
-	logger.Info("process package successfully")
+	if o.OfflineVerify {
+		if err = o.verifyPackage(compression); err != nil {
+			return err
+		}
+	}
+	if err = o.checkDecompressor(compression); err != nil {
+		return err
+	}
+	if err = o.sendPackage(compression); err != nil {
+		return err
+	}
+	if err = o.checkPackageArch(); err != nil {
+		return err
+	}
+	o.log("process-package").Info("process package successfully")
 	return nil
 }
 
+// sendPackage uploads the package to node and extracts it into config.DefaultPkgPath.
+func (o *RegistryOptions) sendPackage(compression string) error {
+	hook := fmt.Sprintf("rm -rf %s/kc && %s | tar -xv -C %s", config.DefaultPkgPath,
+		decompressCmd(compression, filepath.Join(config.DefaultPkgPath, path.Base(o.Pkg))), config.DefaultPkgPath)
+	o.log("process-package").V(3).Info("processPackage hook:", hook)
+	return utils.SendPackageV2(o.SSHConfig, o.Pkg, []string{o.Node}, config.DefaultPkgPath, nil, &hook)
+}
+
 func (o *RegistryOptions) installDocker() error {
+	if o.Rootless {
+		// rootless docker is set up by user and checked at precheck, only daemon.json is synced
+		if err := o.syncDaemonConfig(); err != nil {
+			return err
+		}
+		return o.validateDaemonRegistry()
+	}
+	if o.DockerHost != "" {
+		// the external daemon is checked at precheck, its daemon.json is out of reach
+		o.log("install-docker").Warnf("docker daemon %s is not managed by deploy, make sure it trusts registry %s, e.g. by insecure-registries of its daemon.json",
+			o.DockerHost, o.publishedRegistry())
+		return nil
+	}
 	// install docker, if not exist
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, "docker ps")
+	ret, err := o.runCmd("docker ps")
 	if err != nil {
 		return err
 	}
@@ -739,9 +2017,10 @@ func (o *RegistryOptions) installDocker() error {
 		if err != nil {
 			return err
 		}
+		o.dockerInstalled = true
 		cmdList := []string{
 			// cp docker service file
-			fmt.Sprintf("tar -zxvf %s/kc/resource/docker/19.03.12/%s/configs.tar.gz -C /", config.DefaultPkgPath, o.Arch),
+			fmt.Sprintf("tar -zxvf %s/kc/resource/docker/%s/%s/configs.tar.gz -C /", config.DefaultPkgPath, dockerVersion, o.archDir()),
 			"mkdir -pv /etc/docker",
 			// write daemon.json
 			sshutils.WrapEcho(data, "/etc/docker/daemon.json"),
@@ -749,7 +2028,7 @@ func (o *RegistryOptions) installDocker() error {
 			"systemctl daemon-reload && systemctl enable docker --now",
 		}
 		for _, cmd := range cmdList {
-			ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+			ret, err = o.runCmd(cmd)
 			if err != nil {
 				return err
 			}
@@ -757,20 +2036,68 @@ func (o *RegistryOptions) installDocker() error {
 				return err
 			}
 		}
+	} else if err = o.syncDaemonConfig(); err != nil {
+		return err
+	}
+	if err = o.validateDaemonRegistry(); err != nil {
+		return err
+	}
+	return o.installPushCA()
+}
+
+// readPushCA reads the CA bundle and makes sure it contains PEM encoded certificates.
+func (o *RegistryOptions) readPushCA() ([]byte, error) {
+	data, err := os.ReadFile(o.PushCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read --push-ca-file error: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("--push-ca-file %s does not contain any PEM encoded certificate", o.PushCAFile)
 	}
+	return data, nil
+}
 
+// installPushCA installs the CA bundle into '/etc/docker/certs.d/<address>:<port>/ca.crt' of the published registry,
+// so that docker trusts the TLS registry without '--insecure-registries'.
+func (o *RegistryOptions) installPushCA() error {
+	if o.PushCAFile == "" {
+		return nil
+	}
+	data, err := o.readPushCA()
+	if err != nil {
+		return err
+	}
+	dir := "/etc/docker/certs.d/" + o.publishedRegistry()
+	cmdList := []string{
+		fmt.Sprintf("mkdir -pv %s", dir),
+		sshutils.WrapEcho(strings.TrimSpace(string(data)), dir+"/ca.crt"),
+	}
+	nodes := append([]string{o.Node}, o.CANodes...)
+	for _, node := range nodes {
+		for _, cmd := range cmdList {
+			ret, err := o.runCmdOn(node, cmd)
+			if err != nil {
+				return err
+			}
+			if err = ret.Error(); err != nil {
+				return err
+			}
+		}
+		o.logOn(node, "install-ca").Info("install registry CA successfully")
+	}
 	return nil
 }
 
 func (o *RegistryOptions) installRegistry() error {
 	cmdList := []string{
-		fmt.Sprintf("gzip -df %s/kc/registry/v2/%s/images.tar.gz", config.DefaultPkgPath, o.Arch),
-		fmt.Sprintf("docker load -i %s/kc/registry/v2/%s/images.tar", config.DefaultPkgPath, o.Arch), // load images
-		fmt.Sprintf("docker run -d -v %s:/var/lib/registry -p %d:5000 --restart=always --name registry registry:2",
-			o.RegistryVolume, o.RegistryPort), // running registry
+		fmt.Sprintf("gzip -df %s/kc/registry/v2/%s/images.tar.gz", config.DefaultPkgPath, o.archDir()),
+	}
+	if o.Rootless {
+		// rootless docker can not create the volume out of the home of ssh user
+		cmdList = append(cmdList, fmt.Sprintf("mkdir -p %s && chown %s %s", o.RegistryVolume, o.SSHConfig.User, o.RegistryVolume))
 	}
 	for _, cmd := range cmdList {
-		ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		ret, err := o.runCmd(cmd)
 		if err != nil {
 			return err
 		}
@@ -778,32 +2105,26 @@ func (o *RegistryOptions) installRegistry() error {
 			return err
 		}
 	}
-
-	logger.Info("install registry successfully")
-	return nil
-}
-
-func (o *RegistryOptions) loadImages() error {
-	// docker load images
-	// find /root/kc/pkg/kc/resource -name images.tar.gz | grep 'x86-64' | awk '{print}' | sed -r 's#(.*)#docker load -i \1#'
-	hook := fmt.Sprintf("find %s/kc/resource -name images.tar.gz | grep '%s' | awk '{print}' | sed -r 's#(.*)#docker load -i \\1#'", config.DefaultPkgPath, o.Arch)
-	logger.V(3).Info("loadImages hook :", hook)
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
+	exists, running, err := o.inspectRegistryContainer()
+	if err != nil {
+		return err
+	}
+	ret, err := o.runDockerCmd(fmt.Sprintf("docker load -i %s/kc/registry/v2/%s/images.tar", config.DefaultPkgPath, o.archDir())) // load images
 	if err != nil {
 		return err
 	}
 	if err = ret.Error(); err != nil {
 		return err
 	}
-	logger.V(4).Info("loadImages out :", ret.Stdout)
-	split := strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("loadImages out cmd count:", len(split))
-	logger.V(4).Info("loadImages out cmd list:", split)
-	for _, cmd := range split {
-		if cmd == "" {
-			continue
+	// the systemd service replaces an existing registry container
+	if o.Systemd || !exists {
+		if err = o.ensureRegistryImage(); err != nil {
+			return err
 		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		o.warnManagedEnv()
+	}
+	if !o.Systemd && !exists {
+		ret, err = o.runDockerCmd(o.runRegistryCmd()) // running registry
 		if err != nil {
 			return err
 		}
@@ -811,48 +2132,119 @@ func (o *RegistryOptions) loadImages() error {
 			return err
 		}
 	}
+	if o.Systemd {
+		if err = o.installRegistryUnit(); err != nil {
+			return err
+		}
+	} else if exists {
+		if err = o.reuseRegistryContainer(running); err != nil {
+			return err
+		}
+	}
 
-	logger.Info("image load successfully")
+	o.log("install-registry").Info("install registry successfully")
 	return nil
 }
 
-func (o *RegistryOptions) removePkg() error {
-	hook := fmt.Sprintf(`rm -rf %s/kc`, config.DefaultPkgPath)
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, hook)
-	if err != nil {
-		return err
+// runRegistryCmd returns the 'docker run' command of the registry container.
+// With --systemd, the container runs in foreground and is removed on exit, systemd restarts it.
+func (o *RegistryOptions) runRegistryCmd() string {
+	args := []string{
+		"docker run -d",
+		fmt.Sprintf("-v %s:/var/lib/registry", o.RegistryVolume),
+		o.publishArg(),
+		fmt.Sprintf("--restart=%s", o.RestartPolicy),
 	}
-	if err = ret.Error(); err != nil {
-		return err
+	if o.Systemd {
+		args = []string{"docker run --rm", fmt.Sprintf("-v %s:/var/lib/registry", o.RegistryVolume), o.publishArg()}
 	}
-	logger.Info("remove pkg successfully")
-	return nil
+	if o.MemoryLimit != "" {
+		args = append(args, fmt.Sprintf("--memory=%s", o.MemoryLimit))
+	}
+	if o.CPULimit > 0 {
+		args = append(args, fmt.Sprintf("--cpus=%s", strconv.FormatFloat(o.CPULimit, 'f', -1, 64)))
+	}
+	args = append(args, o.addHostArgs()...)
+	args = append(args, o.envArgs()...)
+	args = append(args, fmt.Sprintf("--name %s %s", o.RegistryName, o.RegistryImage))
+	return strings.Join(args, " ")
 }
 
-func (o *RegistryOptions) push() error {
-	err := o.specialTag()
-	if err != nil {
+// publishArg returns the '-p' argument of the registry container, the port is published on
+// all interfaces unless --bind-address narrows it, e.g. '-p 127.0.0.1:5000:5000'.
+func (o *RegistryOptions) publishArg() string {
+	if o.BindAddress == "" || o.BindAddress == "0.0.0.0" {
+		return fmt.Sprintf("-p %d:5000", o.RegistryPort)
+	}
+	return fmt.Sprintf("-p %s:5000", o.publishedRegistry())
+}
+
+// pushRegistry returns the 'host:port' which images are re-tagged and pushed to on node, --target-registry if set,
+// otherwise the registry on node.
+func (o *RegistryOptions) pushRegistry() string {
+	if o.TargetRegistry != "" {
+		return o.TargetRegistry
+	}
+	return o.publishedRegistry()
+}
+
+// publishedRegistry returns the 'host:port' the registry on node is reachable at, the bind address
+// if registry is published on a single address, otherwise the node.
+func (o *RegistryOptions) publishedRegistry() string {
+	return o.publishedRegistryOn(o.Node)
+}
+
+// publishedRegistryOn is publishedRegistry of the registry on node.
+func (o *RegistryOptions) publishedRegistryOn(node string) string {
+	ip := net.ParseIP(o.BindAddress)
+	if ip == nil || ip.IsUnspecified() {
+		return fmt.Sprintf("%s:%d", node, o.RegistryPort)
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(o.RegistryPort))
+}
+
+// apiURL returns the url of registry API path, e.g. '/v2/_catalog', through the tunnel if it is open.
+func (o *RegistryOptions) apiURL(apiPath string) string {
+	if o.apiBase != "" {
+		return o.apiBase + apiPath
+	}
+	return fmt.Sprintf("http://%s:%d%s", o.Node, o.RegistryPort, apiPath)
+}
+
+// withRegistryLogs appends the last lines of the registry container logs to err,
+// so that a registry which exited right after 'docker run' is easy to diagnose.
+func (o *RegistryOptions) withRegistryLogs(err error) error {
+	if o.LogsTail <= 0 {
 		return err
 	}
-	// image re-tag 'ip:port/'
-	retag := fmt.Sprintf(`docker images | grep / | grep -v k8s.gcr.io | grep -v %s:%d | grep -v REPOSITORY | awk '{print "docker tag "$3" %s:%d/"$1":"$2}'`, o.Node, o.RegistryPort, o.Node, o.RegistryPort)
-	logger.V(3).Info("push retag:", retag)
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, retag)
-	if err != nil {
+	hook := fmt.Sprintf("docker logs --tail %d %s", o.LogsTail, o.RegistryName)
+	ret, sshErr := o.runDockerCmd(hook)
+	if sshErr != nil {
+		o.log("registry-logs").V(2).Warnf("get registry container logs error: %s", sshErr.Error())
 		return err
 	}
-	if err = ret.Error(); err != nil {
+	if sshErr = ret.Error(); sshErr != nil {
+		// registry container may not be created yet
+		o.log("registry-logs").V(2).Warnf("get registry container logs error: %s", sshErr.Error())
 		return err
 	}
-	logger.V(4).Info("push retag out:", ret.Stdout)
-	split := strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("push retag cmd count:", len(split))
-	logger.V(4).Info("push retag cmd list:", split)
-	for _, cmd := range split {
-		if cmd == "" {
-			continue
-		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+	// docker logs writes the container stderr to its own stderr
+	logs := strings.TrimSpace(strings.Join([]string{ret.Stdout, ret.Stderr}, "\n"))
+	if logs == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nregistry container logs (last %d lines):\n%s", err, o.LogsTail, logs)
+}
+
+// loadImages loads the image archives under --resource-dir matching --image-glob into docker on node.
+func (o *RegistryOptions) loadImages() error {
+	archives, err := o.imageArchives()
+	if err != nil {
+		return err
+	}
+	for _, archive := range archives {
+		o.log("load-images").V(2).Infof("load image archive %s", archive)
+		ret, err := o.runDockerCmd("docker load -i " + shellQuote(archive))
 		if err != nil {
 			return err
 		}
@@ -861,110 +2253,253 @@ func (o *RegistryOptions) push() error {
 		}
 	}
 
-	//  image push
-	push := fmt.Sprintf(`docker images | grep %s:%d | awk '{print "docker push "$1":"$2}'`, o.Node, o.RegistryPort)
-	logger.V(3).Info("docker push hook:", push)
-	ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, push)
+	o.log("load-images").Infof("load %d image archives successfully", len(archives))
+	return nil
+}
+
+func (o *RegistryOptions) removePkg() error {
+	hook := fmt.Sprintf(`rm -rf %s/kc`, config.DefaultPkgPath)
+	ret, err := o.runCmd(hook)
 	if err != nil {
 		return err
 	}
 	if err = ret.Error(); err != nil {
 		return err
 	}
-	logger.V(4).Info("docker push out:", ret.Stdout)
-	split = strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("docker push cmd count:", len(split))
-	logger.V(4).Info("docker push cmd list:", split)
-	for _, cmd := range split {
-		if cmd == "" {
-			continue
+	o.log("remove-package").Info("remove pkg successfully")
+	return nil
+}
+
+func (o *RegistryOptions) push() error {
+	images, err := o.pushableImages()
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		o.log("push").Info("no images to push")
+		return nil
+	}
+
+	// image re-tag 'ip:port/' and push, failures are recorded in the report instead of aborting
+	report := &PushReport{}
+	for _, image := range images {
+		for _, target := range o.retagTargets(image) {
+			if o.SkipExisting {
+				if result, ok := o.skipExisting(image, target); ok {
+					report.Items = append(report.Items, result)
+					continue
+				}
+			}
+			report.Items = append(report.Items, o.pushImage(image, target))
 		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
-		if err != nil {
+	}
+	o.log("push").V(4).Info("push retag count:", len(report.Items))
+	if o.ScanCmd != "" {
+		if err = o.scanImages(report); err != nil {
 			return err
 		}
-		if err = ret.Error(); err != nil {
+	}
+
+	if o.KeepLocalImages {
+		o.keepImages()
+	} else if err = o.removeImages(); err != nil {
+		return err
+	}
+	return o.reportPush(report)
+}
+
+// reportPush prints report, writes --manifest-out, and fails if any image failed to push or the scan.
+func (o *RegistryOptions) reportPush(report *PushReport) error {
+	if !o.quietOutput() {
+		if err := o.PrintFlags.Print(report, o.IOStreams.Out); err != nil {
 			return err
 		}
 	}
-
-	// docker rmi images
-	rmi := `docker images | awk '{print $1":"$2}' | grep -v registry | grep -v REPOSITORY`
-	ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, rmi)
-	if err != nil {
-		logger.Warnf("docker remove image error: %s", err.Error())
+	o.invalidateCache()
+	if o.ManifestOut != "" {
+		if err := o.writeDeliveryManifest(report); err != nil {
+			return err
+		}
 	}
-	if err = ret.Error(); err != nil {
-		logger.Warnf("docker remove image error: %s", err.Error())
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d images failed to push", failed, len(report.Items))
 	}
-	logger.V(4).Info("docker rmi out", ret.Stdout)
-	split = strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("docker rmi cmd count:", len(split))
-	logger.V(4).Info("docker rmi cmd list:", split)
-	for _, cmd := range split {
-		if cmd == "" {
-			continue
+	if failed := report.ScanFailed(); failed > 0 {
+		if !o.ScanReportOnly {
+			return fmt.Errorf("%d pushed images failed the scan of --scan-cmd", failed)
+		}
+		o.log("scan").Warnf("%d pushed images failed the scan of --scan-cmd, ignored by --scan-report-only", failed)
+	}
+	if skipped := report.Skipped(); skipped > 0 {
+		o.log("push").Infof("image push successfully, %d pushed, %d skipped as existing", len(report.Items)-skipped, skipped)
+		return nil
+	}
+	o.log("push").Info("image push successfully")
+	return nil
+}
+
+// pushImage re-tags image as target and pushes it, the outcome is returned as a PushResult.
+func (o *RegistryOptions) pushImage(image localImage, target string) PushResult {
+	result := PushResult{Image: image.Ref(), Target: target}
+	for _, cmd := range []string{
+		fmt.Sprintf("docker tag %s %s", image.ID, target),
+		"docker push " + target,
+	} {
+		ret, err := o.runDockerCmd(cmd)
+		if err == nil {
+			err = ret.Error()
 		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, "docker rmi "+cmd)
 		if err != nil {
-			return err
+			o.log("push-image").V(2).Infof("push image %s failed: %s", target, err.Error())
+			result.Status = PushStatusFailed
+			result.Error = err.Error()
+			return result
 		}
-		if err = ret.Error(); err != nil {
-			return err
+		result.Status = PushStatusTagged
+		if strings.HasPrefix(cmd, "docker push") {
+			result.Digest, result.Size = parsePushDigest(ret.Stdout)
 		}
 	}
+	result.Status = PushStatusPushed
+	return result
+}
 
-	logger.Info("image push successfully")
-	return nil
+// pushDigestRegexp matches the last line of 'docker push', e.g. 'v1: digest: sha256:<hex> size: 528'.
+var pushDigestRegexp = regexp.MustCompile(`digest: (sha256:[0-9a-f]{64}) size: ([0-9]+)`)
+
+// parsePushDigest returns the digest and size of the pushed manifest from the output of 'docker push'.
+func parsePushDigest(out string) (string, int64) {
+	match := pushDigestRegexp.FindStringSubmatch(out)
+	if match == nil {
+		return "", 0
+	}
+	size, _ := strconv.ParseInt(match[2], 10, 64)
+	return match[1], size
 }
 
-func (o *RegistryOptions) specialTag() error {
-	// add 'ip:port/library'
-	dockerTag := fmt.Sprintf(`docker images | grep -v registry | grep / | grep -v k8s.gcr.io | grep -v REPOSITORY | awk '{print "docker tag "$3" %s:%d/library/"$1":"$2}'`, o.Node, o.RegistryPort)
-	logger.V(3).Info("dockerTag hook:", dockerTag)
-	ret, err := sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, dockerTag)
+// writeDeliveryManifest writes the images pushed successfully in report to --manifest-out,
+// so that the delivered set can be audited and verified later. Failed images are not listed.
+func (o *RegistryOptions) writeDeliveryManifest(report *PushReport) error {
+	registry := o.pushRegistry()
+	delivered := DeliveryManifest{Registry: registry, Images: []DeliveredImage{}}
+	for _, v := range report.Items {
+		if v.Status != PushStatusPushed && v.Status != PushStatusSkipped {
+			continue
+		}
+		delivered.Images = append(delivered.Images, DeliveredImage{
+			Image:  strings.TrimPrefix(v.Target, registry+"/"),
+			Digest: v.Digest,
+			Size:   v.Size,
+		})
+	}
+	data, err := json.MarshalIndent(delivered, "", "  ")
 	if err != nil {
 		return err
 	}
+	if err = os.WriteFile(o.ManifestOut, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write delivery manifest %s error: %w", o.ManifestOut, err)
+	}
+	o.log("push").Infof("delivery manifest of %d images is written to %s", len(delivered.Images), o.ManifestOut)
+	return nil
+}
+
+// localImage is an image listed by 'docker images'.
+type localImage struct {
+	Repository string
+	Tag        string
+	ID         string
+}
+
+func (i localImage) Ref() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// pushableImages lists the local images which will be re-tagged and pushed,
+// images without namespace, dangling images, images of this registry and excluded images are skipped.
+func (o *RegistryOptions) pushableImages() ([]localImage, error) {
+	ret, err := o.runDockerCmd(`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`)
+	if err != nil {
+		return nil, err
+	}
 	if err = ret.Error(); err != nil {
-		return err
+		return nil, err
 	}
-	logger.V(4).Info("dockerTag out:", ret.Stdout)
-	split := strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("dockerTag cmd count:", len(split))
-	logger.V(4).Info("dockerTag cmd list:", split)
-	for _, cmd := range split {
-		if cmd == "" {
+	registry := o.pushRegistry() + "/"
+	var images []localImage
+	for _, line := range nonEmptyLines(ret.Stdout) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
 			continue
 		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
-		if err != nil {
-			return err
+		image := localImage{Repository: fields[0], Tag: fields[1], ID: fields[2]}
+		if !strings.Contains(image.Repository, "/") || image.Tag == "<none>" || strings.HasPrefix(image.Repository, registry) {
+			continue
 		}
-		if err = ret.Error(); err != nil {
-			return err
+		if pattern, ok := o.excluded(image); ok {
+			o.log("pushable-images").Infof("image %s is excluded by pattern %q", image.Ref(), pattern)
+			continue
+		}
+		images = append(images, image)
+	}
+	o.log("pushable-images").V(3).Info("pushable images count:", len(images))
+	return images, nil
+}
+
+// excluded returns the first --exclude pattern matching the image reference or repository.
+func (o *RegistryOptions) excluded(image localImage) (string, bool) {
+	for _, pattern := range o.Exclude {
+		if ok, _ := path.Match(pattern, image.Ref()); ok {
+			return pattern, true
+		}
+		if ok, _ := path.Match(pattern, image.Repository); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// retagTargets returns the references an image is re-tagged to:
+// 'k8s.gcr.io/' prefix is replaced by 'ip:port/', other images are tagged as 'ip:port/<repo>'
+// and 'ip:port/library/<repo>' except the registry images.
+func (o *RegistryOptions) retagTargets(image localImage) []string {
+	registry := o.pushRegistry()
+	if strings.HasPrefix(image.Repository, "k8s.gcr.io/") {
+		return []string{fmt.Sprintf("%s/%s:%s", registry, strings.TrimPrefix(image.Repository, "k8s.gcr.io/"), image.Tag)}
+	}
+	var targets []string
+	if !strings.Contains(image.Repository, "registry") {
+		targets = append(targets, fmt.Sprintf("%s/library/%s:%s", registry, image.Repository, image.Tag))
+	}
+	return append(targets, fmt.Sprintf("%s/%s:%s", registry, image.Repository, image.Tag))
+}
+
+// nonEmptyLines splits out into lines, blank lines are dropped.
+func nonEmptyLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
 		}
 	}
+	return lines
+}
 
-	// remove tag 'k8s.gcr.io'
-	dockerTag2 := fmt.Sprintf(`docker images | grep k8s.gcr.io | sed 's/k8s.gcr.io\///' | awk '{print "docker tag "$3" %s:%d/"$1":"$2}'`, o.Node, o.RegistryPort)
-	logger.V(3).Info("dockerTag2 hook:", dockerTag2)
-	ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, dockerTag2)
+func (o *RegistryOptions) removeImages() error {
+	// docker rmi images
+	rmi := `docker images | awk '{print $1":"$2}' | grep -v registry | grep -v REPOSITORY`
+	ret, err := o.runDockerCmd(rmi)
 	if err != nil {
-		return err
+		o.log("remove-images").Warnf("docker remove image error: %s", err.Error())
 	}
 	if err = ret.Error(); err != nil {
-		return err
+		o.log("remove-images").Warnf("docker remove image error: %s", err.Error())
 	}
-	logger.V(4).Info("dockerTag2 out:", ret.Stdout)
-	split = strings.Split(ret.Stdout, "\n")
-	logger.V(4).Info("dockerTag2 out cmd count:", len(split))
-	logger.V(4).Info("dockerTag2 out cmd list:", split)
+	o.log("remove-images").V(4).Info("docker rmi out", ret.Stdout)
+	split := nonEmptyLines(ret.Stdout)
+	o.log("remove-images").V(4).Info("docker rmi cmd count:", len(split))
+	o.log("remove-images").V(4).Info("docker rmi cmd list:", split)
 	for _, cmd := range split {
-		if cmd == "" {
-			continue
-		}
-		ret, err = sshutils.SSHCmdWithSudo(o.SSHConfig, o.Node, cmd)
+		ret, err = o.runDockerCmd("docker rmi " + cmd)
 		if err != nil {
 			return err
 		}
@@ -972,19 +2507,32 @@ func (o *RegistryOptions) specialTag() error {
 			return err
 		}
 	}
-
 	return nil
 }
 
+func (o *RegistryOptions) keepImages() {
+	hook := `docker images | grep -v REPOSITORY | wc -l`
+	ret, err := o.runDockerCmd(hook)
+	if err != nil {
+		o.log("keep-images").Warnf("count local images error: %s", err.Error())
+		return
+	}
+	if err = ret.Error(); err != nil {
+		o.log("keep-images").Warnf("count local images error: %s", err.Error())
+		return
+	}
+	o.log("keep-images").Infof("keep %s local images", ret.StdoutToString(""))
+}
+
 func (o *RegistryOptions) listTags(toComplete string) []string {
 	utils.CheckErr(o.Complete())
 
 	if o.Name == "" {
 		return nil
 	}
-	tags, err := o.tags()
+	tags, err := o.cachedValues(cacheKeyTagPrefix+o.Name, o.tags)
 	if err != nil {
-		logger.V(2).Warnf("list tags error: %s", err.Error())
+		o.log("list-tags").V(2).Warnf("list tags error: %s", err.Error())
 	}
 	set := sets.NewString()
 	for _, v := range tags {
@@ -996,45 +2544,98 @@ func (o *RegistryOptions) listTags(toComplete string) []string {
 }
 
 func (o *RegistryOptions) tags() ([]string, error) {
-	url := fmt.Sprintf("http://%s:%d/v2/%s/tags/list", o.Node, o.RegistryPort, o.Name)
-	resp, code, respErr := httputil.CommonRequest(url, "GET", nil, nil, nil)
+	return o.tagsOf(o.Name)
+}
+
+func (o *RegistryOptions) tagsOf(name string) ([]string, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/tags/list", name))
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(nil), nil)
 	if respErr != nil {
 		return nil, pkgerr.WithMessage(respErr, "request failed")
 	}
-	body, codeErr := httputil.CodeDispose(resp, code)
+	body, codeErr := repositoryResponse(name, resp, code)
 	if codeErr != nil {
-		return nil, pkgerr.WithMessage(respErr, "code err failed")
+		return nil, pkgerr.WithMessage(codeErr, "code err failed")
 	}
 	img := new(Image)
 	err := json.Unmarshal(body, img)
 	return img.Tags, err
 }
 
+// repositoryResponse returns the body of a repository request, the non-200 status
+// which registry answers with an unexpected body is converted to a clear error.
+func repositoryResponse(name string, body []byte, code int) ([]byte, error) {
+	switch code {
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("repository %s not found", name)
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("unauthorized to access repository %s, registry requires authentication", name)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("forbidden to access repository %s", name)
+	}
+	return httputil.CodeDispose(body, code)
+}
+
+// manifestExists checks whether the image manifest is present by 'HEAD /v2/<name>/manifests/<tag>'.
+func (o *RegistryOptions) manifestExists(name, tag string) (bool, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, tag))
+	header := map[string]string{
+		"Accept": "application/vnd.docker.distribution.manifest.v2+json",
+	}
+	_, code, _, respErr := o.request(url, "HEAD", o.requestHeader(header), nil)
+	if respErr != nil {
+		return false, respErr
+	}
+	switch code {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// HEAD has no body, repositoryResponse only gives the clear error of the status.
+		_, err := repositoryResponse(name, nil, code)
+		return false, err
+	default:
+		return false, fmt.Errorf("unexpected status code %d", code)
+	}
+}
+
 func (o *RegistryOptions) listRepos(toComplete string) []string {
 	utils.CheckErr(o.Complete())
-	repositories, err := o.repos()
+	repositories, err := o.cachedValues(cacheKeyCatalog, o.catalog)
 	if err != nil {
-		logger.V(2).Warnf("list repositories error: %s", err.Error())
+		o.log("list-repositories").V(2).Warnf("list repositories error: %s", err.Error())
 		return nil
 	}
 	set := sets.NewString()
-	for _, values := range repositories {
-		for _, value := range values {
-			if strings.HasPrefix(value, toComplete) {
-				set.Insert(value)
-			}
+	for _, value := range repositories {
+		if strings.HasPrefix(value, toComplete) {
+			set.Insert(value)
 		}
 	}
 	return set.List()
 }
 
+// catalog returns all repository names of the registry.
+func (o *RegistryOptions) catalog() ([]string, error) {
+	repositories, err := o.repos()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, values := range repositories {
+		names = append(names, values...)
+	}
+	return names, nil
+}
+
 func (o *RegistryOptions) repos() (map[string][]string, error) {
-	url := fmt.Sprintf("http://%s:%d/v2/_catalog", o.Node, o.RegistryPort)
+	url := o.apiURL("/v2/_catalog")
 	params := make(map[string]string)
 	if o.Number != 0 {
 		params["n"] = strconv.Itoa(o.Number)
 	}
-	resp, code, respErr := httputil.CommonRequest(url, "GET", nil, params, nil)
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(nil), params)
 	if respErr != nil {
 		return nil, respErr
 	}
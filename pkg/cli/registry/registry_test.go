@@ -0,0 +1,756 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+// fakeRunner returns the stdout registered for each command, and records the executed commands.
+// Commands registered in failures exit with code 1, the registered stdout and stderr.
+type fakeRunner struct {
+	outputs  map[string]string
+	failures map[string]string
+	cmds     []string
+	mu       sync.Mutex
+}
+
+func (f *fakeRunner) run(sshConfig *sshutils.SSH, host, cmd string) (sshutils.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cmds = append(f.cmds, cmd)
+	if stderr, ok := f.failures[cmd]; ok {
+		return sshutils.Result{Host: host, Cmd: cmd, Stdout: f.outputs[cmd], Stderr: stderr, ExitCode: 1}, nil
+	}
+	return sshutils.Result{Host: host, Cmd: cmd, Stdout: f.outputs[cmd]}, nil
+}
+
+func newFakeOptions(runner *fakeRunner) *RegistryOptions {
+	o := NewRegistryOptions(options.IOStreams{})
+	o.Node = "10.0.0.111"
+	o.cmdRunner = runner.run
+	return o
+}
+
+func TestPush_NoImages(t *testing.T) {
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	if err := o.push(); err != nil {
+		t.Fatalf("push with empty docker images output: %v", err)
+	}
+	if len(runner.cmds) != 1 {
+		t.Errorf("expected only the image listing command to run, got %v", runner.cmds)
+	}
+}
+
+func TestPushableImages(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "registry 2 b8604a3fe854\n" +
+			"calico/cni v3.21.2 4c5c32530391\n" +
+			"calico/node v3.21.2 f1bca4d4ced2\n" +
+			"caas4/centos <none> 5d0da3dc9764\n" +
+			"10.0.0.111:5000/calico/cni v3.21.2 4c5c32530391\n\n",
+	}}
+	o := newFakeOptions(runner)
+	o.Exclude = []string{"calico/node"}
+	images, err := o.pushableImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Ref() != "calico/cni:v3.21.2" {
+		t.Errorf("unexpected pushable images: %v", images)
+	}
+}
+
+func TestRetagTargets(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	tests := []struct {
+		image   localImage
+		targets []string
+	}{
+		{
+			image:   localImage{Repository: "k8s.gcr.io/pause", Tag: "3.2"},
+			targets: []string{"10.0.0.111:5000/pause:3.2"},
+		},
+		{
+			image:   localImage{Repository: "calico/cni", Tag: "v3.21.2"},
+			targets: []string{"10.0.0.111:5000/library/calico/cni:v3.21.2", "10.0.0.111:5000/calico/cni:v3.21.2"},
+		},
+		{
+			image:   localImage{Repository: "caas4/registry", Tag: "2"},
+			targets: []string{"10.0.0.111:5000/caas4/registry:2"},
+		},
+	}
+	for _, tt := range tests {
+		targets := o.retagTargets(tt.image)
+		if strings.Join(targets, ",") != strings.Join(tt.targets, ",") {
+			t.Errorf("retagTargets(%s) = %v, want %v", tt.image.Ref(), targets, tt.targets)
+		}
+	}
+}
+
+func TestPush_Report(t *testing.T) {
+	runner := &fakeRunner{
+		outputs: map[string]string{
+			`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n" +
+				"k8s.gcr.io/etcd 3.4.13-0 0369cf4303ff\n",
+		},
+		failures: map[string]string{
+			"docker push 10.0.0.111:5000/etcd:3.4.13-0": "connection refused",
+		},
+	}
+	o := newFakeOptions(runner)
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	o.KeepLocalImages = true
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err := cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.push(); err == nil {
+		t.Fatal("expected push error when an image failed to push")
+	}
+	report := &PushReport{}
+	if err := json.Unmarshal(out.Bytes(), report); err != nil {
+		t.Fatalf("unmarshal push report %q: %v", out.String(), err)
+	}
+	expected := map[string]string{
+		"10.0.0.111:5000/pause:3.2":     PushStatusPushed,
+		"10.0.0.111:5000/etcd:3.4.13-0": PushStatusFailed,
+	}
+	if len(report.Items) != len(expected) {
+		t.Fatalf("expected %d report items, got %v", len(expected), report.Items)
+	}
+	for _, item := range report.Items {
+		if item.Status != expected[item.Target] {
+			t.Errorf("expected %s to be %s, got %s", item.Target, expected[item.Target], item.Status)
+		}
+	}
+	if report.Failed() != 1 {
+		t.Errorf("expected 1 failed image, got %d", report.Failed())
+	}
+}
+
+func TestRunRegistryCmd(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	expected := "docker run -d -v /opt/registry:/var/lib/registry -p 5000:5000 --restart=always --name registry registry:2"
+	if cmd := o.runRegistryCmd(); cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+	o.RestartPolicy = "on-failure:3"
+	o.MemoryLimit = "512m"
+	o.CPULimit = 1.5
+	expected = "docker run -d -v /opt/registry:/var/lib/registry -p 5000:5000 --restart=on-failure:3 --memory=512m --cpus=1.5 --name registry registry:2"
+	if cmd := o.runRegistryCmd(); cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+	o.ExtraHosts = []string{"registry.example.com:10.0.0.5", "mirror:fd00::5"}
+	expected = "docker run -d -v /opt/registry:/var/lib/registry -p 5000:5000 --restart=on-failure:3 --memory=512m --cpus=1.5 " +
+		"--add-host registry.example.com:10.0.0.5 --add-host mirror:fd00::5 --name registry registry:2"
+	if cmd := o.runRegistryCmd(); cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestValidateRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{policy: "always"},
+		{policy: "unless-stopped"},
+		{policy: "on-failure:5"},
+		{policy: "sometimes", wantErr: true},
+		{policy: "always:3", wantErr: true},
+		{policy: "on-failure:0", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateRestartPolicy(tt.policy); (err != nil) != tt.wantErr {
+			t.Errorf("validateRestartPolicy(%q) error = %v, wantErr %v", tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestListImages_NonOK(t *testing.T) {
+	tests := []struct {
+		code    int
+		body    string
+		message string
+	}{
+		{code: http.StatusNotFound, body: "404 page not found", message: "repository caas4/none not found"},
+		{code: http.StatusUnauthorized, body: "", message: "unauthorized to access repository caas4/none"},
+		{code: http.StatusForbidden, body: "", message: "forbidden to access repository caas4/none"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.code)
+			_, _ = w.Write([]byte(tt.body))
+		}))
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := newFakeOptions(&fakeRunner{})
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.Name = "caas4/none"
+		err = o.listImages()
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("code %d: expected error containing %q, got %v", tt.code, tt.message, err)
+		}
+		if _, err = o.tags(); err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("code %d: expected tags error containing %q, got %v", tt.code, tt.message, err)
+		}
+		if tt.code != http.StatusNotFound {
+			if _, err = o.manifestExists(o.Name, "v1"); err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("code %d: expected manifest error containing %q, got %v", tt.code, tt.message, err)
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestCleanRegistry_RemoveVolume(t *testing.T) {
+	for _, removeVolume := range []bool{false, true} {
+		runner := &fakeRunner{}
+		o := newFakeOptions(runner)
+		o.RemoveVolume = removeVolume
+		if err := o.cleanRegistry(); err != nil {
+			t.Fatal(err)
+		}
+		var removed bool
+		for _, cmd := range runner.cmds {
+			if strings.Contains(cmd, o.RegistryVolume) {
+				removed = true
+			}
+		}
+		if removed != removeVolume {
+			t.Errorf("remove-volume=%v: expected volume removed %v, got commands %v", removeVolume, removeVolume, runner.cmds)
+		}
+	}
+}
+
+func TestPush_Quiet(t *testing.T) {
+	for _, outputSet := range []bool{false, true} {
+		runner := &fakeRunner{outputs: map[string]string{
+			`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n",
+		}}
+		o := newFakeOptions(runner)
+		out := &bytes.Buffer{}
+		o.IOStreams.Out = out
+		o.KeepLocalImages = true
+		o.Quiet = true
+		o.outputSet = outputSet
+		cmd := &cobra.Command{}
+		o.PrintFlags.AddFlags(cmd)
+		if outputSet {
+			if err := cmd.Flags().Set("output", "json"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := o.push(); err != nil {
+			t.Fatalf("output set %v: unexpected error: %v", outputSet, err)
+		}
+		if printed := out.Len() > 0; printed != outputSet {
+			t.Errorf("output set %v: expected report printed %v, got %q", outputSet, outputSet, out.String())
+		}
+	}
+}
+
+func TestDeleteTag_Strategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleteCode int
+		stderr     string
+		removed    bool
+		message    string
+	}{
+		{name: "delete by api", deleteCode: http.StatusAccepted},
+		{name: "fallback on 405", deleteCode: http.StatusMethodNotAllowed, removed: true},
+		{name: "fallback permission denied", deleteCode: http.StatusMethodNotAllowed, removed: true,
+			stderr: "rm: cannot remove 'v3.4.0': Permission denied", message: "permission denied to remove /opt/registry/docker/registry/v2/repositories/caas4/cephcsi/_manifests/tags/v3.4.0"},
+	}
+	for _, tt := range tests {
+		var deletedDigest string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodHead:
+				w.Header().Set("Docker-Content-Digest", "sha256:abc")
+				w.WriteHeader(http.StatusOK)
+			case http.MethodDelete:
+				deletedDigest = strings.TrimPrefix(r.URL.Path, "/v2/caas4/cephcsi/manifests/")
+				w.WriteHeader(tt.deleteCode)
+			}
+		}))
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &fakeRunner{failures: map[string]string{}}
+		o := newFakeOptions(runner)
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.RegistryVolume = "/opt/registry"
+		o.Name = "caas4/cephcsi"
+		o.Tag = "v3.4.0"
+		rm := "rm -rf /opt/registry/docker/registry/v2/repositories/caas4/cephcsi/_manifests/tags/v3.4.0"
+		if tt.stderr != "" {
+			runner.failures[rm] = tt.stderr
+		}
+		err = o.deleteTag()
+		server.Close()
+		if tt.message == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.message != "" && (err == nil || !strings.Contains(err.Error(), tt.message)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+		if deletedDigest != "sha256:abc" {
+			t.Errorf("%s: expected manifest deleted by digest, got %q", tt.name, deletedDigest)
+		}
+		if removed := len(runner.cmds) == 1 && runner.cmds[0] == rm; removed != tt.removed {
+			t.Errorf("%s: expected tag path removed %v, got commands %v", tt.name, tt.removed, runner.cmds)
+		}
+	}
+}
+
+func TestDeleteAllTags_Strategies(t *testing.T) {
+	assumeYes := options.AssumeYes
+	options.AssumeYes = true
+	defer func() { options.AssumeYes = assumeYes }()
+	for _, deleteCode := range []int{http.StatusAccepted, http.StatusMethodNotAllowed} {
+		var (
+			mu      sync.Mutex
+			deleted []string
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch r.Method {
+			case http.MethodGet:
+				_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["v3.4.0","v3.5.0"]}`))
+			case http.MethodHead:
+				tag := strings.TrimPrefix(r.URL.Path, "/v2/caas4/cephcsi/manifests/")
+				w.Header().Set("Docker-Content-Digest", "sha256:"+tag)
+				w.WriteHeader(http.StatusOK)
+			case http.MethodDelete:
+				deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v2/caas4/cephcsi/manifests/"))
+				w.WriteHeader(deleteCode)
+			}
+		}))
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &fakeRunner{}
+		o := newFakeOptions(runner)
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.RegistryVolume = "/opt/registry"
+		o.Name = "caas4/cephcsi"
+		o.AllTags = true
+		err = o.deleteAllTags()
+		server.Close()
+		if err != nil {
+			t.Fatalf("code %d: unexpected error: %v", deleteCode, err)
+		}
+		if expected := []string{"sha256:v3.4.0", "sha256:v3.5.0"}; strings.Join(deleted, ",") != strings.Join(expected, ",") {
+			t.Errorf("code %d: expected manifests %v deleted by API, got %v", deleteCode, expected, deleted)
+		}
+		var expectedCmds []string
+		if deleteCode == http.StatusMethodNotAllowed {
+			expectedCmds = []string{
+				"rm -rf /opt/registry/docker/registry/v2/repositories/caas4/cephcsi/_manifests/tags/v3.4.0",
+				"rm -rf /opt/registry/docker/registry/v2/repositories/caas4/cephcsi/_manifests/tags/v3.5.0",
+			}
+		}
+		if strings.Join(runner.cmds, ",") != strings.Join(expectedCmds, ",") {
+			t.Errorf("code %d: expected commands %v, got %v", deleteCode, expectedCmds, runner.cmds)
+		}
+	}
+}
+
+func TestDeleteAllTags_RemoveRepository(t *testing.T) {
+	assumeYes := options.AssumeYes
+	options.AssumeYes = true
+	defer func() { options.AssumeYes = assumeYes }()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["v3.4.0"]}`))
+		case http.MethodHead:
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	cmd := NewCmdRegistryDelete(o)
+	for name, value := range map[string]string{"node": host, "registry-port": port, "name": "caas4/cephcsi",
+		"all-tags": "true", "remove-repository": "true", "registry-volume": "/data/registry"} {
+		if err = cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = o.deleteAllTags(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"rm -rf /data/registry/docker/registry/v2/repositories/caas4/cephcsi"}; strings.Join(runner.cmds, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected commands %v, got %v", expected, runner.cmds)
+	}
+}
+
+func TestInstallSteps_NoPush(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.NoPush = true
+	for _, step := range o.installSteps() {
+		if step.name == "load images" || step.name == "push images" {
+			t.Errorf("unexpected step %q with --no-push", step.name)
+		}
+	}
+	o.KeepLocalImages = true
+	o.SSHConfig.PkFile = "key"
+	o.Pkg = "kc.tar.gz"
+	if err := o.ValidateArgsDeploy(); err == nil || !strings.Contains(err.Error(), "--no-push") {
+		t.Errorf("expected --keep-local-images conflicts with --no-push, got %v", err)
+	}
+}
+
+func TestRequestHeader_UserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["v3.4.0"]}`))
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newFakeOptions(&fakeRunner{})
+	if !strings.HasPrefix(o.UserAgent, "kcctl/") {
+		t.Errorf("expected default user-agent kcctl/<version>, got %q", o.UserAgent)
+	}
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.Name = "caas4/cephcsi"
+	o.UserAgent = "kcctl/audit"
+	if _, err = o.tags(); err != nil {
+		t.Fatal(err)
+	}
+	if userAgent != "kcctl/audit" {
+		t.Errorf("expected user-agent kcctl/audit, got %q", userAgent)
+	}
+}
+
+func TestPush_ManifestOut(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	runner := &fakeRunner{
+		outputs: map[string]string{
+			`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n" +
+				"k8s.gcr.io/etcd 3.4.13-0 0369cf4303ff\n",
+			"docker push 10.0.0.111:5000/pause:3.2": "3.2: digest: " + digest + " size: 526\n",
+		},
+		failures: map[string]string{
+			"docker push 10.0.0.111:5000/etcd:3.4.13-0": "connection refused",
+		},
+	}
+	o := newFakeOptions(runner)
+	o.IOStreams.Out = &bytes.Buffer{}
+	o.KeepLocalImages = true
+	o.cacheDir = t.TempDir()
+	o.ManifestOut = filepath.Join(t.TempDir(), "delivered.json")
+	if err := o.push(); err == nil {
+		t.Fatal("expected push error when an image failed to push")
+	}
+	data, err := os.ReadFile(o.ManifestOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := &DeliveryManifest{}
+	if err = json.Unmarshal(data, delivered); err != nil {
+		t.Fatalf("unmarshal delivery manifest %q: %v", data, err)
+	}
+	expected := DeliveredImage{Image: "pause:3.2", Digest: digest, Size: 526}
+	if delivered.Registry != "10.0.0.111:5000" || len(delivered.Images) != 1 || delivered.Images[0] != expected {
+		t.Errorf("expected only %v delivered to 10.0.0.111:5000, got %+v", expected, delivered)
+	}
+}
+
+func TestSSHPortFlag(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.SSHConfig.PkFile = "key"
+	cmd := NewCmdRegistryClean(o)
+	if o.SSHConfig.ConnectPort() != sshutils.DefaultPort {
+		t.Errorf("expected default ssh port %d, got %d", sshutils.DefaultPort, o.SSHConfig.ConnectPort())
+	}
+	if err := cmd.Flags().Set("ssh-port", "2222"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.validateSSH(); err != nil {
+		t.Fatal(err)
+	}
+	if o.SSHConfig.ConnectPort() != 2222 || o.RegistryPort != 5000 {
+		t.Errorf("expected ssh port 2222 independent of registry port 5000, got %d and %d", o.SSHConfig.ConnectPort(), o.RegistryPort)
+	}
+	if err := cmd.Flags().Set("ssh-port", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.validateSSH(); err == nil || !strings.Contains(err.Error(), "--ssh-port") {
+		t.Errorf("expected invalid --ssh-port error, got %v", err)
+	}
+}
+
+func TestInstallPushCA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "registry-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	o.PushCAFile = caFile
+	o.CANodes = []string{"10.0.0.112"}
+	if err = o.installPushCA(); err != nil {
+		t.Fatal(err)
+	}
+	if len(runner.cmds) != 4 {
+		t.Fatalf("expected mkdir and write of ca.crt on 2 nodes, got %v", runner.cmds)
+	}
+	dir := "/etc/docker/certs.d/10.0.0.111:5000"
+	if runner.cmds[0] != "mkdir -pv "+dir {
+		t.Errorf("expected %s created, got %q", dir, runner.cmds[0])
+	}
+	for _, cmd := range []string{runner.cmds[1], runner.cmds[3]} {
+		if !strings.Contains(cmd, "-----BEGIN CERTIFICATE-----") || !strings.HasSuffix(cmd, "> "+dir+"/ca.crt\"") {
+			t.Errorf("expected the CA written to %s/ca.crt, got %q", dir, cmd)
+		}
+	}
+
+	runner = &fakeRunner{}
+	o = newFakeOptions(runner)
+	o.PushCAFile = caFile
+	o.BindAddress = "127.0.0.1"
+	o.RegistryPort = 5001
+	if err = o.installPushCA(); err != nil {
+		t.Fatal(err)
+	}
+	if dir = "/etc/docker/certs.d/127.0.0.1:5001"; len(runner.cmds) == 0 || runner.cmds[0] != "mkdir -pv "+dir {
+		t.Errorf("expected %s of the published registry created, got %v", dir, runner.cmds)
+	}
+
+	runner = &fakeRunner{}
+	o = newFakeOptions(runner)
+	o.PushCAFile = filepath.Join(t.TempDir(), "bad.crt")
+	if err = os.WriteFile(o.PushCAFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.installPushCA(); err == nil || !strings.Contains(err.Error(), "does not contain any PEM encoded certificate") {
+		t.Errorf("expected invalid PEM error, got %v", err)
+	}
+	if len(runner.cmds) != 0 {
+		t.Errorf("expected nothing run with an invalid CA, got %v", runner.cmds)
+	}
+}
+
+func TestRunInstallSteps_DeployTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	tests := []struct {
+		name string
+		run  func(o *RegistryOptions) error
+	}{
+		{name: "cancelable step", run: func(o *RegistryOptions) error {
+			_, err := o.runCmd("docker load -i images.tar")
+			return err
+		}},
+		{name: "uncancelable step", run: func(o *RegistryOptions) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.cmdRunner = func(sshConfig *sshutils.SSH, host, cmd string) (sshutils.Result, error) {
+			<-block
+			return sshutils.Result{Host: host, Cmd: cmd}, nil
+		}
+		o.IOStreams.Out = &bytes.Buffer{}
+		o.DeployTimeout = 20 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), o.DeployTimeout)
+		o.ctx = ctx
+		var nextRun bool
+		err := o.runInstallSteps([]installStep{
+			{name: "load-images", run: func() error { return tt.run(o) }},
+			{name: "push", run: func() error {
+				nextRun = true
+				return nil
+			}},
+		})
+		cancel()
+		message := "deploy timed out after 20ms while running step 'load-images'"
+		if err == nil || err.Error() != message {
+			t.Errorf("%s: expected error %q, got %v", tt.name, message, err)
+		}
+		if nextRun {
+			t.Errorf("%s: expected the steps after the timeout not run", tt.name)
+		}
+	}
+}
+
+func TestListRepositories_Truncated(t *testing.T) {
+	repositories := []string{"caas4/etcd", "caas4/pause", "caas4/coredns"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := repositories
+		if n, _ := strconv.Atoi(r.URL.Query().Get("n")); n > 0 && n < len(list) {
+			list = list[:n]
+			w.Header().Set("Link", fmt.Sprintf(`</v2/_catalog?last=%s&n=%d>; rel="next"`, list[n-1], n))
+		}
+		_ = json.NewEncoder(w).Encode(Repositories{Repositories: list})
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		number    int
+		total     int
+		truncated bool
+	}{
+		{number: 0, total: 3},
+		{number: 2, total: 2, truncated: true},
+		{number: 3, total: 3},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.Number = tt.number
+		out := &bytes.Buffer{}
+		o.IOStreams.Out = out
+		cmd := &cobra.Command{}
+		o.PrintFlags.AddFlags(cmd)
+		if err = cmd.Flags().Set("output", "json"); err != nil {
+			t.Fatal(err)
+		}
+		if err = o.listRepositories(); err != nil {
+			t.Fatalf("number %d: unexpected error: %v", tt.number, err)
+		}
+		got := &Repositories{}
+		if err = json.Unmarshal(out.Bytes(), got); err != nil {
+			t.Fatalf("number %d: unmarshal repositories %q: %v", tt.number, out.String(), err)
+		}
+		if got.Total != tt.total || got.Truncated != tt.truncated || len(got.Repositories) != tt.total {
+			t.Errorf("number %d: expected total %d and truncated %v, got %+v", tt.number, tt.total, tt.truncated, got)
+		}
+	}
+}
+
+func TestLog_Fields(t *testing.T) {
+	output, noColor := color.Output, color.NoColor
+	defer func() {
+		color.Output, color.NoColor = output, noColor
+	}()
+	stdout := &bytes.Buffer{}
+	color.Output, color.NoColor = stdout, true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newFakeOptions(&fakeRunner{})
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.subcommand = "delete"
+	o.Name = "caas4/cephcsi"
+	o.Tag = "v3.4.0"
+	if err = o.deleteTag(); err != nil {
+		t.Fatal(err)
+	}
+	line := "deleted caas4/cephcsi:v3.4.0 by registry API node=" + host + " cmd=delete step=delete-tag\n"
+	if !strings.HasSuffix(stdout.String(), line) {
+		t.Errorf("expected log line ending with %q, got %q", line, stdout.String())
+	}
+
+	stdout.Reset()
+	o.logOn("10.0.0.112", "push-ca").Info("CA installed")
+	if line = "CA installed node=10.0.0.112 cmd=delete step=push-ca\n"; !strings.HasSuffix(stdout.String(), line) {
+		t.Errorf("expected log line ending with %q, got %q", line, stdout.String())
+	}
+}
@@ -0,0 +1,286 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/httputil"
+)
+
+const (
+	renameRepoLongDescription = `
+  Rename a repository of docker registry.
+
+  Every tag of --name is copied to --new-name by registry API with the same manifest, so the digests are kept
+  and the blobs are mounted to the new repository instead of being uploaded again.
+  After all tags are copied, the repository --name is removed from registry volume.`
+	renameRepoExample = `
+  # Print the tags which would be renamed
+  kcctl registry rename-repo --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --new-name csi/cephcsi --dry-run
+  # Rename repository caas4/cephcsi to csi/cephcsi
+  kcctl registry rename-repo --pk-file key --node 10.0.0.111 --registry-port 5000 --name caas4/cephcsi --new-name csi/cephcsi
+
+  Please read 'kcctl registry rename-repo -h' get more registry rename-repo flags.`
+)
+
+// manifestMediaTypes is the 'Accept' header of reading a manifest, so that registry returns it as stored
+// instead of converting it to schema1, which would change the digest.
+var manifestMediaTypes = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}, ",")
+
+// descriptor references a blob or a manifest.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	// URLs is set on foreign layers, which are not stored in registry
+	URLs []string `json:"urls,omitempty"`
+	// Platform is set on the manifests of a manifest list
+	Platform *imageConfig `json:"platform,omitempty"`
+}
+
+// manifest holds the references of an image manifest or a manifest list.
+type manifest struct {
+	Config    *descriptor  `json:"config,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+}
+
+func NewCmdRegistryRenameRepo(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rename-repo (--pk-file <file path>) (--node <node>) (--name <name>) (--new-name <new-name>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry rename repository",
+		Long:                  renameRepoLongDescription,
+		Example:               renameRepoExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsRenameRepo(cmd)))
+			if !o.preCheck() {
+				return
+			}
+			o.checkErr(o.RenameRepo())
+		},
+	}
+
+	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "registry volume path, the old repository is removed from it")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "repository to rename")
+	cmd.Flags().StringVar(&o.NewName, "new-name", o.NewName, "new name of the repository")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "only print the tags which would be renamed")
+	cmd.Flags().BoolVar(&o.Tunnel, "tunnel", o.Tunnel, "reach registry API through a ssh tunnel to 127.0.0.1 of node, for a registry deployed with --bind-address 127.0.0.1")
+	cmd.Flags().StringVar(&o.DockerConfig, "docker-config", o.DockerConfig, "path of docker config.json the credential of the registry is read from, $DOCKER_CONFIG/config.json or ~/.docker/config.json by default")
+	cmd.Flags().StringVar(&o.RegistryAuth, "registry-auth", o.RegistryAuth, "'user:password' of the registry, used if docker config.json has no credential of node:registry-port")
+	cmd.Flags().BoolVar(&o.NoCache, "no-cache", o.NoCache, "bypass the local cache used by completion")
+	cmd.Flags().DurationVar(&o.CacheTTL, "cache-ttl", o.CacheTTL, "time to live of the local cache used by completion")
+
+	utils.CheckErr(cmd.RegisterFlagCompletionFunc("name", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return o.listRepos(toComplete), cobra.ShellCompDirectiveNoFileComp
+	}))
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	utils.CheckErr(cmd.MarkFlagRequired("name"))
+	utils.CheckErr(cmd.MarkFlagRequired("new-name"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsRenameRepo(cmd *cobra.Command) error {
+	if err := o.validateSSH(); err != nil {
+		return err
+	}
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if err := o.validateRegistryAuth(); err != nil {
+		return err
+	}
+	if o.Name == "" || o.NewName == "" {
+		return utils.UsageErrorf(cmd, "--name and --new-name must be specified")
+	}
+	if o.Name == o.NewName {
+		return utils.UsageErrorf(cmd, "--new-name must be different from --name")
+	}
+	return nil
+}
+
+// RenameRepo copies every tag of --name to --new-name, then removes the repository --name.
+// Tags already in --new-name are never overwritten.
+func (o *RegistryOptions) RenameRepo() error {
+	if o.Tunnel {
+		closeTunnel, err := o.openTunnel()
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+	}
+	tags, err := o.tagsOf(o.Name)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		o.log("rename-repo").Infof("repository %s has no tags", o.Name)
+		return nil
+	}
+	existing, err := o.existingTags(o.NewName)
+	if err != nil {
+		return err
+	}
+	if conflicts := sets.NewString(existing...).Intersection(sets.NewString(tags...)); conflicts.Len() > 0 {
+		return fmt.Errorf("tags %s already exist in repository %s", strings.Join(conflicts.List(), ","), o.NewName)
+	}
+	if o.DryRun {
+		for _, tag := range tags {
+			_, _ = fmt.Fprintf(o.IOStreams.Out, "%s:%s would be renamed to %s:%s\n", o.Name, tag, o.NewName, tag)
+		}
+		return nil
+	}
+	if !options.AssumeYes {
+		_, _ = fmt.Fprintf(o.IOStreams.Out, "%d tags of %s will be renamed to %s, continue? Please input (yes/no)", len(tags), o.Name, o.NewName)
+		if !utils.AskForConfirmation() {
+			return nil
+		}
+	}
+	// tags may be copied before an error, so the cache is dropped anyway
+	defer o.invalidateCache()
+	for i, tag := range tags {
+		if o.interrupted() {
+			return fmt.Errorf("copied %d of %d tags, %s is kept", i, len(tags), o.Name)
+		}
+		if err = o.copyManifest(o.Name, o.NewName, tag); err != nil {
+			return fmt.Errorf("copied %d of %d tags, copy %s:%s error: %w", i, len(tags), o.Name, tag, err)
+		}
+		o.log("rename-repo").V(2).Infof("copied %s:%s to %s:%s", o.Name, tag, o.NewName, tag)
+	}
+	// the blobs are referenced by the new repository now, nothing is left for garbage-collect
+	repoPath := fmt.Sprintf("%s/docker/registry/v2/repositories/%s", o.RegistryVolume, o.Name)
+	if err = o.removePath(repoPath); err != nil {
+		return fmt.Errorf("copied %d tags to %s, remove repository %s error: %w", len(tags), o.NewName, o.Name, err)
+	}
+	o.log("rename-repo").Infof("renamed repository %s to %s with %d tags", o.Name, o.NewName, len(tags))
+	return nil
+}
+
+// existingTags returns the tags of repository name, or nothing if it doesn't exist.
+func (o *RegistryOptions) existingTags(name string) ([]string, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/tags/list", name))
+	resp, code, _, respErr := o.request(url, "GET", o.requestHeader(nil), nil)
+	if respErr != nil {
+		return nil, respErr
+	}
+	if code == http.StatusNotFound {
+		return nil, nil
+	}
+	body, err := repositoryResponse(name, resp, code)
+	if err != nil {
+		return nil, err
+	}
+	img := new(Image)
+	err = json.Unmarshal(body, img)
+	return img.Tags, err
+}
+
+// copyManifest puts the manifest of reference, a tag or a digest, in repository from into repository to
+// with the same content and media type, so that its digest is kept. The blobs are mounted across repositories
+// first, and the manifests of a manifest list are copied by digest.
+func (o *RegistryOptions) copyManifest(from, to, reference string) error {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", from, reference))
+	header := map[string]string{"Accept": manifestMediaTypes}
+	resp, code, respHeader, respErr := o.request(url, "GET", o.requestHeader(header), nil)
+	if respErr != nil {
+		return respErr
+	}
+	body, err := repositoryResponse(from, resp, code)
+	if err != nil {
+		return err
+	}
+	digest, mediaType := respHeader.Get("Docker-Content-Digest"), respHeader.Get("Content-Type")
+	m := new(manifest)
+	if err = json.Unmarshal(body, m); err != nil {
+		return fmt.Errorf("invalid manifest %s of %s: %w", reference, from, err)
+	}
+	for _, child := range m.Manifests {
+		if err = o.copyManifest(from, to, child.Digest); err != nil {
+			return err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		if len(blob.URLs) > 0 {
+			continue
+		}
+		if err = o.mountBlob(from, to, blob.Digest); err != nil {
+			return err
+		}
+	}
+
+	url = o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", to, reference))
+	resp, code, respHeader, respErr = httputil.CommonRequestWithHeader(url, "PUT", o.requestHeader(map[string]string{"Content-Type": mediaType}), nil, body)
+	if respErr != nil {
+		return respErr
+	}
+	if code != http.StatusCreated {
+		if _, err = repositoryResponse(to, resp, code); err != nil {
+			return err
+		}
+		return fmt.Errorf("put manifest %s of %s returns unexpected status code %d", reference, to, code)
+	}
+	if put := respHeader.Get("Docker-Content-Digest"); digest != "" && put != digest {
+		return fmt.Errorf("digest of %s changed from %s to %s after copied to %s", reference, digest, put, to)
+	}
+	return nil
+}
+
+// mountBlob links the blob of repository from into repository to by
+// 'POST /v2/<to>/blobs/uploads/?mount=<digest>&from=<from>', no data is uploaded.
+func (o *RegistryOptions) mountBlob(from, to, digest string) error {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/blobs/uploads/", to))
+	params := map[string]string{"mount": digest, "from": from}
+	resp, code, respErr := httputil.CommonRequest(url, "POST", o.requestHeader(nil), params, nil)
+	if respErr != nil {
+		return respErr
+	}
+	switch code {
+	case http.StatusCreated:
+		return nil
+	case http.StatusAccepted:
+		// registry started an upload instead, i.e. the blob is missing in repository from
+		return fmt.Errorf("blob %s of %s can not be mounted to %s", digest, from, to)
+	}
+	if _, err := repositoryResponse(to, resp, code); err != nil {
+		return err
+	}
+	return fmt.Errorf("mount blob %s to %s returns unexpected status code %d", digest, to, code)
+}
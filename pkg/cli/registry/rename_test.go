@@ -0,0 +1,107 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+)
+
+func TestRenameRepo(t *testing.T) {
+	const (
+		mediaType = "application/vnd.docker.distribution.manifest.v2+json"
+		body      = `{"schemaVersion":2,"config":{"digest":"sha256:c1"},"layers":[{"digest":"sha256:l1"},{"digest":"sha256:f1","urls":["https://example.com/f1"]}]}`
+	)
+	var (
+		mu      sync.Mutex
+		mounted []string
+		put     = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v2/caas4/cephcsi/tags/list":
+			_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["v1","v2"]}`))
+		case r.URL.Path == "/v2/csi/cephcsi/tags/list":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/caas4/cephcsi/manifests/"):
+			w.Header().Set("Content-Type", mediaType)
+			w.Header().Set("Docker-Content-Digest", "sha256:m1")
+			_, _ = w.Write([]byte(body))
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/csi/cephcsi/blobs/uploads/":
+			if r.URL.Query().Get("from") != "caas4/cephcsi" {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			mounted = append(mounted, r.URL.Query().Get("mount"))
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/csi/cephcsi/manifests/"):
+			data, _ := io.ReadAll(r.Body)
+			if r.Header.Get("Content-Type") != mediaType || string(data) != body {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			put[strings.TrimPrefix(r.URL.Path, "/v2/csi/cephcsi/manifests/")] = string(data)
+			w.Header().Set("Docker-Content-Digest", "sha256:m1")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assumeYes := options.AssumeYes
+	options.AssumeYes = true
+	defer func() { options.AssumeYes = assumeYes }()
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.RegistryVolume = "/opt/registry"
+	o.Name = "caas4/cephcsi"
+	o.NewName = "csi/cephcsi"
+	o.cacheDir = t.TempDir()
+	if err = o.RenameRepo(); err != nil {
+		t.Fatal(err)
+	}
+	if len(put) != 2 || put["v1"] == "" || put["v2"] == "" {
+		t.Errorf("expected manifests of v1 and v2 put to csi/cephcsi, got %v", put)
+	}
+	// the foreign layer is skipped
+	if len(mounted) != 4 || mounted[0] != "sha256:c1" || mounted[1] != "sha256:l1" {
+		t.Errorf("expected config and layer mounted for each tag, got %v", mounted)
+	}
+	rm := "rm -rf /opt/registry/docker/registry/v2/repositories/caas4/cephcsi"
+	if len(runner.cmds) != 1 || runner.cmds[0] != rm {
+		t.Errorf("expected only %q to run, got %v", rm, runner.cmds)
+	}
+}
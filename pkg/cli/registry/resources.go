@@ -0,0 +1,132 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+)
+
+const (
+	// defaultResourceDir is the directory of the bundled image archives, relative to the package on node.
+	defaultResourceDir = "kc/resource"
+	// defaultImageGlob matches the image archive of each resource for the arch of deploy.
+	defaultImageGlob = "**/{arch}/images.tar.gz"
+)
+
+// validateImageGlob validates --image-glob is a '/' separated glob, whose elements are '**' or path.Match patterns.
+func validateImageGlob(glob string) error {
+	if glob == "" || path.IsAbs(glob) {
+		return fmt.Errorf("--image-glob %q is invalid, must be a pattern relative to --resource-dir", glob)
+	}
+	for _, elem := range strings.Split(glob, "/") {
+		if elem == "**" {
+			continue
+		}
+		if _, err := path.Match(elem, ""); err != nil {
+			return fmt.Errorf("--image-glob %s is invalid: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the '/' separated name matches glob, where '**' matches any number of path elements
+// and the other elements are matched by path.Match.
+func matchGlob(glob, name string) bool {
+	return matchElems(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(glob, name []string) bool {
+	if len(glob) == 0 {
+		return len(name) == 0
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchElems(glob[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(glob[0], name[0])
+	return ok && matchElems(glob[1:], name[1:])
+}
+
+// imageGlob returns --image-glob with '{arch}' replaced by the arch directory.
+func (o *RegistryOptions) imageGlob() string {
+	return o.archImageGlob(o.archDir())
+}
+
+// archImageGlob returns --image-glob with '{arch}' replaced by the arch directory dir.
+func (o *RegistryOptions) archImageGlob(dir string) string {
+	return strings.ReplaceAll(o.ImageGlob, "{arch}", dir)
+}
+
+// resourceDir returns --resource-dir on node, a relative one is in the package.
+func (o *RegistryOptions) resourceDir() string {
+	if path.IsAbs(o.ResourceDir) {
+		return path.Clean(o.ResourceDir)
+	}
+	return path.Join(config.DefaultPkgPath, o.ResourceDir)
+}
+
+// imageArchives returns the files under --resource-dir on node matching --image-glob, sorted.
+func (o *RegistryOptions) imageArchives() ([]string, error) {
+	return o.archImageArchives(o.archDir())
+}
+
+// archImageArchives returns the files under --resource-dir on node matching --image-glob of the arch directory
+// archDir, sorted.
+func (o *RegistryOptions) archImageArchives(archDir string) ([]string, error) {
+	dir, files, err := o.resourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	glob := o.archImageGlob(archDir)
+	var archives []string
+	for _, file := range files {
+		if rel := strings.TrimPrefix(file, dir+"/"); rel != file && matchGlob(glob, rel) {
+			archives = append(archives, file)
+		}
+	}
+	if len(archives) == 0 {
+		return nil, fmt.Errorf("no image archives match --image-glob %s under --resource-dir %s on %s", glob, dir, o.Node)
+	}
+	sort.Strings(archives)
+	return archives, nil
+}
+
+// resourceFiles lists the files under --resource-dir on node, along with the directory.
+func (o *RegistryOptions) resourceFiles() (string, []string, error) {
+	dir := o.resourceDir()
+	ret, err := o.runCmd(fmt.Sprintf("find %s -type f", shellQuote(dir)))
+	if err != nil {
+		return dir, nil, err
+	}
+	if err = ret.Error(); err != nil {
+		return dir, nil, fmt.Errorf("list --resource-dir %s on %s error: %s", dir, o.Node, strings.TrimSpace(ret.Stderr))
+	}
+	return dir, nonEmptyLines(ret.Stdout), nil
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, name string
+		match      bool
+	}{
+		{glob: "**/amd64/images.tar.gz", name: "k8s/v1.23.6/amd64/images.tar.gz", match: true},
+		{glob: "**/amd64/images.tar.gz", name: "amd64/images.tar.gz", match: true},
+		{glob: "**/amd64/images.tar.gz", name: "k8s/v1.23.6/arm64/images.tar.gz"},
+		{glob: "**/arm/images.tar.gz", name: "k8s/v1.23.6/arm64/images.tar.gz"},
+		{glob: "images/*.tar", name: "images/etcd.tar", match: true},
+		{glob: "images/*.tar", name: "images/etcd/etcd.tar"},
+		{glob: "**", name: "a/b/c", match: true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.glob, tt.name); got != tt.match {
+			t.Errorf("%s %s: expected match %v, got %v", tt.glob, tt.name, tt.match, got)
+		}
+	}
+}
+
+func TestValidateImageGlob(t *testing.T) {
+	for glob, valid := range map[string]bool{defaultImageGlob: true, "images/*.tar": true, "": false, "/images/*.tar": false, "images/[.tar": false} {
+		if err := validateImageGlob(glob); (err == nil) != valid {
+			t.Errorf("--image-glob %q: expected valid %v, got %v", glob, valid, err)
+		}
+	}
+}
+
+func TestLoadImages(t *testing.T) {
+	find := "find '/tmp/kc/resource' -type f"
+	runner := &fakeRunner{outputs: map[string]string{find: strings.Join([]string{
+		"/tmp/kc/resource/k8s/v1.23.6/amd64/images.tar.gz",
+		"/tmp/kc/resource/k8s/v1.23.6/arm64/images.tar.gz",
+		"/tmp/kc/resource/calico/v3.21.2/amd64/images.tar.gz",
+		"/tmp/kc/resource/docker/19.03.12/amd64/configs.tar.gz",
+	}, "\n")}}
+	o := newFakeOptions(runner)
+	if err := o.loadImages(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		find,
+		"docker load -i '/tmp/kc/resource/calico/v3.21.2/amd64/images.tar.gz'",
+		"docker load -i '/tmp/kc/resource/k8s/v1.23.6/amd64/images.tar.gz'",
+	}
+	if !reflect.DeepEqual(runner.cmds, expected) {
+		t.Errorf("expected %v, got %v", expected, runner.cmds)
+	}
+
+	o.ResourceDir = "/data/bundle"
+	o.ImageGlob = "images/*.tar"
+	if err := o.loadImages(); err == nil || !strings.Contains(err.Error(), "no image archives match --image-glob images/*.tar under --resource-dir /data/bundle") {
+		t.Errorf("expected no matching archives error, got %v", err)
+	}
+}
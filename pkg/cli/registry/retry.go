@@ -0,0 +1,115 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/httputil"
+)
+
+const (
+	// defaultRetryBackoff is the wait before the first retry, doubled on every retry up to maxRetryBackoff.
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 8 * time.Second
+	// maxRetryAfter caps the wait a 'Retry-After' header asks for.
+	maxRetryAfter = 30 * time.Second
+)
+
+// retryableStatus reports whether code is a transient failure of registry, e.g. while it runs garbage-collect.
+// The other 4xx are terminal.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError reports whether err is a transient connection failure, e.g. reset by a restarting registry.
+func retryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter parses the 'Retry-After' header in seconds or as an HTTP date, ok is false if it is absent or invalid.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, true
+}
+
+// request is httputil.CommonRequestWithHeader retried up to --http-retries times with exponential backoff,
+// on a retryable status or, for the idempotent GET and HEAD, on a transient connection failure.
+// A 'Retry-After' header of 429 or 503 is honored instead of the backoff.
+func (o *RegistryOptions) request(url, method string, header, params map[string]string) ([]byte, int, http.Header, error) {
+	backoff := o.retryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		body, code, respHeader, err := httputil.CommonRequestWithHeader(url, method, header, params, nil)
+		idempotent := method == http.MethodGet || method == http.MethodHead
+		retry := (err == nil && retryableStatus(code)) || (err != nil && idempotent && retryableError(err))
+		if !retry || attempt >= o.HTTPRetries {
+			return body, code, respHeader, err
+		}
+		wait := backoff
+		if after, ok := retryAfter(respHeader); ok && (code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable) {
+			wait = after
+		}
+		if err != nil {
+			o.log("http-retry").V(2).Infof("%s %s error: %s, retry %d of %d after %s", method, url, err.Error(), attempt+1, o.HTTPRetries, wait)
+		} else {
+			o.log("http-retry").V(2).Infof("%s %s returned %d, retry %d of %d after %s", method, url, code, attempt+1, o.HTTPRetries, wait)
+		}
+		if o.sleep(wait) != nil {
+			return body, code, respHeader, err
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
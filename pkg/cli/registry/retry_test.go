@@ -0,0 +1,99 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "2", wait: 2 * time.Second, ok: true},
+		{value: "3600", wait: maxRetryAfter, ok: true},
+		{value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), wait: 0, ok: true},
+		{value: "soon", ok: false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		wait, ok := retryAfter(header)
+		if wait != tt.wait || ok != tt.ok {
+			t.Errorf("%q: expected %v %v, got %v %v", tt.value, tt.wait, tt.ok, wait, ok)
+		}
+	}
+}
+
+func TestRequestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+		retries  int
+		code     int
+		requests int32
+	}{
+		{name: "recovered", failures: 2, status: http.StatusServiceUnavailable, retries: 3, code: http.StatusOK, requests: 3},
+		{name: "too many requests", failures: 1, status: http.StatusTooManyRequests, retries: 3, code: http.StatusOK, requests: 2},
+		{name: "exhausted", failures: 10, status: http.StatusBadGateway, retries: 2, code: http.StatusBadGateway, requests: 3},
+		{name: "terminal", failures: 10, status: http.StatusNotFound, retries: 3, code: http.StatusNotFound, requests: 1},
+		{name: "disabled", failures: 10, status: http.StatusServiceUnavailable, retries: 0, code: http.StatusServiceUnavailable, requests: 1},
+	}
+	for _, tt := range tests {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= tt.failures {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tt.status)
+				return
+			}
+			_, _ = w.Write([]byte(`{"repositories":["caas4/etcd"]}`))
+		}))
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := newFakeOptions(&fakeRunner{})
+		o.Node = host
+		o.RegistryPort, _ = strconv.Atoi(port)
+		o.HTTPRetries = tt.retries
+		o.retryBackoff = time.Millisecond
+		_, code, _, err := o.request(o.apiURL("/v2/_catalog"), http.MethodGet, o.requestHeader(nil), nil)
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if code != tt.code || requests != tt.requests {
+			t.Errorf("%s: expected code %d after %d requests, got %d after %d", tt.name, tt.code, tt.requests, code, requests)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/config"
+)
+
+// rollback undoes steps in reverse after deploy failed with cause. A failed rollback step
+// doesn't stop the others, the returned error carries cause and the steps failed to roll back.
+func (o *RegistryOptions) rollback(steps []installStep, cause error) error {
+	// the deploy deadline may have passed, it must not cancel the rollback
+	o.ctx = nil
+	var failed []string
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.rollback == nil {
+			continue
+		}
+		o.log("rollback").Infof("rollback step '%s'", step.name)
+		if err := step.rollback(); err != nil {
+			o.log("rollback").Warnf("rollback step '%s' error: %s", step.name, err.Error())
+			failed = append(failed, step.name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w\nrollback of steps '%s' failed, please run 'kcctl registry clean' on %s before retry",
+			cause, strings.Join(failed, "', '"), o.Node)
+	}
+	o.log("rollback").Info("deploy is rolled back")
+	if o.keptDockerChanges(steps) {
+		return fmt.Errorf("%w\ndeploy is rolled back on %s, except the docker existed before deploy, whose %s and /etc/docker/certs.d may have been changed by deploy",
+			cause, o.Node, o.daemonConfigFile())
+	}
+	return fmt.Errorf("%w\ndeploy is rolled back on %s", cause, o.Node)
+}
+
+// keptDockerChanges reports whether steps may have changed the daemon.json or the registry CA of a docker
+// existed before deploy, which rollback keeps as is.
+func (o *RegistryOptions) keptDockerChanges(steps []installStep) bool {
+	if o.dockerInstalled {
+		return false
+	}
+	for _, step := range steps {
+		if step.name == "install docker" || step.name == "install push CA" {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPackage removes the package sent to node and its extracted files.
+func (o *RegistryOptions) cleanPackage() error {
+	ret, err := o.runCmd(fmt.Sprintf(`rm -rf %s/kc*`, config.DefaultPkgPath))
+	if err != nil {
+		return err
+	}
+	return ret.Error()
+}
+
+// rollbackDocker removes docker if deploy installed it, a docker which existed before deploy is kept.
+func (o *RegistryOptions) rollbackDocker() error {
+	if !o.dockerInstalled {
+		o.log("rollback").Info("docker existed before deploy, keep it")
+		return nil
+	}
+	return o.cleanDocker()
+}
+
+// removeRegistryContainer removes the registry container if it was created, and its systemd service with --systemd.
+// A registry container which existed before deploy is kept.
+func (o *RegistryOptions) removeRegistryContainer() error {
+	if o.registryReused {
+		o.log("rollback").Infof("registry container %s existed before deploy, keep it", o.RegistryName)
+		return nil
+	}
+	if o.Systemd {
+		if _, err := o.removeRegistryUnit(); err != nil {
+			return err
+		}
+	}
+	ret, err := o.runDockerCmd("docker rm -f " + o.RegistryName)
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil && !strings.Contains(ret.Stderr, "No such container") {
+		return err
+	}
+	return nil
+}
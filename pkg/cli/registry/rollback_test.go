@@ -0,0 +1,81 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	runner := &fakeRunner{
+		outputs: map[string]string{
+			`docker images | awk '{print $1":"$2}' | grep -v registry | grep -v REPOSITORY`: "caas4/a:v1\n",
+		},
+		failures: map[string]string{
+			"docker rm -f registry": "Error: No such container: registry",
+		},
+	}
+	o := newFakeOptions(runner)
+	var steps []installStep
+	for _, step := range o.installSteps() {
+		steps = append(steps, step)
+		if step.name == "load images" {
+			break
+		}
+	}
+	err := o.rollback(steps, errors.New("load images error: no space left on device"))
+	if err == nil || !strings.Contains(err.Error(), "no space left on device") || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected rolled back error with the cause, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "except the docker existed before deploy, whose /etc/docker/daemon.json") {
+		t.Errorf("expected the kept daemon.json of the existing docker reported, got %v", err)
+	}
+	// docker existed before deploy, so it is kept
+	expected := []string{
+		`docker images | awk '{print $1":"$2}' | grep -v registry | grep -v REPOSITORY`,
+		"docker rmi caas4/a:v1",
+		"docker rm -f registry",
+		"rm -rf /tmp/kc*",
+	}
+	if strings.Join(runner.cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected rollback commands %v, got %v", expected, runner.cmds)
+	}
+}
+
+func TestRollback_Failed(t *testing.T) {
+	runner := &fakeRunner{
+		failures: map[string]string{
+			"docker rm -f registry": "Cannot connect to the Docker daemon",
+		},
+	}
+	o := newFakeOptions(runner)
+	steps := []installStep{
+		{name: "process package", rollback: o.cleanPackage},
+		{name: "install registry", rollback: o.removeRegistryContainer},
+	}
+	err := o.rollback(steps, errors.New("install registry error"))
+	if err == nil || !strings.Contains(err.Error(), "rollback of steps 'install registry' failed") {
+		t.Errorf("expected rollback failure of install registry, got %v", err)
+	}
+	if len(runner.cmds) != 2 {
+		t.Errorf("expected the other steps rolled back after a failure, got %v", runner.cmds)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+const (
+	// rootlessDaemonConfigPath is the daemon.json of rootless docker, relative to the home of the ssh user.
+	rootlessDaemonConfigPath = "~/.config/docker/daemon.json"
+	// rootlessDockerHost is the socket of rootless docker, DOCKER_HOST of the ssh user wins if it is set.
+	rootlessDockerHost = `${DOCKER_HOST:-unix://${XDG_RUNTIME_DIR:-/run/user/$(id -u)}/docker.sock}`
+)
+
+// runDockerCmd runs cmd which operates docker on the registry node, see runDockerCmdOn.
+func (o *RegistryOptions) runDockerCmd(cmd string) (sshutils.Result, error) {
+	return o.runDockerCmdOn(o.Node, cmd)
+}
+
+// runDockerCmdOn runs cmd on host with sudo, targeting --docker-host if set,
+// or as the ssh user with DOCKER_HOST exported for rootless docker.
+func (o *RegistryOptions) runDockerCmdOn(host, cmd string) (sshutils.Result, error) {
+	if !o.Rootless {
+		return o.runCmdOn(host, o.withDockerHost(cmd))
+	}
+	run := o.userCmdRunner
+	if run == nil {
+		run = sshutils.SSHCmd
+	}
+	return o.runWith(run, host, fmt.Sprintf(`export DOCKER_HOST="%s"; %s`, rootlessDockerHost, cmd))
+}
+
+// daemonConfigFile returns the daemon.json of docker on node.
+func (o *RegistryOptions) daemonConfigFile() string {
+	if o.Rootless {
+		return rootlessDaemonConfigPath
+	}
+	return daemonConfigPath
+}
+
+// restartDockerCmd returns the command restarting docker on node, rootless docker is a systemd user service.
+func (o *RegistryOptions) restartDockerCmd() string {
+	if o.Rootless {
+		return "systemctl --user restart docker"
+	}
+	return "systemctl restart docker"
+}
+
+// checkRootless checks the ssh user reaches a rootless docker by DOCKER_HOST,
+// which must be set up before deploy, e.g. by 'dockerd-rootless-setuptool.sh install'.
+func (o *RegistryOptions) checkRootless() error {
+	if o.SSHConfig.User == "root" {
+		return fmt.Errorf("--rootless requires a non-root ssh user which runs rootless docker")
+	}
+	ret, err := o.runDockerCmd(`echo "$DOCKER_HOST" && docker info -f '{{.SecurityOptions}}'`)
+	if err != nil {
+		return err
+	}
+	host, security, _ := strings.Cut(ret.Stdout, "\n")
+	if !strings.HasPrefix(host, "unix://") {
+		return fmt.Errorf("DOCKER_HOST %q of user %s on %s is not a unix socket of rootless docker", host, o.SSHConfig.User, o.Node)
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("rootless docker of user %s on %s is not reachable by DOCKER_HOST %s: %s, "+
+			"please set it up by 'dockerd-rootless-setuptool.sh install' first", o.SSHConfig.User, o.Node, host, strings.TrimSpace(ret.Stderr))
+	}
+	if !strings.Contains(security, "rootless") {
+		return fmt.Errorf("docker of DOCKER_HOST %s on %s is not rootless", host, o.Node)
+	}
+	o.log("rootless").V(2).Infof("rootless docker of user %s is reachable by %s", o.SSHConfig.User, host)
+	return nil
+}
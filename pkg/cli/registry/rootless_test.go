@@ -0,0 +1,96 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// userCmd returns cmd as run by runDockerCmd for rootless docker.
+func userCmd(cmd string) string {
+	return fmt.Sprintf(`export DOCKER_HOST="%s"; %s`, rootlessDockerHost, cmd)
+}
+
+func TestInstallRegistry_Rootless(t *testing.T) {
+	inspect := userCmd("docker inspect -f '{{.Config.Image}} {{.State.Running}}' registry")
+	sudoRunner := &fakeRunner{}
+	userRunner := &fakeRunner{failures: map[string]string{inspect: "Error: No such object: registry"}}
+	o := newFakeOptions(sudoRunner)
+	o.userCmdRunner = userRunner.run
+	o.Rootless = true
+	o.SSHConfig.User = "kc"
+	if err := o.installRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"gzip -df /tmp/kc/registry/v2/amd64/images.tar.gz",
+		"mkdir -p /opt/registry && chown kc /opt/registry",
+	}
+	if strings.Join(sudoRunner.cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected sudo commands %v, got %v", expected, sudoRunner.cmds)
+	}
+	expected = []string{
+		inspect,
+		userCmd("docker load -i /tmp/kc/registry/v2/amd64/images.tar"),
+		userCmd("docker image inspect -f '{{.Id}}' registry:2"),
+		userCmd(o.runRegistryCmd()),
+	}
+	if strings.Join(userRunner.cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected docker commands as ssh user %v, got %v", expected, userRunner.cmds)
+	}
+}
+
+func TestCheckRootless(t *testing.T) {
+	check := userCmd(`echo "$DOCKER_HOST" && docker info -f '{{.SecurityOptions}}'`)
+	tests := []struct {
+		name    string
+		user    string
+		output  string
+		failure string
+		message string
+	}{
+		{name: "rootless", user: "kc", output: "unix:///run/user/1000/docker.sock\n[name=seccomp,profile=default name=rootless name=cgroupns]\n"},
+		{name: "root user", user: "root", message: "non-root ssh user"},
+		{name: "tcp host", user: "kc", output: "tcp://10.0.0.111:2375\n", message: "is not a unix socket"},
+		{name: "not running", user: "kc", output: "unix:///run/user/1000/docker.sock\n", failure: "Cannot connect to the Docker daemon", message: "is not reachable"},
+		{name: "rootful", user: "kc", output: "unix:///var/run/docker.sock\n[name=seccomp,profile=default]\n", message: "is not rootless"},
+	}
+	for _, tt := range tests {
+		userRunner := &fakeRunner{outputs: map[string]string{check: tt.output}}
+		if tt.failure != "" {
+			userRunner.failures = map[string]string{check: tt.failure}
+		}
+		o := newFakeOptions(&fakeRunner{})
+		o.userCmdRunner = userRunner.run
+		o.Rootless = true
+		o.SSHConfig.User = tt.user
+		err := o.checkRootless()
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
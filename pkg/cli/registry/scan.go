@@ -0,0 +1,95 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	// ScanStatusPassed means the scan command exited 0 for the pushed image.
+	ScanStatusPassed = "passed"
+	// ScanStatusFailed means the scan command exited non-zero, or could not be rendered, for the pushed image.
+	ScanStatusFailed = "failed"
+)
+
+// scanData is what --scan-cmd is rendered with, Image is the pushed reference and Digest its manifest digest.
+type scanData struct {
+	Image  string
+	Digest string
+}
+
+func (o *RegistryOptions) validateScan() error {
+	if o.ScanCmd == "" {
+		if o.ScanReportOnly {
+			return fmt.Errorf("--scan-report-only requires --scan-cmd")
+		}
+		return nil
+	}
+	if o.Estimate {
+		return fmt.Errorf("--scan-cmd can not be used with --estimate, nothing is pushed")
+	}
+	if o.NoPush {
+		return fmt.Errorf("--scan-cmd can not be used with --no-push, nothing is pushed")
+	}
+	_, err := o.scanCmd(scanData{Image: "registry.example.com/library/example:latest"})
+	return err
+}
+
+// scanCmd renders --scan-cmd for the pushed image.
+func (o *RegistryOptions) scanCmd(data scanData) (string, error) {
+	tmpl, err := template.New("scan-cmd").Option("missingkey=error").Parse(o.ScanCmd)
+	if err != nil {
+		return "", fmt.Errorf("invalid --scan-cmd: %w", err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid --scan-cmd: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// scanImages runs --scan-cmd on node for every image pushed in report, and records its outcome in the report.
+// A non-zero exit code fails the scan of the image, an error of ssh aborts.
+func (o *RegistryOptions) scanImages(report *PushReport) error {
+	for i := range report.Items {
+		item := &report.Items[i]
+		if item.Status != PushStatusPushed {
+			continue
+		}
+		cmd, err := o.scanCmd(scanData{Image: item.Target, Digest: item.Digest})
+		if err != nil {
+			item.Scan, item.ScanError = ScanStatusFailed, err.Error()
+			continue
+		}
+		ret, err := o.runCmd(cmd)
+		if err != nil {
+			return err
+		}
+		if err = ret.Error(); err != nil {
+			o.log("scan").Warnf("image %s failed the scan: %s", item.Target, err.Error())
+			item.Scan, item.ScanError = ScanStatusFailed, err.Error()
+			continue
+		}
+		o.log("scan").V(2).Infof("image %s passed the scan", item.Target)
+		item.Scan = ScanStatusPassed
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		scanCmd    string
+		reportOnly bool
+		estimate   bool
+		wantErr    string
+	}{
+		{name: "no scan"},
+		{name: "scan", scanCmd: "trivy image --exit-code 1 {{.Image}}"},
+		{name: "scan by digest", scanCmd: "scan {{.Image}}@{{.Digest}}", reportOnly: true},
+		{name: "report only without scan", reportOnly: true, wantErr: "--scan-report-only requires --scan-cmd"},
+		{name: "estimate", scanCmd: "trivy image {{.Image}}", estimate: true, wantErr: "--estimate"},
+		{name: "invalid template", scanCmd: "trivy image {{.Image", wantErr: "invalid --scan-cmd"},
+		{name: "unknown field", scanCmd: "trivy image {{.Reference}}", wantErr: "invalid --scan-cmd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newFakeOptions(&fakeRunner{})
+			o.ScanCmd, o.ScanReportOnly, o.Estimate = tt.scanCmd, tt.reportOnly, tt.estimate
+			err := o.validateScan()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPush_Scan(t *testing.T) {
+	for _, reportOnly := range []bool{false, true} {
+		runner := &fakeRunner{
+			outputs: map[string]string{
+				`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n" +
+					"k8s.gcr.io/etcd 3.4.13-0 0369cf4303ff\n",
+			},
+			failures: map[string]string{
+				"trivy image 10.0.0.111:5000/etcd:3.4.13-0": "Total: 1 (CRITICAL: 1)",
+			},
+		}
+		o := newFakeOptions(runner)
+		out := &bytes.Buffer{}
+		o.IOStreams.Out = out
+		o.KeepLocalImages = true
+		o.ScanCmd = "trivy image {{.Image}}"
+		o.ScanReportOnly = reportOnly
+		cmd := &cobra.Command{}
+		o.PrintFlags.AddFlags(cmd)
+		if err := cmd.Flags().Set("output", "json"); err != nil {
+			t.Fatal(err)
+		}
+		err := o.push()
+		if reportOnly && err != nil {
+			t.Errorf("expected failed scan ignored with --scan-report-only, got %v", err)
+		}
+		if !reportOnly && (err == nil || !strings.Contains(err.Error(), "1 pushed images failed the scan")) {
+			t.Errorf("expected push error when an image failed the scan, got %v", err)
+		}
+		report := &PushReport{}
+		if err = json.Unmarshal(out.Bytes(), report); err != nil {
+			t.Fatalf("unmarshal push report %q: %v", out.String(), err)
+		}
+		expected := map[string]string{
+			"10.0.0.111:5000/pause:3.2":     ScanStatusPassed,
+			"10.0.0.111:5000/etcd:3.4.13-0": ScanStatusFailed,
+		}
+		for _, item := range report.Items {
+			if item.Scan != expected[item.Target] {
+				t.Errorf("expected scan of %s to be %s, got %s", item.Target, expected[item.Target], item.Scan)
+			}
+			if item.Scan == ScanStatusFailed && !strings.Contains(item.ScanError, "CRITICAL") {
+				t.Errorf("expected scan error of %s recorded, got %q", item.Target, item.ScanError)
+			}
+		}
+		if !hasCmd(runner.cmds, "trivy image 10.0.0.111:5000/pause:3.2") {
+			t.Errorf("expected pushed images scanned, got %v", runner.cmds)
+		}
+	}
+}
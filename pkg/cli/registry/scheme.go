@@ -19,6 +19,10 @@
 package registry
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/kubeclipper/kubeclipper/pkg/cli/printer"
 )
 
@@ -48,7 +52,72 @@ func (i *Image) TablePrint() ([]string, [][]string) {
 	return headers, data
 }
 
+// PlatformManifest is the image manifest of a platform.
+type PlatformManifest struct {
+	Digest       string `json:"digest" yaml:"digest"`
+	MediaType    string `json:"mediaType" yaml:"mediaType"`
+	Architecture string `json:"architecture" yaml:"architecture"`
+	OS           string `json:"os" yaml:"os"`
+	Variant      string `json:"variant,omitempty" yaml:"variant,omitempty"`
+}
+
+// Platform returns the platform as 'os/architecture[/variant]'.
+func (p PlatformManifest) Platform() string {
+	platform := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		platform += "/" + p.Variant
+	}
+	return platform
+}
+
+// ImageDetail is a tag with the manifests of the platforms it supports,
+// a single platform image has one manifest, which is the tag itself. Created is the creation time of the image,
+// the latest of the platform images of a manifest list.
+type ImageDetail struct {
+	Tag       string             `json:"tag" yaml:"tag"`
+	Digest    string             `json:"digest" yaml:"digest"`
+	MediaType string             `json:"mediaType" yaml:"mediaType"`
+	Created   *time.Time         `json:"created,omitempty" yaml:"created,omitempty"`
+	Manifests []PlatformManifest `json:"manifests" yaml:"manifests"`
+}
+
+type ImageDetails struct {
+	Name  string        `json:"name" yaml:"name"`
+	Items []ImageDetail `json:"items" yaml:"items"`
+}
+
+func (i *ImageDetails) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(i)
+}
+
+func (i *ImageDetails) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(i)
+}
+
+func (i *ImageDetails) TablePrint() ([]string, [][]string) {
+	headers := []string{"name", "tag", "digest", "created", "platform", "platform digest"}
+	var data [][]string
+	for _, v := range i.Items {
+		created := ""
+		if v.Created != nil {
+			created = v.Created.Format(time.RFC3339)
+		}
+		for index, m := range v.Manifests {
+			if index == 0 {
+				data = append(data, []string{i.Name, v.Tag, v.Digest, created, m.Platform(), m.Digest})
+			} else {
+				data = append(data, []string{"", "", "", "", m.Platform(), m.Digest})
+			}
+		}
+	}
+	return headers, data
+}
+
 type Repositories struct {
+	// Total is the number of returned repositories.
+	Total int `json:"total" yaml:"total"`
+	// Truncated is true when more repositories exist beyond the --number limit.
+	Truncated    bool     `json:"truncated" yaml:"truncated"`
 	Repositories []string `json:"repositories" yaml:"repositories"`
 }
 
@@ -68,3 +137,416 @@ func (i *Repositories) TablePrint() ([]string, [][]string) {
 	}
 	return headers, data
 }
+
+// RepositoryEntry is a line of 'list --type repository -o jsonl'.
+type RepositoryEntry struct {
+	Repository string `json:"repository"`
+}
+
+// TagEntry is a line of 'list --type image -o jsonl'.
+type TagEntry struct {
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+type VerifyResult struct {
+	Present []string `json:"present" yaml:"present"`
+	Missing []string `json:"missing" yaml:"missing"`
+}
+
+func (v *VerifyResult) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(v)
+}
+
+func (v *VerifyResult) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(v)
+}
+
+func (v *VerifyResult) TablePrint() ([]string, [][]string) {
+	headers := []string{"image", "status"}
+	var data [][]string
+	for _, image := range v.Missing {
+		data = append(data, []string{image, "missing"})
+	}
+	for _, image := range v.Present {
+		data = append(data, []string{image, "present"})
+	}
+	return headers, data
+}
+
+type TagCount struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Count      int    `json:"count" yaml:"count"`
+}
+
+type TagCounts struct {
+	Items []TagCount `json:"items" yaml:"items"`
+}
+
+func (t *TagCounts) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(t)
+}
+
+func (t *TagCounts) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(t)
+}
+
+func (t *TagCounts) TablePrint() ([]string, [][]string) {
+	headers := []string{"repository", "tags"}
+	var data [][]string
+	for _, v := range t.Items {
+		data = append(data, []string{v.Repository, strconv.Itoa(v.Count)})
+	}
+	return headers, data
+}
+
+// BlobUsageItem is a blob in the registry volume with what references it.
+type BlobUsageItem struct {
+	Digest string `json:"digest" yaml:"digest"`
+	Size   int64  `json:"size" yaml:"size"`
+	// Repositories link the blob as a layer or manifest, Tags are 'repo:tag' whose image contains it.
+	Repositories []string `json:"repositories" yaml:"repositories"`
+	Tags         []string `json:"tags" yaml:"tags"`
+	// Orphaned blobs are referenced by no repository, garbage-collect reclaims them.
+	Orphaned bool `json:"orphaned" yaml:"orphaned"`
+}
+
+type BlobUsage struct {
+	Items []BlobUsageItem `json:"items" yaml:"items"`
+}
+
+func (b *BlobUsage) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(b)
+}
+
+func (b *BlobUsage) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(b)
+}
+
+func (b *BlobUsage) TablePrint() ([]string, [][]string) {
+	headers := []string{"digest", "size", "repositories", "tags"}
+	var data [][]string
+	for _, v := range b.Items {
+		repositories := strings.Join(v.Repositories, ",")
+		if v.Orphaned {
+			repositories = "<orphaned>"
+		}
+		data = append(data, []string{v.Digest, humanBytes(v.Size), repositories, strings.Join(v.Tags, ",")})
+	}
+	return headers, data
+}
+
+const (
+	PushStatusTagged = "tagged"
+	PushStatusPushed = "pushed"
+	PushStatusFailed = "failed"
+	// PushStatusSkipped means the target is in registry with the same image already, see --skip-existing.
+	PushStatusSkipped = "skipped"
+)
+
+type PushResult struct {
+	Image  string `json:"image" yaml:"image"`
+	Target string `json:"target" yaml:"target"`
+	// Arch of the image pushed with --all-arch, or the comma separated arches of a manifest list.
+	Arch string `json:"arch,omitempty" yaml:"arch,omitempty"`
+	// Status is the last step reached, one of tagged, pushed or failed, or skipped.
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Digest and Size of the pushed manifest, reported by docker push.
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Size   int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	// Scan is passed or failed by the exit code of --scan-cmd, empty if the image was not scanned.
+	Scan      string `json:"scan,omitempty" yaml:"scan,omitempty"`
+	ScanError string `json:"scanError,omitempty" yaml:"scanError,omitempty"`
+}
+
+type PushReport struct {
+	Items []PushResult `json:"items" yaml:"items"`
+	// Arches found in the package and processed with --all-arch.
+	Arches []string `json:"arches,omitempty" yaml:"arches,omitempty"`
+}
+
+// Failed returns the number of images failed to push.
+func (r *PushReport) Failed() int {
+	var count int
+	for _, v := range r.Items {
+		if v.Status == PushStatusFailed {
+			count++
+		}
+	}
+	return count
+}
+
+// ScanFailed returns the number of pushed images failed the scan of --scan-cmd.
+func (r *PushReport) ScanFailed() int {
+	var count int
+	for _, v := range r.Items {
+		if v.Scan == ScanStatusFailed {
+			count++
+		}
+	}
+	return count
+}
+
+// Skipped returns the number of images skipped as existing.
+func (r *PushReport) Skipped() int {
+	var count int
+	for _, v := range r.Items {
+		if v.Status == PushStatusSkipped {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *PushReport) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(r)
+}
+
+func (r *PushReport) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(r)
+}
+
+func (r *PushReport) TablePrint() ([]string, [][]string) {
+	headers := []string{"image", "target", "arch", "status", "scan", "error"}
+	var data [][]string
+	for _, v := range r.Items {
+		msg := v.Error
+		if msg == "" {
+			msg = v.ScanError
+		}
+		data = append(data, []string{v.Image, v.Target, v.Arch, v.Status, v.Scan, msg})
+	}
+	return headers, data
+}
+
+const (
+	WarmupStatusPulled = "pulled"
+	WarmupStatusFailed = "failed"
+)
+
+type WarmupResult struct {
+	Image string `json:"image" yaml:"image"`
+	// Status is one of pulled or failed.
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type WarmupReport struct {
+	Items []WarmupResult `json:"items" yaml:"items"`
+}
+
+// Failed returns the number of images failed to pull.
+func (r *WarmupReport) Failed() int {
+	var count int
+	for _, v := range r.Items {
+		if v.Status == WarmupStatusFailed {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *WarmupReport) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(r)
+}
+
+func (r *WarmupReport) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(r)
+}
+
+func (r *WarmupReport) TablePrint() ([]string, [][]string) {
+	headers := []string{"image", "status", "error"}
+	var data [][]string
+	for _, v := range r.Items {
+		data = append(data, []string{v.Image, v.Status, v.Error})
+	}
+	return headers, data
+}
+
+// TagDeletion is the outcome of deleting the tags of a repository matching --tag-pattern.
+type TagDeletion struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Matched    int    `json:"matched" yaml:"matched"`
+	Deleted    int    `json:"deleted" yaml:"deleted"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type TagDeletionReport struct {
+	Items []TagDeletion `json:"items" yaml:"items"`
+}
+
+// Failed returns the number of repositories with tags failed to delete.
+func (r *TagDeletionReport) Failed() int {
+	var count int
+	for _, v := range r.Items {
+		if v.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *TagDeletionReport) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(r)
+}
+
+func (r *TagDeletionReport) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(r)
+}
+
+func (r *TagDeletionReport) TablePrint() ([]string, [][]string) {
+	headers := []string{"repository", "matched", "deleted", "error"}
+	var data [][]string
+	for _, v := range r.Items {
+		data = append(data, []string{v.Repository, strconv.Itoa(v.Matched), strconv.Itoa(v.Deleted), v.Error})
+	}
+	return headers, data
+}
+
+// DeliveredImage is an image in registry delivered by push.
+type DeliveredImage struct {
+	// Image is the 'repo:tag' in registry.
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// DeliveryManifest lists the images a push delivered, written by --manifest-out.
+type DeliveryManifest struct {
+	Registry string           `json:"registry"`
+	Images   []DeliveredImage `json:"images"`
+}
+
+type PushEstimateItem struct {
+	Image  string `json:"image" yaml:"image"`
+	Target string `json:"target" yaml:"target"`
+	Layers int    `json:"layers" yaml:"layers"`
+	// NewLayers is the number of layers which are neither in registry nor pushed by a previous item.
+	NewLayers  int   `json:"newLayers" yaml:"newLayers"`
+	TotalBytes int64 `json:"totalBytes" yaml:"totalBytes"`
+	NewBytes   int64 `json:"newBytes" yaml:"newBytes"`
+}
+
+// PushEstimate reports the layer bytes a push would transfer, sizes are uncompressed
+// and the totals count every shared layer once.
+type PushEstimate struct {
+	Items      []PushEstimateItem `json:"items" yaml:"items"`
+	TotalBytes int64              `json:"totalBytes" yaml:"totalBytes"`
+	NewBytes   int64              `json:"newBytes" yaml:"newBytes"`
+}
+
+func (e *PushEstimate) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(e)
+}
+
+func (e *PushEstimate) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(e)
+}
+
+func (e *PushEstimate) TablePrint() ([]string, [][]string) {
+	headers := []string{"image", "target", "layers", "new layers", "size", "new size"}
+	var data [][]string
+	for _, v := range e.Items {
+		data = append(data, []string{v.Image, v.Target, strconv.Itoa(v.Layers), strconv.Itoa(v.NewLayers),
+			humanBytes(v.TotalBytes), humanBytes(v.NewBytes)})
+	}
+	data = append(data, []string{"TOTAL", "", "", "", humanBytes(e.TotalBytes), humanBytes(e.NewBytes)})
+	return headers, data
+}
+
+type StepTiming struct {
+	Step    string  `json:"step" yaml:"step"`
+	Seconds float64 `json:"seconds" yaml:"seconds"`
+}
+
+// DeployTiming is the duration of each deploy step, a failed step is the last one.
+type DeployTiming struct {
+	Steps        []StepTiming `json:"steps" yaml:"steps"`
+	TotalSeconds float64      `json:"totalSeconds" yaml:"totalSeconds"`
+}
+
+// Add records the duration of step.
+func (d *DeployTiming) Add(step string, duration time.Duration) {
+	d.Steps = append(d.Steps, StepTiming{Step: step, Seconds: duration.Seconds()})
+	d.TotalSeconds += duration.Seconds()
+}
+
+func (d *DeployTiming) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(d)
+}
+
+func (d *DeployTiming) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(d)
+}
+
+func (d *DeployTiming) TablePrint() ([]string, [][]string) {
+	headers := []string{"step", "duration"}
+	var data [][]string
+	for _, v := range d.Steps {
+		data = append(data, []string{v.Step, humanSeconds(v.Seconds)})
+	}
+	data = append(data, []string{"TOTAL", humanSeconds(d.TotalSeconds)})
+	return headers, data
+}
+
+// humanSeconds formats seconds as a duration rounded to 0.1s, e.g. 1m2.3s.
+func humanSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(100 * time.Millisecond).String()
+}
+
+// humanBytes formats n in binary units, e.g. 1.5MiB.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + string("KMGTPE"[exp]) + "iB"
+}
+
+type NodeStatus struct {
+	Node       string `json:"node" yaml:"node"`
+	Running    bool   `json:"running" yaml:"running"`
+	Version    string `json:"version" yaml:"version"`
+	VolumeSize string `json:"volumeSize" yaml:"volumeSize"`
+	Healthy    bool   `json:"healthy" yaml:"healthy"`
+	// Message describes why the node is unhealthy.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+type RegistryStatus struct {
+	Items []NodeStatus `json:"items" yaml:"items"`
+}
+
+// Unhealthy returns the number of unhealthy nodes.
+func (r *RegistryStatus) Unhealthy() int {
+	var count int
+	for _, v := range r.Items {
+		if !v.Healthy {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *RegistryStatus) JSONPrint() ([]byte, error) {
+	return printer.JSONPrinter(r)
+}
+
+func (r *RegistryStatus) YAMLPrint() ([]byte, error) {
+	return printer.YAMLPrinter(r)
+}
+
+func (r *RegistryStatus) TablePrint() ([]string, [][]string) {
+	headers := []string{"node", "running", "version", "volume size", "healthy", "message"}
+	var data [][]string
+	for _, v := range r.Items {
+		data = append(data, []string{v.Node, strconv.FormatBool(v.Running), v.Version, v.VolumeSize, strconv.FormatBool(v.Healthy), v.Message})
+	}
+	return headers, data
+}
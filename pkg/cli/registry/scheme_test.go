@@ -20,7 +20,9 @@ package registry
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -60,3 +62,34 @@ func TestRepository_Printer(t *testing.T) {
 	cmd.Flags().Set("output", "yaml")
 	p.Print(repos, os.Stdout)
 }
+
+func TestVerifyResult_Printer(t *testing.T) {
+	result := &VerifyResult{
+		Present: []string{"calico/cni:v3.21.2"},
+		Missing: []string{"pause:3.2"},
+	}
+	cmd := &cobra.Command{}
+	p := printer.NewPrintFlags()
+	p.AddFlags(cmd)
+	cmd.Flags().Set("output", "table")
+	p.Print(result, os.Stdout)
+	cmd.Flags().Set("output", "json")
+	p.Print(result, os.Stdout)
+	cmd.Flags().Set("output", "yaml")
+	p.Print(result, os.Stdout)
+}
+
+func TestDeployTiming(t *testing.T) {
+	timing := &DeployTiming{}
+	timing.Add("load images", 3*time.Minute+12340*time.Millisecond)
+	timing.Add("push images", 8*time.Minute+5*time.Second)
+	_, data := timing.TablePrint()
+	expected := [][]string{
+		{"load images", "3m12.3s"},
+		{"push images", "8m5s"},
+		{"TOTAL", "11m17.3s"},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
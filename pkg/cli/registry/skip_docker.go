@@ -0,0 +1,50 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (o *RegistryOptions) validateSkipDockerInstall() error {
+	if !o.SkipDockerInstall {
+		return nil
+	}
+	if o.DockerHost != "" {
+		return fmt.Errorf("--skip-docker-install can not be used with --docker-host, docker is never installed with it")
+	}
+	return nil
+}
+
+// checkPreinstalledDocker checks docker on node works with --skip-docker-install, which is neither installed
+// nor configured by deploy then.
+func (o *RegistryOptions) checkPreinstalledDocker() error {
+	ret, err := o.runDockerCmd("docker info -f '{{.ServerVersion}}'")
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("--skip-docker-install is set, but docker on %s is not working: %s, install docker or deploy without --skip-docker-install",
+			o.Node, strings.TrimSpace(ret.Stderr))
+	}
+	o.log("docker").V(2).Infof("docker of version %s is preinstalled", strings.TrimSpace(ret.Stdout))
+	o.log("docker").Warnf("daemon.json of docker on %s is not managed with --skip-docker-install, make sure it trusts registry %s, e.g. by insecure-registries",
+		o.Node, o.publishedRegistry())
+	return nil
+}
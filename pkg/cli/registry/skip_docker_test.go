@@ -0,0 +1,75 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInstallSteps_SkipDockerInstall(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.SkipDockerInstall = true
+	for _, step := range o.installSteps() {
+		if step.name == "install docker" || step.name == "install push CA" {
+			t.Errorf("unexpected step %q with --skip-docker-install", step.name)
+		}
+	}
+	o.PushCAFile = "ca.crt"
+	var found bool
+	for _, step := range o.installSteps() {
+		if step.name == "install docker" {
+			t.Errorf("unexpected step %q with --skip-docker-install", step.name)
+		}
+		found = found || step.name == "install push CA"
+	}
+	if !found {
+		t.Error("expected --push-ca-file installed with --skip-docker-install")
+	}
+}
+
+func TestCheckPreinstalledDocker(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{"docker info -f '{{.ServerVersion}}'": "20.10.21\n"}}
+	o := newFakeOptions(runner)
+	o.SkipDockerInstall = true
+	if err := o.checkPreinstalledDocker(); err != nil {
+		t.Fatalf("unexpected error with docker working: %v", err)
+	}
+	runner.failures = map[string]string{"docker info -f '{{.ServerVersion}}'": "sudo: docker: command not found"}
+	err := o.checkPreinstalledDocker()
+	if err == nil || !strings.Contains(err.Error(), "docker on 10.0.0.111 is not working: sudo: docker: command not found") {
+		t.Errorf("expected error of docker not working, got %v", err)
+	}
+	for _, cmd := range runner.cmds {
+		if strings.Contains(cmd, "daemon.json") || strings.Contains(cmd, "systemctl") {
+			t.Errorf("unexpected command changing docker: %s", cmd)
+		}
+	}
+}
+
+func TestValidateSkipDockerInstall(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.SkipDockerInstall = true
+	if err := o.validateSkipDockerInstall(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	o.DockerHost = "tcp://10.0.0.5:2375"
+	if err := o.validateSkipDockerInstall(); err == nil || !strings.Contains(err.Error(), "--docker-host") {
+		t.Errorf("expected --skip-docker-install conflicts with --docker-host, got %v", err)
+	}
+}
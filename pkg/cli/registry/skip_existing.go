@@ -0,0 +1,77 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// imageManifestTypes is the 'Accept' header of reading the manifest of a pushed image.
+var imageManifestTypes = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}, ",")
+
+// skipExisting returns a skipped PushResult if target is in registry with the same image already.
+// The tag is checked by 'HEAD /v2/<name>/manifests/<tag>' first, so that a missing tag costs one request only.
+// A failed check is logged and the image is pushed.
+func (o *RegistryOptions) skipExisting(image localImage, target string) (PushResult, bool) {
+	name, tag := splitImageRef(strings.TrimPrefix(target, o.pushRegistry()+"/"))
+	exists, err := o.manifestExists(name, tag)
+	if err == nil && exists {
+		var digest string
+		var size int64
+		if digest, size, exists, err = o.sameImage(name, tag, image.ID); err == nil && exists {
+			o.log("push-image").V(2).Infof("image %s exists with digest %s, skipped", target, digest)
+			return PushResult{Image: image.Ref(), Target: target, Status: PushStatusSkipped, Digest: digest, Size: size}, true
+		}
+	}
+	if err != nil {
+		o.log("push-image").V(2).Warnf("check image %s existing error: %s, push it", target, err.Error())
+	}
+	return PushResult{}, false
+}
+
+// sameImage reports whether the manifest of name:tag refers to the image of id by its config digest,
+// the digest and size of the manifest are returned as well. A manifest list is never the same.
+func (o *RegistryOptions) sameImage(name, tag, id string) (string, int64, bool, error) {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/manifests/%s", name, tag))
+	header := map[string]string{"Accept": imageManifestTypes}
+	resp, code, respHeader, respErr := o.request(url, "GET", o.requestHeader(header), nil)
+	if respErr != nil {
+		return "", 0, false, respErr
+	}
+	body, err := repositoryResponse(name, resp, code)
+	if err != nil {
+		return "", 0, false, err
+	}
+	m := new(manifest)
+	if err = json.Unmarshal(body, m); err != nil {
+		return "", 0, false, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Config == nil {
+		return "", 0, false, nil
+	}
+	// 'docker images' prints the short id, which is the prefix of the config digest
+	id = strings.TrimPrefix(id, "sha256:")
+	same := id != "" && strings.HasPrefix(strings.TrimPrefix(m.Config.Digest, "sha256:"), id)
+	return respHeader.Get("Docker-Content-Digest"), int64(len(body)), same, nil
+}
@@ -0,0 +1,100 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPush_SkipExisting(t *testing.T) {
+	manifests := map[string]string{
+		"/v2/pause/manifests/3.2":     "sha256:80d28bedfe5d" + strings.Repeat("0", 52),
+		"/v2/etcd/manifests/3.4.13-0": "sha256:" + strings.Repeat("f", 64),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config, ok := manifests[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		_, _ = fmt.Fprintf(w, `{"schemaVersion":2,"config":{"digest":"%s"},"layers":[]}`, config)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{
+		outputs: map[string]string{
+			`docker images --format '{{.Repository}} {{.Tag}} {{.ID}}'`: "k8s.gcr.io/pause 3.2 80d28bedfe5d\n" +
+				"k8s.gcr.io/etcd 3.4.13-0 0369cf4303ff\n" +
+				"k8s.gcr.io/coredns 1.8.0 296a6d5035e2\n",
+		},
+	}
+	o := newFakeOptions(runner)
+	o.IOStreams.Out = &bytes.Buffer{}
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.KeepLocalImages = true
+	o.SkipExisting = true
+	o.cacheDir = t.TempDir()
+	if err = o.push(); err != nil {
+		t.Fatal(err)
+	}
+	registry := o.pushRegistry()
+	var pushed []string
+	for _, cmd := range runner.cmds {
+		if strings.HasPrefix(cmd, "docker push ") {
+			pushed = append(pushed, strings.TrimPrefix(cmd, "docker push "))
+		}
+	}
+	want := []string{registry + "/etcd:3.4.13-0", registry + "/coredns:1.8.0"}
+	if !reflect.DeepEqual(pushed, want) {
+		t.Errorf("expected only changed and missing images pushed %v, got %v", want, pushed)
+	}
+}
+
+func TestValidateArgsPush_SkipExisting(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.SSHConfig.PkFile = "key"
+	o.Pkg = writePackage(t, nil)
+	o.SkipExisting = true
+	if err := o.ValidateArgsPush(); err != nil {
+		t.Fatal(err)
+	}
+	o.TargetRegistry = "harbor.local:443"
+	if err := o.ValidateArgsPush(); err == nil || !strings.Contains(err.Error(), "--skip-existing") {
+		t.Errorf("expected --skip-existing conflicts with --target-registry, got %v", err)
+	}
+	o.TargetRegistry = ""
+	o.Estimate = true
+	if err := o.ValidateArgsPush(); err == nil || !strings.Contains(err.Error(), "--skip-existing") {
+		t.Errorf("expected --skip-existing conflicts with --estimate, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/sudo"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/httputil"
+)
+
+const (
+	statusLongDescription = `
+  Show status of docker registry on each node.
+
+  The registry container state, port binding, volume usage and V2 API health are checked on every node,
+  the command exits non-zero if any node is unhealthy.`
+	statusExample = `
+  # Show status of docker registry nodes
+  kcctl registry status --pk-file key --nodes 10.0.0.111,10.0.0.112
+  # Show status of docker registry nodes as json
+  kcctl registry status --pk-file key --nodes 10.0.0.111,10.0.0.112 --registry-port 5000 -o json
+  # Show status of a TLS docker registry published on 127.0.0.1 only
+  kcctl registry status --pk-file key --nodes 10.0.0.111 --bind-address 127.0.0.1 --tls
+
+  Please read 'kcctl registry status -h' get more registry status flags.`
+)
+
+func NewCmdRegistryStatus(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "status (--pk-file <file path>) (--nodes <nodes>) (--registry-port <registry-port>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry status of nodes",
+		Long:                  statusLongDescription,
+		Example:               statusExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsStatus()))
+			if !sudo.PreCheck("sudo", o.SSHConfig, o.IOStreams, o.Nodes) {
+				return
+			}
+			o.checkErr(o.Status())
+		},
+	}
+	o.PrintFlags.AddFlags(cmd)
+	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	cmd.Flags().StringSliceVar(&o.Nodes, "nodes", o.Nodes, "registry nodes.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().StringVar(&o.RegistryVolume, "registry-volume", o.RegistryVolume, "set registry volume path")
+	cmd.Flags().StringVar(&o.RegistryName, "registry-name", o.RegistryName, "name of the registry container")
+	cmd.Flags().StringVar(&o.BindAddress, "bind-address", o.BindAddress, "address on node the registry port is published on, the V2 API is probed on it unless it is 0.0.0.0")
+	cmd.Flags().BoolVar(&o.TLS, "tls", o.TLS, "registry is served with TLS, the V2 API is probed by https")
+	cmd.Flags().BoolVar(&o.Rootless, "rootless", o.Rootless, "docker on node runs rootless as the ssh user, docker commands run without sudo")
+	cmd.Flags().StringVar(&o.DockerHost, "docker-host", o.DockerHost, "address of the external docker daemon the registry was deployed to by --docker-host")
+
+	utils.CheckErr(cmd.MarkFlagRequired("nodes"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsStatus() error {
+	if err := o.validateSSH(); err != nil {
+		return err
+	}
+	if len(o.Nodes) == 0 {
+		return fmt.Errorf("--nodes must be specified")
+	}
+	if err := validateBindAddress(o.BindAddress); err != nil {
+		return err
+	}
+	return validateRegistryName(o.RegistryName)
+}
+
+// Status checks registry of every node concurrently, and prints the consolidated status.
+func (o *RegistryOptions) Status() error {
+	status := &RegistryStatus{Items: make([]NodeStatus, len(o.Nodes))}
+	var wg sync.WaitGroup
+	for i, node := range o.Nodes {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			status.Items[i] = o.nodeStatus(node)
+		}(i, node)
+	}
+	wg.Wait()
+
+	if err := o.PrintFlags.Print(status, o.IOStreams.Out); err != nil {
+		return err
+	}
+	if unhealthy := status.Unhealthy(); unhealthy > 0 {
+		return fmt.Errorf("%d of %d registry nodes are unhealthy", unhealthy, len(status.Items))
+	}
+	return nil
+}
+
+// nodeStatus collects the registry status of node, check failures are recorded instead of returned.
+func (o *RegistryOptions) nodeStatus(node string) NodeStatus {
+	status := NodeStatus{Node: node}
+	var errs []string
+
+	ret, err := o.runDockerCmdOn(node, fmt.Sprintf("docker inspect -f '{{.State.Running}} {{.Config.Image}}' %s", o.RegistryName))
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil {
+		errs = append(errs, "container not found")
+	} else if fields := strings.Fields(ret.Stdout); len(fields) == 2 {
+		status.Running = fields[0] == "true"
+		status.Version = fields[1]
+	}
+
+	ret, err = o.runDockerCmdOn(node, fmt.Sprintf("docker port %s 5000/tcp", o.RegistryName))
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil || !strings.Contains(ret.Stdout, fmt.Sprintf(":%d", o.RegistryPort)) {
+		errs = append(errs, fmt.Sprintf("port %d not bound", o.RegistryPort))
+	}
+
+	ret, err = o.runCmdOn(node, fmt.Sprintf("du -sh %s | cut -f1", o.RegistryVolume))
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil {
+		o.logOn(node, "volume-size").V(2).Warnf("get registry volume size error: %s", err.Error())
+	} else {
+		status.VolumeSize = strings.TrimSpace(ret.Stdout)
+	}
+
+	if err = o.checkAPI(node); err != nil {
+		errs = append(errs, err.Error())
+	}
+	status.Healthy = status.Running && len(errs) == 0
+	status.Message = strings.Join(errs, "; ")
+	return status
+}
+
+// checkAPI checks the V2 API of registry on node at the address its port is published on.
+func (o *RegistryOptions) checkAPI(node string) error {
+	scheme := "http"
+	if o.TLS {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s/v2/", scheme, o.publishedRegistryOn(node))
+	_, code, err := httputil.CommonRequest(url, "GET", o.requestHeader(nil), nil, nil)
+	if err != nil {
+		return fmt.Errorf("V2 API unreachable")
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("V2 API returned %d", code)
+	}
+	return nil
+}
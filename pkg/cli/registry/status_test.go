@@ -0,0 +1,85 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNodeStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{outputs: map[string]string{
+		`docker inspect -f '{{.State.Running}} {{.Config.Image}}' registry`: "true registry:2\n",
+		"docker port registry 5000/tcp":                                     "0.0.0.0:" + port + "\n",
+		"du -sh /opt/registry | cut -f1":                                    "1.2G\n",
+	}}
+	o := newFakeOptions(runner)
+	o.RegistryPort, _ = strconv.Atoi(port)
+
+	status := o.nodeStatus(host)
+	expected := NodeStatus{Node: host, Running: true, Version: "registry:2", VolumeSize: "1.2G", Healthy: true}
+	if status != expected {
+		t.Errorf("expected %+v, got %+v", expected, status)
+	}
+
+	runner.failures = map[string]string{"docker port registry 5000/tcp": "No such container: registry"}
+	if status = o.nodeStatus(host); status.Healthy {
+		t.Errorf("expected unhealthy when port is not bound, got %+v", status)
+	}
+}
+
+func TestNodeStatus_PublishedRegistry(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{outputs: map[string]string{
+		`docker -H 'tcp://10.0.0.5:2375' inspect -f '{{.State.Running}} {{.Config.Image}}' registry`: "true registry:2\n",
+		"docker -H 'tcp://10.0.0.5:2375' port registry 5000/tcp":                                     host + ":" + port + "\n",
+	}}
+	o := newFakeOptions(runner)
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.BindAddress = host
+	o.TLS = true
+	o.DockerHost = "tcp://10.0.0.5:2375"
+
+	// the registry is published on the bind address only, not on the address of node
+	if status := o.nodeStatus("10.0.0.111"); !status.Healthy || !status.Running {
+		t.Errorf("expected healthy registry probed by https on %s, got %+v", host, status)
+	}
+	o.TLS = false
+	if status := o.nodeStatus("10.0.0.111"); status.Healthy {
+		t.Errorf("expected unhealthy when TLS registry is probed by http, got %+v", status)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/printer"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/httputil"
+)
+
+// defaultPageSize is the number of entries requested in each page when streaming without --number.
+const defaultPageSize = 1000
+
+// streamRepositories writes one RepositoryEntry per line as the catalog pages are fetched.
+func (o *RegistryOptions) streamRepositories() error {
+	url := o.apiURL("/v2/_catalog")
+	return o.fetchPages(url, httputil.CodeDispose, func(body []byte) error {
+		page := new(Repositories)
+		if err := json.Unmarshal(body, page); err != nil {
+			return err
+		}
+		for _, v := range page.Repositories {
+			if err := printer.PrintLine(RepositoryEntry{Repository: v}, o.IOStreams.Out); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// streamImages writes one TagEntry per line as the tag pages of o.Name are fetched.
+func (o *RegistryOptions) streamImages() error {
+	url := o.apiURL(fmt.Sprintf("/v2/%s/tags/list", o.Name))
+	check := func(body []byte, code int) ([]byte, error) {
+		return repositoryResponse(o.Name, body, code)
+	}
+	return o.fetchPages(url, check, func(body []byte) error {
+		page := new(Image)
+		if err := json.Unmarshal(body, page); err != nil {
+			return err
+		}
+		for _, v := range page.Tags {
+			if err := printer.PrintLine(TagEntry{Name: page.Name, Tag: v}, o.IOStreams.Out); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fetchPages requests url with page size of --number, and follows the 'Link' header
+// of each response to the next page until the last one. check converts a non-200 response to an error.
+func (o *RegistryOptions) fetchPages(url string, check func([]byte, int) ([]byte, error), handle func([]byte) error) error {
+	size := o.Number
+	if size == 0 {
+		size = defaultPageSize
+	}
+	params := map[string]string{"n": strconv.Itoa(size)}
+	for url != "" {
+		resp, code, header, respErr := o.request(url, "GET", o.requestHeader(nil), params)
+		if respErr != nil {
+			return respErr
+		}
+		body, codeErr := check(resp, code)
+		if codeErr != nil {
+			return codeErr
+		}
+		if err := handle(body); err != nil {
+			return err
+		}
+		// the link of next page carries its own query
+		url, params = o.nextPage(header.Get("Link")), nil
+	}
+	return nil
+}
+
+// nextPage returns the absolute url of a 'Link: </v2/_catalog?last=b&n=2>; rel="next"' header,
+// or empty if there is no next page.
+func (o *RegistryOptions) nextPage(link string) string {
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end <= start+1 || !strings.Contains(link[end:], `rel="next"`) {
+		return ""
+	}
+	next := link[start+1 : end]
+	if strings.HasPrefix(next, "http://") || strings.HasPrefix(next, "https://") {
+		return next
+	}
+	return o.apiURL(next)
+}
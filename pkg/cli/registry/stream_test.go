@@ -0,0 +1,84 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestStreamRepositories(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"repositories":["caas4/a","caas4/b"]}`,
+		"b": `{"repositories":["caas4/c"]}`,
+	}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if n := r.URL.Query().Get("n"); n != "2" {
+			t.Errorf("expected page size 2, got %q", n)
+		}
+		last := r.URL.Query().Get("last")
+		if last == "" {
+			w.Header().Set("Link", `</v2/_catalog?last=b&n=2>; rel="next"`)
+		}
+		_, _ = w.Write([]byte(pages[last]))
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newFakeOptions(&fakeRunner{})
+	o.Node = host
+	o.RegistryPort, _ = strconv.Atoi(port)
+	o.Number = 2
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	if err = o.streamRepositories(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"repository":"caas4/a"}` + "\n" + `{"repository":"caas4/b"}` + "\n" + `{"repository":"caas4/c"}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 page requests, got %d", requests)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.RegistryPort = 5000
+	tests := map[string]string{
+		"":                                      "",
+		`</v2/_catalog?last=b&n=2>; rel="next"`: "http://10.0.0.111:5000/v2/_catalog?last=b&n=2",
+		`<http://mirror:5000/v2/a/tags/list?last=v1>; rel="next"`: "http://mirror:5000/v2/a/tags/list?last=v1",
+		`</v2/_catalog?last=b>; rel="prev"`:                       "",
+	}
+	for link, expected := range tests {
+		if next := o.nextPage(link); next != expected {
+			t.Errorf("link %q: expected %q, got %q", link, expected, next)
+		}
+	}
+}
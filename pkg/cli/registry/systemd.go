@@ -0,0 +1,121 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	registryUnit     = "kc-registry"
+	registryUnitPath = "/etc/systemd/system/kc-registry.service"
+	// registryEnvPath is the docker env file of the registry container run by kc-registry.service,
+	// the values are read verbatim, so neither systemd nor a shell expands '$' or strips quotes in them.
+	registryEnvPath = "/etc/kc-registry.env"
+)
+
+// registryUnitContent returns the systemd unit of --systemd, which runs the registry container in foreground,
+// so that it starts after docker on boot and is restarted by systemd instead of docker.
+func (o *RegistryOptions) registryUnitContent() string {
+	return strings.Join([]string{
+		"[Unit]",
+		"Description=kubeclipper docker registry",
+		"After=docker.service",
+		"Requires=docker.service",
+		"",
+		"[Service]",
+		"ExecStartPre=-/usr/bin/env docker rm -f " + o.RegistryName,
+		"ExecStart=/usr/bin/env " + o.runRegistryCmd(),
+		"ExecStop=/usr/bin/env docker stop " + o.RegistryName,
+		"Restart=always",
+		"RestartSec=5",
+		"",
+		"[Install]",
+		"WantedBy=multi-user.target",
+	}, "\n")
+}
+
+// registryEnvContent returns the env file of the registry container, one 'KEY=VALUE' per line,
+// the cache settings come first so that an --env entry of the same key overrides them.
+func (o *RegistryOptions) registryEnvContent() string {
+	env := o.registryEnv()
+	if len(env) == 0 {
+		return ""
+	}
+	return strings.Join(env, "\n") + "\n"
+}
+
+// writeFileCmd returns the command writing content to path as is, it's base64 encoded on the way,
+// so that bash doesn't strip quotes or expand '$' in it.
+func writeFileCmd(content, path string) string {
+	return fmt.Sprintf("echo %s | base64 -d > %s", base64.StdEncoding.EncodeToString([]byte(content)), path)
+}
+
+// installRegistryUnit writes kc-registry.service along with its env file, and starts it.
+func (o *RegistryOptions) installRegistryUnit() error {
+	var cmdList []string
+	if env := o.registryEnvContent(); env != "" {
+		// the env file may hold secrets, e.g. the redis password
+		cmdList = append(cmdList, fmt.Sprintf("%s && chmod 600 %s", writeFileCmd(env, registryEnvPath), registryEnvPath))
+	}
+	cmdList = append(cmdList,
+		writeFileCmd(o.registryUnitContent(), registryUnitPath),
+		fmt.Sprintf("systemctl daemon-reload && systemctl enable --now %s", registryUnit),
+	)
+	for _, cmd := range cmdList {
+		ret, err := o.runCmd(cmd)
+		if err != nil {
+			return err
+		}
+		if err = ret.Error(); err != nil {
+			return err
+		}
+	}
+	o.log("install-registry").Infof("registry runs as systemd service %s, check it by 'systemctl status %s'", registryUnit, registryUnit)
+	return nil
+}
+
+// removeRegistryUnit stops and removes kc-registry.service if it exists, whether deploy used --systemd or not.
+// The container is removed along with the service, since it runs with '--rm'.
+func (o *RegistryOptions) removeRegistryUnit() (bool, error) {
+	exists, err := o.registryUnitExists()
+	if err != nil || !exists {
+		return false, err
+	}
+	ret, err := o.runCmd(fmt.Sprintf("systemctl disable --now %s && rm -f %s %s && systemctl daemon-reload", registryUnit, registryUnitPath, registryEnvPath))
+	if err != nil {
+		return false, err
+	}
+	if err = ret.Error(); err != nil {
+		return false, err
+	}
+	o.log("uninstall").Infof("systemd service %s is removed", registryUnit)
+	return true, nil
+}
+
+// registryUnitExists reports whether the registry is run by kc-registry.service.
+func (o *RegistryOptions) registryUnitExists() (bool, error) {
+	ret, err := o.runCmd(fmt.Sprintf("test -f %s", registryUnitPath))
+	if err != nil {
+		return false, err
+	}
+	return ret.ExitCode == 0, nil
+}
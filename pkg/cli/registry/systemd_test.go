@@ -0,0 +1,103 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestRegistryUnitContent(t *testing.T) {
+	o := newFakeOptions(&fakeRunner{})
+	o.Systemd = true
+	o.MemoryLimit = "512m"
+	unit := o.registryUnitContent()
+	expected := "ExecStart=/usr/bin/env docker run --rm -v /opt/registry:/var/lib/registry -p 5000:5000 --memory=512m --name registry registry:2\n"
+	if !strings.Contains(unit, expected) {
+		t.Errorf("expected unit containing %q, got %q", expected, unit)
+	}
+	if !strings.Contains(unit, "After=docker.service\n") || strings.Contains(unit, "--restart") {
+		t.Errorf("expected unit ordered after docker without docker restart policy, got %q", unit)
+	}
+}
+
+func TestUninstallSystemd(t *testing.T) {
+	const (
+		test    = "test -f /etc/systemd/system/kc-registry.service"
+		disable = "systemctl disable --now kc-registry && rm -f /etc/systemd/system/kc-registry.service /etc/kc-registry.env && systemctl daemon-reload"
+		stop    = "docker stop registry && docker rm registry"
+	)
+	tests := []struct {
+		name     string
+		failures map[string]string
+		disabled bool
+	}{
+		{name: "systemd", disabled: true},
+		{name: "docker restart policy", failures: map[string]string{test: ""}},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{failures: tt.failures}
+		o := newFakeOptions(runner)
+		if err := o.Uninstall(); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		cmds := strings.Join(runner.cmds, "\n")
+		if strings.Contains(cmds, disable) != tt.disabled || strings.Contains(cmds, stop) == tt.disabled {
+			t.Errorf("%s: expected service disabled %v, got commands %v", tt.name, tt.disabled, runner.cmds)
+		}
+	}
+}
+
+func TestInstallRegistryUnit_Env(t *testing.T) {
+	runner := &fakeRunner{}
+	o := newFakeOptions(runner)
+	o.Systemd = true
+	o.Cache, o.RedisAddr, o.RedisPassword = "redis", "10.0.0.5:6379", "pa$$word"
+	o.Env = []string{"REGISTRY_HTTP_SECRET=a b$c", "REGISTRY_LOG_LEVEL='debug'"}
+	if err := o.installRegistryUnit(); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, cmd := range runner.cmds {
+		if !strings.HasPrefix(cmd, "echo ") {
+			continue
+		}
+		encoded, path, _ := strings.Cut(strings.TrimPrefix(cmd, "echo "), " | base64 -d > ")
+		path, _, _ = strings.Cut(path, " ")
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("invalid base64 of %s: %v", path, err)
+		}
+		files[path] = string(data)
+	}
+	env := "REGISTRY_STORAGE_CACHE_BLOBDESCRIPTOR=redis\nREGISTRY_REDIS_ADDR=10.0.0.5:6379\nREGISTRY_REDIS_PASSWORD=pa$$word\n" +
+		"REGISTRY_HTTP_SECRET=a b$c\nREGISTRY_LOG_LEVEL='debug'\n"
+	if files[registryEnvPath] != env {
+		t.Errorf("expected env file %q, got %q", env, files[registryEnvPath])
+	}
+	unit := files[registryUnitPath]
+	if !strings.Contains(unit, " --env-file /etc/kc-registry.env --name registry registry:2\n") || strings.Contains(unit, " -e ") {
+		t.Errorf("expected unit passing env by the env file only, got %q", unit)
+	}
+	if unit != o.registryUnitContent() {
+		t.Errorf("expected unit written as is, got %q", unit)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// validateTargetRegistry validates --target-registry is 'host:port' and --target-auth is 'user:password'.
+func (o *RegistryOptions) validateTargetRegistry() error {
+	if o.TargetRegistry == "" {
+		if o.TargetAuth != "" {
+			return fmt.Errorf("--target-auth requires --target-registry")
+		}
+		return nil
+	}
+	if host, port, err := net.SplitHostPort(o.TargetRegistry); err != nil || host == "" || port == "" {
+		return fmt.Errorf("--target-registry %s is invalid, must be host:port", o.TargetRegistry)
+	}
+	if o.TargetAuth != "" {
+		if user, password, ok := strings.Cut(o.TargetAuth, ":"); !ok || user == "" || password == "" {
+			return fmt.Errorf("--target-auth must be user:password")
+		}
+	}
+	if o.Estimate {
+		return fmt.Errorf("--estimate can not be used with --target-registry, it checks the layers of the registry on --node")
+	}
+	if o.SkipExisting {
+		return fmt.Errorf("--skip-existing can not be used with --target-registry, it checks the tags of the registry on --node")
+	}
+	return nil
+}
+
+// checkTargetRegistry checks the target registry answers the registry API from node.
+func (o *RegistryOptions) checkTargetRegistry() error {
+	ok, err := o.registryReachable(o.TargetRegistry)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return unreachableError(fmt.Errorf("target registry %s is not reachable from %s, please check the address and the network of node", o.TargetRegistry, o.Node))
+	}
+	return nil
+}
+
+// registryReachable checks registry answers the registry API from node, by https first and then http.
+func (o *RegistryOptions) registryReachable(registry string) (bool, error) {
+	for _, scheme := range []string{"https", "http"} {
+		hook := fmt.Sprintf("curl -k -s -o /dev/null -w '%%{http_code}' --connect-timeout 5 %s://%s/v2/", scheme, registry)
+		// curl exits non-zero if the connection failed, the http code is checked only
+		ret, err := o.runCmd(hook)
+		if err != nil {
+			return false, err
+		}
+		// 401 means the registry requires authentication, it is reachable still
+		if code := strings.TrimSpace(ret.Stdout); code == "200" || code == "401" {
+			o.log("check-registry").V(2).Infof("registry %s answers %s over %s", registry, code, scheme)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// loginTarget logs docker on node in to the target registry with --target-auth,
+// the returned func logs out so that the credential is not left on node.
+func (o *RegistryOptions) loginTarget() (func(), error) {
+	user, password, _ := strings.Cut(o.TargetAuth, ":")
+	hook := fmt.Sprintf("echo %s | docker login %s -u %s --password-stdin", shellQuote(password), o.TargetRegistry, shellQuote(user))
+	ret, err := o.runDockerCmd(hook)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Error() != nil {
+		// the command carries the password, only stderr is reported
+		return nil, fmt.Errorf("login target registry %s as %s error: %s", o.TargetRegistry, user, strings.TrimSpace(ret.Stderr))
+	}
+	return func() {
+		ret, err := o.runDockerCmd("docker logout " + o.TargetRegistry)
+		if err == nil {
+			err = ret.Error()
+		}
+		if err != nil {
+			o.log("logout-target").Warnf("logout target registry %s error: %s", o.TargetRegistry, err.Error())
+		}
+	}, nil
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,62 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTargetRegistry(t *testing.T) {
+	const (
+		https = "curl -k -s -o /dev/null -w '%{http_code}' --connect-timeout 5 https://harbor.local:443/v2/"
+		http  = "curl -k -s -o /dev/null -w '%{http_code}' --connect-timeout 5 http://harbor.local:443/v2/"
+		login = "echo 'it'\\''s' | docker login harbor.local:443 -u 'admin' --password-stdin"
+	)
+	runner := &fakeRunner{
+		outputs:  map[string]string{https: "000", http: "401"},
+		failures: map[string]string{login: "unauthorized: incorrect username or password"},
+	}
+	o := newFakeOptions(runner)
+	o.TargetRegistry = "harbor.local:443"
+	o.TargetAuth = "admin:it's"
+	if err := o.validateTargetRegistry(); err != nil {
+		t.Fatal(err)
+	}
+	targets := o.retagTargets(localImage{Repository: "k8s.gcr.io/pause", Tag: "3.2"})
+	if len(targets) != 1 || targets[0] != "harbor.local:443/pause:3.2" {
+		t.Errorf("expected image re-tagged to target registry, got %v", targets)
+	}
+	if err := o.checkTargetRegistry(); err != nil {
+		t.Errorf("expected target registry answering 401 over http reachable, got %v", err)
+	}
+	_, err := o.loginTarget()
+	if err == nil || !strings.Contains(err.Error(), "incorrect username or password") || strings.Contains(err.Error(), "it's") {
+		t.Errorf("expected login error without password, got %v", err)
+	}
+
+	runner.outputs[http] = "000"
+	if err = o.checkTargetRegistry(); err == nil || !strings.Contains(err.Error(), "not reachable") {
+		t.Errorf("expected unreachable target registry, got %v", err)
+	}
+	o.TargetRegistry = "harbor.local"
+	if err = o.validateTargetRegistry(); err == nil {
+		t.Error("expected error of target registry without port")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// registryContainer is the part of 'docker inspect registry' the deploy settings are read from.
+type registryContainer struct {
+	Config struct {
+		Image string   `json:"Image"`
+		Env   []string `json:"Env"`
+	} `json:"Config"`
+	HostConfig struct {
+		PortBindings map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"PortBindings"`
+		RestartPolicy struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount int    `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+		Memory     int64    `json:"Memory"`
+		NanoCpus   int64    `json:"NanoCpus"`
+		ExtraHosts []string `json:"ExtraHosts"`
+	} `json:"HostConfig"`
+	Mounts []struct {
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+	} `json:"Mounts"`
+}
+
+// volume returns the host path mounted as /var/lib/registry of the container, empty if there is none.
+func (c *registryContainer) volume() string {
+	for _, m := range c.Mounts {
+		if m.Destination == "/var/lib/registry" {
+			return m.Source
+		}
+	}
+	return ""
+}
+
+// restartPolicy returns the restart policy of the container in the form of --restart-policy.
+func (c *registryContainer) restartPolicy() string {
+	policy := c.HostConfig.RestartPolicy.Name
+	if policy == "" {
+		policy = "no"
+	}
+	if policy == "on-failure" && c.HostConfig.RestartPolicy.MaximumRetryCount > 0 {
+		policy = fmt.Sprintf("%s:%d", policy, c.HostConfig.RestartPolicy.MaximumRetryCount)
+	}
+	return policy
+}
+
+// inspectContainer inspects the registry container name on node, where names node in the errors, e.g. "template node".
+func (o *RegistryOptions) inspectContainer(where, node, name string) (*registryContainer, error) {
+	ret, err := o.runDockerCmdOn(node, "docker inspect "+name)
+	if err != nil {
+		return nil, unreachableError(fmt.Errorf("%s %s is not reachable: %w", where, node, err))
+	}
+	if err = ret.Error(); err != nil {
+		return nil, fmt.Errorf("no registry container %s on %s %s: %s", name, where, node, strings.TrimSpace(ret.Stderr))
+	}
+	var containers []registryContainer
+	if err = json.Unmarshal([]byte(ret.Stdout), &containers); err != nil || len(containers) == 0 {
+		return nil, fmt.Errorf("invalid output of 'docker inspect %s' on %s %s", name, where, node)
+	}
+	c := &containers[0]
+	if !isRegistryImage(c.Config.Image) && c.Config.Image != o.RegistryImage {
+		return nil, fmt.Errorf("container %s on %s %s runs image %s, not a docker registry", name, where, node, c.Config.Image)
+	}
+	return c, nil
+}
+
+// inspectTemplate inspects the registry container --template-name running on --template-from.
+func (o *RegistryOptions) inspectTemplate() (*registryContainer, error) {
+	name := o.TemplateName
+	if name == "" {
+		name = o.RegistryName
+	}
+	c, err := o.inspectContainer("template node", o.TemplateFrom, name)
+	if err != nil {
+		return nil, err
+	}
+	// deploy runs registry with neither auth nor TLS, a mirror of such a registry would be inconsistent
+	for _, env := range c.Config.Env {
+		if strings.HasPrefix(env, "REGISTRY_AUTH") || strings.HasPrefix(env, "REGISTRY_HTTP_TLS") {
+			name, _, _ := strings.Cut(env, "=")
+			return nil, fmt.Errorf("registry on template node %s is configured with %s, auth and TLS can not be replicated by deploy", o.TemplateFrom, name)
+		}
+	}
+	return c, nil
+}
+
+// applyTemplate pre-fills the deploy options from the registry running on --template-from,
+// the flags set on command line win over the template.
+func (o *RegistryOptions) applyTemplate(flags *pflag.FlagSet) error {
+	c, err := o.inspectTemplate()
+	if err != nil {
+		return err
+	}
+	set := func(flag, value string, apply func()) {
+		if flags.Changed(flag) {
+			o.log("template").V(2).Infof("--%s is set, %s of template node %s is ignored", flag, value, o.TemplateFrom)
+			return
+		}
+		apply()
+		o.log("template").Infof("--%s %s from template node %s", flag, value, o.TemplateFrom)
+	}
+	if bindings := c.HostConfig.PortBindings["5000/tcp"]; len(bindings) > 0 {
+		binding := bindings[0]
+		if port, err := strconv.Atoi(binding.HostPort); err == nil {
+			set("registry-port", binding.HostPort, func() { o.RegistryPort = port })
+		}
+		if binding.HostIP != "" {
+			set("bind-address", binding.HostIP, func() { o.BindAddress = binding.HostIP })
+		}
+	}
+	if volume := c.volume(); volume != "" {
+		set("registry-volume", volume, func() { o.RegistryVolume = volume })
+	}
+	policy := c.restartPolicy()
+	set("restart-policy", policy, func() { o.RestartPolicy = policy })
+	if c.HostConfig.Memory > 0 {
+		memory := strconv.FormatInt(c.HostConfig.Memory, 10)
+		set("memory-limit", memory, func() { o.MemoryLimit = memory })
+	}
+	if c.HostConfig.NanoCpus > 0 {
+		cpus := float64(c.HostConfig.NanoCpus) / 1e9
+		set("cpu-limit", strconv.FormatFloat(cpus, 'f', -1, 64), func() { o.CPULimit = cpus })
+	}
+	return nil
+}
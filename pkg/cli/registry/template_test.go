@@ -0,0 +1,110 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	const inspect = `[{
+  "Config": {"Image": "registry:2", "Env": ["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"]},
+  "HostConfig": {
+    "PortBindings": {"5000/tcp": [{"HostIp": "127.0.0.1", "HostPort": "5001"}]},
+    "RestartPolicy": {"Name": "on-failure", "MaximumRetryCount": 3},
+    "Memory": 536870912,
+    "NanoCpus": 1500000000
+  },
+  "Mounts": [{"Source": "/data/registry", "Destination": "/var/lib/registry"}]
+}]`
+	runner := &fakeRunner{outputs: map[string]string{"docker inspect registry": inspect}}
+	o := newFakeOptions(runner)
+	o.TemplateFrom = "10.0.0.110"
+	cmd := NewCmdRegistryDeploy(o)
+	if err := cmd.Flags().Set("registry-volume", "/opt/mirror"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.applyTemplate(cmd.Flags()); err != nil {
+		t.Fatal(err)
+	}
+	if o.RegistryPort != 5001 || o.BindAddress != "127.0.0.1" {
+		t.Errorf("expected port 5001 bound on 127.0.0.1, got %d on %s", o.RegistryPort, o.BindAddress)
+	}
+	if o.RegistryVolume != "/opt/mirror" {
+		t.Errorf("expected --registry-volume set on command line kept, got %s", o.RegistryVolume)
+	}
+	if o.RestartPolicy != "on-failure:3" || o.MemoryLimit != "536870912" || o.CPULimit != 1.5 {
+		t.Errorf("expected restart policy and limits from template, got %s %s %v", o.RestartPolicy, o.MemoryLimit, o.CPULimit)
+	}
+	if err := validateRestartPolicy(o.RestartPolicy); err != nil {
+		t.Error(err)
+	}
+	if !memoryLimitRegexp.MatchString(o.MemoryLimit) {
+		t.Errorf("expected a valid memory limit, got %s", o.MemoryLimit)
+	}
+}
+
+func TestInspectTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		registryName string
+		templateName string
+		output       string
+		failure      string
+		message      string
+	}{
+		{name: "mirror image", output: `[{"Config": {"Image": "10.0.0.110:5000/library/registry:2.8"}}]`},
+		{name: "registry name", registryName: "mirror", output: `[{"Config": {"Image": "registry:2"}}]`},
+		{name: "template name", registryName: "mirror", templateName: "hub", output: `[{"Config": {"Image": "registry:2"}}]`},
+		{name: "no container", failure: "Error: No such object: registry", message: "no registry container"},
+		{name: "not registry", output: `[{"Config": {"Image": "nginx:1.25"}}]`, message: "not a docker registry"},
+		{name: "auth", output: `[{"Config": {"Image": "registry:2", "Env": ["REGISTRY_AUTH=htpasswd"]}}]`, message: "REGISTRY_AUTH"},
+		{name: "tls", output: `[{"Config": {"Image": "registry:2", "Env": ["REGISTRY_HTTP_TLS_CERTIFICATE=/certs/tls.crt"]}}]`, message: "REGISTRY_HTTP_TLS_CERTIFICATE"},
+	}
+	for _, tt := range tests {
+		inspect := "docker inspect registry"
+		switch {
+		case tt.templateName != "":
+			inspect = "docker inspect " + tt.templateName
+		case tt.registryName != "":
+			inspect = "docker inspect " + tt.registryName
+		}
+		runner := &fakeRunner{outputs: map[string]string{inspect: tt.output}}
+		if tt.failure != "" {
+			runner.failures = map[string]string{inspect: tt.failure}
+		}
+		o := newFakeOptions(runner)
+		o.TemplateFrom = "10.0.0.110"
+		if tt.registryName != "" {
+			o.RegistryName = tt.registryName
+		}
+		o.TemplateName = tt.templateName
+		_, err := o.inspectTemplate()
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the layout of a --since or --before date without time, in UTC.
+const dateLayout = "2006-01-02"
+
+// parseTimeBound parses a --since or --before value, a duration like 720h is the time that long before now,
+// otherwise it is a RFC3339 time or a date. An empty value is the zero time, which bounds nothing.
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("duration %q must be positive", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration like 720h, a RFC3339 time nor a date like %s", value, dateLayout)
+}
+
+// timeFiltered reports whether list filters the tags by the creation time of their images.
+func (o *RegistryOptions) timeFiltered() bool {
+	return o.Since != "" || o.Before != ""
+}
+
+// timeWindow returns the bounds of --since and --before relative to now.
+func (o *RegistryOptions) timeWindow(now time.Time) (since, before time.Time, err error) {
+	if since, err = parseTimeBound(o.Since, now); err != nil {
+		return since, before, fmt.Errorf("invalid --since: %w", err)
+	}
+	if before, err = parseTimeBound(o.Before, now); err != nil {
+		return since, before, fmt.Errorf("invalid --before: %w", err)
+	}
+	if !since.IsZero() && !before.IsZero() && !since.Before(before) {
+		return since, before, fmt.Errorf("--since %s is not earlier than --before %s", o.Since, o.Before)
+	}
+	return since, before, nil
+}
+
+func (o *RegistryOptions) validateTimeFilter() error {
+	if !o.timeFiltered() {
+		return nil
+	}
+	if !o.Detail {
+		return fmt.Errorf("--since and --before require --type image --detail")
+	}
+	_, _, err := o.timeWindow(time.Now())
+	return err
+}
+
+// inTimeWindow reports whether created is at or after since and before before, a zero bound is open.
+// An image without creation time is never in a bounded window.
+func inTimeWindow(created *time.Time, since, before time.Time) bool {
+	if created == nil || created.IsZero() {
+		return false
+	}
+	if !since.IsZero() && created.Before(since) {
+		return false
+	}
+	return before.IsZero() || created.Before(before)
+}
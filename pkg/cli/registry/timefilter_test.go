@@ -0,0 +1,185 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "720h", want: now.Add(-720 * time.Hour)},
+		{value: "90m", want: now.Add(-90 * time.Minute)},
+		{value: "2024-01-01", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-01T08:00:00+08:00", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "0s", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "30d", wantErr: true},
+		{value: "2024/01/01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeBound(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTimeFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		detail  bool
+		since   string
+		before  string
+		wantErr bool
+	}{
+		{name: "no filter", detail: false},
+		{name: "since with detail", detail: true, since: "720h"},
+		{name: "window", detail: true, since: "2024-01-01", before: "2025-01-01"},
+		{name: "without detail", detail: false, before: "720h", wantErr: true},
+		{name: "invalid since", detail: true, since: "yesterday", wantErr: true},
+		{name: "empty window", detail: true, since: "2025-01-01", before: "2024-01-01", wantErr: true},
+		{name: "since after before", detail: true, since: "24h", before: "720h", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newFakeOptions(&fakeRunner{})
+			o.Detail, o.Since, o.Before = tt.detail, tt.since, tt.before
+			if err := o.validateTimeFilter(); (err != nil) != tt.wantErr {
+				t.Errorf("validateTimeFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListImageDetailsTimeWindow(t *testing.T) {
+	const (
+		listType     = "application/vnd.docker.distribution.manifest.list.v2+json"
+		manifestType = "application/vnd.docker.distribution.manifest.v2+json"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/caas4/cephcsi/tags/list":
+			_, _ = w.Write([]byte(`{"name":"caas4/cephcsi","tags":["old","multi","new","unknown"]}`))
+		case "/v2/caas4/cephcsi/manifests/old":
+			w.Header().Set("Content-Type", manifestType)
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:old"}}`))
+		case "/v2/caas4/cephcsi/manifests/new":
+			w.Header().Set("Content-Type", manifestType)
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:new"}}`))
+		case "/v2/caas4/cephcsi/manifests/unknown":
+			w.Header().Set("Content-Type", manifestType)
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:unknown"}}`))
+		case "/v2/caas4/cephcsi/manifests/multi":
+			w.Header().Set("Content-Type", listType)
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"manifests":[` +
+				`{"mediaType":"` + manifestType + `","digest":"sha256:amd64","platform":{"architecture":"amd64","os":"linux"}},` +
+				`{"mediaType":"` + manifestType + `","digest":"sha256:arm64","platform":{"architecture":"arm64","os":"linux"}}]}`))
+		case "/v2/caas4/cephcsi/manifests/sha256:amd64":
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:old"}}`))
+		case "/v2/caas4/cephcsi/manifests/sha256:arm64":
+			_, _ = w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:mid"}}`))
+		case "/v2/caas4/cephcsi/blobs/sha256:old":
+			_, _ = w.Write([]byte(`{"architecture":"amd64","os":"linux","created":"2023-03-01T00:00:00Z"}`))
+		case "/v2/caas4/cephcsi/blobs/sha256:mid":
+			_, _ = w.Write([]byte(`{"architecture":"arm64","os":"linux","created":"2024-03-01T00:00:00Z"}`))
+		case "/v2/caas4/cephcsi/blobs/sha256:new":
+			_, _ = w.Write([]byte(`{"architecture":"amd64","os":"linux","created":"2025-03-01T00:00:00Z"}`))
+		case "/v2/caas4/cephcsi/blobs/sha256:unknown":
+			_, _ = w.Write([]byte(`{"architecture":"amd64","os":"linux"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		since  string
+		before string
+		want   []string
+	}{
+		{name: "since", since: "2024-01-01", want: []string{"multi", "new"}},
+		{name: "before", before: "2024-01-01", want: []string{"old"}},
+		{name: "window", since: "2024-01-01", before: "2025-01-01", want: []string{"multi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := newFakeOptions(&fakeRunner{})
+			o.IOStreams.Out = out
+			o.Node = host
+			o.RegistryPort, _ = strconv.Atoi(port)
+			o.Name = "caas4/cephcsi"
+			o.Detail, o.Since, o.Before = true, tt.since, tt.before
+			o.cacheDir = t.TempDir()
+			cmd := &cobra.Command{}
+			o.PrintFlags.AddFlags(cmd)
+			if err := cmd.Flags().Set("output", "json"); err != nil {
+				t.Fatal(err)
+			}
+			if err := o.listImageDetails(); err != nil {
+				t.Fatal(err)
+			}
+			details := &ImageDetails{}
+			if err := json.Unmarshal(out.Bytes(), details); err != nil {
+				t.Fatalf("invalid output %q: %v", out.String(), err)
+			}
+			var got []string
+			for _, v := range details.Items {
+				got = append(got, v.Tag)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected tags %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected tags %v, got %v", tt.want, got)
+				}
+			}
+			for _, v := range details.Items {
+				if v.Tag == "multi" && (v.Created == nil || !v.Created.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))) {
+					t.Errorf("expected created of manifest list to be its latest platform image, got %v", v.Created)
+				}
+			}
+		})
+	}
+}
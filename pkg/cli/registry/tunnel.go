@@ -0,0 +1,85 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// openTunnel forwards a local port to 127.0.0.1:<registry-port> of the node through ssh,
+// and points the registry API requests to it, so that a registry deployed with
+// --bind-address 127.0.0.1 can be listed and deleted from outside. The returned func closes the tunnel.
+func (o *RegistryOptions) openTunnel() (func(), error) {
+	client, err := o.SSHConfig.NewClient(o.Node)
+	if err != nil {
+		return nil, fmt.Errorf("ssh to %s for registry tunnel error: %w", o.Node, err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	remote := net.JoinHostPort("127.0.0.1", strconv.Itoa(o.RegistryPort))
+	go serveTunnel(listener, func() (net.Conn, error) {
+		return client.Dial("tcp", remote)
+	})
+	o.apiBase = "http://" + listener.Addr().String()
+	o.log("tunnel").V(2).Infof("forward %s to %s of %s", listener.Addr().String(), remote, o.Node)
+	return func() {
+		o.apiBase = ""
+		_ = listener.Close()
+		_ = client.Close()
+	}, nil
+}
+
+// serveTunnel accepts connections on listener until it is closed, and pipes each one to a connection of dial.
+func serveTunnel(listener net.Listener, dial func() (net.Conn, error)) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer local.Close()
+			remote, err := dial()
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+			pipe(local, remote)
+		}()
+	}
+}
+
+// pipe copies data between a and b in both directions until either side is done.
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	done := make(chan struct{})
+	copyConn := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		once.Do(func() { close(done) })
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+}
@@ -0,0 +1,93 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestServeTunnel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"repositories":["caas4/a"]}`))
+	}))
+	defer server.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go serveTunnel(listener, func() (net.Conn, error) {
+		return net.Dial("tcp", server.Listener.Addr().String())
+	})
+
+	o := newFakeOptions(&fakeRunner{})
+	// node is unreachable, requests must go through the tunnel
+	o.Node = "192.0.2.1"
+	o.apiBase = "http://" + listener.Addr().String()
+	o.Type = "repository"
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err = cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.List(); err != nil {
+		t.Fatal(err)
+	}
+	repos := &Repositories{}
+	if err = json.Unmarshal(out.Bytes(), repos); err != nil {
+		t.Fatalf("unmarshal repositories %q: %v", out.String(), err)
+	}
+	if len(repos.Repositories) != 1 || repos.Repositories[0] != "caas4/a" {
+		t.Errorf("expected repositories [caas4/a], got %v", repos.Repositories)
+	}
+}
+
+func TestPublishArg(t *testing.T) {
+	tests := []struct {
+		bindAddress string
+		publish     string
+		registry    string
+	}{
+		{bindAddress: "0.0.0.0", publish: "-p 5000:5000", registry: "10.0.0.111:5000"},
+		{bindAddress: "127.0.0.1", publish: "-p 127.0.0.1:5000:5000", registry: "127.0.0.1:5000"},
+		{bindAddress: "::1", publish: "-p [::1]:5000:5000", registry: "[::1]:5000"},
+	}
+	for _, tt := range tests {
+		o := newFakeOptions(&fakeRunner{})
+		o.BindAddress = tt.bindAddress
+		if publish := o.publishArg(); publish != tt.publish {
+			t.Errorf("%s: expected %q, got %q", tt.bindAddress, tt.publish, publish)
+		}
+		if registry := o.pushRegistry(); registry != tt.registry {
+			t.Errorf("%s: expected push registry %q, got %q", tt.bindAddress, tt.registry, registry)
+		}
+	}
+	if err := validateBindAddress("localhost"); err == nil {
+		t.Error("expected error of bind address localhost")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/pkg/cli/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+)
+
+const (
+	verifyLongDescription = `
+  Verify the expected images are present in docker registry.
+
+  The expected images are read from a list file which contains one 'repo:tag' per line,
+  or from the metadata of the images package used by push.`
+	verifyExample = `
+  # Verify images listed in file
+  kcctl registry verify --node 10.0.0.111 --registry-port 5000 --file images.txt
+  # Verify images in the images package
+  kcctl registry verify --node 10.0.0.111 --registry-port 5000 --images-pkg images.tar.gz
+
+  Please read 'kcctl registry verify -h' get more registry verify flags.`
+)
+
+func NewCmdRegistryVerify(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "verify (--node <node>) (--registry-port <registry-port>) (--file <file>) (--images-pkg <images-pkg>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry verify images",
+		Long:                  verifyLongDescription,
+		Example:               verifyExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsVerify()))
+			o.checkErr(o.Verify())
+		},
+	}
+	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().StringVar(&o.ImagesFile, "file", o.ImagesFile, "file of expected images, one 'repo:tag' per line.")
+	cmd.Flags().StringVar(&o.Pkg, "images-pkg", o.Pkg, "docker images pkg to read expected images from.")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsVerify() error {
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if o.ImagesFile == "" && o.Pkg == "" {
+		return fmt.Errorf("one of --file or --images-pkg must be specified")
+	}
+	return nil
+}
+
+func (o *RegistryOptions) Verify() error {
+	var (
+		expected []string
+		err      error
+	)
+	if o.ImagesFile != "" {
+		expected, err = readImagesFile(o.ImagesFile)
+	} else {
+		expected, err = o.readImagesPkg(o.Pkg)
+	}
+	if err != nil {
+		return err
+	}
+	result := &VerifyResult{}
+	for _, ref := range expected {
+		name, tag := splitImageRef(ref)
+		ok, err := o.manifestExists(name, tag)
+		if err != nil {
+			return fmt.Errorf("verify image %s error: %w", ref, err)
+		}
+		if ok {
+			result.Present = append(result.Present, ref)
+		} else {
+			result.Missing = append(result.Missing, ref)
+		}
+	}
+	if err = o.PrintFlags.Print(result, o.IOStreams.Out); err != nil {
+		return err
+	}
+	if len(result.Missing) > 0 {
+		return fmt.Errorf("%d of %d images are missing in registry", len(result.Missing), len(expected))
+	}
+	logger.Infof("all %d images are present in registry", len(expected))
+	return nil
+}
+
+// readImagesFile reads 'repo:tag' entries line by line, blank lines and '#' comments are ignored.
+func readImagesFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var images []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, line)
+	}
+	return images, scanner.Err()
+}
+
+// readImagesPkg reads the image references from the manifest.json of a local 'docker save' tarball,
+// and converts them to the references pushed into registry. Only gzipped and uncompressed tarballs can be read locally.
+func (o *RegistryOptions) readImagesPkg(pkg string) ([]string, error) {
+	compression, err := o.detectCompression(pkg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(pkg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch compression {
+	case compressionGzip:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case compressionNone:
+	default:
+		return nil, fmt.Errorf("%s compressed pkg %s can not be read locally, please decompress it first", compression, pkg)
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("manifest.json not found in %s", pkg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name != "manifest.json" {
+			continue
+		}
+		var manifests []struct {
+			RepoTags []string `json:"RepoTags"`
+		}
+		if err = json.NewDecoder(tr).Decode(&manifests); err != nil {
+			return nil, err
+		}
+		var images []string
+		for _, m := range manifests {
+			for _, ref := range m.RepoTags {
+				if pushed, ok := pushedImageRef(ref); ok {
+					images = append(images, pushed)
+				}
+			}
+		}
+		return images, nil
+	}
+}
+
+// pushedImageRef returns the reference of a local image after push, images without namespace are not pushed.
+func pushedImageRef(ref string) (string, bool) {
+	if !strings.Contains(ref, "/") {
+		return "", false
+	}
+	return strings.TrimPrefix(ref, "k8s.gcr.io/"), true
+}
+
+// splitImageRef splits 'repo:tag' into repository and tag, tag defaults to latest.
+func splitImageRef(ref string) (string, string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || strings.Contains(ref[i:], "/") {
+		return ref, "latest"
+	}
+	return ref[:i], ref[i+1:]
+}
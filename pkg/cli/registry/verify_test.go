@@ -0,0 +1,60 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"testing"
+)
+
+func TestSplitImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		name string
+		tag  string
+	}{
+		{ref: "calico/cni:v3.21.2", name: "calico/cni", tag: "v3.21.2"},
+		{ref: "10.0.0.111:5000/pause:3.2", name: "10.0.0.111:5000/pause", tag: "3.2"},
+		{ref: "10.0.0.111:5000/pause", name: "10.0.0.111:5000/pause", tag: "latest"},
+		{ref: "pause", name: "pause", tag: "latest"},
+	}
+	for _, tt := range tests {
+		name, tag := splitImageRef(tt.ref)
+		if name != tt.name || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = %q, %q, want %q, %q", tt.ref, name, tag, tt.name, tt.tag)
+		}
+	}
+}
+
+func TestPushedImageRef(t *testing.T) {
+	tests := []struct {
+		ref    string
+		pushed string
+		ok     bool
+	}{
+		{ref: "k8s.gcr.io/pause:3.2", pushed: "pause:3.2", ok: true},
+		{ref: "calico/cni:v3.21.2", pushed: "calico/cni:v3.21.2", ok: true},
+		{ref: "registry:2", ok: false},
+	}
+	for _, tt := range tests {
+		pushed, ok := pushedImageRef(tt.ref)
+		if pushed != tt.pushed || ok != tt.ok {
+			t.Errorf("pushedImageRef(%q) = %q, %v, want %q, %v", tt.ref, pushed, ok, tt.pushed, tt.ok)
+		}
+	}
+}
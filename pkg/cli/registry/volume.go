@@ -0,0 +1,72 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// checkRegistryVolume fails the deploy early if the registry volume is on the wrong filesystem,
+// e.g. 'docker run -v' silently creates a local directory when the network mount is missing.
+// The mountpoint is checked with --expect-mount, the volume must always be writable,
+// and its filesystem must have --min-free-gb free space if set.
+func (o *RegistryOptions) checkRegistryVolume() error {
+	volume := path.Clean(o.RegistryVolume)
+	if o.ExpectMount {
+		parent := path.Dir(volume)
+		ret, err := o.runCmd(fmt.Sprintf("mountpoint -q %s || mountpoint -q %s", volume, parent))
+		if err != nil {
+			return err
+		}
+		if ret.Error() != nil {
+			return fmt.Errorf("neither registry volume %s nor its parent %s is a mountpoint on %s, please mount it first", volume, parent, o.Node)
+		}
+	}
+
+	probe := path.Join(volume, ".kc-write-probe")
+	ret, err := o.runCmd(fmt.Sprintf("mkdir -p %s && touch %s && rm -f %s", volume, probe, probe))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("registry volume %s is not writable on %s: %w", volume, o.Node, err)
+	}
+
+	if o.MinFreeGB == 0 {
+		return nil
+	}
+	ret, err = o.runCmd(fmt.Sprintf("df -P -k %s | awk 'NR==2{print $4}'", volume))
+	if err != nil {
+		return err
+	}
+	if err = ret.Error(); err != nil {
+		return fmt.Errorf("get free space of registry volume %s error: %w", volume, err)
+	}
+	freeKB, err := strconv.ParseInt(strings.TrimSpace(ret.Stdout), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid free space of registry volume %s: %q", volume, ret.Stdout)
+	}
+	if freeGB := freeKB / (1024 * 1024); freeGB < int64(o.MinFreeGB) {
+		return fmt.Errorf("registry volume %s has %dGB free on %s, less than --min-free-gb %d", volume, freeGB, o.Node, o.MinFreeGB)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRegistryVolume(t *testing.T) {
+	const (
+		mount = "mountpoint -q /mnt/nfs/registry || mountpoint -q /mnt/nfs"
+		write = "mkdir -p /mnt/nfs/registry && touch /mnt/nfs/registry/.kc-write-probe && rm -f /mnt/nfs/registry/.kc-write-probe"
+		df    = "df -P -k /mnt/nfs/registry | awk 'NR==2{print $4}'"
+	)
+	tests := []struct {
+		name        string
+		expectMount bool
+		minFreeGB   int
+		failures    map[string]string
+		message     string
+	}{
+		{name: "defaults"},
+		{name: "mounted", expectMount: true, minFreeGB: 10},
+		{name: "not mounted", expectMount: true, failures: map[string]string{mount: ""}, message: "is a mountpoint"},
+		{name: "not writable", failures: map[string]string{write: "Permission denied"}, message: "is not writable"},
+		{name: "not enough space", minFreeGB: 100, message: "less than --min-free-gb 100"},
+	}
+	for _, tt := range tests {
+		runner := &fakeRunner{
+			outputs:  map[string]string{df: "20971520\n"}, // 20GB
+			failures: tt.failures,
+		}
+		o := newFakeOptions(runner)
+		o.RegistryVolume = "/mnt/nfs/registry/"
+		o.ExpectMount = tt.expectMount
+		o.MinFreeGB = tt.minFreeGB
+		err := o.checkRegistryVolume()
+		if tt.message == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.message, err)
+		}
+	}
+}
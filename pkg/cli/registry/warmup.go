@@ -0,0 +1,140 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
+	"github.com/kubeclipper/kubeclipper/pkg/cli/utils"
+)
+
+const (
+	warmupLongDescription = `
+  Pre-pull images through docker registry.
+
+  Each image of --images-file is pulled on the registry node by 'docker pull <node>:<port>/<image>',
+  so that a pull-through cache registry fetches it from upstream before clients ask for it.
+  The pulled images are removed from the node afterwards unless --keep-local-images is specified.`
+	warmupExample = `
+  # Warm up the cache of a pull-through registry with images listed in file
+  kcctl registry warmup --pk-file key --node 10.0.0.111 --registry-port 5000 --images-file images.txt
+  # Warm up the cache and print the result as json
+  kcctl registry warmup --pk-file key --node 10.0.0.111 --registry-port 5000 --images-file images.txt -o json
+
+  Please read 'kcctl registry warmup -h' get more registry warmup flags.`
+)
+
+func NewCmdRegistryWarmup(o *RegistryOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "warmup (--pk-file <file path>) (--node <node>) (--images-file <file>) [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "registry pre-pull images",
+		Long:                  warmupLongDescription,
+		Example:               warmupExample,
+		Args:                  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			utils.CheckErr(o.Complete())
+			o.checkErr(usageError(o.ValidateArgsWarmup()))
+			if !o.preCheck() {
+				return
+			}
+			o.checkErr(o.Warmup())
+		},
+	}
+
+	options.AddFlagsToSSH(o.SSHConfig, cmd.Flags())
+	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&o.Node, "node", o.Node, "registry node.")
+	cmd.Flags().IntVar(&o.RegistryPort, "registry-port", o.RegistryPort, "set registry container port")
+	cmd.Flags().StringVar(&o.ImagesFile, "images-file", o.ImagesFile, "file of images to pull, one 'repo:tag' per line.")
+	cmd.Flags().BoolVar(&o.KeepLocalImages, "keep-local-images", o.KeepLocalImages, "keep the pulled images on node")
+
+	utils.CheckErr(cmd.MarkFlagRequired("node"))
+	utils.CheckErr(cmd.MarkFlagRequired("images-file"))
+	return cmd
+}
+
+func (o *RegistryOptions) ValidateArgsWarmup() error {
+	if err := o.validateSSH(); err != nil {
+		return err
+	}
+	if o.Node == "" {
+		return fmt.Errorf("--node must be specified")
+	}
+	if o.ImagesFile == "" {
+		return fmt.Errorf("--images-file must be specified")
+	}
+	return nil
+}
+
+// Warmup pulls every image of --images-file through registry, failures are recorded in the report instead of aborting.
+func (o *RegistryOptions) Warmup() error {
+	images, err := readImagesFile(o.ImagesFile)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		o.log("warmup").Infof("no images in %s", o.ImagesFile)
+		return nil
+	}
+	report := &WarmupReport{}
+	for _, image := range images {
+		report.Items = append(report.Items, o.warmupImage(image))
+	}
+	if err = o.PrintFlags.Print(report, o.IOStreams.Out); err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d images failed to pull", failed, len(report.Items))
+	}
+	o.log("warmup").Infof("%d images pulled through registry", len(report.Items))
+	return nil
+}
+
+// warmupImage pulls image by registry on node, the outcome is returned as a WarmupResult.
+func (o *RegistryOptions) warmupImage(image string) WarmupResult {
+	result := WarmupResult{Image: image}
+	ref := fmt.Sprintf("%s:%d/%s", o.Node, o.RegistryPort, image)
+	ret, err := o.runDockerCmd("docker pull " + ref)
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil {
+		o.log("warmup").V(2).Infof("pull image %s failed: %s", ref, err.Error())
+		result.Status = WarmupStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = WarmupStatusPulled
+	if o.KeepLocalImages {
+		return result
+	}
+	// the image is cached by registry already, a failed removal only leaves it on node
+	ret, err = o.runDockerCmd("docker rmi " + ref)
+	if err == nil {
+		err = ret.Error()
+	}
+	if err != nil {
+		o.log("warmup").Warnf("remove image %s error: %s", ref, err.Error())
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWarmup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "images.txt")
+	content := "# cache of first boot\nlibrary/nginx:1.25\n\ncalico/node:v3.21.2\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runner := &fakeRunner{failures: map[string]string{
+		"docker pull 10.0.0.111:5000/calico/node:v3.21.2": "manifest unknown",
+	}}
+	o := newFakeOptions(runner)
+	out := &bytes.Buffer{}
+	o.IOStreams.Out = out
+	o.ImagesFile = file
+	cmd := &cobra.Command{}
+	o.PrintFlags.AddFlags(cmd)
+	if err := cmd.Flags().Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Warmup(); err == nil || !strings.Contains(err.Error(), "1 of 2 images") {
+		t.Fatalf("expected warmup error of 1 failed image, got %v", err)
+	}
+	report := &WarmupReport{}
+	if err := json.Unmarshal(out.Bytes(), report); err != nil {
+		t.Fatalf("unmarshal warmup report %q: %v", out.String(), err)
+	}
+	expected := map[string]string{
+		"library/nginx:1.25":  WarmupStatusPulled,
+		"calico/node:v3.21.2": WarmupStatusFailed,
+	}
+	if len(report.Items) != len(expected) {
+		t.Fatalf("expected %d report items, got %v", len(expected), report.Items)
+	}
+	for _, item := range report.Items {
+		if item.Status != expected[item.Image] {
+			t.Errorf("expected %s to be %s, got %s", item.Image, expected[item.Image], item.Status)
+		}
+	}
+	// only the pulled image is removed from node
+	cmds := []string{
+		"docker pull 10.0.0.111:5000/library/nginx:1.25",
+		"docker rmi 10.0.0.111:5000/library/nginx:1.25",
+		"docker pull 10.0.0.111:5000/calico/node:v3.21.2",
+	}
+	if strings.Join(runner.cmds, "\n") != strings.Join(cmds, "\n") {
+		t.Errorf("expected commands %v, got %v", cmds, runner.cmds)
+	}
+}
@@ -39,8 +39,8 @@ func PreCheck(name string, sshConfig *sshutils.SSH, streams options.IOStreams, a
 		return true
 	}
 	for {
-		// need enter passwd to run sudo
-		if sshConfig.Password == "" {
+		// need enter passwd to run sudo, other commands can't read it and must permit the user without password
+		if sshConfig.Password == "" && sshConfig.SudoReadsPassword() {
 			_, _ = streams.Out.Write([]byte(fmt.Sprintf("ensure cmd exec success,need enter passwd for user '%s'. "+
 				"Please input (user %s's password)", sshConfig.User, sshConfig.User)))
 			passwd, err := utils.WaitInputPasswd()
@@ -54,6 +54,17 @@ func PreCheck(name string, sshConfig *sshutils.SSH, streams options.IOStreams, a
 			_, _ = streams.Out.Write([]byte("\n"))
 			sshConfig.Password = passwd
 		}
+		// check sudo command exists
+		if err := checkSudoCmd(sshConfig, allNodes); err != nil {
+			logger.Error(err)
+			logger.Errorf("===========>%s PRECHECK FAILED!", name)
+			_, _ = streams.Out.Write([]byte(err.Error() + "\n"))
+			if strings.Contains(err.Error(), "passwd or user error") {
+				sshConfig.Password = ""
+				continue
+			}
+			break
+		}
 		// check sudo access
 		err := sshutils.CmdBatchWithSudo(sshConfig, allNodes, "id -u", func(result sshutils.Result, err error) error {
 			if err != nil {
@@ -70,6 +81,11 @@ func PreCheck(name string, sshConfig *sshutils.SSH, streams options.IOStreams, a
 				if strings.Contains(result.Stderr, "incorrect password attempt") {
 					return fmt.Errorf("passwd error for '%s@%s',please try again", result.User, result.Host)
 				}
+				if !sshConfig.SudoReadsPassword() {
+					return fmt.Errorf("sudo command '%s' failed for '%s@%s', it can't read password from stdin, "+
+						"please permit the user without password, e.g. by nopass in doas.conf, stderr:%s",
+						sshConfig.SudoCommand(), result.User, result.Host, result.Stderr)
+				}
 				return fmt.Errorf("%s stderr:%s", result.Short(), result.Stderr)
 			}
 			return nil
@@ -98,6 +114,23 @@ func PreCheck(name string, sshConfig *sshutils.SSH, streams options.IOStreams, a
 	return utils.AskForConfirmation()
 }
 
+// checkSudoCmd makes sure the privilege escalation command of ssh user exists on all nodes.
+func checkSudoCmd(sshConfig *sshutils.SSH, allNodes []string) error {
+	sudoCmd := sshConfig.SudoCommand()
+	return sshutils.CmdBatch(sshConfig, allNodes, fmt.Sprintf("command -v %s", sudoCmd), func(result sshutils.Result, err error) error {
+		if err != nil {
+			if strings.Contains(err.Error(), "handshake failed: ssh: unable to authenticate, attempted methods [none password]") {
+				return fmt.Errorf("passwd or user error while ssh '%s@%s',please try again", result.User, result.Host)
+			}
+			return err
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("sudo command '%s' not found on '%s@%s',please install it or specify --sudo-cmd", sudoCmd, result.User, result.Host)
+		}
+		return nil
+	})
+}
+
 var (
 	errorMultiNIC = errors.New("node has multi nic bug not specify --ip-detect flag")
 )
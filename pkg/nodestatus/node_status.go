@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -195,6 +196,59 @@ func ReadyCondition(
 	}
 }
 
+// SchedulableCondition returns a Setter that updates the v1.NodeSchedulable condition on the node.
+func SchedulableCondition(
+	nowFunc func() time.Time,
+	drainedFunc func() bool, // whether the agent is drained and rejects new tasks
+) Setter {
+	return func(node *v1.Node) error {
+		currentTime := metav1.NewTime(nowFunc())
+		newCondition := v1.NodeCondition{
+			Type:              v1.NodeSchedulable,
+			Status:            v1.ConditionTrue,
+			Reason:            "KcAgentSchedulable",
+			Message:           "kc agent is accepting new tasks",
+			LastHeartbeatTime: currentTime,
+		}
+		if drainedFunc() {
+			newCondition = v1.NodeCondition{
+				Type:              v1.NodeSchedulable,
+				Status:            v1.ConditionFalse,
+				Reason:            "KcAgentDrained",
+				Message:           "kc agent is drained and rejects new tasks",
+				LastHeartbeatTime: currentTime,
+			}
+		}
+		for i := range node.Status.Conditions {
+			if node.Status.Conditions[i].Type == v1.NodeSchedulable {
+				if node.Status.Conditions[i].Status == newCondition.Status {
+					newCondition.LastTransitionTime = node.Status.Conditions[i].LastTransitionTime
+				} else {
+					newCondition.LastTransitionTime = currentTime
+				}
+				node.Status.Conditions[i] = newCondition
+				return nil
+			}
+		}
+		newCondition.LastTransitionTime = currentTime
+		node.Status.Conditions = append(node.Status.Conditions, newCondition)
+		return nil
+	}
+}
+
+// OperationQueue returns a Setter that reports the running and queued operations of agent on node annotations.
+func OperationQueue(operationsFunc func() (running, queued int)) Setter {
+	return func(node *v1.Node) error {
+		running, queued := operationsFunc()
+		if node.Annotations == nil {
+			node.Annotations = make(map[string]string)
+		}
+		node.Annotations[common.AnnotationRunningOperations] = strconv.Itoa(running)
+		node.Annotations[common.AnnotationQueuedOperations] = strconv.Itoa(queued)
+		return nil
+	}
+}
+
 func attachedVolumes(d sysutil.Disk) []v1.AttachedVolume {
 	m := make([]v1.AttachedVolume, len(d.DiskDevices))
 	for i, item := range d.DiskDevices {
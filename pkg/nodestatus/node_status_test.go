@@ -0,0 +1,66 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package nodestatus
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestSchedulableCondition(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	drained := false
+	setter := SchedulableCondition(func() time.Time { return now }, func() bool { return drained })
+	node := &v1.Node{}
+
+	steps := []struct {
+		name       string
+		drained    bool
+		status     v1.ConditionStatus
+		reason     string
+		transition time.Time
+	}{
+		{name: "schedulable at first", status: v1.ConditionTrue, reason: "KcAgentSchedulable", transition: now},
+		{name: "heartbeat keeps transition", status: v1.ConditionTrue, reason: "KcAgentSchedulable", transition: now},
+		{name: "drained", drained: true, status: v1.ConditionFalse, reason: "KcAgentDrained", transition: now.Add(2 * time.Minute)},
+		{name: "uncordoned", status: v1.ConditionTrue, reason: "KcAgentSchedulable", transition: now.Add(3 * time.Minute)},
+	}
+	for i, step := range steps {
+		if i > 0 {
+			now = now.Add(time.Minute)
+		}
+		drained = step.drained
+		if err := setter(node); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if len(node.Status.Conditions) != 1 {
+			t.Fatalf("%s: expected a single condition, got %v", step.name, node.Status.Conditions)
+		}
+		c := node.Status.Conditions[0]
+		if c.Type != v1.NodeSchedulable || c.Status != step.status || c.Reason != step.reason {
+			t.Errorf("%s: expected %s %s, got %s %s", step.name, step.status, step.reason, c.Status, c.Reason)
+		}
+		if !c.LastTransitionTime.Time.Equal(step.transition) || !c.LastHeartbeatTime.Time.Equal(now) {
+			t.Errorf("%s: expected transition at %v and heartbeat at %v, got %v and %v",
+				step.name, step.transition, now, c.LastTransitionTime, c.LastHeartbeatTime)
+		}
+	}
+}
@@ -20,8 +20,10 @@ package oplog
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
@@ -43,10 +45,14 @@ func NewOperationLog(opts *Options) (component.OperationLogFile, error) {
 		return nil, err
 	}
 
-	return &OperationLog{
+	op := &OperationLog{
 		cfg:    opts,
 		suffix: OperationLogSuffix,
-	}, nil
+	}
+	if err := op.pruneExpired(); err != nil {
+		return nil, err
+	}
+	return op, nil
 }
 
 // GetRootDir get operation log root dir
@@ -59,7 +65,14 @@ func (op *OperationLog) CreateOperationDir(opID string) error {
 	if opID == "" {
 		return errors.New("opID is invalid")
 	}
-	return fileutil.CreateDirIfNotExists(filepath.Join(op.cfg.Dir, opID), 0755)
+	dir := filepath.Join(op.cfg.Dir, opID)
+	if !fileutil.PathExist(dir) {
+		// a new operation comes, it's a good time to remove the expired ones
+		if err := op.pruneExpired(); err != nil {
+			return err
+		}
+	}
+	return fileutil.CreateDirIfNotExists(dir, 0755)
 }
 
 // GetOperationDir get operation dir
@@ -73,11 +86,21 @@ func (op *OperationLog) GetOperationDir(opID string) (path string, err error) {
 }
 
 // CreateStepLogFile create a file based on opID and stepID, open the file to return the file descriptor. You should close it.
+// The max size is only checked when the file is opened, the returned file is not rotated however much is written to it.
 func (op *OperationLog) CreateStepLogFile(opID, stepID string) (*os.File, error) {
+	return op.openStepLogFile(opID, stepID, 0)
+}
+
+// openStepLogFile opens the step log file, rotating it first if appending n bytes would exceed the max size.
+func (op *OperationLog) openStepLogFile(opID, stepID string, n int64) (*os.File, error) {
 	if opID == "" || stepID == "" {
 		return nil, errors.New("opID or stepID is invalid")
 	}
-	return os.OpenFile(filepath.Join(op.cfg.Dir, opID, stepID+OperationLogSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0777)
+	filename := filepath.Join(op.cfg.Dir, opID, stepID+OperationLogSuffix)
+	if err := op.rotate(filename, n); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0777)
 }
 
 // GetStepLogFile get step log file path
@@ -127,11 +150,12 @@ func (op *OperationLog) GetStepLogContent(opID, stepID string, offset int64, len
 }
 
 // CreateStepLogFileAndAppend create a file based on opID and stepID, and append data to step log file.
+// The step log file is rotated before the append which would make it exceed the max size.
 func (op *OperationLog) CreateStepLogFileAndAppend(opID, stepID string, data []byte) error {
 	if err := op.CreateOperationDir(opID); err != nil {
 		return err
 	}
-	f, err := op.CreateStepLogFile(opID, stepID)
+	f, err := op.openStepLogFile(opID, stepID, int64(len(data)))
 	if err != nil {
 		return err
 	}
@@ -149,3 +173,65 @@ func (op *OperationLog) TruncateStepLogFile(opID, stepID string) error {
 	}
 	return os.Truncate(path, 0)
 }
+
+// rotate renames the step log file to filename.1 once it reaches the max size, or
+// appending n bytes would exceed it, shifting the older rotated files and removing
+// those beyond the max backups.
+func (op *OperationLog) rotate(filename string, n int64) error {
+	if op.cfg.MaxSize <= 0 {
+		return nil
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if stat.Size() == 0 || (stat.Size() < op.cfg.MaxSize && stat.Size()+n <= op.cfg.MaxSize) {
+		return nil
+	}
+	if op.cfg.MaxBackups == 0 {
+		return os.Remove(filename)
+	}
+	if err = os.Remove(backupName(filename, op.cfg.MaxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := op.cfg.MaxBackups - 1; i > 0; i-- {
+		if err = os.Rename(backupName(filename, i), backupName(filename, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(filename, backupName(filename, 1))
+}
+
+// pruneExpired removes the operation log dirs which are not modified within the max age.
+func (op *OperationLog) pruneExpired() error {
+	if op.cfg.MaxAge <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(op.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-op.cfg.MaxAge)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(deadline) {
+			if err = os.RemoveAll(filepath.Join(op.cfg.Dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func backupName(filename string, index int) string {
+	return fmt.Sprintf("%s.%d", filename, index)
+}
@@ -0,0 +1,116 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package oplog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationLog_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	ol, err := NewOperationLog(&Options{
+		Dir:             dir,
+		SingleThreshold: DefaultThreshold,
+		MaxSize:         10,
+		MaxBackups:      2,
+	})
+	if err != nil {
+		assert.FailNowf(t, "failed to create operation log", err.Error())
+	}
+	data := bytes.Repeat([]byte("a"), 10)
+	for i := 0; i < 4; i++ {
+		if err = ol.CreateStepLogFileAndAppend("op", "step", data); err != nil {
+			assert.FailNowf(t, "failed to append step log", err.Error())
+		}
+	}
+	filename := filepath.Join(dir, "op", "step"+OperationLogSuffix)
+	assert.FileExists(t, filename)
+	assert.FileExists(t, filename+".1")
+	assert.FileExists(t, filename+".2")
+	assert.NoFileExists(t, filename+".3")
+	stat, err := os.Stat(filename)
+	if err != nil {
+		assert.FailNowf(t, "failed to stat step log", err.Error())
+	}
+	assert.Equal(t, int64(len(data)), stat.Size())
+}
+
+func TestOperationLog_RotateBeforeExceeding(t *testing.T) {
+	dir := t.TempDir()
+	ol, err := NewOperationLog(&Options{
+		Dir:             dir,
+		SingleThreshold: DefaultThreshold,
+		MaxSize:         10,
+		MaxBackups:      1,
+	})
+	if err != nil {
+		assert.FailNowf(t, "failed to create operation log", err.Error())
+	}
+	data := bytes.Repeat([]byte("a"), 6)
+	for i := 0; i < 2; i++ {
+		if err = ol.CreateStepLogFileAndAppend("op", "step", data); err != nil {
+			assert.FailNowf(t, "failed to append step log", err.Error())
+		}
+	}
+	filename := filepath.Join(dir, "op", "step"+OperationLogSuffix)
+	assert.FileExists(t, filename+".1")
+	stat, err := os.Stat(filename)
+	if err != nil {
+		assert.FailNowf(t, "failed to stat step log", err.Error())
+	}
+	assert.Equal(t, int64(len(data)), stat.Size())
+}
+
+func TestOperationLog_PruneExpired(t *testing.T) {
+	dir := t.TempDir()
+	expired := filepath.Join(dir, "expired")
+	if err := os.MkdirAll(expired, 0755); err != nil {
+		assert.FailNowf(t, "failed to create dir", err.Error())
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(expired, old, old); err != nil {
+		assert.FailNowf(t, "failed to change dir times", err.Error())
+	}
+	ol, err := NewOperationLog(&Options{
+		Dir:             dir,
+		SingleThreshold: DefaultThreshold,
+		MaxAge:          time.Hour,
+	})
+	if err != nil {
+		assert.FailNowf(t, "failed to create operation log", err.Error())
+	}
+	assert.NoDirExists(t, expired)
+	if err = ol.CreateOperationDir("fresh"); err != nil {
+		assert.FailNowf(t, "failed to create operation dir", err.Error())
+	}
+	assert.DirExists(t, filepath.Join(dir, "fresh"))
+}
+
+func TestOptions_Validate(t *testing.T) {
+	opts := NewOptions()
+	assert.Empty(t, opts.Validate())
+	opts.MaxBackups = -1
+	assert.NotEmpty(t, opts.Validate())
+}
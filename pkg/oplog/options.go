@@ -21,6 +21,7 @@ package oplog
 import (
 	"errors"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -31,15 +32,30 @@ const (
 	DefaultThreshold = 1048576 // 1MB
 )
 
+const (
+	DefaultMaxSize    = 100 * 1048576 // 100MB
+	DefaultMaxAge     = 30 * 24 * time.Hour
+	DefaultMaxBackups = 3
+)
+
 type Options struct {
 	Dir             string `json:"dir" yaml:"dir"`
 	SingleThreshold int64  `json:"singleThreshold" yaml:"singleThreshold"`
+	// MaxSize is the maximum size in bytes of a step log file before it gets rotated, 0 means no limit.
+	MaxSize int64 `json:"maxSize" yaml:"maxSize"`
+	// MaxAge is the maximum time to retain operation logs, 0 means no limit.
+	MaxAge time.Duration `json:"maxAge" yaml:"maxAge"`
+	// MaxBackups is the maximum number of rotated files retained for each step log.
+	MaxBackups int `json:"maxBackups" yaml:"maxBackups"`
 }
 
 func NewOptions() *Options {
 	return &Options{
 		Dir:             DefaultDir,
 		SingleThreshold: DefaultThreshold,
+		MaxSize:         DefaultMaxSize,
+		MaxAge:          DefaultMaxAge,
+		MaxBackups:      DefaultMaxBackups,
 	}
 }
 
@@ -62,10 +78,22 @@ func (s *Options) Validate() (errs []error) {
 	if s.SingleThreshold > MaximumThreshold {
 		return append(errs, errors.New("the threshold exceeded the limit, the maximum threshold is 1MB"))
 	}
+	if s.MaxSize < 0 {
+		return append(errs, errors.New("the max size of operation log must not be negative"))
+	}
+	if s.MaxAge < 0 {
+		return append(errs, errors.New("the max age of operation log must not be negative"))
+	}
+	if s.MaxBackups < 0 {
+		return append(errs, errors.New("the max backups of operation log must not be negative"))
+	}
 	return
 }
 
 func (s *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.Dir, "oplog-dir", s.Dir, "directory of op log file")
-	fs.StringVar(&s.Dir, "oplog-threshold", s.Dir, "maximum value of log data transfer")
+	fs.Int64Var(&s.SingleThreshold, "oplog-threshold", s.SingleThreshold, "maximum value of log data transfer")
+	fs.Int64Var(&s.MaxSize, "oplog-max-size", s.MaxSize, "maximum size in bytes of a step log file before it gets rotated, 0 means no limit")
+	fs.DurationVar(&s.MaxAge, "oplog-max-age", s.MaxAge, "maximum time to retain operation logs, 0 means no limit")
+	fs.IntVar(&s.MaxBackups, "oplog-max-backups", s.MaxBackups, "maximum number of rotated files retained for each step log")
 }
@@ -82,4 +82,7 @@ const (
 	AnnotationDisplayName = "kubeclipper.io/display-name"
 	AnnotationDescription = "kubeclipper.io/description"
 	AnnotationOffline     = "kubeclipper.io/offline"
+	// operations running and queued on agent, reported on heartbeat
+	AnnotationRunningOperations = "kubeclipper.io/running-operations"
+	AnnotationQueuedOperations  = "kubeclipper.io/queued-operations"
 )
@@ -84,6 +84,7 @@ const (
 	NodeDiskPressure       NodeConditionType = "DiskPressure"
 	NodePIDPressure        NodeConditionType = "PIDPressure"
 	NodeNetworkUnavailable NodeConditionType = "NetworkUnavailable"
+	NodeSchedulable        NodeConditionType = "Schedulable"
 )
 
 type ConditionStatus string
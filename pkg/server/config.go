@@ -435,7 +435,7 @@ var Roles = []iamv1.GlobalRole{
 		Rules: []rbacv1.PolicyRule{
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "clusters/status", "regions", "nodes/disable", "nodes/enable"},
+				Resources: []string{"clusters", "clusters/status", "regions", "nodes/disable", "nodes/enable", "nodes/cordon", "nodes/uncordon"},
 				Verbs:     []string{"update", "patch"},
 			},
 			{
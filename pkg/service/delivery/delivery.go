@@ -502,6 +502,34 @@ func (s *Service) DeliverCmd(ctx context.Context, toNode string, cmds []string,
 	return resp.Data, nil
 }
 
+func (s *Service) DeliverNodeDrain(ctx context.Context, toNode string, drain bool) error {
+	op := service.OperationUncordonNode
+	if drain {
+		op = service.OperationDrainNode
+	}
+	payload, err := initPayload("", op, nil, nil, nil, false, false)
+	if err != nil {
+		return err
+	}
+	msg := &natsio.Msg{
+		Subject: fmt.Sprintf(service.MsgSubjectFormat, toNode, s.subjectSuffix),
+		Data:    payload,
+	}
+	data, err := s.client.RequestWithContext(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp := &service.CommonReply{}
+	if err = json.Unmarshal(data, resp); err != nil {
+		logger.Error("unmarshal agent reply error", zap.Error(err))
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
 func (s *Service) deliveryTaskStep(ctx context.Context, opName string, step *v1.Step, lastStepReply []byte, cond *v1.OperationCondition, dryRun bool) error {
 	payloadBytes, err := initPayload(opName, service.OperationRunTask, step, lastStepReply, nil, dryRun, component.GetRetry(ctx))
 	if err != nil {
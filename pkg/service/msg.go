@@ -40,6 +40,9 @@ const (
 	OperationBackup
 	OperationRecovery
 	OperationRunCmd
+	// node maintenance operation
+	OperationDrainNode
+	OperationUncordonNode
 )
 
 const (
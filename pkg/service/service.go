@@ -42,6 +42,9 @@ type Interface interface {
 
 type IDelivery interface {
 	DeliverLogRequest(ctx context.Context, operation *LogOperation) (oplog.LogContentResponse, error) // request & response synchronously.
+	// DeliverNodeDrain drains the agent of node, which rejects new tasks while finishing in-flight ones,
+	// or uncordons it if drain is false.
+	DeliverNodeDrain(ctx context.Context, toNode string, drain bool) error
 	CmdDelivery
 }
 
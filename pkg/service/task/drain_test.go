@@ -0,0 +1,43 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"testing"
+)
+
+func TestRejectIfDrained(t *testing.T) {
+	s := &Service{AgentID: "node-1"}
+	if err := s.rejectIfDrained(); err != nil {
+		t.Fatalf("expected tasks accepted before drain, got %v", err)
+	}
+	s.Drain()
+	s.Drain()
+	err := s.rejectIfDrained()
+	if err == nil {
+		t.Fatal("expected tasks rejected after drain")
+	}
+	if err.Code != 503 || string(err.Reason) != "node node-1 is drained for maintenance" {
+		t.Errorf("expected 503 with the drained reason, got %d %q", err.Code, err.Reason)
+	}
+	s.Uncordon()
+	if err = s.rejectIfDrained(); err != nil || s.IsDrained() {
+		t.Errorf("expected tasks accepted after uncordon, got %v", err)
+	}
+}
@@ -43,7 +43,12 @@ func (s *Service) runTaskStep(ctx context.Context, payload *service.MsgPayload,
 	ctx = component.WithOperationID(ctx, payload.OperationIdentity) // put operation ID into context
 	ctx = component.WithStepID(ctx, stepKey)                        // put step ID into context
 	ctx = component.WithOplog(ctx, s.oplog)                         // put operation log object into context
-	ctx = component.WithRepoMirror(ctx, s.repoMirror)
+	mirror := s.selectRepoMirror(ctx)
+	ctx = component.WithRepoMirror(ctx, mirror)
+	if mirror != "" {
+		logger.Debug("image pulls of step are served by repo mirror",
+			zap.String("operation", payload.OperationIdentity), zap.String("step", stepKey), zap.String("mirror", mirror))
+	}
 
 	var entry string
 	// truncate step log file
@@ -111,7 +116,23 @@ func (s *Service) taskHandler(msg *nats.Msg) {
 	var statusError *errors.StatusError
 
 	switch payload.Op {
+	case service.OperationDrainNode:
+		s.Drain()
+		responseMessage(msg, nil, nil)
+	case service.OperationUncordonNode:
+		s.Uncordon()
+		responseMessage(msg, nil, nil)
 	case service.OperationRunCmd:
+		if statusError = s.rejectIfDrained(); statusError != nil {
+			responseMessage(msg, nil, statusError)
+			return
+		}
+		var release func()
+		if release, statusError = s.acquireOperation(ctx); statusError != nil {
+			responseMessage(msg, nil, statusError)
+			return
+		}
+		defer release()
 		var replyData []byte
 		logger.Debug("run shell command", zap.Strings("cmd", payload.Cmds))
 		ec, err := cmdutil.RunCmdWithContext(ctx, payload.DryRun, payload.Cmds[0], payload.Cmds[1:]...)
@@ -151,6 +172,16 @@ func (s *Service) taskHandler(msg *nats.Msg) {
 			return
 		}
 	case service.OperationRunTask:
+		if statusError = s.rejectIfDrained(); statusError != nil {
+			responseMessage(msg, nil, statusError)
+			return
+		}
+		var release func()
+		if release, statusError = s.acquireOperation(ctx); statusError != nil {
+			responseMessage(msg, nil, statusError)
+			return
+		}
+		defer release()
 		var replyData []byte
 		for i := 0; i <= int(payload.Step.RetryTimes); i++ {
 			// reset retry field
@@ -173,6 +204,17 @@ func (s *Service) taskHandler(msg *nats.Msg) {
 	}
 }
 
+func (s *Service) rejectIfDrained() *errors.StatusError {
+	if !s.IsDrained() {
+		return nil
+	}
+	return &errors.StatusError{
+		Message: "node is unschedulable",
+		Reason:  errors.StatusReason(fmt.Sprintf("node %s is drained for maintenance", s.AgentID)),
+		Code:    503,
+	}
+}
+
 func runShellCommand(ctx context.Context, cmds []string, dryRun bool) error {
 	_, err := cmdutil.RunCmdWithContext(ctx, dryRun, cmds[0], cmds[1:]...)
 	return err
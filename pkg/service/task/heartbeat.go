@@ -0,0 +1,57 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package task
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultNodeStatusUpdateJitter spreads the node status updates of agents by ±10% of the update frequency.
+const DefaultNodeStatusUpdateJitter = 0.1
+
+// jitteredInterval returns period shifted by up to ±fraction of it, r is a random number in [0, 1).
+// A fraction out of (0, 1) means no jitter.
+func jitteredInterval(period time.Duration, fraction, r float64) time.Duration {
+	if fraction <= 0 || fraction >= 1 {
+		return period
+	}
+	return period + time.Duration((2*r-1)*fraction*float64(period))
+}
+
+// syncNodeStatusLoop syncs node status every NodeStatusUpdateFrequency with jitter until stopCh is closed,
+// so that the heartbeats of many agents started together don't stay synchronized.
+func (s *Service) syncNodeStatusLoop(stopCh <-chan struct{}) {
+	// the agents seed apart, the global source is seeded the same on every agent
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		s.syncNodeStatus()
+		t := s.clock.NewTimer(jitteredInterval(s.NodeStatusUpdateFrequency, s.nodeStatusUpdateJitter, rnd.Float64()))
+		select {
+		case <-stopCh:
+			t.Stop()
+			return
+		case <-t.C():
+		}
+	}
+}
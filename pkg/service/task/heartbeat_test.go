@@ -0,0 +1,46 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+package task
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitteredInterval(t *testing.T) {
+	period := time.Minute
+	min, max := 54*time.Second, 66*time.Second
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 1000; i++ {
+		if got := jitteredInterval(period, DefaultNodeStatusUpdateJitter, rnd.Float64()); got < min || got > max {
+			t.Fatalf("expected interval in [%v, %v], got %v", min, max, got)
+		}
+	}
+	if got := jitteredInterval(period, DefaultNodeStatusUpdateJitter, 0); got != min {
+		t.Errorf("expected the lower bound %v, got %v", min, got)
+	}
+	if got := jitteredInterval(period, DefaultNodeStatusUpdateJitter, 0.5); got != period {
+		t.Errorf("expected the period %v, got %v", period, got)
+	}
+	for _, fraction := range []float64{0, -0.1, 1} {
+		if got := jitteredInterval(period, fraction, 0.9); got != period {
+			t.Errorf("fraction %v: expected no jitter, got %v", fraction, got)
+		}
+	}
+}
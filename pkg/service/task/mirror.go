@@ -0,0 +1,113 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+)
+
+const (
+	mirrorProbeTimeout = 3 * time.Second
+	// mirrorSelectionTTL is how long a selected mirror, or the fallback to upstream, is reused by task steps
+	// before the mirrors are probed again.
+	mirrorSelectionTTL = time.Minute
+)
+
+// probeClient skips certificate verification, the probe only checks the mirror is up,
+// pulls verify the mirror by the trust of the container runtime.
+var probeClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
+// mirrorSelection caches the result of selectRepoMirror.
+type mirrorSelection struct {
+	mirror     string
+	selectedAt time.Time
+}
+
+// selectRepoMirror returns the first reachable mirror in order of priority,
+// or empty string to fall back to the upstream registry.
+// The selection is reused for mirrorSelectionTTL, so that dead mirrors are not probed on every step.
+func (s *Service) selectRepoMirror(ctx context.Context) string {
+	if len(s.repoMirrors) == 0 {
+		return ""
+	}
+	s.mirrorMux.Lock()
+	defer s.mirrorMux.Unlock()
+	if s.mirrorSelection != nil && s.clock.Since(s.mirrorSelection.selectedAt) < mirrorSelectionTTL {
+		return s.mirrorSelection.mirror
+	}
+	selected := ""
+	for _, mirror := range s.repoMirrors {
+		if err := s.probeMirror(ctx, mirror); err != nil {
+			logger.Warn("image repo mirror is unreachable, try next one", zap.String("mirror", mirror), zap.Error(err))
+			continue
+		}
+		logger.Debug("image repo mirror selected", zap.String("mirror", mirror))
+		selected = mirror
+		break
+	}
+	if selected == "" {
+		logger.Warn("no image repo mirror is reachable, fall back to upstream registry", zap.Strings("mirrors", s.repoMirrors))
+	}
+	s.mirrorSelection = &mirrorSelection{mirror: selected, selectedAt: s.clock.Now()}
+	return selected
+}
+
+// probeRepoMirror checks whether the registry V2 API of mirror responds, by https first and then http,
+// since a mirror is configured without scheme.
+func probeRepoMirror(ctx context.Context, mirror string) error {
+	// mirror may contain a namespace, e.g. 10.0.0.1:5000/library
+	host := strings.SplitN(mirror, "/", 2)[0]
+	var errs []string
+	for _, scheme := range []string{"https", "http"} {
+		err := probeURL(ctx, fmt.Sprintf("%s://%s/v2/", scheme, host))
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func probeURL(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, mirrorProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,110 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestSelectRepoMirror(t *testing.T) {
+	tests := []struct {
+		name      string
+		reachable map[string]bool
+		expected  string
+	}{
+		{name: "first reachable", reachable: map[string]bool{"10.0.0.1:5000": true, "10.0.0.2:5000": true}, expected: "10.0.0.1:5000"},
+		{name: "fall back to next", reachable: map[string]bool{"10.0.0.2:5000": true}, expected: "10.0.0.2:5000"},
+		{name: "fall back to upstream", reachable: map[string]bool{}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed []string
+			s := &Service{
+				clock:       clock.NewFakeClock(time.Now()),
+				repoMirrors: []string{"10.0.0.1:5000", "10.0.0.2:5000"},
+				probeMirror: func(ctx context.Context, mirror string) error {
+					probed = append(probed, mirror)
+					if !tt.reachable[mirror] {
+						return fmt.Errorf("connection refused")
+					}
+					return nil
+				},
+			}
+			if got := s.selectRepoMirror(context.TODO()); got != tt.expected {
+				t.Errorf("expected mirror %q, got %q", tt.expected, got)
+			}
+			if len(probed) == 0 || probed[0] != "10.0.0.1:5000" {
+				t.Errorf("expected mirrors probed in order of priority, got %v", probed)
+			}
+		})
+	}
+}
+
+func TestSelectRepoMirror_Cached(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	reachable := true
+	var probes int
+	s := &Service{
+		clock:       fakeClock,
+		repoMirrors: []string{"10.0.0.1:5000"},
+		probeMirror: func(ctx context.Context, mirror string) error {
+			probes++
+			if !reachable {
+				return fmt.Errorf("connection refused")
+			}
+			return nil
+		},
+	}
+	if got := s.selectRepoMirror(context.TODO()); got != "10.0.0.1:5000" {
+		t.Fatalf("expected mirror 10.0.0.1:5000, got %q", got)
+	}
+	reachable = false
+	fakeClock.Step(mirrorSelectionTTL / 2)
+	if got := s.selectRepoMirror(context.TODO()); got != "10.0.0.1:5000" || probes != 1 {
+		t.Errorf("expected the cached mirror without probing, got %q after %d probes", got, probes)
+	}
+	fakeClock.Step(mirrorSelectionTTL)
+	if got := s.selectRepoMirror(context.TODO()); got != "" || probes != 2 {
+		t.Errorf("expected mirrors probed again after the ttl and fall back to upstream, got %q after %d probes", got, probes)
+	}
+}
+
+func TestProbeRepoMirror(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	for _, server := range []*httptest.Server{httptest.NewTLSServer(handler), httptest.NewServer(handler)} {
+		mirror := strings.TrimPrefix(strings.TrimPrefix(server.URL, "https://"), "http://") + "/library"
+		if err := probeRepoMirror(context.TODO(), mirror); err != nil {
+			t.Errorf("expected mirror %s reachable, got %v", server.URL, err)
+		}
+		server.Close()
+		if err := probeRepoMirror(context.TODO(), mirror); err == nil {
+			t.Errorf("expected closed mirror %s unreachable", server.URL)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kubeclipper/kubeclipper/pkg/errors"
+)
+
+func TestAcquireOperation(t *testing.T) {
+	s := &Service{AgentID: "node-1"}
+	WithMaxConcurrentOperations(1)(s)
+
+	release, err := s.acquireOperation(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error acquiring a free slot: %v", err)
+	}
+	if running, queued := s.Operations(); running != 1 || queued != 0 {
+		t.Fatalf("expected 1 running and 0 queued, got %d and %d", running, queued)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan *errors.StatusError, 1)
+	go func() {
+		_, err := s.acquireOperation(ctx)
+		result <- err
+	}()
+	if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+		_, queued := s.Operations()
+		return queued > 0, nil
+	}); err != nil {
+		t.Fatal("expected the second operation to be queued")
+	}
+	cancel()
+	got := <-result
+	if got == nil {
+		t.Fatal("expected the queued operation to be rejected when ctx is cancelled")
+	}
+	if got.Code != 503 {
+		t.Errorf("expected status 503, got %d", got.Code)
+	}
+	if running, queued := s.Operations(); running != 1 || queued != 0 {
+		t.Errorf("expected 1 running and 0 queued after rejection, got %d and %d", running, queued)
+	}
+
+	release()
+	if n := len(s.operationSlots); n != 0 {
+		t.Fatalf("expected all slots free after release, got %d held", n)
+	}
+	release, err = s.acquireOperation(context.Background())
+	if err != nil {
+		t.Fatalf("expected the slot to be reusable after release, got %v", err)
+	}
+	release()
+	if running, _ := s.Operations(); running != 0 {
+		t.Errorf("expected 0 running, got %d", running)
+	}
+}
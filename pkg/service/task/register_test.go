@@ -0,0 +1,119 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+)
+
+// failingMQ fails every request, counting the attempts.
+type failingMQ struct {
+	natsio.Interface
+	requests int32
+}
+
+func (m *failingMQ) Request(msg *natsio.Msg, timeoutHandler natsio.TimeoutHandler) ([]byte, error) {
+	atomic.AddInt32(&m.requests, 1)
+	return nil, fmt.Errorf("no responders")
+}
+
+func TestRegisterWithBackoff(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	mq := &failingMQ{}
+	s := &Service{
+		AgentID:  "node-1",
+		mqClient: mq,
+		clock:    fakeClock,
+		registerBackoff: registerBackoff{
+			duration:   time.Second,
+			maxDelay:   3 * time.Second,
+			factor:     2,
+			maxRetries: 4,
+		},
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- s.registerWithBackoff(make(chan struct{}))
+	}()
+
+	// the third delay of 4s is capped to 3s.
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+			return fakeClock.HasWaiters(), nil
+		}); err != nil {
+			t.Fatalf("retry %d: expected a backoff timer", i+1)
+		}
+		fakeClock.Step(delay - time.Millisecond)
+		if !fakeClock.HasWaiters() {
+			t.Fatalf("retry %d: expected the backoff to last %v", i+1, delay)
+		}
+		fakeClock.Step(time.Millisecond)
+	}
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Fatal("expected an error once the retries are exhausted")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected registration to give up after the max retries")
+	}
+	if n := atomic.LoadInt32(&mq.requests); n != 4 {
+		t.Errorf("expected 4 register attempts, got %d", n)
+	}
+}
+
+func TestRegisterWithBackoff_Canceled(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	s := &Service{
+		AgentID:  "node-1",
+		mqClient: &failingMQ{},
+		clock:    fakeClock,
+		registerBackoff: registerBackoff{
+			duration:   time.Minute,
+			factor:     2,
+			maxRetries: 10,
+		},
+	}
+	stopCh := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- s.registerWithBackoff(stopCh)
+	}()
+	if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	}); err != nil {
+		t.Fatal("expected a backoff timer")
+	}
+	close(stopCh)
+	if err := <-result; err == nil {
+		t.Fatal("expected an error when stopped")
+	}
+	if fakeClock.HasWaiters() {
+		t.Error("expected the backoff timer to be stopped")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	goruntime "runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kubeclipper/kubeclipper/pkg/oplog"
@@ -39,12 +40,14 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/errors"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	"github.com/kubeclipper/kubeclipper/pkg/nodestatus"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
 	"github.com/kubeclipper/kubeclipper/pkg/service"
 	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/imageproxy"
 )
 
 var _ service.Interface = (*Service)(nil)
@@ -81,7 +84,9 @@ type Service struct {
 	//    status. Kubelet may fail to update node status reliably if the value is too small,
 	//    as it takes time to gather all necessary node information.
 	NodeStatusUpdateFrequency time.Duration
-	registrationCompleted     bool
+	// nodeStatusUpdateJitter is the fraction of NodeStatusUpdateFrequency each update is shifted by at random.
+	nodeStatusUpdateJitter float64
+	registrationCompleted  bool
 
 	// clock is an interface that provides time related functionality in a way that makes it
 	// easy to test the code.
@@ -98,7 +103,30 @@ type Service struct {
 	latestLease *coordinationv1.Lease
 	oplog       component.OperationLogFile
 	backupStore bs.BackupStore
-	repoMirror  string
+	// repoMirrors is a prioritized mirror list, the upstream registry is used when none is reachable.
+	repoMirrors []string
+	// probeMirror checks whether a mirror is reachable.
+	probeMirror     func(ctx context.Context, mirror string) error
+	mirrorMux       sync.Mutex
+	mirrorSelection *mirrorSelection
+	// registerBackoff controls node registration retries in PrepareRun,
+	// zero retries means registering lazily in syncNodeStatus.
+	registerBackoff registerBackoff
+	// drained is set to 1 when the agent rejects new tasks before maintenance.
+	drained int32
+	// runtimeErrors reports errors making the node not ready, e.g. missing required binaries.
+	runtimeErrors func() error
+	// operationSlots limits the number of operations run at once, nil means unlimited.
+	operationSlots    chan struct{}
+	runningOperations int32
+	queuedOperations  int32
+}
+
+type registerBackoff struct {
+	duration   time.Duration
+	maxDelay   time.Duration
+	factor     float64
+	maxRetries int
 }
 
 type ServiceOption func(*Service)
@@ -109,6 +137,14 @@ func WithNodeStatusUpdateFrequency(frequency time.Duration) ServiceOption {
 	}
 }
 
+// WithNodeStatusUpdateJitter shifts each node status update by up to ±fraction of the update frequency,
+// so that the heartbeats of a large fleet spread out. 0 disables the jitter.
+func WithNodeStatusUpdateJitter(fraction float64) ServiceOption {
+	return func(s *Service) {
+		s.nodeStatusUpdateJitter = fraction
+	}
+}
+
 func WithOplog(ol component.OperationLogFile) ServiceOption {
 	return func(s *Service) {
 		s.oplog = ol
@@ -122,8 +158,39 @@ func WithBackupStore(backupStore bs.BackupStore) ServiceOption {
 }
 
 func WithRepoMirror(mirror string) ServiceOption {
+	return WithRepoMirrors(imageproxy.ParseMirrors(mirror))
+}
+
+func WithRepoMirrors(mirrors []string) ServiceOption {
+	return func(s *Service) {
+		s.repoMirrors = mirrors
+	}
+}
+
+func WithRegisterBackoff(duration, maxDelay time.Duration, factor float64, maxRetries int) ServiceOption {
+	return func(s *Service) {
+		s.registerBackoff = registerBackoff{
+			duration:   duration,
+			maxDelay:   maxDelay,
+			factor:     factor,
+			maxRetries: maxRetries,
+		}
+	}
+}
+
+// WithRuntimeErrors sets the check whose error is reported on the node ready condition.
+func WithRuntimeErrors(fn func() error) ServiceOption {
 	return func(s *Service) {
-		s.repoMirror = mirror
+		s.runtimeErrors = fn
+	}
+}
+
+// WithMaxConcurrentOperations limits the agent to run at most n operations at once, the rest are queued.
+func WithMaxConcurrentOperations(n int) ServiceOption {
+	return func(s *Service) {
+		if n > 0 {
+			s.operationSlots = make(chan struct{}, n)
+		}
 	}
 }
 
@@ -170,6 +237,9 @@ func NewService(agentID, region, ipDetectMethod string, registerNode bool, natOp
 		RegisterNode:               registerNode,
 		clock:                      clock.RealClock{},
 		onRepeatedHeartbeatFailure: defaultRepeatedHeartbeatFailure,
+		runtimeErrors:              TODO,
+		probeMirror:                probeRepoMirror,
+		nodeStatusUpdateJitter:     DefaultNodeStatusUpdateJitter,
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -186,7 +256,7 @@ func (s *Service) Run(stopCh <-chan struct{}) error {
 	if err := s.mqClient.Subscribe(s.AgentSubject, s.msgHandler); err != nil {
 		return err
 	}
-	go wait.Until(s.syncNodeStatus, s.NodeStatusUpdateFrequency, stopCh)
+	go s.syncNodeStatusLoop(stopCh)
 	go s.fastStatusUpdateOnce()
 	// start syncing lease
 	// TODO: disable node lease provisional
@@ -195,7 +265,13 @@ func (s *Service) Run(stopCh <-chan struct{}) error {
 }
 
 func (s *Service) PrepareRun(stopCh <-chan struct{}) error {
-	return s.mqClient.InitConn(stopCh)
+	if err := s.mqClient.InitConn(stopCh); err != nil {
+		return err
+	}
+	if s.RegisterNode && s.registerBackoff.maxRetries > 0 {
+		return s.registerWithBackoff(stopCh)
+	}
+	return nil
 }
 
 func (s *Service) Close() {
@@ -208,11 +284,64 @@ func (s *Service) defaultNodeStatusFuncs() []func(*v1.Node) error {
 		nodestatus.Metadata(),
 		nodestatus.NodeAddress(s.IPDetect),
 		nodestatus.MachineInfo(),
-		nodestatus.ReadyCondition(s.clock.Now, TODO, TODO, TODO))
+		nodestatus.SchedulableCondition(s.clock.Now, s.IsDrained),
+		nodestatus.OperationQueue(s.Operations),
+		nodestatus.ReadyCondition(s.clock.Now, s.runtimeErrors, TODO, TODO))
 
 	return setters
 }
 
+// Drain marks the agent unschedulable, new tasks are rejected while in-flight ones keep running.
+func (s *Service) Drain() {
+	if atomic.CompareAndSwapInt32(&s.drained, 0, 1) {
+		logger.Info("node drained, new tasks will be rejected", zap.String("node_id", s.AgentID))
+	}
+}
+
+// Uncordon marks the agent schedulable again.
+func (s *Service) Uncordon() {
+	if atomic.CompareAndSwapInt32(&s.drained, 1, 0) {
+		logger.Info("node uncordoned, accepting new tasks", zap.String("node_id", s.AgentID))
+	}
+}
+
+func (s *Service) IsDrained() bool {
+	return atomic.LoadInt32(&s.drained) == 1
+}
+
+// Operations returns the number of running and queued operations.
+func (s *Service) Operations() (running, queued int) {
+	return int(atomic.LoadInt32(&s.runningOperations)), int(atomic.LoadInt32(&s.queuedOperations))
+}
+
+// acquireOperation waits for an operation slot, and returns the func releasing it.
+// The operation is rejected if ctx is done before a slot is available.
+func (s *Service) acquireOperation(ctx context.Context) (func(), *errors.StatusError) {
+	release := func() {
+		atomic.AddInt32(&s.runningOperations, -1)
+	}
+	if s.operationSlots == nil {
+		atomic.AddInt32(&s.runningOperations, 1)
+		return release, nil
+	}
+	atomic.AddInt32(&s.queuedOperations, 1)
+	defer atomic.AddInt32(&s.queuedOperations, -1)
+	select {
+	case s.operationSlots <- struct{}{}:
+		atomic.AddInt32(&s.runningOperations, 1)
+		return func() {
+			release()
+			<-s.operationSlots
+		}, nil
+	case <-ctx.Done():
+		return nil, &errors.StatusError{
+			Message: "node is busy",
+			Reason:  errors.StatusReason(fmt.Sprintf("timed out waiting for an operation slot on node %s", s.AgentID)),
+			Code:    503,
+		}
+	}
+}
+
 func TODO() error {
 	return nil
 }
@@ -283,6 +412,40 @@ func (s *Service) registerWithAPIServer() {
 	}
 }
 
+// registerWithBackoff registers node with exponential backoff,
+// and gives up once the max retries are exhausted.
+func (s *Service) registerWithBackoff(stopCh <-chan struct{}) error {
+	delay := s.registerBackoff.duration
+	for attempt := 1; ; attempt++ {
+		node, err := s.initialNode(context.TODO())
+		if err != nil {
+			logger.Error("Unable to construct v1.Node object for kubeclipper-agent", zap.Error(err))
+		} else {
+			logger.Info("Attempting to register node", zap.String("node_id", node.Name), zap.Int("attempt", attempt))
+			if s.tryRegisterWithAPIServer(node) {
+				logger.Info("Successfully registered node", zap.String("node_id", node.Name))
+				s.registrationCompleted = true
+				return nil
+			}
+		}
+		if attempt >= s.registerBackoff.maxRetries {
+			return fmt.Errorf("register node %s failed after %d attempts", s.AgentID, attempt)
+		}
+		logger.Info("Retry registering node", zap.String("node_id", s.AgentID), zap.Duration("delay", delay))
+		timer := s.clock.NewTimer(delay)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return fmt.Errorf("register node %s canceled", s.AgentID)
+		case <-timer.C():
+		}
+		delay = time.Duration(float64(delay) * s.registerBackoff.factor)
+		if s.registerBackoff.maxDelay > 0 && delay > s.registerBackoff.maxDelay {
+			delay = s.registerBackoff.maxDelay
+		}
+	}
+}
+
 func (s *Service) initialNode(ctx context.Context) (*v1.Node, error) {
 	node := &v1.Node{
 		TypeMeta: metav1.TypeMeta{
@@ -36,6 +36,7 @@ const (
 	componentPath     = "/api/core.kubeclipper.io/v1/clusters/%s/plugins"
 	backupPath        = "/api/core.kubeclipper.io/v1/backups"
 	backupPonitPath   = "/api/core.kubeclipper.io/v1/backuppoints"
+	operationsPath    = "/api/core.kubeclipper.io/v1/operations"
 	usersPath         = "/api/iam.kubeclipper.io/v1/users"
 	rolesPath         = "/api/iam.kubeclipper.io/v1/roles"
 	platformPath      = "/api/config.kubeclipper.io/v1/template"
@@ -74,6 +75,20 @@ func (cli *Client) DeleteNode(ctx context.Context, name string) error {
 	return err
 }
 
+// CordonNode drains the agent of node, it rejects new tasks while finishing in-flight ones.
+func (cli *Client) CordonNode(ctx context.Context, name string) error {
+	serverResp, err := cli.patch(ctx, fmt.Sprintf("%s/%s/cordon", listNodesPath, name), nil, nil, nil)
+	defer ensureReaderClosed(serverResp)
+	return err
+}
+
+// UncordonNode makes the agent of node accept new tasks again.
+func (cli *Client) UncordonNode(ctx context.Context, name string) error {
+	serverResp, err := cli.patch(ctx, fmt.Sprintf("%s/%s/uncordon", listNodesPath, name), nil, nil, nil)
+	defer ensureReaderClosed(serverResp)
+	return err
+}
+
 func (cli *Client) ListUsers(ctx context.Context, query Queries) (*UsersList, error) {
 	serverResp, err := cli.get(ctx, usersPath, query.ToRawQuery(), nil)
 	defer ensureReaderClosed(serverResp)
@@ -277,6 +292,31 @@ func (cli *Client) ListBackupsWithCluster(ctx context.Context, clusterName strin
 	return &backups, err
 }
 
+func (cli *Client) ListOperations(ctx context.Context, query Queries) (*OperationList, error) {
+	serverResp, err := cli.get(ctx, operationsPath, query.ToRawQuery(), nil)
+	defer ensureReaderClosed(serverResp)
+	if err != nil {
+		return nil, err
+	}
+	operations := OperationList{}
+	err = json.NewDecoder(serverResp.body).Decode(&operations)
+	return &operations, err
+}
+
+func (cli *Client) DescribeOperation(ctx context.Context, name string) (*OperationList, error) {
+	serverResp, err := cli.get(ctx, fmt.Sprintf("%s/%s", operationsPath, name), nil, nil)
+	defer ensureReaderClosed(serverResp)
+	if err != nil {
+		return nil, err
+	}
+	op := v1.Operation{}
+	err = json.NewDecoder(serverResp.body).Decode(&op)
+	operations := OperationList{
+		Items: []v1.Operation{op},
+	}
+	return &operations, err
+}
+
 func (cli *Client) DescribeBackup(ctx context.Context, backupName string) (*BackupList, error) {
 	resp, err := cli.get(ctx, fmt.Sprintf("%s/%s", backupPath, backupName), nil, nil)
 	defer ensureReaderClosed(resp)
@@ -236,6 +236,11 @@ type BackupList struct {
 	TotalCount int         `json:"totalCount,omitempty" description:"total count"`
 }
 
+type OperationList struct {
+	Items      []v1.Operation `json:"items" description:"paging data"`
+	TotalCount int            `json:"totalCount,omitempty" description:"total count"`
+}
+
 type BackupPointList struct {
 	Items      []v1.BackupPoint `json:"items" description:"paging data"`
 	TotalCount int              `json:"totalCount,omitempty" description:"total count"`
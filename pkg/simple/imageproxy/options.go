@@ -3,11 +3,13 @@ package imageproxy
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
+	// KcImageRepoMirror is a comma separated list of mirrors in order of priority.
 	KcImageRepoMirror string `json:"kcImageRepoMirror" yaml:"kcImageRepoMirror"`
 }
 
@@ -17,19 +19,37 @@ func NewOptions() *Options {
 	}
 }
 
+// Mirrors returns the prioritized mirror list.
+func (s *Options) Mirrors() []string {
+	return ParseMirrors(s.KcImageRepoMirror)
+}
+
 func (s *Options) Validate() []error {
 	var errs []error
-	uri, err := url.Parse(s.KcImageRepoMirror)
-	if err != nil {
-		errs = append(errs, err)
-		return errs
-	}
-	if uri.Scheme != "" {
-		errs = append(errs, fmt.Errorf("kc image repo mirror is not support incoming protcol: %s", uri.Scheme))
+	for _, mirror := range s.Mirrors() {
+		uri, err := url.Parse(mirror)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if uri.Scheme != "" {
+			errs = append(errs, fmt.Errorf("kc image repo mirror is not support incoming protcol: %s", uri.Scheme))
+		}
 	}
 	return errs
 }
 
+// ParseMirrors splits a comma separated mirror list, empty items are dropped.
+func ParseMirrors(mirrors string) []string {
+	var list []string
+	for _, mirror := range strings.Split(mirrors, ",") {
+		if mirror = strings.TrimSpace(mirror); mirror != "" {
+			list = append(list, mirror)
+		}
+	}
+	return list
+}
+
 func (s *Options) AddFlags(fs *pflag.FlagSet) {
-	fs.StringVar(&s.KcImageRepoMirror, "kc-image-repo-mirror", s.KcImageRepoMirror, "K8s image repository mirror")
+	fs.StringVar(&s.KcImageRepoMirror, "kc-image-repo-mirror", s.KcImageRepoMirror, "K8s image repository mirrors, separated by comma in order of priority")
 }
@@ -0,0 +1,49 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package imageproxy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMirrors(t *testing.T) {
+	tests := []struct {
+		mirrors  string
+		expected []string
+	}{
+		{mirrors: "", expected: nil},
+		{mirrors: "10.0.0.1:5000", expected: []string{"10.0.0.1:5000"}},
+		{mirrors: " 10.0.0.1:5000, ,10.0.0.2:5000/library,", expected: []string{"10.0.0.1:5000", "10.0.0.2:5000/library"}},
+	}
+	for _, tt := range tests {
+		if got := ParseMirrors(tt.mirrors); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseMirrors(%q) = %v, want %v", tt.mirrors, got, tt.expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if errs := (&Options{KcImageRepoMirror: "10.0.0.1:5000,10.0.0.2:5000"}).Validate(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if errs := (&Options{KcImageRepoMirror: "10.0.0.1:5000,https://10.0.0.2:5000"}).Validate(); len(errs) != 1 {
+		t.Errorf("expected an error for the mirror with scheme, got %v", errs)
+	}
+}
@@ -36,16 +36,23 @@ type RespError struct {
 }
 
 func CommonRequest(requestURL, httpMethod string, header, rawQuery map[string]string, postBody json.RawMessage) ([]byte, int, error) {
+	body, code, _, err := CommonRequestWithHeader(requestURL, httpMethod, header, rawQuery, postBody)
+	return body, code, err
+}
+
+// CommonRequestWithHeader is like CommonRequest, and returns the response header as well.
+func CommonRequestWithHeader(requestURL, httpMethod string, header, rawQuery map[string]string, postBody json.RawMessage) ([]byte, int, http.Header, error) {
 	var req *http.Request
 	var reqErr error
 
 	req, reqErr = http.NewRequest(httpMethod, requestURL, bytes.NewReader(postBody))
 	if reqErr != nil {
-		return []byte{}, http.StatusInternalServerError, reqErr
+		return []byte{}, http.StatusInternalServerError, nil, reqErr
 	}
 	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	// header overrides the default content type, e.g. the media type of a manifest
 	for key, val := range header {
-		req.Header.Add(key, val)
+		req.Header.Set(key, val)
 	}
 	if rawQuery != nil {
 		p := make(url.Values)
@@ -67,14 +74,14 @@ func CommonRequest(requestURL, httpMethod string, header, rawQuery map[string]st
 	client.Timeout = 5 * time.Second
 	resp, respErr := client.Do(req)
 	if respErr != nil {
-		return []byte{}, http.StatusInternalServerError, respErr
+		return []byte{}, http.StatusInternalServerError, nil, respErr
 	}
 	defer resp.Body.Close()
 	body, readBodyErr := ioutil.ReadAll(resp.Body)
 	if readBodyErr != nil {
-		return []byte{}, http.StatusInternalServerError, readBodyErr
+		return []byte{}, http.StatusInternalServerError, nil, readBodyErr
 	}
-	return body, resp.StatusCode, nil
+	return body, resp.StatusCode, resp.Header, nil
 }
 
 func CodeDispose(body []byte, code int) ([]byte, error) {
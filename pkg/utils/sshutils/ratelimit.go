@@ -0,0 +1,52 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package sshutils
+
+import (
+	"io"
+	"time"
+)
+
+// rateLimitReader limits the throughput of reading r to limit bytes per second.
+type rateLimitReader struct {
+	r     io.Reader
+	limit int64
+	start time.Time
+	read  int64
+	// sleep is time.Sleep, replaced in test
+	sleep func(time.Duration)
+}
+
+func newRateLimitReader(r io.Reader, limit int64) *rateLimitReader {
+	return &rateLimitReader{r: r, limit: limit, start: time.Now(), sleep: time.Sleep}
+}
+
+// Read reads at most one second worth of bytes, and then sleeps until the bytes read so far are within the limit.
+func (l *rateLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.limit {
+		p = p[:l.limit]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	expected := time.Duration(float64(l.read) / float64(l.limit) * float64(time.Second))
+	if d := expected - time.Since(l.start); d > 0 {
+		l.sleep(d)
+	}
+	return n, err
+}
@@ -0,0 +1,59 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package sshutils
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimitReader(t *testing.T) {
+	data := bytes.Repeat([]byte("k"), 10*KB)
+	r := newRateLimitReader(bytes.NewReader(data), 2*KB)
+	var slept time.Duration
+	r.sleep = func(d time.Duration) {
+		slept += d
+		// the clock moves on while sleeping
+		r.start = r.start.Add(-d)
+	}
+	buf := make([]byte, 100*KB)
+	var out bytes.Buffer
+	for {
+		n, err := r.Read(buf)
+		if n > 2*KB {
+			t.Fatalf("expected at most %d bytes in a read, got %d", 2*KB, n)
+		}
+		out.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("expected %d bytes read unchanged, got %d bytes", len(data), out.Len())
+	}
+	// 10KB at 2KB/s takes 5s, part of which was spent reading
+	if slept < 4900*time.Millisecond || slept > 5*time.Second {
+		t.Errorf("expected about 5s slept, got %v", slept)
+	}
+}
@@ -20,6 +20,7 @@ package sshutils
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
@@ -144,11 +145,15 @@ func (ss *SSH) Copy(host, localFilePath, remoteFilePath string) error {
 
 	}
 	defer dstFile.Close()
+	var src io.Reader = srcFile
+	if ss.UploadRateLimit > 0 {
+		src = newRateLimitReader(srcFile, ss.UploadRateLimit)
+	}
 	buf := make([]byte, 100*MB) // 100mb
 	total := 0
 	unit := ""
 	for {
-		n, _ := srcFile.Read(buf)
+		n, _ := src.Read(buf)
 		if n == 0 {
 			break
 		}
@@ -40,6 +40,26 @@ type SSH struct {
 	PkFile            string         `json:"pkFile" yaml:"pkFile,omitempty"`
 	PkPassword        string         `json:"pkPassword" yaml:"pkPassword,omitempty"`
 	ConnectionTimeout *time.Duration `json:"connectionTimeout,omitempty" yaml:"connectionTimeout,omitempty"`
+	// KeepAliveInterval is the interval of keepalive requests sent on an idle connection, 0 disables keepalive.
+	KeepAliveInterval time.Duration `json:"keepAliveInterval,omitempty" yaml:"keepAliveInterval,omitempty"`
+	// UploadRateLimit is the maximum bytes per second of copying a file to host, 0 means unlimited.
+	UploadRateLimit int64 `json:"uploadRateLimit,omitempty" yaml:"uploadRateLimit,omitempty"`
+	// SudoCmd is the privilege escalation command used by the non-root user, e.g. doas or a sudo at non-standard path,
+	// empty means DefaultSudoCmd. Password is piped to a sudo only, other commands must permit the user without password.
+	SudoCmd string `json:"sudoCmd,omitempty" yaml:"sudoCmd,omitempty"`
+	// Port is the ssh port of hosts without an explicit 'host:port', 0 means DefaultPort.
+	Port int `json:"port,omitempty" yaml:"port,omitempty"`
+}
+
+// DefaultPort is the ssh port if SSH.Port is not specified.
+const DefaultPort = 22
+
+// ConnectPort returns the ssh port of hosts without an explicit 'host:port'.
+func (ss *SSH) ConnectPort() int {
+	if ss.Port == 0 {
+		return DefaultPort
+	}
+	return ss.Port
 }
 
 func (ss *SSH) Connect(host string) (*ssh.Session, error) {
@@ -89,12 +109,41 @@ func (ss *SSH) connect(host string) (*ssh.Client, error) {
 	}
 
 	addr := ss.addrReformat(host)
-	return ssh.Dial("tcp", addr, clientConfig)
+	client, err := ssh.Dial("tcp", addr, clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	if ss.KeepAliveInterval > 0 {
+		go keepAlive(client, ss.KeepAliveInterval)
+	}
+	return client, nil
+}
+
+// keepAlive sends keepalive requests every interval until the client is closed,
+// so that firewalls don't drop the connection during long-running commands.
+func keepAlive(client *ssh.Client, interval time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				return
+			}
+		}
+	}
 }
 
 func (ss *SSH) addrReformat(host string) string {
 	if !strings.Contains(host, ":") {
-		host = fmt.Sprintf("%s:22", host)
+		host = fmt.Sprintf("%s:%d", host, ss.ConnectPort())
 	}
 	return host
 }
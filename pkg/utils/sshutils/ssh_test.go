@@ -0,0 +1,122 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package sshutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHAddrReformat(t *testing.T) {
+	tests := []struct {
+		name string
+		port int
+		host string
+		want string
+	}{
+		{name: "default port", host: "10.0.0.111", want: "10.0.0.111:22"},
+		{name: "custom port", port: 2222, host: "10.0.0.111", want: "10.0.0.111:2222"},
+		{name: "explicit port", port: 2222, host: "10.0.0.111:2200", want: "10.0.0.111:2200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SSH{Port: tt.port}
+			if got := ss.addrReformat(tt.host); got != tt.want {
+				t.Errorf("addrReformat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// newPipeClient returns a ssh client connected to an in-memory server, keepalive
+// requests received by the server are sent to the returned channel.
+func newPipeClient(t *testing.T) (*ssh.Client, <-chan struct{}) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	clientConn, serverConn := net.Pipe()
+	keepalives := make(chan struct{}, 16)
+	go func() {
+		_, chans, reqs, err := ssh.NewServerConn(serverConn, serverConfig)
+		if err != nil {
+			return
+		}
+		go func() {
+			for ch := range chans {
+				_ = ch.Reject(ssh.Prohibited, "no channels")
+			}
+		}()
+		for req := range reqs {
+			if req.Type == "keepalive@openssh.com" {
+				select {
+				case keepalives <- struct{}{}:
+				default:
+				}
+			}
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}()
+
+	conn, chans, reqs, err := ssh.NewClientConn(clientConn, "pipe", &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ssh.NewClient(conn, chans, reqs), keepalives
+}
+
+func TestKeepAlive(t *testing.T) {
+	client, keepalives := newPipeClient(t)
+	done := make(chan struct{})
+	go func() {
+		keepAlive(client, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-keepalives:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a keepalive request on the idle connection")
+	}
+
+	_ = client.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected keepAlive to return once the client is closed")
+	}
+}
@@ -20,7 +20,9 @@ package sshutils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 
@@ -43,11 +45,21 @@ func (r Result) Short() string {
 	return fmt.Sprintf("(run `%s` on %s@%s)", r.PrintCmd, r.User, r.Host)
 }
 
+// Error returns a *CmdError if the command exits non-zero.
 func (r Result) Error() error {
 	if r.ExitCode == 0 {
 		return nil
 	}
-	return fmt.Errorf("%s err: %s", r.Short(), r.Stderr)
+	return &CmdError{Result: r}
+}
+
+// CmdError is the error of a command which ran on host and exited non-zero.
+type CmdError struct {
+	Result Result
+}
+
+func (e *CmdError) Error() string {
+	return fmt.Sprintf("%s err: %s", e.Result.Short(), e.Result.Stderr)
 }
 
 func (r Result) String() string {
@@ -130,6 +142,54 @@ func runSSHCommand(sshConfig *SSH, host, cmd string) (stdout, stderr string, exi
 	return bout.String(), berr.String(), exitcode, err
 }
 
+// SSHStreamCmd runs cmd on host and writes its output to stdout and stderr as the command runs.
+type SSHStreamCmd func(ctx context.Context, sshConfig *SSH, host, cmd string, stdout, stderr io.Writer) (int, error)
+
+// SSHStreamCmdWithSudo is like SSHCmdWithSudo, and writes the output to stdout and stderr as the command runs
+// instead of buffering it, e.g. to follow logs. The session is closed once ctx is done, which returns without error.
+func SSHStreamCmdWithSudo(ctx context.Context, sshConfig *SSH, host, cmd string, stdout, stderr io.Writer) (int, error) {
+	sudoCmd, err := fillCmd(sshConfig, cmd)
+	if err != nil {
+		return 0, err
+	}
+	return SSHCmdStream(ctx, sshConfig, host, sudoCmd, stdout, stderr)
+}
+
+// SSHCmdStream is like SSHStreamCmdWithSudo, but runs cmd as the ssh user without sudo.
+func SSHCmdStream(ctx context.Context, sshConfig *SSH, host, cmd string, stdout, stderr io.Writer) (int, error) {
+	pCmd := printCmd(sshConfig.Password, cmd)
+	logger.V(2).Infof("streaming `%s` on %s@%s", pCmd, sshConfig.User, host)
+	client, err := sshConfig.NewClient(host)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.Close()
+
+	session.Stdout, session.Stderr = stdout, stderr
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+	select {
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGINT)
+		return 0, nil
+	case err = <-done:
+	}
+	if exiterr, ok := err.(*ssh.ExitError); ok {
+		return exiterr.ExitStatus(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed running `%s` on %s@%s: '%v'", pCmd, sshConfig.User, host, err)
+	}
+	return 0, nil
+}
+
 type Walk func(result Result, err error) error
 
 func DefaultWalk(result Result, err error) error {
@@ -20,9 +20,27 @@ package sshutils
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
+// DefaultSudoCmd is the privilege escalation command if SSH.SudoCmd is not specified.
+const DefaultSudoCmd = "sudo"
+
+// SudoCommand returns the privilege escalation command of the ssh user.
+func (ss *SSH) SudoCommand() string {
+	if ss.SudoCmd == "" {
+		return DefaultSudoCmd
+	}
+	return ss.SudoCmd
+}
+
+// SudoReadsPassword reports whether the privilege escalation command reads password from stdin by -S, i.e. it is
+// a sudo. Other commands, e.g. doas, have no such option, the user must be permitted without password.
+func (ss *SSH) SudoReadsPassword() bool {
+	return path.Base(strings.Fields(ss.SudoCommand() + " ")[0]) == DefaultSudoCmd
+}
+
 func fillCmd(sshConfig *SSH, cmd string) (string, error) {
 	if sshConfig.User == "root" {
 		return cmd, nil
@@ -32,12 +50,12 @@ func fillCmd(sshConfig *SSH, cmd string) (string, error) {
 	list := make([]string, 0, len(split))
 	for _, v := range split {
 		var sudoCmd string
-		if sshConfig.Password != "" {
+		if sshConfig.Password != "" && sshConfig.SudoReadsPassword() {
 			// use `echo '$passwd' |sudo -S $cmd` cmd avoid interactive enter passwd
-			sudoCmd = fmt.Sprintf("echo '%s' | sudo -S %s", sshConfig.Password, v)
+			sudoCmd = fmt.Sprintf("echo '%s' | %s -S %s", sshConfig.Password, sshConfig.SudoCommand(), v)
 		} else {
-			// no passwd maybe user configured NOPASSWD in sudoers.
-			sudoCmd = "sudo " + v
+			// no passwd maybe user configured NOPASSWD in sudoers, or nopass in doas.conf.
+			sudoCmd = sshConfig.SudoCommand() + " " + v
 		}
 		list = append(list, sudoCmd)
 	}
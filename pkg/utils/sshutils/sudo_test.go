@@ -44,3 +44,63 @@ func TestBuildEcho(t *testing.T) {
 		t.Failed()
 	}
 }
+
+func TestFillCmdSudoCmd(t *testing.T) {
+	cmd := "systemctl daemon-reload && systemctl enable kc-etcd"
+	tests := []struct {
+		name string
+		ssh  *SSH
+		want string
+	}{
+		{
+			name: "root",
+			ssh:  &SSH{User: "root", SudoCmd: "doas"},
+			want: cmd,
+		},
+		{
+			name: "default sudo",
+			ssh:  &SSH{User: "kc"},
+			want: "sudo systemctl daemon-reload &&sudo  systemctl enable kc-etcd",
+		},
+		{
+			name: "custom sudo cmd",
+			ssh:  &SSH{User: "kc", SudoCmd: "doas"},
+			want: "doas systemctl daemon-reload &&doas  systemctl enable kc-etcd",
+		},
+		{
+			name: "doas with password",
+			ssh:  &SSH{User: "kc", Password: "pw", SudoCmd: "/usr/bin/doas"},
+			want: "/usr/bin/doas systemctl daemon-reload &&/usr/bin/doas  systemctl enable kc-etcd",
+		},
+		{
+			name: "custom sudo cmd with password",
+			ssh:  &SSH{User: "kc", Password: "pw", SudoCmd: "/opt/bin/sudo"},
+			want: "echo 'pw' | /opt/bin/sudo -S systemctl daemon-reload &&echo 'pw' | /opt/bin/sudo -S  systemctl enable kc-etcd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fillCmd(tt.ssh, cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("fillCmd() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSudoReadsPassword(t *testing.T) {
+	for sudoCmd, want := range map[string]bool{
+		"":              true,
+		"/opt/bin/sudo": true,
+		"sudo -E":       true,
+		"doas":          false,
+		"/usr/bin/doas": false,
+	} {
+		if got := (&SSH{SudoCmd: sudoCmd}).SudoReadsPassword(); got != want {
+			t.Errorf("SudoReadsPassword() of %q = %v, want %v", sudoCmd, got, want)
+		}
+	}
+}
@@ -69,7 +69,7 @@ var _ = SIGDescribe("[Slow] [Serial] Recovery", func() {
 		_, err := f.Client.CreateRecovery(context.TODO(), clu.Name, initRecovery(backupName))
 		framework.ExpectError(err)
 		ginkgo.By(" check recovery successful")
-		err = cluster.WaitForRecovery(f.Client, clu.Name, f.Timeouts.CommonTimeout)
+		err = cluster.WaitForRecoveryToVersion(f.Client, clu.Name, backupName, f.Timeouts.CommonTimeout)
 		framework.ExpectNoError(err)
 	})
 })
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
+	"github.com/kubeclipper/kubeclipper/test/framework"
+)
+
+// endpointProbeTimeout bounds a single reachability probe of the apiserver endpoint.
+const endpointProbeTimeout = 5 * time.Second
+
+// clusterEndpoint returns the apiserver URL of the current context in the kubeconfig of the cluster.
+func clusterEndpoint(clu *corev1.Cluster) (*url.URL, error) {
+	if len(clu.KubeConfig) == 0 {
+		return nil, fmt.Errorf("cluster %s has no kubeconfig yet", clu.Name)
+	}
+	config, err := clientcmd.Load(clu.KubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig of cluster %s: %w", clu.Name, err)
+	}
+	current, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig of cluster %s has no current context %q", clu.Name, config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[current.Cluster]
+	if !ok || cluster.Server == "" {
+		return nil, fmt.Errorf("kubeconfig of cluster %s has no server of cluster %q", clu.Name, current.Cluster)
+	}
+	endpoint, err := url.Parse(cluster.Server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server %q in kubeconfig of cluster %s: %w", cluster.Server, clu.Name, err)
+	}
+	return endpoint, nil
+}
+
+// probeEndpoint checks endpoint accepts TCP connections, and answers HTTP(S) requests to /healthz.
+// Any response means reachable, the probe is unauthenticated and may well be refused with 401 or 403.
+func probeEndpoint(endpoint *url.URL) error {
+	host := endpoint.Host
+	if endpoint.Port() == "" {
+		port := "443"
+		if endpoint.Scheme == "http" {
+			port = "80"
+		}
+		host = net.JoinHostPort(endpoint.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", host, endpointProbeTimeout)
+	if err != nil {
+		return err
+	}
+	_ = conn.Close()
+	client := &http.Client{
+		Timeout: endpointProbeTimeout,
+		Transport: &http.Transport{
+			// reachability only, the serving certificate is verified by the clients of the cluster
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err := client.Get(endpoint.Scheme + "://" + host + "/healthz")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// WaitForClusterEndpointReachable waits the cluster to be running and its apiserver endpoint, from the kubeconfig
+// of the cluster, to accept connections. The timeout error tells a cluster which is running but unreachable from
+// one which is not running, with the last probe error.
+func WaitForClusterEndpointReachable(c *kc.Client, clusterName string, timeout time.Duration, opts ...WaitOption) error {
+	var (
+		phase    corev1.ClusterPhase
+		endpoint string
+		probeErr error
+	)
+	err := WaitForResourceCondition(context.TODO(), clusterGetter(context.TODO(), c, clusterName),
+		fmt.Sprintf("cluster %s apiserver endpoint to be reachable", clusterName), timeout, func(clu *corev1.Cluster) (bool, error) {
+			phase = clu.Status.Phase
+			if phase != corev1.ClusterRunning {
+				framework.Logf("Cluster %q: Phase=%q, waiting for it to be running", clusterName, phase)
+				return false, nil
+			}
+			u, err := clusterEndpoint(clu)
+			if err != nil {
+				probeErr = err
+				framework.Logf("Cluster %q: %v", clusterName, err)
+				return false, nil
+			}
+			endpoint = u.String()
+			if probeErr = probeEndpoint(u); probeErr != nil {
+				framework.Logf("Cluster %q: Phase=%q, apiserver %s unreachable: %v", clusterName, phase, endpoint, probeErr)
+				return false, nil
+			}
+			return true, nil
+		}, opts...)
+	if !IsTimeout(err) {
+		return err
+	}
+	details, _ := TimeoutDetails(err)
+	switch {
+	case phase != corev1.ClusterRunning:
+		return TimeoutError(fmt.Sprintf("%s, cluster is %q, not running", err.Error(), phase), details...)
+	case endpoint == "":
+		return TimeoutError(fmt.Sprintf("%s, cluster reports running but its endpoint is unknown: %v", err.Error(), probeErr), details...)
+	default:
+		return TimeoutError(fmt.Sprintf("%s, cluster reports running but apiserver %s is unreachable: %v",
+			err.Error(), endpoint, probeErr), details...)
+	}
+}
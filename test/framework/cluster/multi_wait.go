@@ -0,0 +1,169 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
+)
+
+// WaitFunc is a single wait to be run by WaitForAll, e.g. a closure over WaitForClusterRunning.
+type WaitFunc func() error
+
+// MultiWaitError aggregates the failures of the waits run by WaitForAll, keyed by wait name.
+type MultiWaitError struct {
+	Errors map[string]error
+}
+
+func (e *MultiWaitError) Error() string {
+	names := e.names()
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		kind := "error"
+		if IsTimeout(e.Errors[name]) {
+			kind = "timeout"
+		}
+		msgs = append(msgs, fmt.Sprintf("%s (%s): %v", name, kind, e.Errors[name]))
+	}
+	return fmt.Sprintf("%d of the waits failed: [%s]", len(names), strings.Join(msgs, "; "))
+}
+
+// Timeouts returns the names of the waits which timed out.
+func (e *MultiWaitError) Timeouts() []string {
+	var names []string
+	for _, name := range e.names() {
+		if IsTimeout(e.Errors[name]) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// HardErrors returns the failures of the waits which didn't time out.
+func (e *MultiWaitError) HardErrors() map[string]error {
+	errs := make(map[string]error)
+	for name, err := range e.Errors {
+		if !IsTimeout(err) {
+			errs[name] = err
+		}
+	}
+	return errs
+}
+
+// Details returns the objects observed by every timed out wait, keyed by wait name.
+func (e *MultiWaitError) Details() map[string][]interface{} {
+	details := make(map[string][]interface{})
+	for name, err := range e.Errors {
+		if objs, ok := TimeoutDetails(err); ok {
+			details[name] = objs
+		}
+	}
+	return details
+}
+
+// allTimeouts reports whether every aggregated failure is a timeout.
+func (e *MultiWaitError) allTimeouts() bool {
+	if len(e.Errors) == 0 {
+		return false
+	}
+	for _, err := range e.Errors {
+		if !IsTimeout(err) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *MultiWaitError) names() []string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WaitForAll runs the waits concurrently and waits all of them to finish.
+// It returns nil if all waits succeed, or a *MultiWaitError holding every failure,
+// on which IsTimeout is true if all of them timed out.
+func WaitForAll(waits map[string]WaitFunc) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[string]error)
+	)
+	for name, fn := range waits {
+		wg.Add(1)
+		go func(name string, fn WaitFunc) {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}
+		}(name, fn)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiWaitError{Errors: errs}
+}
+
+// errWaitAborted stops the waits of WaitForClustersRunning after another cluster failed.
+var errWaitAborted = errors.New("aborted after another cluster failed")
+
+// WaitForClustersRunning waits the clusters to be running concurrently, instead of calling WaitForClusterRunning one by one.
+// It returns nil once all clusters are running. On the first failure which is not a timeout, the other waits are aborted
+// at their next poll, and the returned *MultiWaitError holds that failure only. Otherwise it holds every timeout
+// with the last observed cluster, and IsTimeout is true on it.
+func WaitForClustersRunning(c *kc.Client, timeout time.Duration, clusterNames ...string) error {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed bool
+		abort  = make(chan struct{})
+		errs   = make(map[string]error)
+	)
+	condition := func(clu *corev1.Cluster) (bool, error) {
+		select {
+		case <-abort:
+			return true, errWaitAborted
+		default:
+		}
+		return clusterRunning(clu)
+	}
+	for _, name := range clusterNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := WaitForClusterCondition(c, name, fmt.Sprintf("cluster %s running", name), timeout, condition)
+			if err == nil || errors.Is(err, errWaitAborted) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if failed {
+				return
+			}
+			if IsTimeout(err) {
+				errs[name] = err
+				return
+			}
+			// the first hard failure replaces the timeouts collected so far
+			failed = true
+			errs = map[string]error{name: err}
+			close(abort)
+		}(name)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiWaitError{Errors: errs}
+}
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kubeclipper/kubeclipper/test/framework"
+)
+
+// ErrConditionNotMet is returned in plan mode when the single evaluation of the condition is false.
+var ErrConditionNotMet = errors.New("condition not met")
+
+// WithPlanMode makes the waiters built on WaitForResourceCondition, e.g. WaitForClusterCondition, get the
+// resource once, log the condition result and the resource, and return without polling. It is for
+// introspecting a condition against a live cluster, e.g. when documenting or debugging e2e tests, never
+// for waiting. The timeout and the other polling options, e.g. WithStablePolls, have no effect.
+func WithPlanMode() WaitOption {
+	return func(o *waitOptions) {
+		o.plan = true
+	}
+}
+
+// planResource evaluates condition on the resource once. It returns nil if the condition is met,
+// ErrConditionNotMet if not, or the error of getting the resource or of the condition.
+func planResource[T any](ctx context.Context, getter func() (T, error), conditionDesc string, condition func(T) (bool, error)) error {
+	framework.Logf("Plan: evaluating %s once", conditionDesc)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	obj, err := getter()
+	if err != nil {
+		framework.Logf("Plan: getting resource for %s failed: %v", conditionDesc, err)
+		return fmt.Errorf("error while planning %s: %w", conditionDesc, err)
+	}
+	done, err := condition(obj)
+	if content, marshalErr := json.MarshalIndent(obj, "", "  "); marshalErr == nil {
+		framework.Logf("Plan: %s: met=%v, err=%v, resource:\n%s", conditionDesc, done, err, content)
+	} else {
+		framework.Logf("Plan: %s: met=%v, err=%v, resource: %+v", conditionDesc, done, err, obj)
+	}
+	switch {
+	case err != nil:
+		return err
+	case !done:
+		return fmt.Errorf("%s: %w", conditionDesc, ErrConditionNotMet)
+	}
+	return nil
+}
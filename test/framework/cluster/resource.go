@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/kubeclipper/kubeclipper/pkg/errors"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
+	"github.com/kubeclipper/kubeclipper/test/framework"
+)
+
+// WaitForResourceCondition polls getter until condition is met, it's the common part of every waiter.
+// A NotFound or retryable error of getter is retried, other errors stop the wait, and so does ctx.
+// conditionDesc names the awaited state in logs and errors, e.g. "cluster c1 to be running".
+// On timeout, the returned TimeoutError carries the last observed resource. WithFirstPollDelay and
+// WithStablePolls apply to every resource, and so does WithPlanMode.
+func WaitForResourceCondition[T any](ctx context.Context, getter func() (T, error), conditionDesc string, timeout time.Duration, condition func(T) (bool, error), opts ...WaitOption) error {
+	return waitForResource(ctx, getter, conditionDesc, scaleTimeout(timeout), condition, newWaitOptions(opts...))
+}
+
+// waitForResource is WaitForResourceCondition with the timeout scaled already.
+func waitForResource[T any](ctx context.Context, getter func() (T, error), conditionDesc string, timeout time.Duration, condition func(T) (bool, error), o *waitOptions) error {
+	if o.plan {
+		return planResource(ctx, getter, conditionDesc, condition)
+	}
+	framework.Logf("Waiting up to %v for %s", timeout, conditionDesc)
+	var (
+		lastError error
+		last      T
+		observed  bool
+		held      int
+	)
+	err := o.poll(poll, timeout, func() (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		obj, err := getter()
+		lastError = err
+		if err != nil {
+			return handleWaitingAPIError(err, true, "getting resource for %s", conditionDesc)
+		}
+		last, observed = obj, true
+		done, err := condition(obj)
+		if !done {
+			if err != nil {
+				framework.Logf("Error evaluating condition %s: %v", conditionDesc, err)
+			}
+			held = 0
+			return false, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		if held++; held < o.stablePolls {
+			framework.Logf("Satisfied condition %s for %d of %d polls", conditionDesc, held, o.stablePolls)
+			return false, nil
+		}
+		framework.Logf("Satisfied condition %s", conditionDesc)
+		return true, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if IsTimeout(err) && observed {
+		return TimeoutError(fmt.Sprintf("timed out while waiting for %s", conditionDesc), last)
+	}
+	if lastError != nil {
+		// If the last API call was an error.
+		err = lastError
+	}
+	return maybeTimeoutError(err, "waiting for %s", conditionDesc)
+}
+
+// notFoundError is a NotFound error of the API, for a resource missing in a list response.
+func notFoundError(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return &apierror.StatusError{Message: msg + " not found", Code: http.StatusNotFound}
+}
+
+// nilIfNotFound turns a NotFound error of getter into a nil resource, for waiting a resource to be gone.
+func nilIfNotFound[T any](getter func() (*T, error)) func() (*T, error) {
+	return func() (*T, error) {
+		obj, err := getter()
+		if apierror.IsNotFound(err) {
+			return nil, nil
+		}
+		return obj, err
+	}
+}
+
+// clusterGetter returns the getter of cluster clusterName.
+func clusterGetter(ctx context.Context, c *kc.Client, clusterName string) func() (*corev1.Cluster, error) {
+	return func() (*corev1.Cluster, error) {
+		clu, err := c.DescribeCluster(ctx, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if len(clu.Items) == 0 {
+			return nil, notFoundError("cluster %s", clusterName)
+		}
+		return clu.Items[0].DeepCopy(), nil
+	}
+}
+
+// backupGetter returns the getter of backup backupName of cluster clusterName.
+func backupGetter(ctx context.Context, c *kc.Client, clusterName, backupName string) func() (*corev1.Backup, error) {
+	return func() (*corev1.Backup, error) {
+		backups, err := c.ListBackupsWithCluster(ctx, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		for i := range backups.Items {
+			if backups.Items[i].Name == backupName {
+				return backups.Items[i].DeepCopy(), nil
+			}
+		}
+		return nil, notFoundError("backup %s of cluster %s", backupName, clusterName)
+	}
+}
+
+// operationGetter returns the getter of operation operationID.
+func operationGetter(ctx context.Context, c *kc.Client, operationID string) func() (*corev1.Operation, error) {
+	return func() (*corev1.Operation, error) {
+		operations, err := c.DescribeOperation(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+		if len(operations.Items) == 0 {
+			return nil, notFoundError("operation %s", operationID)
+		}
+		return operations.Items[0].DeepCopy(), nil
+	}
+}
@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/test/framework"
+)
+
+// TimeoutMultiplierEnv names the environment variable which scales the timeout of every
+// waiter built on WaitForResourceCondition, e.g. 2.5 on slow shared CI hardware.
+const TimeoutMultiplierEnv = "KC_E2E_TIMEOUT_MULTIPLIER"
+
+var (
+	timeoutMultiplierOnce sync.Once
+	timeoutMultiplier     = 1.0
+)
+
+// getTimeoutMultiplier reads TimeoutMultiplierEnv once, an unset or invalid value means 1.
+func getTimeoutMultiplier() float64 {
+	timeoutMultiplierOnce.Do(func() {
+		value := os.Getenv(TimeoutMultiplierEnv)
+		if value == "" {
+			return
+		}
+		multiplier, err := strconv.ParseFloat(value, 64)
+		if err != nil || multiplier <= 0 {
+			framework.Logf("Ignoring invalid %s=%q, must be a positive number", TimeoutMultiplierEnv, value)
+			return
+		}
+		timeoutMultiplier = multiplier
+	})
+	return timeoutMultiplier
+}
+
+// scaleTimeout applies the timeout multiplier to timeout, and logs the effective timeout if it's scaled.
+func scaleTimeout(timeout time.Duration) time.Duration {
+	multiplier := getTimeoutMultiplier()
+	if multiplier == 1 {
+		return timeout
+	}
+	scaled := time.Duration(float64(timeout) * multiplier)
+	framework.Logf("Scaling timeout %v by %s=%v to %v", timeout, TimeoutMultiplierEnv, multiplier, scaled)
+	return scaled
+}
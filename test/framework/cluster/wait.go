@@ -2,12 +2,17 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	apierror "github.com/kubeclipper/kubeclipper/pkg/errors"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
 	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
 	"github.com/kubeclipper/kubeclipper/test/framework"
@@ -16,6 +21,12 @@ import (
 const (
 	// poll is how often to poll clusters.
 	poll = 15 * time.Second
+	// defaultStallTimeout is how long a backup may report no progress before it's considered hung.
+	defaultStallTimeout = 5 * time.Minute
+	// DefaultMinProvisioningTime is how long a fresh cluster takes at least before it can be running.
+	DefaultMinProvisioningTime = 3 * time.Minute
+	// DefaultClusterLogInterval is how often WaitForClusterCondition logs the cluster while its phase is unchanged.
+	DefaultClusterLogInterval = 2 * time.Minute
 )
 
 type timeoutError struct {
@@ -27,11 +38,53 @@ func (e *timeoutError) Error() string {
 	return e.msg
 }
 
+// Details returns the objects observed when the wait timed out.
+func (e *timeoutError) Details() []interface{} {
+	return e.observedObjects
+}
+
+// MarshalJSON serializes the message along with the observed objects,
+// so that test harnesses can dump the captured state on timeout.
+func (e *timeoutError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message         string        `json:"message"`
+		ObservedObjects []interface{} `json:"observedObjects,omitempty"`
+	}{
+		Message:         e.msg,
+		ObservedObjects: e.observedObjects,
+	})
+}
+
 func TimeoutError(msg string, observedObjects ...interface{}) error {
 	return &timeoutError{
 		msg:             msg,
-		observedObjects: observedObjects,
+		observedObjects: deepCopyObjects(observedObjects),
+	}
+}
+
+// TimeoutDetails returns the objects observed by a timeout error, or false if err is not one.
+func TimeoutDetails(err error) ([]interface{}, bool) {
+	var te *timeoutError
+	if !errors.As(err, &te) {
+		return nil, false
+	}
+	return te.Details(), true
+}
+
+// deepCopyObjects copies the observed objects, so later mutations don't corrupt the snapshot.
+func deepCopyObjects(objs []interface{}) []interface{} {
+	if len(objs) == 0 {
+		return nil
 	}
+	copied := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		if o, ok := obj.(runtime.Object); ok {
+			copied = append(copied, o.DeepCopyObject())
+			continue
+		}
+		copied = append(copied, obj)
+	}
+	return copied
 }
 
 func IsTimeout(err error) bool {
@@ -41,93 +94,290 @@ func IsTimeout(err error) bool {
 	if _, ok := err.(*timeoutError); ok {
 		return true
 	}
+	if e, ok := err.(*MultiWaitError); ok {
+		return e.allTimeouts()
+	}
 	return false
 }
 
 type clusterCondition func(clu *corev1.Cluster) (bool, error)
 
-type backupCondition func(backup *corev1.Backup) (bool, error)
+// ClusterPollCallback is invoked on every poll with the fetched cluster and the elapsed time.
+type ClusterPollCallback func(clu *corev1.Cluster, elapsed time.Duration)
 
-// WaitForClusterCondition waits a cluster to be matched to the given condition.
-func WaitForClusterCondition(c *kc.Client, clusterName, conditionDesc string, timeout time.Duration, condition clusterCondition) error {
-	framework.Logf("Waiting up to %v for cluster %q to be %q", timeout, clusterName, conditionDesc)
-	var (
-		lastClusterError error
-		lastCluster      *corev1.Cluster
-		start            = time.Now()
-	)
-	err := wait.PollImmediate(poll, timeout, func() (bool, error) {
-		clu, err := c.DescribeCluster(context.TODO(), clusterName)
-		lastClusterError = err
-		if err != nil || len(clu.Items) == 0 {
-			return handleWaitingAPIError(err, true, "getting cluster %s", clusterName)
+type waitOptions struct {
+	clusterCallback ClusterPollCallback
+	firstPollDelay  time.Duration
+	stallTimeout    time.Duration
+	// failOnOperation stops waiting once an operation of the cluster created since operationsSince failed.
+	failOnOperation bool
+	operationsSince time.Time
+	// stablePolls is the number of consecutive polls the cluster condition must hold.
+	stablePolls int
+	// minProvisioningTime defers evaluating the cluster condition until the cluster is that old.
+	minProvisioningTime time.Duration
+	// plan evaluates the condition once without polling, see WithPlanMode.
+	plan bool
+	// clusterLogInterval throttles the per-poll log of WaitForClusterCondition, see WithClusterLogInterval.
+	clusterLogInterval time.Duration
+}
+
+// WaitOption configures optional behaviors of the waiters.
+type WaitOption func(o *waitOptions)
+
+// WithClusterPollCallback sets a callback invoked on every poll of WaitForClusterCondition,
+// e.g. to record phase transition timings. A panicking callback is recovered and logged.
+func WithClusterPollCallback(callback ClusterPollCallback) WaitOption {
+	return func(o *waitOptions) {
+		o.clusterCallback = callback
+	}
+}
+
+// WithFirstPollDelay delays the first poll by delay instead of checking the condition immediately,
+// useful when the resource is known to take minutes and the early transient phases are just noise.
+func WithFirstPollDelay(delay time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.firstPollDelay = delay
+	}
+}
+
+// WithStallTimeout sets how long WaitForBackupProgress tolerates no progress before failing.
+func WithStallTimeout(timeout time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.stallTimeout = timeout
+	}
+}
+
+// WithOperationFailureCheck makes WaitForClusterCondition also poll the cluster operations,
+// and return an OperationFailedError once an operation created since the given time failed,
+// instead of waiting the full timeout. Zero since means the time the wait starts.
+func WithOperationFailureCheck(since time.Time) WaitOption {
+	return func(o *waitOptions) {
+		o.failOnOperation = true
+		o.operationsSince = since
+	}
+}
+
+// WithStablePolls makes WaitForClusterCondition return only after the condition held for n consecutive polls,
+// so a cluster flapping into the phase while addons settle isn't reported as ready. n <= 1 means the first hit.
+func WithStablePolls(n int) WaitOption {
+	return func(o *waitOptions) {
+		o.stablePolls = n
+	}
+}
+
+// WithMinProvisioningTime makes WaitForClusterCondition wait, after the first poll, until the cluster
+// is min old by its creation timestamp before polling normally, since nothing of a fresh cluster is ready earlier.
+// The timeout is kept. min <= 0 means DefaultMinProvisioningTime.
+func WithMinProvisioningTime(min time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		if min <= 0 {
+			min = DefaultMinProvisioningTime
 		}
-		lastCluster = clu.Items[0].DeepCopy()
-		framework.Logf("Cluster %q: Phase=%q, Elapsed: %v",
-			clusterName, lastCluster.Status.Phase, time.Since(start))
+		o.minProvisioningTime = min
+	}
+}
 
-		if done, err := condition(lastCluster); done {
-			if err == nil {
-				framework.Logf("Cluster %q satisfied condition %q", clusterName, conditionDesc)
-			}
-			return true, err
-		} else if err != nil {
-			framework.Logf("Error evaluating cluster condition %s: %v", conditionDesc, err)
+// WithClusterLogInterval makes WaitForClusterCondition log the polled cluster only when its phase changes,
+// and at most once per interval while it's unchanged, DefaultClusterLogInterval by default.
+// The final poll is always logged. interval <= 0 logs every poll.
+func WithClusterLogInterval(interval time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.clusterLogInterval = interval
+	}
+}
+
+func newWaitOptions(opts ...WaitOption) *waitOptions {
+	o := &waitOptions{clusterLogInterval: DefaultClusterLogInterval}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
 		}
-		return false, nil
-	})
-	if err == nil {
-		return nil
 	}
-	if IsTimeout(err) && lastCluster != nil {
-		return TimeoutError(fmt.Sprintf("timed out while waiting for cluster %s to be %s", clusterName, conditionDesc),
-			lastCluster,
-		)
+	return o
+}
+
+// poll polls condition every interval until timeout, checking it immediately unless a first poll delay is set.
+func (o *waitOptions) poll(interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	if o.firstPollDelay <= 0 {
+		return wait.PollImmediate(interval, timeout, condition)
 	}
-	if lastClusterError != nil {
-		// If the last API call was an error.
-		err = lastClusterError
+	if o.firstPollDelay >= timeout {
+		return wait.Poll(timeout, timeout, condition)
 	}
-	return maybeTimeoutError(err, "waiting for cluster %s to be %s", clusterName, conditionDesc)
+	time.Sleep(o.firstPollDelay)
+	return wait.PollImmediate(interval, timeout-o.firstPollDelay, condition)
 }
 
-func WaitForBackupCondition(c *kc.Client, clusterName, backupName, conditionDesc string, timeout time.Duration, condition backupCondition) error {
-	framework.Logf("Waiting up to %v for backup %q to be %q", timeout, backupName, conditionDesc)
-	bp := &corev1.Backup{}
-	start := time.Now()
-	err := wait.PollImmediate(poll, timeout, func() (bool, error) {
-		backups, apiErr := c.ListBackupsWithCluster(context.TODO(), clusterName)
-		if apiErr != nil || len(backups.Items) == 0 {
-			return handleWaitingAPIError(apiErr, true, "getting backup %s", backupName)
+func (o *waitOptions) onClusterPoll(clu *corev1.Cluster, elapsed time.Duration) {
+	if o.clusterCallback == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			framework.Logf("Recovered from panic in cluster poll callback: %v", r)
 		}
-		bp = backups.Items[0].DeepCopy()
-		framework.Logf("Backup %q: Phase=%q, Elapsed: %v", backupName, bp.Status.ClusterBackupStatus, time.Since(start))
-		if done, conErr := condition(bp); done {
-			if conErr == nil {
-				framework.Logf("Backup %q satisfied condition %q", backupName, conditionDesc)
+	}()
+	o.clusterCallback(clu.DeepCopy(), elapsed)
+}
+
+// pollLogger throttles the per-poll log line of a waiter to the changes of the observed state,
+// and a heartbeat every interval while it's unchanged.
+type pollLogger struct {
+	interval time.Duration
+	state    string
+	last     time.Time
+}
+
+// due reports whether the poll observing state is logged, and records it if so.
+func (l *pollLogger) due(state string) bool {
+	now := time.Now()
+	if l.interval > 0 && !l.last.IsZero() && state == l.state && now.Sub(l.last) < l.interval {
+		return false
+	}
+	l.state, l.last = state, now
+	return true
+}
+
+type backupCondition func(backup *corev1.Backup) (bool, error)
+
+// WaitForClusterCondition waits a cluster to be matched to the given condition.
+func WaitForClusterCondition(c *kc.Client, clusterName, conditionDesc string, timeout time.Duration, condition clusterCondition, opts ...WaitOption) error {
+	o := newWaitOptions(opts...)
+	timeout = scaleTimeout(timeout)
+	var (
+		start       = time.Now()
+		provisioned = o.minProvisioningTime <= 0 || o.plan
+		logs        = &pollLogger{interval: o.clusterLogInterval}
+	)
+	return waitForResource(context.TODO(), clusterGetter(context.TODO(), c, clusterName),
+		fmt.Sprintf("cluster %s to be %s", clusterName, conditionDesc), timeout, func(clu *corev1.Cluster) (bool, error) {
+			logged := logs.due(string(clu.Status.Phase))
+			logPoll := func() {
+				framework.Logf("Cluster %q: Phase=%q, Elapsed: %v", clusterName, clu.Status.Phase, time.Since(start))
 			}
-			return true, conErr
-		} else if conErr != nil {
-			framework.Logf("Error evaluating backup condition %s: %v", conditionDesc, conErr)
-		}
-		return false, nil
+			if logged {
+				logPoll()
+			}
+			o.onClusterPoll(clu, time.Since(start))
+			if !provisioned {
+				provisioned = true
+				if rest := o.minProvisioningTime - time.Since(clu.CreationTimestamp.Time); rest > 0 {
+					if left := timeout - time.Since(start); rest > left {
+						rest = left
+					}
+					framework.Logf("Cluster %q is created %v ago, deferring polling for %v",
+						clusterName, time.Since(clu.CreationTimestamp.Time).Round(time.Second), rest.Round(time.Second))
+					time.Sleep(rest)
+					return false, nil
+				}
+			}
+			done, err := condition(clu)
+			if (done || err != nil) && !logged {
+				logPoll()
+			}
+			if done || !o.failOnOperation {
+				return done, err
+			}
+			if err != nil {
+				framework.Logf("Error evaluating cluster condition %s: %v", conditionDesc, err)
+			}
+			since := o.operationsSince
+			if since.IsZero() {
+				since = start
+			}
+			if opErr := failedOperation(c, clusterName, since); opErr != nil {
+				return true, opErr
+			}
+			return false, nil
+		}, o)
+}
+
+// WaitForClusterConditionOrEvent is like WaitForClusterCondition, and fails fast with an OperationFailedError
+// once an operation of the cluster started during the wait failed.
+func WaitForClusterConditionOrEvent(c *kc.Client, clusterName, conditionDesc string, timeout time.Duration, condition clusterCondition, opts ...WaitOption) error {
+	return WaitForClusterCondition(c, clusterName, conditionDesc, timeout, condition, append(opts, WithOperationFailureCheck(time.Time{}))...)
+}
+
+// OperationFailedError is returned by the waiters when a cluster operation failed.
+type OperationFailedError struct {
+	Operation *corev1.Operation
+	// StepID, Node and Message describe the failed step, empty if no step failure was recorded.
+	StepID  string
+	Node    string
+	Message string
+}
+
+func (e *OperationFailedError) Error() string {
+	if e.StepID == "" {
+		return fmt.Sprintf("operation %s failed", e.Operation.Name)
+	}
+	return fmt.Sprintf("operation %s failed at step %s on node %s: %s", e.Operation.Name, e.StepID, e.Node, e.Message)
+}
+
+// failedOperation returns an OperationFailedError if an operation of the cluster created since the given time failed.
+// API errors are logged and ignored, the cluster condition is still being waited.
+func failedOperation(c *kc.Client, clusterName string, since time.Time) error {
+	operations, err := c.ListOperations(context.TODO(), kc.Queries{
+		LabelSelector: fmt.Sprintf("%s=%s", common.LabelClusterName, clusterName),
 	})
-	if err == nil {
+	if err != nil {
+		framework.Logf("Error listing operations of cluster %s: %v", clusterName, err)
 		return nil
 	}
-	if IsTimeout(err) && bp != nil {
-		return TimeoutError(fmt.Sprintf("timed out while waiting for backup %s to be %s", backupName, conditionDesc), bp)
+	for i := range operations.Items {
+		op := &operations.Items[i]
+		if op.CreationTimestamp.Time.Before(since) || op.Status.Status != corev1.OperationStatusFailed {
+			continue
+		}
+		opErr := &OperationFailedError{Operation: op.DeepCopy()}
+		for _, condition := range op.Status.Conditions {
+			for _, status := range condition.Status {
+				if status.Status == corev1.StepStatusFailed {
+					opErr.StepID, opErr.Node, opErr.Message = condition.StepID, status.Node, status.Message
+				}
+			}
+		}
+		framework.Logf("Cluster %q: %v", clusterName, opErr)
+		return opErr
 	}
-	return maybeTimeoutError(err, "waiting for backup %s to be %s", backupName, conditionDesc)
+	return nil
 }
 
-func WaitForClusterRunning(c *kc.Client, clusterName string, timeout time.Duration) error {
-	return WaitForClusterCondition(c, clusterName, fmt.Sprintf("cluster %s running", clusterName), timeout, func(clu *corev1.Cluster) (bool, error) {
-		return clu.Status.Phase == corev1.ClusterRunning, nil
-	})
+// WaitForClusterConditionWithInitialDelay is like WaitForClusterCondition, and defers polling until the cluster
+// is minProvisioningTime old, DefaultMinProvisioningTime if it's not positive.
+func WaitForClusterConditionWithInitialDelay(c *kc.Client, clusterName, conditionDesc string, timeout, minProvisioningTime time.Duration, condition clusterCondition, opts ...WaitOption) error {
+	return WaitForClusterCondition(c, clusterName, conditionDesc, timeout, condition, append(opts, WithMinProvisioningTime(minProvisioningTime))...)
+}
+
+// WaitForClusterConditionWithCallback is like WaitForClusterCondition, and invokes callback on every poll.
+func WaitForClusterConditionWithCallback(c *kc.Client, clusterName, conditionDesc string, timeout time.Duration, condition clusterCondition, callback ClusterPollCallback) error {
+	return WaitForClusterCondition(c, clusterName, conditionDesc, timeout, condition, WithClusterPollCallback(callback))
+}
+
+func WaitForBackupCondition(c *kc.Client, clusterName, backupName, conditionDesc string, timeout time.Duration, condition backupCondition, opts ...WaitOption) error {
+	start := time.Now()
+	return WaitForResourceCondition(context.TODO(), backupGetter(context.TODO(), c, clusterName, backupName),
+		fmt.Sprintf("backup %s to be %s", backupName, conditionDesc), timeout, func(bp *corev1.Backup) (bool, error) {
+			framework.Logf("Backup %q: Phase=%q, Elapsed: %v", backupName, bp.Status.ClusterBackupStatus, time.Since(start))
+			return condition(bp)
+		}, opts...)
 }
 
-func WaitForClusterHealthy(c *kc.Client, clusterName string, timeout time.Duration) error {
+func WaitForClusterRunning(c *kc.Client, clusterName string, timeout time.Duration, opts ...WaitOption) error {
+	return WaitForClusterCondition(c, clusterName, fmt.Sprintf("cluster %s running", clusterName), timeout, clusterRunning, opts...)
+}
+
+// WaitForClusterRunningStable waits the cluster to stay running for polls consecutive polls.
+func WaitForClusterRunningStable(c *kc.Client, clusterName string, polls int, timeout time.Duration, opts ...WaitOption) error {
+	return WaitForClusterRunning(c, clusterName, timeout, append(opts, WithStablePolls(polls))...)
+}
+
+func clusterRunning(clu *corev1.Cluster) (bool, error) {
+	return clu.Status.Phase == corev1.ClusterRunning, nil
+}
+
+func WaitForClusterHealthy(c *kc.Client, clusterName string, timeout time.Duration, opts ...WaitOption) error {
 	return WaitForClusterCondition(c, clusterName, fmt.Sprintf("cluster %s healthy", clusterName), timeout, func(clu *corev1.Cluster) (bool, error) {
 		for _, item := range clu.Status.ComponentConditions {
 			if item.Name == "kubernetes" {
@@ -135,7 +385,7 @@ func WaitForClusterHealthy(c *kc.Client, clusterName string, timeout time.Durati
 			}
 		}
 		return false, nil
-	})
+	}, opts...)
 }
 
 // WaitForClusterNotFound returns an error if it takes too long for the pod to fully terminate.
@@ -143,56 +393,30 @@ func WaitForClusterHealthy(c *kc.Client, clusterName string, timeout time.Durati
 // api returns IsNotFound then the wait stops and nil is returned. If the Get api returns an error other
 // than "not found" then that error is returned and the wait stops.
 func WaitForClusterNotFound(c *kc.Client, clusterName string, timeout time.Duration) error {
-	var lastCluster *corev1.Cluster
-	err := wait.PollImmediate(poll, timeout, func() (done bool, err error) {
-		clu, err := c.DescribeCluster(context.TODO(), clusterName)
-		if apierror.IsNotFound(err) {
-			// done
-			return true, nil
-		}
-		if err != nil {
-			return handleWaitingAPIError(err, true, "getting cluster %s", clusterName)
-		}
-		if len(clu.Items) == 0 {
-			framework.Logf("unexpected problem, cluster not be nil at this time")
-			return false, nil
-		}
-		lastCluster = clu.Items[0].DeepCopy()
-		return false, nil
-	})
-	if err == nil {
-		return nil
-	}
-	if IsTimeout(err) && lastCluster != nil {
-		return TimeoutError(fmt.Sprintf("timeout while waiting for cluster %s to be Not Found", clusterName), lastCluster)
-	}
-	return maybeTimeoutError(err, "waiting for cluster %s not found", clusterName)
+	return WaitForResourceCondition(context.TODO(), nilIfNotFound(clusterGetter(context.TODO(), c, clusterName)),
+		fmt.Sprintf("cluster %s to be Not Found", clusterName), timeout, func(clu *corev1.Cluster) (bool, error) {
+			return clu == nil, nil
+		})
 }
 
 func WaitForComponentNotFound(c *kc.Client, clusterName string, timeout time.Duration) error {
-	var lastCluster *corev1.Cluster
-	err := wait.PollImmediate(poll, timeout, func() (done bool, err error) {
-		clu, err := c.DescribeCluster(context.TODO(), clusterName)
-		if err != nil {
-			return handleWaitingAPIError(err, true, "getting cluster %s", clusterName)
-		}
-		if len(clu.Items) == 0 {
-			framework.Logf("unexpected problem, cluster not be nil at this time")
-			return false, nil
-		}
-		lastCluster = clu.Items[0].DeepCopy()
-		if len(lastCluster.Addons) == 0 {
+	return WaitForResourceCondition(context.TODO(), clusterGetter(context.TODO(), c, clusterName),
+		fmt.Sprintf("cluster %s component to be Not Found", clusterName), timeout, func(clu *corev1.Cluster) (bool, error) {
+			return len(clu.Addons) == 0, nil
+		})
+}
+
+// WaitForAddonRemoved waits until the addon named addonName is absent from the cluster, other addons may remain.
+func WaitForAddonRemoved(c *kc.Client, clusterName, addonName string, timeout time.Duration) error {
+	return WaitForResourceCondition(context.TODO(), clusterGetter(context.TODO(), c, clusterName),
+		fmt.Sprintf("cluster %s addon %s to be removed", clusterName, addonName), timeout, func(clu *corev1.Cluster) (bool, error) {
+			for _, addon := range clu.Addons {
+				if addon.Name == addonName {
+					return false, nil
+				}
+			}
 			return true, nil
-		}
-		return false, nil
-	})
-	if err == nil {
-		return nil
-	}
-	if IsTimeout(err) && lastCluster != nil {
-		return TimeoutError(fmt.Sprintf("timeout while waiting for cluster %s component to be Not Found", clusterName), lastCluster)
-	}
-	return maybeTimeoutError(err, "waiting for cluster %s uninstall component not found", clusterName)
+		})
 }
 
 func WaitForBackupAvailable(c *kc.Client, clusterName, backupName string, timeout time.Duration) error {
@@ -207,26 +431,227 @@ func WaitForBackupAvailable(c *kc.Client, clusterName, backupName string, timeou
 	})
 }
 
-func WaitForBackupNotFound(c *kc.Client, clusterName, backupName string, timeout time.Duration) error {
-	bp := &corev1.Backup{}
-	err := wait.PollImmediate(poll, timeout, func() (done bool, err error) {
-		backups, waitErr := c.ListBackupsWithCluster(context.TODO(), clusterName)
-		if waitErr != nil {
-			return handleWaitingAPIError(waitErr, true, "getting backup %s", backupName)
+// BackupProgress is the last backup progress observed by WaitForBackupProgress.
+type BackupProgress struct {
+	Bytes         int64                      `json:"bytes"`
+	Phase         corev1.ClusterBackupStatus `json:"phase"`
+	LastChangedAt time.Time                  `json:"lastChangedAt"`
+}
+
+// WaitForBackupProgress waits the backup to report at least minProgress bytes,
+// and fails if the reported progress doesn't increase within the stall timeout (see WithStallTimeout).
+func WaitForBackupProgress(c *kc.Client, clusterName, backupName string, minProgress int64, timeout time.Duration, opts ...WaitOption) error {
+	stallTimeout := newWaitOptions(opts...).stallTimeout
+	if stallTimeout <= 0 {
+		stallTimeout = defaultStallTimeout
+	}
+	progress := BackupProgress{Bytes: -1, LastChangedAt: time.Now()}
+	conditionDesc := fmt.Sprintf("backup %s progress at least %d bytes", backupName, minProgress)
+	err := WaitForBackupCondition(c, clusterName, backupName, conditionDesc, timeout, func(backup *corev1.Backup) (bool, error) {
+		progress.Phase = backup.Status.ClusterBackupStatus
+		if backup.Status.BackupFileSize > progress.Bytes {
+			progress.Bytes = backup.Status.BackupFileSize
+			progress.LastChangedAt = time.Now()
 		}
-		if len(backups.Items) == 0 {
+		framework.Logf("Backup %q: Progress=%d bytes", backupName, progress.Bytes)
+		switch {
+		case backup.Status.ClusterBackupStatus == corev1.ClusterBackupError:
+			return true, fmt.Errorf("backup %s create failed at %d bytes", backup.Name, progress.Bytes)
+		case progress.Bytes >= minProgress || backup.Status.ClusterBackupStatus == corev1.ClusterBackupAvailable:
 			return true, nil
+		case time.Since(progress.LastChangedAt) > stallTimeout:
+			return true, fmt.Errorf("backup %s progress stalled at %d bytes for %v", backup.Name, progress.Bytes, stallTimeout)
 		}
-		bp = backups.Items[0].DeepCopy()
 		return false, nil
-	})
-	if err == nil {
-		return nil
+	}, opts...)
+	if IsTimeout(err) {
+		details, _ := TimeoutDetails(err)
+		return TimeoutError(fmt.Sprintf("%s, last observed progress %d bytes", err.Error(), progress.Bytes),
+			append(details, progress)...)
+	}
+	return err
+}
+
+// WaitForBackupNotFound waits the backup to be gone, see WaitForBackupDeleted.
+func WaitForBackupNotFound(c *kc.Client, clusterName, backupName string, timeout time.Duration, opts ...WaitOption) error {
+	_, err := WaitForBackupDeleted(c, clusterName, backupName, timeout, opts...)
+	return err
+}
+
+const (
+	// BackupPhaseDeleting is the phase recorded in BackupPhaseHistory for a backup with deletion timestamp.
+	BackupPhaseDeleting = "deleting"
+	// BackupPhaseGone is the phase recorded in BackupPhaseHistory for a backup missing in the backup list.
+	BackupPhaseGone = "gone"
+)
+
+// BackupPhaseHistory is the phase sequence of a backup observed by WaitForBackupDeleted,
+// consecutive observations of the same phase are recorded once.
+type BackupPhaseHistory []string
+
+func (h *BackupPhaseHistory) observe(bp *corev1.Backup) {
+	phase := BackupPhaseGone
+	if bp != nil {
+		phase = string(bp.Status.ClusterBackupStatus)
+		if bp.DeletionTimestamp != nil {
+			phase = BackupPhaseDeleting
+		}
+	}
+	if n := len(*h); n == 0 || (*h)[n-1] != phase {
+		*h = append(*h, phase)
+	}
+}
+
+func (h BackupPhaseHistory) String() string {
+	if len(h) == 0 {
+		return "none"
+	}
+	return strings.Join(h, " -> ")
+}
+
+// backupGoneConfirmPolls is the number of consecutive polls a backup not seen deleting must be missing to be gone.
+const backupGoneConfirmPolls = 2
+
+// BackupDeletion is how WaitForBackupDeleted saw the backup disappear.
+type BackupDeletion string
+
+const (
+	// BackupNeverExisted means the backup was not found since the first poll.
+	BackupNeverExisted BackupDeletion = "NeverExisted"
+	// BackupDeleted means the backup was observed, and then transitioned to gone.
+	BackupDeleted BackupDeletion = "Deleted"
+)
+
+// WaitForBackupDeleted waits the backup to be gone, and tracks its phase history, so that a backup missing
+// in one list response but listed again is not mistaken as deleted. The returned BackupDeletion tells
+// whether the backup existed at all. On timeout, the observed phase sequence is in the error message and
+// its details.
+func WaitForBackupDeleted(c *kc.Client, clusterName, backupName string, timeout time.Duration, opts ...WaitOption) (BackupDeletion, error) {
+	var (
+		history  BackupPhaseHistory
+		deleting bool
+		gone     int
+	)
+	err := WaitForResourceCondition(context.TODO(), nilIfNotFound(backupGetter(context.TODO(), c, clusterName, backupName)),
+		fmt.Sprintf("backup %s to be Not Found", backupName), timeout, func(bp *corev1.Backup) (bool, error) {
+			history.observe(bp)
+			framework.Logf("Backup %q: Phases=%s", backupName, history)
+			if bp != nil {
+				deleting = deleting || bp.DeletionTimestamp != nil
+				gone = 0
+				return false, nil
+			}
+			// gone after deleting is final, otherwise it may be a stale list response
+			gone++
+			return deleting || gone >= backupGoneConfirmPolls, nil
+		}, opts...)
+	if IsTimeout(err) {
+		details, _ := TimeoutDetails(err)
+		return "", TimeoutError(fmt.Sprintf("%s, observed phases %s", err.Error(), history), append(details, history)...)
 	}
-	if IsTimeout(err) && bp != nil {
-		return TimeoutError(fmt.Sprintf("timeout while waiting for backup %s to be Not Found", backupName), bp)
+	if err != nil {
+		return "", err
+	}
+	if len(history) == 1 {
+		return BackupNeverExisted, nil
+	}
+	return BackupDeleted, nil
+}
+
+// The phases of an operation step in OperationStepStatus.
+const (
+	OperationStepPending   = "pending"
+	OperationStepRunning   = "running"
+	OperationStepCompleted = "completed"
+	OperationStepFailed    = "failed"
+)
+
+// OperationStepStatus is the phase of an operation step observed by WaitForOperationStep.
+type OperationStepStatus struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+}
+
+// OperationStepStatuses is the status of every step of an operation, in step order.
+type OperationStepStatuses []OperationStepStatus
+
+func (s OperationStepStatuses) String() string {
+	phases := make([]string, 0, len(s))
+	for _, step := range s {
+		phases = append(phases, fmt.Sprintf("%s=%s", step.Name, step.Phase))
 	}
-	return maybeTimeoutError(err, "waiting for backup %s not found", backupName)
+	return "[" + strings.Join(phases, " ") + "]"
+}
+
+// operationStepStatuses returns the step statuses of op. A step with a recorded status is completed,
+// or failed if it failed on any node. The first step without one is running while the operation is running.
+func operationStepStatuses(op *corev1.Operation) OperationStepStatuses {
+	phases := make(map[string]string, len(op.Status.Conditions))
+	for _, condition := range op.Status.Conditions {
+		phase := OperationStepCompleted
+		for _, status := range condition.Status {
+			if status.Status == corev1.StepStatusFailed {
+				phase = OperationStepFailed
+			}
+		}
+		phases[condition.StepID] = phase
+	}
+	statuses := make(OperationStepStatuses, 0, len(op.Steps))
+	reached := true
+	for _, step := range op.Steps {
+		phase, ok := phases[step.ID]
+		switch {
+		case ok:
+		case reached && op.Status.Status == corev1.OperationStatusRunning:
+			phase, reached = OperationStepRunning, false
+		default:
+			phase, reached = OperationStepPending, false
+		}
+		statuses = append(statuses, OperationStepStatus{ID: step.ID, Name: step.Name, Phase: phase})
+	}
+	return statuses
+}
+
+// WaitForOperationStep waits the step stepName (name or ID) of the operation to be running or completed,
+// and fails with an OperationFailedError once the step failed. On timeout, the step statuses are in the
+// error message and its details.
+func WaitForOperationStep(c *kc.Client, operationID, stepName string, timeout time.Duration, opts ...WaitOption) error {
+	var statuses OperationStepStatuses
+	err := WaitForResourceCondition(context.TODO(), operationGetter(context.TODO(), c, operationID),
+		fmt.Sprintf("operation %s step %s to be reached", operationID, stepName), timeout, func(op *corev1.Operation) (bool, error) {
+			statuses = operationStepStatuses(op)
+			framework.Logf("Operation %q: Status=%q, Steps=%s", operationID, op.Status.Status, statuses)
+			for _, step := range statuses {
+				if step.Name != stepName && step.ID != stepName {
+					continue
+				}
+				switch step.Phase {
+				case OperationStepRunning, OperationStepCompleted:
+					return true, nil
+				case OperationStepFailed:
+					opErr := &OperationFailedError{Operation: op, StepID: step.ID}
+					for _, condition := range op.Status.Conditions {
+						for _, status := range condition.Status {
+							if condition.StepID == step.ID && status.Status == corev1.StepStatusFailed {
+								opErr.Node, opErr.Message = status.Node, status.Message
+							}
+						}
+					}
+					return true, opErr
+				}
+				if op.Status.Status == corev1.OperationStatusFailed || op.Status.Status == corev1.OperationStatusSuccessful {
+					return true, fmt.Errorf("operation %s is %s, step %s was never reached", operationID, op.Status.Status, stepName)
+				}
+				return false, nil
+			}
+			return true, fmt.Errorf("operation %s has no step %s", operationID, stepName)
+		}, opts...)
+	if IsTimeout(err) {
+		details, _ := TimeoutDetails(err)
+		return TimeoutError(fmt.Sprintf("%s, step statuses %s", err.Error(), statuses), append(details, statuses)...)
+	}
+	return err
 }
 
 func WaitForRecovery(c *kc.Client, clusterName string, timeout time.Duration) error {
@@ -240,6 +665,51 @@ func WaitForRecovery(c *kc.Client, clusterName string, timeout time.Duration) er
 	})
 }
 
+// VersionMismatchError is returned by WaitForRecoveryToVersion when the restored cluster
+// reports another kubernetes version than the backup was taken from.
+type VersionMismatchError struct {
+	Cluster  string
+	Expected string
+	Observed string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("cluster %s restored with kubernetes version %s, expected %s from backup", e.Cluster, e.Observed, e.Expected)
+}
+
+// WaitForRecoveryToVersion is like WaitForRecovery, and also asserts the recovered cluster reports
+// the kubernetes version recorded in the backup, returning a VersionMismatchError otherwise.
+// On timeout, both the expected and the last observed versions are in the error message.
+func WaitForRecoveryToVersion(c *kc.Client, clusterName, backupName string, timeout time.Duration) error {
+	backups, err := c.DescribeBackup(context.TODO(), backupName)
+	if err != nil {
+		return fmt.Errorf("error while getting backup %s: %w", backupName, err)
+	}
+	expected := backups.Items[0].Status.KubernetesVersion
+	if expected == "" {
+		return fmt.Errorf("backup %s records no kubernetes version", backupName)
+	}
+	var observed string
+	err = WaitForClusterCondition(c, clusterName, fmt.Sprintf("recovery successful to version %s", expected), timeout, func(clu *corev1.Cluster) (bool, error) {
+		observed = clu.KubernetesVersion
+		switch clu.Status.Phase {
+		case corev1.ClusterRunning:
+			if observed != expected {
+				return true, &VersionMismatchError{Cluster: clusterName, Expected: expected, Observed: observed}
+			}
+			return true, nil
+		case corev1.ClusterRestoreFailed:
+			return false, fmt.Errorf("recovery cluster %s failed", clusterName)
+		}
+		return false, nil
+	})
+	if IsTimeout(err) {
+		details, _ := TimeoutDetails(err)
+		return TimeoutError(fmt.Sprintf("%s, expected kubernetes version %s, observed %q", err.Error(), expected, observed), details...)
+	}
+	return err
+}
+
 // maybeTimeoutError returns a TimeoutError if err is a timeout. Otherwise, wrap err.
 // taskFormat and taskArgs should be the task being performed when the error occurred,
 // e.g. "waiting for pod to be running".
@@ -0,0 +1,445 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	apierror "github.com/kubeclipper/kubeclipper/pkg/errors"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/kc"
+)
+
+// testTimeout is far below the poll interval, so a wait checks its condition once and then times out.
+const testTimeout = 50 * time.Millisecond
+
+const (
+	testClustersPath   = "/api/core.kubeclipper.io/v1/clusters/"
+	testOperationsPath = "/api/core.kubeclipper.io/v1/operations"
+)
+
+var errCondition = errors.New("condition failed")
+
+// fakeResponse is the canned answer of the fake kc API to a path.
+type fakeResponse struct {
+	code int
+	obj  interface{}
+}
+
+// newFakeClient returns a kc client of a fake API serving responses by path, other paths are 404.
+func newFakeClient(t *testing.T, responses map[string]fakeResponse) *kc.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			resp = fakeResponse{code: http.StatusNotFound, obj: apierror.HTTPError{Code: http.StatusNotFound, Message: r.URL.Path + " not found"}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.code != 0 {
+			w.WriteHeader(resp.code)
+		}
+		_ = json.NewEncoder(w).Encode(resp.obj)
+	}))
+	t.Cleanup(server.Close)
+	c, err := kc.NewClientWithOpts(kc.WithHost(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testCluster(name string, phase corev1.ClusterPhase) fakeResponse {
+	clu := corev1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	clu.Status.Phase = phase
+	return fakeResponse{obj: clu}
+}
+
+func badRequest(msg string) fakeResponse {
+	return fakeResponse{code: http.StatusBadRequest, obj: apierror.HTTPError{Code: http.StatusBadRequest, Message: msg}}
+}
+
+// testKubeConfig returns a kubeconfig whose current context points to server.
+func testKubeConfig(t *testing.T, server string) []byte {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["test"] = &clientcmdapi.Cluster{Server: server}
+	config.Contexts["test"] = &clientcmdapi.Context{Cluster: "test"}
+	config.CurrentContext = "test"
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWaitForResourceCondition(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		getter      func() (string, error)
+		condition   func(string) (bool, error)
+		wantTimeout bool
+		wantErr     error
+		wantMessage string
+		wantDetails []interface{}
+	}{
+		{
+			name:      "condition met",
+			getter:    func() (string, error) { return "ready", nil },
+			condition: func(s string) (bool, error) { return s == "ready", nil },
+		},
+		{
+			name:        "timeout with the last observed resource",
+			getter:      func() (string, error) { return "pending", nil },
+			condition:   func(s string) (bool, error) { return s == "ready", nil },
+			wantTimeout: true,
+			wantDetails: []interface{}{"pending"},
+		},
+		{
+			name:      "condition error stops waiting",
+			getter:    func() (string, error) { return "failed", nil },
+			condition: func(string) (bool, error) { return true, errCondition },
+			wantErr:   errCondition,
+		},
+		{
+			name:        "condition error keeps waiting while not done",
+			getter:      func() (string, error) { return "failed", nil },
+			condition:   func(string) (bool, error) { return false, errCondition },
+			wantTimeout: true,
+			wantDetails: []interface{}{"failed"},
+		},
+		{
+			name: "non-retryable API error",
+			getter: func() (string, error) {
+				return "", &apierror.StatusError{Code: http.StatusBadRequest, Message: "bad request"}
+			},
+			condition:   func(string) (bool, error) { return true, nil },
+			wantMessage: "bad request",
+		},
+		{
+			name:      "context canceled",
+			ctx:       canceled,
+			getter:    func() (string, error) { return "ready", nil },
+			condition: func(string) (bool, error) { return true, nil },
+			wantErr:   context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			err := WaitForResourceCondition(ctx, tt.getter, "resource to be ready", testTimeout, tt.condition)
+			if IsTimeout(err) != tt.wantTimeout {
+				t.Fatalf("IsTimeout() = %v, want %v, err: %v", IsTimeout(err), tt.wantTimeout, err)
+			}
+			wantNil := !tt.wantTimeout && tt.wantErr == nil && tt.wantMessage == ""
+			if (err == nil) != wantNil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("expected error containing %q, got %v", tt.wantMessage, err)
+			}
+			if tt.wantDetails != nil {
+				details, ok := TimeoutDetails(err)
+				if !ok || len(details) != len(tt.wantDetails) || details[0] != tt.wantDetails[0] {
+					t.Errorf("expected timeout details %v, got %v", tt.wantDetails, details)
+				}
+			}
+		})
+	}
+}
+
+func TestClusterWaiters(t *testing.T) {
+	failedOp := corev1.Operation{
+		ObjectMeta: metav1.ObjectMeta{Name: "op1", CreationTimestamp: metav1.NewTime(time.Now().Add(time.Minute))},
+		Steps:      []corev1.Step{{ID: "s1", Name: "install"}},
+		Status: corev1.OperationStatus{
+			Status: corev1.OperationStatusFailed,
+			Conditions: []corev1.OperationCondition{{
+				StepID: "s1",
+				Status: []corev1.StepStatus{{Node: "n1", Status: corev1.StepStatusFailed, Message: "boom"}},
+			}},
+		},
+	}
+	tests := []struct {
+		name        string
+		responses   map[string]fakeResponse
+		wait        func(c *kc.Client) error
+		wantTimeout bool
+		wantMessage string
+		wantOpErr   bool
+	}{
+		{
+			name:      "cluster running",
+			responses: map[string]fakeResponse{testClustersPath + "c1": testCluster("c1", corev1.ClusterRunning)},
+			wait: func(c *kc.Client) error {
+				return WaitForClusterRunning(c, "c1", testTimeout)
+			},
+		},
+		{
+			name:      "cluster not running times out",
+			responses: map[string]fakeResponse{testClustersPath + "c1": testCluster("c1", corev1.ClusterInstalling)},
+			wait: func(c *kc.Client) error {
+				return WaitForClusterRunning(c, "c1", testTimeout)
+			},
+			wantTimeout: true,
+			wantMessage: "timed out while waiting for cluster c1",
+		},
+		{
+			name:      "API error stops waiting",
+			responses: map[string]fakeResponse{testClustersPath + "c1": badRequest("invalid cluster name")},
+			wait: func(c *kc.Client) error {
+				return WaitForClusterRunning(c, "c1", testTimeout)
+			},
+			wantMessage: "invalid cluster name",
+		},
+		{
+			name:      "recovery failed is retried until timeout",
+			responses: map[string]fakeResponse{testClustersPath + "c1": testCluster("c1", corev1.ClusterRestoreFailed)},
+			wait: func(c *kc.Client) error {
+				return WaitForRecovery(c, "c1", testTimeout)
+			},
+			wantTimeout: true,
+		},
+		{
+			name: "failed operation stops waiting",
+			responses: map[string]fakeResponse{
+				testClustersPath + "c1": testCluster("c1", corev1.ClusterInstalling),
+				testOperationsPath:      {obj: kc.OperationList{Items: []corev1.Operation{failedOp}}},
+			},
+			wait: func(c *kc.Client) error {
+				return WaitForClusterConditionOrEvent(c, "c1", "running", testTimeout, clusterRunning)
+			},
+			wantOpErr:   true,
+			wantMessage: "operation op1 failed at step s1 on node n1: boom",
+		},
+		{
+			name:      "failed operation step",
+			responses: map[string]fakeResponse{testOperationsPath + "/op1": {obj: failedOp}},
+			wait: func(c *kc.Client) error {
+				return WaitForOperationStep(c, "op1", "install", testTimeout)
+			},
+			wantOpErr:   true,
+			wantMessage: "failed at step s1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.wait(newFakeClient(t, tt.responses))
+			if IsTimeout(err) != tt.wantTimeout {
+				t.Fatalf("IsTimeout() = %v, want %v, err: %v", IsTimeout(err), tt.wantTimeout, err)
+			}
+			if !tt.wantTimeout && !tt.wantOpErr && tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var opErr *OperationFailedError
+			if errors.As(err, &opErr) != tt.wantOpErr {
+				t.Errorf("expected OperationFailedError %v, got %v", tt.wantOpErr, err)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("expected error containing %q, got %v", tt.wantMessage, err)
+			}
+		})
+	}
+}
+
+func TestWaitForAll(t *testing.T) {
+	ok := func() error { return nil }
+	timeout := func() error { return TimeoutError("timed out") }
+	failed := func() error { return errCondition }
+	tests := []struct {
+		name         string
+		waits        map[string]WaitFunc
+		wantErr      bool
+		wantTimeout  bool
+		wantTimeouts []string
+		wantHard     []string
+	}{
+		{
+			name:  "all succeed",
+			waits: map[string]WaitFunc{"a": ok, "b": ok},
+		},
+		{
+			name:         "all time out",
+			waits:        map[string]WaitFunc{"a": timeout, "b": timeout, "c": ok},
+			wantErr:      true,
+			wantTimeout:  true,
+			wantTimeouts: []string{"a", "b"},
+		},
+		{
+			name:         "timeout and error",
+			waits:        map[string]WaitFunc{"a": timeout, "b": failed},
+			wantErr:      true,
+			wantTimeouts: []string{"a"},
+			wantHard:     []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WaitForAll(tt.waits)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var multiErr *MultiWaitError
+			if !errors.As(err, &multiErr) {
+				t.Fatalf("expected MultiWaitError, got %v", err)
+			}
+			if IsTimeout(err) != tt.wantTimeout {
+				t.Errorf("IsTimeout() = %v, want %v", IsTimeout(err), tt.wantTimeout)
+			}
+			if got := strings.Join(multiErr.Timeouts(), ","); got != strings.Join(tt.wantTimeouts, ",") {
+				t.Errorf("Timeouts() = %s, want %v", got, tt.wantTimeouts)
+			}
+			hard := multiErr.HardErrors()
+			if len(hard) != len(tt.wantHard) {
+				t.Errorf("HardErrors() = %v, want %v", hard, tt.wantHard)
+			}
+			for _, name := range tt.wantHard {
+				if !errors.Is(hard[name], errCondition) {
+					t.Errorf("expected hard error of %s, got %v", name, hard[name])
+				}
+			}
+		})
+	}
+}
+
+func TestWaitForClustersRunning(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    map[string]fakeResponse
+		wantTimeouts []string
+		wantHard     []string
+	}{
+		{
+			name: "all running",
+			responses: map[string]fakeResponse{
+				testClustersPath + "c1": testCluster("c1", corev1.ClusterRunning),
+				testClustersPath + "c2": testCluster("c2", corev1.ClusterRunning),
+			},
+		},
+		{
+			name: "one times out",
+			responses: map[string]fakeResponse{
+				testClustersPath + "c1": testCluster("c1", corev1.ClusterRunning),
+				testClustersPath + "c2": testCluster("c2", corev1.ClusterInstalling),
+			},
+			wantTimeouts: []string{"c2"},
+		},
+		{
+			name: "hard failure replaces timeouts",
+			responses: map[string]fakeResponse{
+				testClustersPath + "c1": testCluster("c1", corev1.ClusterInstalling),
+				testClustersPath + "c2": badRequest("invalid cluster name"),
+			},
+			wantHard: []string{"c2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WaitForClustersRunning(newFakeClient(t, tt.responses), testTimeout, "c1", "c2")
+			if len(tt.wantTimeouts) == 0 && len(tt.wantHard) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var multiErr *MultiWaitError
+			if !errors.As(err, &multiErr) {
+				t.Fatalf("expected MultiWaitError, got %v", err)
+			}
+			if IsTimeout(err) != (len(tt.wantHard) == 0) {
+				t.Errorf("IsTimeout() = %v, err: %v", IsTimeout(err), err)
+			}
+			if got := strings.Join(multiErr.Timeouts(), ","); got != strings.Join(tt.wantTimeouts, ",") {
+				t.Errorf("Timeouts() = %s, want %v", got, tt.wantTimeouts)
+			}
+			for _, name := range tt.wantHard {
+				if _, ok := multiErr.HardErrors()[name]; !ok || len(multiErr.Errors) != 1 {
+					t.Errorf("expected only the hard error of %s, got %v", name, multiErr.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestWaitForClusterEndpointReachable(t *testing.T) {
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer apiserver.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	running := func(kubeConfig []byte) fakeResponse {
+		resp := testCluster("c1", corev1.ClusterRunning)
+		clu := resp.obj.(corev1.Cluster)
+		clu.KubeConfig = kubeConfig
+		resp.obj = clu
+		return resp
+	}
+	tests := []struct {
+		name        string
+		cluster     fakeResponse
+		wantMessage string
+	}{
+		{
+			name:    "reachable",
+			cluster: running(testKubeConfig(t, apiserver.URL)),
+		},
+		{
+			name:        "not running",
+			cluster:     testCluster("c1", corev1.ClusterInstalling),
+			wantMessage: `cluster is "Installing", not running`,
+		},
+		{
+			name:        "running without kubeconfig",
+			cluster:     running(nil),
+			wantMessage: "endpoint is unknown",
+		},
+		{
+			name:        "running but unreachable",
+			cluster:     running(testKubeConfig(t, down.URL)),
+			wantMessage: "apiserver " + down.URL + " is unreachable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClient(t, map[string]fakeResponse{testClustersPath + "c1": tt.cluster})
+			err := WaitForClusterEndpointReachable(c, "c1", testTimeout)
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !IsTimeout(err) || !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("expected timeout containing %q, got %v", tt.wantMessage, err)
+			}
+		})
+	}
+}